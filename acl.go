@@ -1,6 +1,9 @@
 package curator
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
@@ -34,6 +37,72 @@ func NewDefaultACLProvider() ACLProvider {
 	return &defaultACLProvider{OPEN_ACL_UNSAFE}
 }
 
+// Create an ACL provider using the given ACL list for all the paths
+func NewACLProvider(acls ...zk.ACL) ACLProvider {
+	return &defaultACLProvider{acls}
+}
+
+// Create the ACL granting the permissions to the clients of an address or a network, e.g. "10.0.0.1" or "10.0.0.0/8"
+func NewIPACL(perms int32, addr string) zk.ACL {
+	return zk.ACL{Perms: perms, Scheme: IP_SCHEME, ID: addr}
+}
+
+// Create the ACL granting the permissions to the clients connected over TLS with a certificate of the subject,
+// e.g. "CN=client,OU=dev,O=example", the servers must use the X509AuthenticationProvider
+func NewX509ACL(perms int32, subject string) zk.ACL {
+	return zk.ACL{Perms: perms, Scheme: X509_SCHEME, ID: subject}
+}
+
+// An ACL provider using the ACL list of the longest prefix of a path, the default ACL list for the other paths.
+//
+// The paths include the namespace, and a prefix matches the path itself and its descendants, e.g. /app matches /app/node but not /apple.
+type PathACLProvider struct {
+	defaultAcls []zk.ACL
+	prefixes    []string // sorted by length, the longest first
+	acls        map[string][]zk.ACL
+}
+
+func NewPathACLProvider(defaultAcls ...zk.ACL) *PathACLProvider {
+	return &PathACLProvider{defaultAcls: defaultAcls, acls: make(map[string][]zk.ACL)}
+}
+
+// Use the ACL list for the paths of the prefix, e.g. the ip and x509 ACLs of the services owning them
+func (p *PathACLProvider) ForPrefix(prefix string, acls ...zk.ACL) *PathACLProvider {
+	if len(prefix) > 1 {
+		prefix = strings.TrimSuffix(prefix, PATH_SEPARATOR)
+	}
+
+	if _, exists := p.acls[prefix]; !exists {
+		p.prefixes = append(p.prefixes, prefix)
+
+		sort.SliceStable(p.prefixes, func(i, j int) bool { return len(p.prefixes[i]) > len(p.prefixes[j]) })
+	}
+
+	p.acls[prefix] = acls
+
+	return p
+}
+
+func (p *PathACLProvider) GetDefaultAcl() []zk.ACL {
+	return p.defaultAcls
+}
+
+func (p *PathACLProvider) GetAclForPath(path string) []zk.ACL {
+	for _, prefix := range p.prefixes {
+		if path == prefix || prefix == PATH_SEPARATOR || strings.HasPrefix(path, prefix+PATH_SEPARATOR) {
+			return p.acls[prefix]
+		}
+	}
+
+	return p.defaultAcls
+}
+
+// Create an ACL provider granting the permissions to the digest user for all the paths, and the extra ACL list if any,
+// e.g. NewDigestACLProvider(zk.PermAll, "user", "password", READ_ACL_UNSAFE...) lets the others read the nodes
+func NewDigestACLProvider(perms int32, user, password string, extraAcls ...zk.ACL) ACLProvider {
+	return NewACLProvider(append([]zk.ACL{NewDigestACL(perms, user, password)}, extraAcls...)...)
+}
+
 type acling struct {
 	aclList     []zk.ACL
 	aclProvider ACLProvider
@@ -63,7 +132,7 @@ func (b *getACLBuilder) ForPath(givenPath string) ([]zk.ACL, error) {
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return nil, nil
 	} else {
@@ -78,30 +147,29 @@ func (b *getACLBuilder) pathInBackground(path string, givenPath string) {
 
 	acls, err := b.pathInForeground(path)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: GET_ACL,
-			err:       err,
-			path:      b.client.unfixForNamespace(path),
-			acls:      acls,
-			stat:      b.stat,
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: GET_ACL,
+		err:       err,
+		path:      b.client.unfixForNamespace(path),
+		acls:      acls,
+		stat:      b.stat,
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *getACLBuilder) pathInForeground(path string) ([]zk.ACL, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(GET_ACL.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, GET_ACL.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -121,6 +189,8 @@ func (b *getACLBuilder) pathInForeground(path string) ([]zk.ACL, error) {
 
 	acls, _ := result.([]zk.ACL)
 
+	trace.WithStat(b.stat).Commit(err)
+
 	return acls, err
 }
 
@@ -162,10 +232,14 @@ type setACLBuilder struct {
 }
 
 func (b *setACLBuilder) ForPath(givenPath string) (*zk.Stat, error) {
+	if err := b.client.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return nil, nil
 	} else {
@@ -180,30 +254,29 @@ func (b *setACLBuilder) pathInBackground(path string, givenPath string) {
 
 	stat, err := b.pathInForeground(path)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: SET_ACL,
-			err:       err,
-			path:      b.client.unfixForNamespace(path),
-			acls:      b.acling.getAclList(path),
-			stat:      stat,
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: SET_ACL,
+		err:       err,
+		path:      b.client.unfixForNamespace(path),
+		acls:      b.acling.getAclList(path),
+		stat:      stat,
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *setACLBuilder) pathInForeground(path string) (*zk.Stat, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(SET_ACL.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, SET_ACL.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -213,6 +286,8 @@ func (b *setACLBuilder) pathInForeground(path string) (*zk.Stat, error) {
 
 	stat, _ := result.(*zk.Stat)
 
+	trace.WithStat(stat).Commit(err)
+
 	return stat, err
 }
 
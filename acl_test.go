@@ -9,6 +9,31 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+func TestPathACLProvider(t *testing.T) {
+	serviceAcls := []zk.ACL{NewIPACL(zk.PermAll, "10.0.0.0/8"), NewX509ACL(zk.PermRead, "CN=service,O=example")}
+
+	assert.Equal(t, zk.ACL{Perms: zk.PermAll, Scheme: "ip", ID: "10.0.0.0/8"}, serviceAcls[0])
+	assert.Equal(t, zk.ACL{Perms: zk.PermRead, Scheme: "x509", ID: "CN=service,O=example"}, serviceAcls[1])
+
+	provider := NewPathACLProvider(CREATOR_ALL_ACL...).
+		ForPrefix("/app/", OPEN_ACL_UNSAFE...).
+		ForPrefix("/app/service", serviceAcls...)
+
+	assert.Equal(t, CREATOR_ALL_ACL, provider.GetDefaultAcl())
+	assert.Equal(t, CREATOR_ALL_ACL, provider.GetAclForPath("/other"))
+	assert.Equal(t, CREATOR_ALL_ACL, provider.GetAclForPath("/apple"))
+	assert.Equal(t, OPEN_ACL_UNSAFE, provider.GetAclForPath("/app"))
+	assert.Equal(t, OPEN_ACL_UNSAFE, provider.GetAclForPath("/app/other"))
+	assert.Equal(t, OPEN_ACL_UNSAFE, provider.GetAclForPath("/app/services"))
+	assert.Equal(t, serviceAcls, provider.GetAclForPath("/app/service"))
+	assert.Equal(t, serviceAcls, provider.GetAclForPath("/app/service/node"))
+
+	provider.ForPrefix("/", READ_ACL_UNSAFE...)
+
+	assert.Equal(t, READ_ACL_UNSAFE, provider.GetAclForPath("/other"))
+	assert.Equal(t, serviceAcls, provider.GetAclForPath("/app/service/node"))
+}
+
 type GetAclBuilderTestSuite struct {
 	mockContainerTestSuite
 }
@@ -1,6 +1,8 @@
 package curator
 
 import (
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
@@ -34,14 +36,24 @@ const AnyVersion int32 = -1
 type CreateMode int32
 
 const (
-	PERSISTENT            CreateMode = 0
-	PERSISTENT_SEQUENTIAL            = zk.FlagSequence
-	EPHEMERAL                        = zk.FlagEphemeral
-	EPHEMERAL_SEQUENTIAL             = zk.FlagEphemeral + zk.FlagSequence
+	PERSISTENT                     CreateMode = 0
+	PERSISTENT_SEQUENTIAL                     = zk.FlagSequence
+	EPHEMERAL                                 = zk.FlagEphemeral
+	EPHEMERAL_SEQUENTIAL                      = zk.FlagEphemeral + zk.FlagSequence
+	CONTAINER                                 = 4 // since ZooKeeper 3.5.1, deleted by the server when its last child is deleted, created as PERSISTENT on the older servers unless their version is detected
+	PERSISTENT_WITH_TTL                       = 5 // since ZooKeeper 3.5.3, deleted by the server when it isn't modified within the TTL and has no children
+	PERSISTENT_SEQUENTIAL_WITH_TTL            = 6 // since ZooKeeper 3.5.3, same as PERSISTENT_WITH_TTL with a sequence suffix
 )
 
+// The max TTL of a node, the TTL is stored in 40 bits of the ephemeral owner by the server
+const MAX_TTL = time.Duration(0xFFFFFFFFFF) * time.Millisecond
+
 func (m CreateMode) IsSequential() bool { return (m & zk.FlagSequence) == zk.FlagSequence }
-func (m CreateMode) IsEphemeral() bool  { return (m & zk.FlagEphemeral) == zk.FlagEphemeral }
+func (m CreateMode) IsEphemeral() bool  { return !m.IsTTL() && (m&zk.FlagEphemeral) == zk.FlagEphemeral }
+func (m CreateMode) IsContainer() bool  { return m == CONTAINER }
+func (m CreateMode) IsTTL() bool {
+	return m == PERSISTENT_WITH_TTL || m == PERSISTENT_SEQUENTIAL_WITH_TTL
+}
 
 // Called when the async background operation completes
 type BackgroundCallback func(client CuratorFramework, event CuratorEvent) error
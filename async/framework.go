@@ -0,0 +1,355 @@
+// Package async wraps a CuratorFramework so each operation returns a future instead of blocking,
+// which composes with select and errgroup.
+//
+//	client := async.Wrap(framework)
+//
+//	created := client.Create().CreatingParentsIfNeeded().ForPathWithData("/parent/node", data)
+//	children := client.GetChildren().ForPath("/parent")
+//
+//	select {
+//	case <-created.Done():
+//		path, err := created.Get()
+//		...
+//	case <-ctx.Done():
+//		...
+//	}
+//
+// The operations are run by the wrapped client in the background,
+// so they have the same namespace and retry semantics as the blocking operations.
+package async
+
+import (
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A CuratorFramework whose operations return futures
+type AsyncCuratorFramework struct {
+	client curator.CuratorFramework
+}
+
+// Wrap the client so its operations return futures
+func Wrap(client curator.CuratorFramework) *AsyncCuratorFramework {
+	return &AsyncCuratorFramework{client}
+}
+
+// Return the wrapped client
+func (f *AsyncCuratorFramework) Unwrap() curator.CuratorFramework {
+	return f.client
+}
+
+// Start an async create builder
+func (f *AsyncCuratorFramework) Create() *AsyncCreateBuilder {
+	return &AsyncCreateBuilder{f.client.Create()}
+}
+
+// Start an async delete builder
+func (f *AsyncCuratorFramework) Delete() *AsyncDeleteBuilder {
+	return &AsyncDeleteBuilder{f.client.Delete()}
+}
+
+// Start an async exists builder
+func (f *AsyncCuratorFramework) CheckExists() *AsyncExistsBuilder {
+	return &AsyncExistsBuilder{f.client.CheckExists()}
+}
+
+// Start an async get data builder
+func (f *AsyncCuratorFramework) GetData() *AsyncGetDataBuilder {
+	return &AsyncGetDataBuilder{f.client.GetData()}
+}
+
+// Start an async set data builder
+func (f *AsyncCuratorFramework) SetData() *AsyncSetDataBuilder {
+	return &AsyncSetDataBuilder{f.client.SetData()}
+}
+
+// Start an async get children builder
+func (f *AsyncCuratorFramework) GetChildren() *AsyncGetChildrenBuilder {
+	return &AsyncGetChildrenBuilder{f.client.GetChildren()}
+}
+
+// Flush the channel between the server and the leader for the node
+func (f *AsyncCuratorFramework) Sync(path string) *StringFuture {
+	future := &StringFuture{Future: newFuture()}
+
+	go func() {
+		value, err := f.client.Sync().ForPath(path)
+
+		future.value = value
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Commit the operations in a transaction
+func (f *AsyncCuratorFramework) Transaction(operations ...curator.CuratorOp) *TransactionFuture {
+	future := &TransactionFuture{Future: newFuture()}
+
+	go func() {
+		results, err := f.client.Transaction().ForOperations(operations...)
+
+		future.results = results
+		future.complete(err)
+	}()
+
+	return future
+}
+
+type AsyncCreateBuilder struct {
+	builder curator.CreateBuilder
+}
+
+// Create the node with the default data of the client
+func (b *AsyncCreateBuilder) ForPath(path string) *StringFuture {
+	return b.create(func() (string, error) { return b.builder.ForPath(path) })
+}
+
+// Create the node with the given data
+func (b *AsyncCreateBuilder) ForPathWithData(path string, payload []byte) *StringFuture {
+	return b.create(func() (string, error) { return b.builder.ForPathWithData(path, payload) })
+}
+
+func (b *AsyncCreateBuilder) create(create func() (string, error)) *StringFuture {
+	future := &StringFuture{Future: newFuture()}
+
+	go func() {
+		value, err := create()
+
+		future.value = value
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Create any parent nodes
+func (b *AsyncCreateBuilder) CreatingParentsIfNeeded() *AsyncCreateBuilder {
+	b.builder = b.builder.CreatingParentsIfNeeded()
+
+	return b
+}
+
+// Create any parent nodes as containers
+func (b *AsyncCreateBuilder) CreatingParentContainersIfNeeded() *AsyncCreateBuilder {
+	b.builder = b.builder.CreatingParentContainersIfNeeded()
+
+	return b
+}
+
+// Protect the node against the connection loss, with a GUID in its name
+func (b *AsyncCreateBuilder) WithProtection() *AsyncCreateBuilder {
+	b.builder = b.builder.WithProtection()
+
+	return b
+}
+
+// Set the data of the node if it already exists
+func (b *AsyncCreateBuilder) OrSetData() *AsyncCreateBuilder {
+	b.builder = b.builder.OrSetData()
+
+	return b
+}
+
+// Succeed if the node was created by a retry of the operation
+func (b *AsyncCreateBuilder) Idempotent() *AsyncCreateBuilder {
+	b.builder = b.builder.Idempotent()
+
+	return b
+}
+
+// Set a create mode - the default is CreateMode.PERSISTENT
+func (b *AsyncCreateBuilder) WithMode(mode curator.CreateMode) *AsyncCreateBuilder {
+	b.builder = b.builder.WithMode(mode)
+
+	return b
+}
+
+// Set the time to live of a TTL node
+func (b *AsyncCreateBuilder) WithTTL(ttl time.Duration) *AsyncCreateBuilder {
+	b.builder = b.builder.WithTTL(ttl)
+
+	return b
+}
+
+// Set an ACL list
+func (b *AsyncCreateBuilder) WithACL(acls ...zk.ACL) *AsyncCreateBuilder {
+	b.builder = b.builder.WithACL(acls...)
+
+	return b
+}
+
+type AsyncDeleteBuilder struct {
+	builder curator.DeleteBuilder
+}
+
+// Delete the node
+func (b *AsyncDeleteBuilder) ForPath(path string) *Future {
+	future := newFuture()
+
+	go func() {
+		future.complete(b.builder.ForPath(path))
+	}()
+
+	return future
+}
+
+// Delete the children of the node first
+func (b *AsyncDeleteBuilder) DeletingChildrenIfNeeded() *AsyncDeleteBuilder {
+	b.builder = b.builder.DeletingChildrenIfNeeded()
+
+	return b
+}
+
+// Delete the node only if its version matches
+func (b *AsyncDeleteBuilder) WithVersion(version int32) *AsyncDeleteBuilder {
+	b.builder = b.builder.WithVersion(version)
+
+	return b
+}
+
+// Succeed if the node was deleted by a retry of the operation
+func (b *AsyncDeleteBuilder) Idempotent() *AsyncDeleteBuilder {
+	b.builder = b.builder.Idempotent()
+
+	return b
+}
+
+// Succeed if the node doesn't exist
+func (b *AsyncDeleteBuilder) Quietly() *AsyncDeleteBuilder {
+	b.builder = b.builder.Quietly()
+
+	return b
+}
+
+type AsyncExistsBuilder struct {
+	builder curator.CheckExistsBuilder
+}
+
+// Check the node, the stat is nil if the node doesn't exist
+func (b *AsyncExistsBuilder) ForPath(path string) *StatFuture {
+	future := &StatFuture{Future: newFuture()}
+
+	go func() {
+		stat, err := b.builder.ForPath(path)
+
+		future.stat = stat
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Call the function when the node changes
+func (b *AsyncExistsBuilder) UsingWatcherFunc(fn func(event zk.Event)) *AsyncExistsBuilder {
+	b.builder = b.builder.UsingWatcherFunc(fn)
+
+	return b
+}
+
+type AsyncGetDataBuilder struct {
+	builder curator.GetDataBuilder
+}
+
+// Get the data of the node
+func (b *AsyncGetDataBuilder) ForPath(path string) *DataFuture {
+	future := &DataFuture{Future: newFuture()}
+
+	go func() {
+		data, err := b.builder.StoringStatIn(&future.stat).ForPath(path)
+
+		future.data = data
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Decompress the data of the node
+func (b *AsyncGetDataBuilder) Decompressed() *AsyncGetDataBuilder {
+	b.builder = b.builder.Decompressed()
+
+	return b
+}
+
+// Call the function when the node changes
+func (b *AsyncGetDataBuilder) UsingWatcherFunc(fn func(event zk.Event)) *AsyncGetDataBuilder {
+	b.builder = b.builder.UsingWatcherFunc(fn)
+
+	return b
+}
+
+type AsyncSetDataBuilder struct {
+	builder curator.SetDataBuilder
+}
+
+// Set the node to the default data of the client
+func (b *AsyncSetDataBuilder) ForPath(path string) *StatFuture {
+	return b.set(func() (*zk.Stat, error) { return b.builder.ForPath(path) })
+}
+
+// Set the data of the node
+func (b *AsyncSetDataBuilder) ForPathWithData(path string, payload []byte) *StatFuture {
+	return b.set(func() (*zk.Stat, error) { return b.builder.ForPathWithData(path, payload) })
+}
+
+func (b *AsyncSetDataBuilder) set(set func() (*zk.Stat, error)) *StatFuture {
+	future := &StatFuture{Future: newFuture()}
+
+	go func() {
+		stat, err := set()
+
+		future.stat = stat
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Set the data only if the version of the node matches
+func (b *AsyncSetDataBuilder) WithVersion(version int32) *AsyncSetDataBuilder {
+	b.builder = b.builder.WithVersion(version)
+
+	return b
+}
+
+// Succeed if the data was set by a retry of the operation
+func (b *AsyncSetDataBuilder) Idempotent() *AsyncSetDataBuilder {
+	b.builder = b.builder.Idempotent()
+
+	return b
+}
+
+// Compress the data
+func (b *AsyncSetDataBuilder) Compressed() *AsyncSetDataBuilder {
+	b.builder = b.builder.Compressed()
+
+	return b
+}
+
+type AsyncGetChildrenBuilder struct {
+	builder curator.GetChildrenBuilder
+}
+
+// Get the children of the node
+func (b *AsyncGetChildrenBuilder) ForPath(path string) *ChildrenFuture {
+	future := &ChildrenFuture{Future: newFuture()}
+
+	go func() {
+		children, err := b.builder.StoringStatIn(&future.stat).ForPath(path)
+
+		future.children = children
+		future.complete(err)
+	}()
+
+	return future
+}
+
+// Call the function when the children of the node change
+func (b *AsyncGetChildrenBuilder) UsingWatcherFunc(fn func(event zk.Event)) *AsyncGetChildrenBuilder {
+	b.builder = b.builder.UsingWatcherFunc(fn)
+
+	return b
+}
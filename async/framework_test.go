@@ -0,0 +1,111 @@
+package async
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAsyncCuratorFramework(t *testing.T) {
+	Convey("Given an async client", t, func() {
+		mocks := zktest.NewMockBuilder(t)
+
+		framework := mocks.Build()
+
+		So(framework.Start(), ShouldBeNil)
+
+		client := Wrap(framework)
+
+		So(client.Unwrap(), ShouldEqual, framework)
+
+		Convey("When create a node", func() {
+			mocks.Conn.On("Create", "/node", []byte("data"), int32(curator.EPHEMERAL), curator.OPEN_ACL_UNSAFE).Return("/node", nil).Once()
+
+			future := client.Create().WithMode(curator.EPHEMERAL).ForPathWithData("/node", []byte("data"))
+
+			<-future.Done()
+
+			path, err := future.Get()
+
+			So(path, ShouldEqual, "/node")
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When get the data and children of a node", func() {
+			mocks.Conn.On("Get", "/node").Return([]byte("data"), &zk.Stat{Version: 3}, nil).Once()
+			mocks.Conn.On("Children", "/node").Return([]string{"child"}, &zk.Stat{NumChildren: 1}, nil).Once()
+
+			data := client.GetData().ForPath("/node")
+			children := client.GetChildren().ForPath("/node")
+
+			value, stat, err := data.Get()
+
+			So(string(value), ShouldEqual, "data")
+			So(stat.Version, ShouldEqual, 3)
+			So(err, ShouldBeNil)
+
+			names, stat, err := children.Get()
+
+			So(names, ShouldResemble, []string{"child"})
+			So(stat.NumChildren, ShouldEqual, 1)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When set and check a node", func() {
+			mocks.Conn.On("Set", "/node", []byte("data"), int32(3)).Return(&zk.Stat{Version: 4}, nil).Once()
+			mocks.Conn.On("Exists", "/node").Return(true, &zk.Stat{Version: 4}, nil).Once()
+
+			stat, err := client.SetData().WithVersion(3).ForPathWithData("/node", []byte("data")).Get()
+
+			So(stat.Version, ShouldEqual, 4)
+			So(err, ShouldBeNil)
+
+			stat, err = client.CheckExists().ForPath("/node").Get()
+
+			So(stat.Version, ShouldEqual, 4)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When delete a missing node", func() {
+			mocks.Conn.On("Delete", "/node", curator.AnyVersion).Return(zk.ErrNoNode).Once()
+
+			So(client.Delete().ForPath("/node").Err(), ShouldEqual, zk.ErrNoNode)
+
+			mocks.Check(t)
+		})
+
+		Convey("When the operation is slow", func() {
+			blocked := make(chan time.Time)
+
+			mocks.Conn.On("Sync", "/node").WaitUntil(blocked).Return("/node", nil).Once()
+
+			future := client.Sync("/node")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+
+			defer cancel()
+
+			Convey("Wait should return when the context is done", func() {
+				So(future.Wait(ctx), ShouldEqual, context.DeadlineExceeded)
+
+				close(blocked)
+
+				path, err := future.Get()
+
+				So(path, ShouldEqual, "/node")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package async
+
+import (
+	"context"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The result of an asynchronous operation, which is ready once Done() is closed.
+//
+// A future composes with select on Done(), and with errgroup through Err():
+//
+//	g.Go(client.Delete().ForPath("/node").Err)
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) complete(err error) {
+	f.err = err
+
+	close(f.done)
+}
+
+// Return a channel closed once the operation is done
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Block until the operation is done and return its error
+func (f *Future) Err() error {
+	<-f.done
+
+	return f.err
+}
+
+// Block until the operation or the context is done, return the error of the context if it is done first
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// The future of an operation returning a path, e.g. the path of the created node
+type StringFuture struct {
+	*Future
+
+	value string
+}
+
+// Block until the operation is done and return its result
+func (f *StringFuture) Get() (string, error) {
+	err := f.Err()
+
+	return f.value, err
+}
+
+// The future of an operation returning the stat of a node
+type StatFuture struct {
+	*Future
+
+	stat *zk.Stat
+}
+
+// Block until the operation is done and return its result
+func (f *StatFuture) Get() (*zk.Stat, error) {
+	err := f.Err()
+
+	return f.stat, err
+}
+
+// The future of an operation returning the data and stat of a node
+type DataFuture struct {
+	*Future
+
+	data []byte
+	stat zk.Stat
+}
+
+// Block until the operation is done and return its result
+func (f *DataFuture) Get() ([]byte, *zk.Stat, error) {
+	if err := f.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return f.data, &f.stat, nil
+}
+
+// The future of an operation returning the children and stat of a node
+type ChildrenFuture struct {
+	*Future
+
+	children []string
+	stat     zk.Stat
+}
+
+// Block until the operation is done and return its result
+func (f *ChildrenFuture) Get() ([]string, *zk.Stat, error) {
+	if err := f.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return f.children, &f.stat, nil
+}
+
+// The future of a transaction returning the results of its operations
+type TransactionFuture struct {
+	*Future
+
+	results []curator.TransactionResult
+}
+
+// Block until the transaction is done and return its results
+func (f *TransactionFuture) Get() ([]curator.TransactionResult, error) {
+	err := f.Err()
+
+	return f.results, err
+}
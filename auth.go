@@ -1,6 +1,44 @@
 package curator
 
+import (
+	"crypto/sha1"
+	"encoding/base64"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const (
+	DIGEST_SCHEME = "digest" // the scheme of the digest authentication provider
+	IP_SCHEME     = "ip"     // the scheme of the clients by their address
+	X509_SCHEME   = "x509"   // the scheme of the clients by the subject of their TLS certificate
+	SUPER_USER    = "super"  // the user that bypasses all ACL checks when the server is configured with a super digest
+)
+
 type AuthInfo struct {
 	Scheme string
 	Auth   []byte
 }
+
+// Create the digest authorization for the given user and password
+func NewDigestAuthInfo(user, password string) AuthInfo {
+	return AuthInfo{DIGEST_SCHEME, []byte(user + ":" + password)}
+}
+
+// Generate the digest of the given user and password, in the form "user:base64(sha1(user:password))"
+// as used by the digest ACL scheme.
+func GenerateDigest(user, password string) string {
+	digest := sha1.Sum([]byte(user + ":" + password))
+
+	return user + ":" + base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// Create the ACL granting the permissions to the given user and password, with the digest scheme
+func NewDigestACL(perms int32, user, password string) zk.ACL {
+	return zk.ACL{Perms: perms, Scheme: DIGEST_SCHEME, ID: GenerateDigest(user, password)}
+}
+
+// Generate the super user digest for the given password,
+// the server must be started with -Dzookeeper.DigestAuthenticationProvider.superDigest=<digest> to accept it.
+func GenerateSuperDigest(password string) string {
+	return GenerateDigest(SUPER_USER, password)
+}
@@ -0,0 +1,76 @@
+package curator
+
+import (
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDigest(t *testing.T) {
+	assert.Equal(t, "super:lK75jTNcA+U9vtVEw5vB51mj/w4=", GenerateSuperDigest("secret"))
+	assert.Equal(t, zk.DigestACL(zk.PermAll, "user", "password")[0].ID, GenerateDigest("user", "password"))
+}
+
+func TestDigestACL(t *testing.T) {
+	assert.Equal(t, zk.DigestACL(zk.PermRead, "user", "password")[0], NewDigestACL(zk.PermRead, "user", "password"))
+
+	provider := NewDigestACLProvider(zk.PermAll, "user", "password", READ_ACL_UNSAFE...)
+
+	acls := append([]zk.ACL{NewDigestACL(zk.PermAll, "user", "password")}, READ_ACL_UNSAFE...)
+
+	assert.Equal(t, acls, provider.GetDefaultAcl())
+	assert.Equal(t, acls, provider.GetAclForPath("/node"))
+
+	builder := (&CuratorFrameworkBuilder{}).DigestAuthorization("user", "password")
+
+	assert.Equal(t, []AuthInfo{{DIGEST_SCHEME, []byte("user:password")}}, builder.AuthInfos)
+}
+
+func TestSuperUser(t *testing.T) {
+	assert.Panics(t, func() {
+		(&CuratorFrameworkBuilder{}).ConnectString("connStr").SuperUser("secret").Build()
+	})
+
+	conn := &mockConn{log: t.Logf}
+	dialer := &mockZookeeperDialer{log: t.Logf}
+
+	builder := &CuratorFrameworkBuilder{ZookeeperDialer: dialer, AllowSuperUser: true}
+
+	client := builder.ConnectString("connStr").Authorization(DIGEST_SCHEME, []byte("user:password")).SuperUser("secret").Build()
+
+	assert.Equal(t, []AuthInfo{NewDigestAuthInfo("user", "password")}, builder.AuthInfos)
+
+	dialer.On("Dial", "connStr", DEFAULT_SESSION_TIMEOUT, false).Return(conn, nil, nil).Once()
+	conn.On("AddAuth", DIGEST_SCHEME, []byte("user:password")).Return(nil).Once()
+	conn.On("AddAuth", DIGEST_SCHEME, []byte("super:secret")).Return(nil).Once()
+	conn.On("Close").Return().Once()
+
+	assert.NoError(t, client.Start())
+	assert.NoError(t, client.Close())
+
+	conn.AssertExpectations(t)
+	dialer.AssertExpectations(t)
+}
+
+func TestAuthInfos(t *testing.T) {
+	conn := &mockConn{log: t.Logf}
+	dialer := &mockZookeeperDialer{log: t.Logf}
+
+	builder := &CuratorFrameworkBuilder{ZookeeperDialer: dialer}
+
+	client := builder.ConnectString("connStr").Authorizations(NewDigestAuthInfo("user", "password"), AuthInfo{"ip", []byte("127.0.0.1")}).Build()
+
+	// the authorizations are applied again on the new connection
+	dialer.On("Dial", "connStr", DEFAULT_SESSION_TIMEOUT, false).Return(conn, nil, nil).Twice()
+	conn.On("AddAuth", DIGEST_SCHEME, []byte("user:password")).Return(nil).Twice()
+	conn.On("AddAuth", "ip", []byte("127.0.0.1")).Return(nil).Twice()
+	conn.On("Close").Return().Twice()
+
+	assert.NoError(t, client.Start())
+	assert.NoError(t, client.(*curatorFramework).client.state.reset())
+	assert.NoError(t, client.Close())
+
+	conn.AssertExpectations(t)
+	dialer.AssertExpectations(t)
+}
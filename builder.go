@@ -1,6 +1,8 @@
 package curator
 
 import (
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
@@ -18,11 +20,46 @@ type CreateBuilder interface {
 	// Causes any parent nodes to get created if they haven't already been
 	CreatingParentsIfNeeded() CreateBuilder
 
+	// ParentsCreatable[T]
+	//
+	// Causes any parent nodes to get created as containers if they haven't already been,
+	// falls back to persistent parents if the server doesn't support containers
+	CreatingParentContainersIfNeeded() CreateBuilder
+
+	// Protectable[T]
+	//
+	// Prefix the node name with a GUID, so the node can be found after a connection loss.
+	//
+	// A sequential node may be created on the server even if the connection is lost before the
+	// created name is returned to the client, the retried create would then leave an orphaned node,
+	// e.g. a lock which would never be released. With protection, the children of the parent are searched
+	// for the GUID before the create is retried, and the found node is returned instead.
+	WithProtection() CreateBuilder
+
+	// Set the data of the node if it already exists, instead of failing with zk.ErrNodeExists
+	OrSetData() CreateBuilder
+
+	// Idempotentable[T]
+	//
+	// Succeed if a retried create finds the node already created with the same data
+	Idempotent() CreateBuilder
+
+	// Quietly[T]
+	//
+	// Succeed if the node already exists, keeping its data
+	Quietly() CreateBuilder
+
 	// CreateModable[T]
 	//
 	// Set a create mode - the default is CreateMode.PERSISTENT
 	WithMode(mode CreateMode) CreateBuilder
 
+	// TTLable[T]
+	//
+	// Set the TTL of the node created with PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL,
+	// the server deletes the node if it isn't modified within the TTL and has no children
+	WithTTL(ttl time.Duration) CreateBuilder
+
 	// ACLable[T]
 	//
 	// Set an ACL list
@@ -33,6 +70,9 @@ type CreateBuilder interface {
 	// Cause the data to be compressed using the configured compression provider
 	Compressed() CreateBuilder
 
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() CreateBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -54,6 +94,11 @@ type CheckExistsBuilder interface {
 	// Commit the currently building operation using the given path
 	ForPath(path string) (*zk.Stat, error)
 
+	// Statable[T]
+	//
+	// Have the operation fill the provided stat object if the node exists
+	StoringStatIn(stat *zk.Stat) CheckExistsBuilder
+
 	// Watchable[T]
 	//
 	// Have the operation set a watch
@@ -62,6 +107,13 @@ type CheckExistsBuilder interface {
 	// Set a watcher for the operation
 	UsingWatcher(watcher Watcher) CheckExistsBuilder
 
+	// Deliver the watched event to the function, with the path relative to the namespace of the client
+	UsingWatcherFunc(fn func(event zk.Event)) CheckExistsBuilder
+
+	// Send the watched event to the channel, with the path relative to the namespace of the client.
+	// The following events of the path are delivered once it is received, see DispatchMode.
+	UsingWatcherChannel(events chan<- zk.Event) CheckExistsBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -85,7 +137,8 @@ type DeleteBuilder interface {
 
 	// ChildrenDeletable[T]
 	//
-	// Will also delete children if they exist.
+	// Will also delete children if they exist. The children created concurrently are deleted too,
+	// up to DELETE_CHILDREN_MAX_RETRIES times before failing with zk.ErrNotEmpty.
 	DeletingChildrenIfNeeded() DeleteBuilder
 
 	// Versionable[T]
@@ -93,6 +146,16 @@ type DeleteBuilder interface {
 	// Use the given version (the default is -1)
 	WithVersion(version int32) DeleteBuilder
 
+	// Idempotentable[T]
+	//
+	// Succeed if a retried delete finds the node already deleted
+	Idempotent() DeleteBuilder
+
+	// Quietly[T]
+	//
+	// Succeed if the node doesn't exist
+	Quietly() DeleteBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -119,6 +182,9 @@ type GetDataBuilder interface {
 	// Cause the data to be de-compressed using the configured compression provider
 	Decompressed() GetDataBuilder
 
+	// Don't de-compress the data, even if the compression is enabled for the client
+	Undecompressed() GetDataBuilder
+
 	// Statable[T]
 	//
 	// Have the operation fill the provided stat object
@@ -132,6 +198,13 @@ type GetDataBuilder interface {
 	// Set a watcher for the operation
 	UsingWatcher(watcher Watcher) GetDataBuilder
 
+	// Deliver the watched event to the function, with the path relative to the namespace of the client
+	UsingWatcherFunc(fn func(event zk.Event)) GetDataBuilder
+
+	// Send the watched event to the channel, with the path relative to the namespace of the client.
+	// The following events of the path are delivered once it is received, see DispatchMode.
+	UsingWatcherChannel(events chan<- zk.Event) GetDataBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -161,11 +234,24 @@ type SetDataBuilder interface {
 	// Use the given version (the default is -1)
 	WithVersion(version int32) SetDataBuilder
 
+	// Idempotentable[T]
+	//
+	// Succeed if a retried set finds the data already set, i.e. the node has the same data and the next version
+	Idempotent() SetDataBuilder
+
 	// Compressible[T]
 	//
 	// Cause the data to be compressed using the configured compression provider
 	Compressed() SetDataBuilder
 
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() SetDataBuilder
+
+	// Statable[T]
+	//
+	// Have the operation fill the provided stat object
+	StoringStatIn(stat *zk.Stat) SetDataBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -200,6 +286,13 @@ type GetChildrenBuilder interface {
 	// Set a watcher for the operation
 	UsingWatcher(watcher Watcher) GetChildrenBuilder
 
+	// Deliver the watched event to the function, with the path relative to the namespace of the client
+	UsingWatcherFunc(fn func(event zk.Event)) GetChildrenBuilder
+
+	// Send the watched event to the channel, with the path relative to the namespace of the client.
+	// The following events of the path are delivered once it is received, see DispatchMode.
+	UsingWatcherChannel(events chan<- zk.Event) GetChildrenBuilder
+
 	// Backgroundable[T]
 	//
 	// Perform the action in the background
@@ -293,6 +386,124 @@ type SyncBuilder interface {
 	InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) SyncBuilder
 }
 
+type GetConfigBuilder interface {
+	// Ensembleable[T]
+	//
+	// Return the config of the ensemble
+	ForEnsemble() ([]byte, error)
+
+	// Statable[T]
+	//
+	// Have the operation fill the provided stat object
+	StoringStatIn(stat *zk.Stat) GetConfigBuilder
+
+	// Watchable[T]
+	//
+	// Have the operation set a watch
+	Watched() GetConfigBuilder
+
+	// Set a watcher for the operation
+	UsingWatcher(watcher Watcher) GetConfigBuilder
+
+	// Deliver the watched event to the function, with the path relative to the namespace of the client
+	UsingWatcherFunc(fn func(event zk.Event)) GetConfigBuilder
+
+	// Send the watched event to the channel, with the path relative to the namespace of the client.
+	// The following events of the path are delivered once it is received, see DispatchMode.
+	UsingWatcherChannel(events chan<- zk.Event) GetConfigBuilder
+
+	// Backgroundable[T]
+	//
+	// Perform the action in the background
+	InBackground() GetConfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithContext(context interface{}) GetConfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallback(callback BackgroundCallback) GetConfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) GetConfigBuilder
+}
+
+type WatchesBuilder interface {
+	// Start an add watch builder, which adds a persistent watch with ZooKeeper 3.6.0+
+	Add() AddWatchBuilder
+}
+
+type AddWatchBuilder interface {
+	// Commit the currently building operation using the given path
+	ForPath(path string) error
+
+	// Set the mode of the watch - the default is PERSISTENT_RECURSIVE_WATCH
+	WithMode(mode AddWatchMode) AddWatchBuilder
+
+	// Set a watcher for the events of the watch
+	UsingWatcher(watcher Watcher) AddWatchBuilder
+
+	// Deliver the watched events to the function, with the paths relative to the namespace of the client
+	UsingWatcherFunc(fn func(event zk.Event)) AddWatchBuilder
+
+	// Send the watched events to the channel, with the paths relative to the namespace of the client.
+	// The following events of the path are delivered once it is received, see DispatchMode.
+	UsingWatcherChannel(events chan<- zk.Event) AddWatchBuilder
+
+	// Backgroundable[T]
+	//
+	// Perform the action in the background
+	InBackground() AddWatchBuilder
+
+	// Perform the action in the background
+	InBackgroundWithContext(context interface{}) AddWatchBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallback(callback BackgroundCallback) AddWatchBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) AddWatchBuilder
+}
+
+type ReconfigBuilder interface {
+	// Ensembleable[T]
+	//
+	// Commit the change of the ensemble and return the new config
+	ForEnsemble() ([]byte, error)
+
+	// Add the servers to the ensemble, e.g. "server.4=host4:2888:3888;2181"
+	Joining(servers ...string) ReconfigBuilder
+
+	// Remove the servers from the ensemble by their ids, e.g. "4"
+	Leaving(ids ...string) ReconfigBuilder
+
+	// Replace all the servers of the ensemble, which can't be combined with the joining or leaving servers
+	WithNewMembers(servers ...string) ReconfigBuilder
+
+	// Configurable[T]
+	//
+	// Only change the ensemble if the version of the current config is the given version
+	FromConfig(config int64) ReconfigBuilder
+
+	// Statable[T]
+	//
+	// Have the operation fill the provided stat object
+	StoringStatIn(stat *zk.Stat) ReconfigBuilder
+
+	// Backgroundable[T]
+	//
+	// Perform the action in the background
+	InBackground() ReconfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithContext(context interface{}) ReconfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallback(callback BackgroundCallback) ReconfigBuilder
+
+	// Perform the action in the background
+	InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) ReconfigBuilder
+}
+
 type TransactionCreateBuilder interface {
 	// PathAndBytesable[T]
 	//
@@ -307,6 +518,11 @@ type TransactionCreateBuilder interface {
 	// Set a create mode - the default is CreateMode.PERSISTENT
 	WithMode(mode CreateMode) TransactionCreateBuilder
 
+	// TTLable[T]
+	//
+	// Set the TTL of the node created with PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL
+	WithTTL(ttl time.Duration) TransactionCreateBuilder
+
 	// ACLable[T]
 	//
 	// Set an ACL list
@@ -316,6 +532,9 @@ type TransactionCreateBuilder interface {
 	//
 	// Cause the data to be compressed using the configured compression provider
 	Compressed() TransactionCreateBuilder
+
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() TransactionCreateBuilder
 }
 
 type TransactionDeleteBuilder interface {
@@ -348,6 +567,108 @@ type TransactionSetDataBuilder interface {
 	//
 	// Cause the data to be compressed using the configured compression provider
 	Compressed() TransactionSetDataBuilder
+
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() TransactionSetDataBuilder
+}
+
+type TransactionOpCreateBuilder interface {
+	// PathAndBytesable[T]
+	//
+	// Create the operation using the given path
+	ForPath(path string) CuratorOp
+
+	// Create the operation using the given path and data
+	ForPathWithData(path string, payload []byte) CuratorOp
+
+	// CreateModable[T]
+	//
+	// Set a create mode - the default is CreateMode.PERSISTENT
+	WithMode(mode CreateMode) TransactionOpCreateBuilder
+
+	// TTLable[T]
+	//
+	// Set the TTL of the node created with PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL
+	WithTTL(ttl time.Duration) TransactionOpCreateBuilder
+
+	// ACLable[T]
+	//
+	// Set an ACL list
+	WithACL(acls ...zk.ACL) TransactionOpCreateBuilder
+
+	// Compressible[T]
+	//
+	// Cause the data to be compressed using the configured compression provider
+	Compressed() TransactionOpCreateBuilder
+
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() TransactionOpCreateBuilder
+}
+
+type TransactionOpDeleteBuilder interface {
+	// Pathable[T]
+	//
+	// Create the operation using the given path
+	ForPath(path string) CuratorOp
+
+	// Versionable[T]
+	//
+	// Use the given version (the default is -1)
+	WithVersion(version int32) TransactionOpDeleteBuilder
+}
+
+type TransactionOpSetDataBuilder interface {
+	// PathAndBytesable[T]
+	//
+	// Create the operation using the given path
+	ForPath(path string) CuratorOp
+
+	// Create the operation using the given path and data
+	ForPathWithData(path string, payload []byte) CuratorOp
+
+	// Versionable[T]
+	//
+	// Use the given version (the default is -1)
+	WithVersion(version int32) TransactionOpSetDataBuilder
+
+	// Compressible[T]
+	//
+	// Cause the data to be compressed using the configured compression provider
+	Compressed() TransactionOpSetDataBuilder
+
+	// Don't compress the data, even if the compression is enabled for the client
+	Uncompressed() TransactionOpSetDataBuilder
+}
+
+type TransactionOpCheckBuilder interface {
+	// Pathable[T]
+	//
+	// Create the operation using the given path
+	ForPath(path string) CuratorOp
+
+	// Versionable[T]
+	//
+	// Use the given version (the default is -1)
+	WithVersion(version int32) TransactionOpCheckBuilder
+}
+
+type CuratorMultiTransaction interface {
+	// Commit the given operations as a single transaction
+	ForOperations(operations ...CuratorOp) ([]TransactionResult, error)
+
+	// Backgroundable[T]
+	//
+	// Perform the action in the background
+	InBackground() CuratorMultiTransaction
+
+	// Perform the action in the background
+	InBackgroundWithContext(context interface{}) CuratorMultiTransaction
+
+	// Perform the action in the background
+	InBackgroundWithCallback(callback BackgroundCallback) CuratorMultiTransaction
+
+	// Perform the action in the background
+	InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) CuratorMultiTransaction
 }
 
 type TransactionCheckBuilder interface {
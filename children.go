@@ -15,7 +15,7 @@ func (b *getChildrenBuilder) ForPath(givenPath string) ([]string, error) {
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return nil, nil
 	}
@@ -34,30 +34,29 @@ func (b *getChildrenBuilder) pathInBackground(adjustedPath, givenPath string) {
 
 	children, err := b.pathInForeground(adjustedPath)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: CHILDREN,
-			err:       err,
-			path:      b.client.unfixForNamespace(adjustedPath),
-			children:  children,
-			stat:      b.stat,
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: CHILDREN,
+		err:       err,
+		path:      b.client.unfixForNamespace(adjustedPath),
+		children:  children,
+		stat:      b.stat,
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *getChildrenBuilder) pathInForeground(path string) ([]string, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(CHILDREN.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, CHILDREN.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -70,7 +69,7 @@ func (b *getChildrenBuilder) pathInForeground(path string) ([]string, error) {
 				children, stat, events, err = conn.ChildrenW(path)
 
 				if events != nil && b.watching.watcher != nil {
-					go NewWatchers(b.watching.watcher).Watch(events)
+					go newWatchers(b.client.dispatcher, b.watching.watcher).Watch(events)
 				}
 			} else {
 				children, stat, err = conn.Children(path)
@@ -90,6 +89,8 @@ func (b *getChildrenBuilder) pathInForeground(path string) ([]string, error) {
 
 	children, _ := result.([]string)
 
+	trace.WithWatcher(b.watching.watched || b.watching.watcher != nil).WithStat(b.stat).Commit(err)
+
 	return children, err
 }
 
@@ -111,6 +112,16 @@ func (b *getChildrenBuilder) UsingWatcher(watcher Watcher) GetChildrenBuilder {
 	return b
 }
 
+func (b *getChildrenBuilder) UsingWatcherFunc(fn func(event zk.Event)) GetChildrenBuilder {
+	b.watching.watcher = b.client.newNamespaceWatcher(fn)
+
+	return b
+}
+
+func (b *getChildrenBuilder) UsingWatcherChannel(events chan<- zk.Event) GetChildrenBuilder {
+	return b.UsingWatcherFunc(func(event zk.Event) { events <- event })
+}
+
 func (b *getChildrenBuilder) InBackground() GetChildrenBuilder {
 	b.backgrounding = backgrounding{inBackground: true}
 
@@ -97,3 +97,29 @@ func (s *GetChildrenBuilderTestSuite) TestWatcher() {
 		}
 	})
 }
+
+func (s *GetChildrenBuilderTestSuite) TestWatcherFunc() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup, stat *zk.Stat) {
+		events := make(chan zk.Event)
+
+		defer close(events)
+
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("ChildrenW", "/parent/node").Return([]string{"child"}, stat, events, nil).Once()
+
+		children, err := client.GetChildren().UsingWatcherFunc(func(event zk.Event) {
+			defer wg.Done()
+
+			assert.Equal(s.T(), zk.EventNodeChildrenChanged, event.Type)
+			assert.Equal(s.T(), "/node", event.Path)
+		}).ForPath("/node")
+
+		assert.Equal(s.T(), []string{"child"}, children)
+		assert.NoError(s.T(), err)
+
+		events <- zk.Event{
+			Type: zk.EventNodeChildrenChanged,
+			Path: "/parent/node",
+		}
+	})
+}
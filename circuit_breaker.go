@@ -0,0 +1,210 @@
+package curator
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// A connection state listener collapsing the flapping states of an unstable network,
+// so the recipes don't release and re-acquire their locks on each SUSPENDED and RECONNECTED.
+//
+// The first state which isn't connected is passed to the listener and opens the circuit.
+// While the circuit is open the states are only recorded, except the first LOST state, which is passed at once.
+// The circuit is checked after each sleep of the retry policy, it closes once the connection is connected again
+// or the retry policy is exhausted, and the last recorded state is passed if it differs from the last passed one.
+type CircuitBreakingConnectionStateListener struct {
+	listener       ConnectionStateListener
+	retryPolicy    RetryPolicy
+	unhandledError func(err error) // report the panics of the listener called on the checks, which are logged by default
+	lock           sync.Mutex
+	client         CuratorFramework
+	open           bool
+	openedAt       time.Time
+	retryCount     int
+	timer          *time.Timer
+	lastState      ConnectionState // the last state passed to the listener
+	newState       ConnectionState // the last state recorded while the circuit is open, UNKNOWN if none
+	lostSent       bool
+}
+
+// Wrap the listener with a circuit breaker, which keeps open as long as the retry policy allows
+func NewCircuitBreakingConnectionStateListener(listener ConnectionStateListener, retryPolicy RetryPolicy) *CircuitBreakingConnectionStateListener {
+	return &CircuitBreakingConnectionStateListener{listener: listener, retryPolicy: retryPolicy}
+}
+
+func (l *CircuitBreakingConnectionStateListener) StateChanged(client CuratorFramework, newState ConnectionState) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.client = client
+
+	if l.open {
+		l.newState = newState
+
+		if newState == LOST && !l.lostSent {
+			l.lostSent = true
+
+			l.callListener(newState)
+		}
+	} else {
+		if !newState.Connected() {
+			l.retryCount = 0
+			l.openedAt = time.Now()
+
+			if l.scheduleCheck() {
+				l.lostSent = newState == LOST
+			}
+		}
+
+		l.callListener(newState)
+	}
+}
+
+// Return true if the circuit is open
+func (l *CircuitBreakingConnectionStateListener) IsOpen() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.open
+}
+
+// Stop checking the circuit, the recorded states are dropped
+func (l *CircuitBreakingConnectionStateListener) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.reset()
+
+	return nil
+}
+
+// Open the circuit until the sleep of the retry policy elapses, return false if the retry policy is exhausted
+func (l *CircuitBreakingConnectionStateListener) scheduleCheck() bool {
+	sleeper := &recordingRetrySleeper{}
+
+	if !l.retryPolicy.AllowRetry(l.retryCount, time.Since(l.openedAt), sleeper) {
+		return false
+	}
+
+	l.retryCount++
+	l.open = true
+	l.timer = time.AfterFunc(sleeper.sleepTime, l.checkCircuit)
+
+	return true
+}
+
+func (l *CircuitBreakingConnectionStateListener) checkCircuit() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.open {
+		return // closed in the meantime
+	}
+
+	if l.newState != UNKNOWN && !l.newState.Connected() && l.scheduleCheck() {
+		return // still disconnected
+	}
+
+	newState := l.newState
+
+	l.reset()
+
+	if newState != UNKNOWN && newState != l.lastState {
+		l.callListener(newState)
+	}
+}
+
+func (l *CircuitBreakingConnectionStateListener) reset() {
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+
+	l.open = false
+	l.timer = nil
+	l.newState = UNKNOWN
+	l.lostSent = false
+}
+
+func (l *CircuitBreakingConnectionStateListener) callListener(newState ConnectionState) {
+	defer recoverPanic("Connection state listener", l.logError)
+
+	l.lastState = newState
+
+	l.listener.StateChanged(l.client, newState)
+}
+
+func (l *CircuitBreakingConnectionStateListener) logError(err error) {
+	if l.unhandledError != nil {
+		l.unhandledError(err)
+	} else {
+		log.Printf("error: %s", err)
+	}
+}
+
+// Record the sleep time of a retry policy instead of sleeping
+type recordingRetrySleeper struct {
+	sleepTime time.Duration
+}
+
+func (s *recordingRetrySleeper) SleepFor(sleepTime time.Duration) error {
+	s.sleepTime = sleepTime
+
+	return nil
+}
+
+// The listeners of the connection state manager, which are wrapped with the circuit breakers when they are added
+type circuitBreakingListenerContainer struct {
+	connectionStateListenerContainer
+
+	retryPolicy    RetryPolicy
+	unhandledError func(err error)
+	lock           sync.Mutex
+	wrapped        map[ConnectionStateListener]*CircuitBreakingConnectionStateListener
+}
+
+func newCircuitBreakingListenerContainer(retryPolicy RetryPolicy, unhandledError func(err error)) *circuitBreakingListenerContainer {
+	return &circuitBreakingListenerContainer{
+		retryPolicy:    retryPolicy,
+		unhandledError: unhandledError,
+		wrapped:        make(map[ConnectionStateListener]*CircuitBreakingConnectionStateListener),
+	}
+}
+
+func (c *circuitBreakingListenerContainer) AddListener(listener ConnectionStateListener) {
+	wrapped := NewCircuitBreakingConnectionStateListener(listener, c.retryPolicy)
+	wrapped.unhandledError = c.unhandledError
+
+	c.lock.Lock()
+	c.wrapped[listener] = wrapped
+	c.lock.Unlock()
+
+	c.Add(wrapped)
+}
+
+func (c *circuitBreakingListenerContainer) RemoveListener(listener ConnectionStateListener) {
+	c.lock.Lock()
+	wrapped, ok := c.wrapped[listener]
+	delete(c.wrapped, listener)
+	c.lock.Unlock()
+
+	if ok {
+		c.Remove(wrapped)
+
+		wrapped.Close()
+	}
+}
+
+func (c *circuitBreakingListenerContainer) Clear() {
+	c.lock.Lock()
+
+	for _, wrapped := range c.wrapped {
+		wrapped.Close()
+	}
+
+	c.wrapped = make(map[ConnectionStateListener]*CircuitBreakingConnectionStateListener)
+
+	c.lock.Unlock()
+
+	c.ListenerContainer.Clear()
+}
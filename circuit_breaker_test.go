@@ -0,0 +1,94 @@
+package curator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingConnectionStateListener struct {
+	lock   sync.Mutex
+	states []ConnectionState
+}
+
+func (l *recordingConnectionStateListener) StateChanged(client CuratorFramework, newState ConnectionState) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.states = append(l.states, newState)
+}
+
+func (l *recordingConnectionStateListener) States() []ConnectionState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return append([]ConnectionState(nil), l.states...)
+}
+
+func TestCircuitBreakingConnectionStateListener(t *testing.T) {
+	recorder := &recordingConnectionStateListener{}
+	listener := NewCircuitBreakingConnectionStateListener(recorder, NewRetryNTimes(3, 20*time.Millisecond))
+
+	defer listener.Close()
+
+	listener.StateChanged(nil, CONNECTED)
+
+	assert.False(t, listener.IsOpen())
+
+	// the flapping states are collapsed
+	listener.StateChanged(nil, SUSPENDED)
+
+	assert.True(t, listener.IsOpen())
+
+	listener.StateChanged(nil, RECONNECTED)
+	listener.StateChanged(nil, SUSPENDED)
+	listener.StateChanged(nil, RECONNECTED)
+
+	assert.Equal(t, []ConnectionState{CONNECTED, SUSPENDED}, recorder.States())
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.False(t, listener.IsOpen())
+	assert.Equal(t, []ConnectionState{CONNECTED, SUSPENDED, RECONNECTED}, recorder.States())
+
+	// the LOST state is passed at once, and the circuit closes once the retries are exhausted
+	listener.StateChanged(nil, SUSPENDED)
+	listener.StateChanged(nil, LOST)
+	listener.StateChanged(nil, SUSPENDED)
+	listener.StateChanged(nil, LOST)
+
+	assert.Equal(t, []ConnectionState{CONNECTED, SUSPENDED, RECONNECTED, SUSPENDED, LOST}, recorder.States())
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.False(t, listener.IsOpen())
+
+	listener.StateChanged(nil, RECONNECTED)
+
+	assert.Equal(t, []ConnectionState{CONNECTED, SUSPENDED, RECONNECTED, SUSPENDED, LOST, RECONNECTED}, recorder.States())
+}
+
+func TestCircuitBreakingListenerContainer(t *testing.T) {
+	container := newCircuitBreakingListenerContainer(NewRetryNTimes(3, time.Second), nil)
+	recorder := &recordingConnectionStateListener{}
+
+	container.AddListener(recorder)
+
+	assert.Equal(t, 1, container.Len())
+
+	container.ForEach(func(listener interface{}) {
+		listener.(ConnectionStateListener).StateChanged(nil, SUSPENDED)
+
+		assert.True(t, listener.(*CircuitBreakingConnectionStateListener).IsOpen())
+	})
+
+	assert.Equal(t, []ConnectionState{SUSPENDED}, recorder.States())
+
+	// the circuit breaker is closed with the removed listener
+	container.RemoveListener(recorder)
+
+	assert.Equal(t, 0, container.Len())
+	assert.Empty(t, container.wrapped)
+}
@@ -1,14 +1,20 @@
 package curator
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"log"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+// The writes fail fast with the error while the client is connected to a read-only server
+var ErrReadOnlyConnection = errors.New("the connection is read-only")
+
 type ZookeeperConnection interface {
 	// Add the specified scheme:auth information to this connection.
 	AddAuth(scheme string, auth []byte) error
@@ -63,6 +69,54 @@ type ZookeeperConnection interface {
 	Sync(path string) (string, error)
 }
 
+// A ZooKeeper connection supporting the container nodes, which requires ZooKeeper 3.5.1+
+type ContainerZookeeperConnection interface {
+	ZookeeperConnection
+
+	// Create a container node with the given path, with the createContainer opcode.
+	//
+	// Return ErrUnimplemented or zk.ErrBadArguments if the server doesn't support containers.
+	CreateContainer(path string, data []byte, acl []zk.ACL) (string, error)
+}
+
+// A ZooKeeper connection supporting the TTL nodes, which requires ZooKeeper 3.5.3+ with the extended types enabled.
+type TTLZookeeperConnection interface {
+	ZookeeperConnection
+
+	// Create a node with the given path and TTL, with the createTTL opcode.
+	//
+	// The flags are the CreateMode, i.e. PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL.
+	CreateTTL(path string, data []byte, flags int32, acl []zk.ACL, ttl time.Duration) (string, error)
+}
+
+// A ZooKeeper connection supporting the persistent watches, which requires ZooKeeper 3.6.0+
+type PersistentWatchZookeeperConnection interface {
+	ZookeeperConnection
+
+	// Add a persistent watch of the given path, with the addWatch opcode.
+	// A recursive watch also watches all the descendants of the path.
+	//
+	// The events are delivered to the session events channel, the watch is lost when the connection is lost.
+	// Return ErrUnimplemented or zk.ErrBadArguments if the server doesn't support persistent watches.
+	AddPersistentWatch(path string, recursive bool) error
+
+	// Remove the persistent watch of the given path, with the removeWatches opcode.
+	RemovePersistentWatch(path string, recursive bool) error
+}
+
+// A ZooKeeper connection supporting the dynamic reconfiguration, which requires ZooKeeper 3.5.0+ with the reconfiguration enabled
+type ReconfigZookeeperConnection interface {
+	ZookeeperConnection
+
+	// Change the servers of the ensemble, with the reconfig opcode.
+	// The joining and leaving servers make an incremental change, the members replace all the servers,
+	// the servers not given are nil. The change fails unless the version of the config is fromConfig, or fromConfig is -1.
+	//
+	// Return the new config and its stat.
+	// Return ErrUnimplemented or zk.ErrBadArguments if the server doesn't support the reconfiguration.
+	Reconfig(joining, leaving, members []string, fromConfig int64) ([]byte, *zk.Stat, error)
+}
+
 // Allocate a new ZooKeeper connection
 type ZookeeperDialer interface {
 	Dial(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (ZookeeperConnection, <-chan zk.Event, error)
@@ -83,11 +137,103 @@ func NewZookeeperDialer(dial ZookeeperDialFunc) ZookeeperDialer {
 }
 
 type DefaultZookeeperDialer struct {
-	Dialer zk.Dialer
+	Dialer         zk.Dialer
+	ServerSelector ServerSelector // the order of the servers on each connection, shuffled by the zk package by default
+	HostResolution HostResolution // how the hostnames of the servers are resolved, once per connection string by default
+	TLSConfig      *tls.Config    // connect to the secure client port of the servers, e.g. 2281, the server name is the host of each server by default
+	SASLMechanism  SASLMechanism  // authenticate each connection with SASL, including the reconnections, e.g. NewDigestSASLMechanism
+	RequestTimeout time.Duration  // abandon a request waiting longer for its response with ErrRequestTimeout and move to the next server, only the session timeout by default
 }
 
 func (d *DefaultZookeeperDialer) Dial(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (ZookeeperConnection, <-chan zk.Event, error) {
-	return zk.ConnectWithDialer(strings.Split(connString, ","), sessionTimeout, d.Dialer)
+	dialer := d.Dialer
+
+	if d.TLSConfig != nil {
+		dialer = newTLSDialer(dialer, d.TLSConfig)
+	}
+
+	var hostProvider zk.HostProvider = &zk.DNSHostProvider{}
+
+	if d.ServerSelector != nil || d.HostResolution != RESOLVE_ONCE {
+		hostProvider = newHostProvider(d.ServerSelector, d.HostResolution)
+	}
+
+	var readOnly *AtomicBool
+
+	if canBeReadOnly {
+		readOnly = new(AtomicBool)
+	}
+
+	if conn, events, err := zk.Connect(strings.Split(connString, ","), sessionTimeout,
+		zk.WithDialer(newProtocolDialer(dialer, d.SASLMechanism, readOnly, d.RequestTimeout)), zk.WithHostProvider(hostProvider)); err != nil {
+		return nil, nil, err
+	} else if readOnly != nil {
+		return &extendedConn{Conn: conn, rewriting: true}, translateReadOnlyEvents(events, readOnly), nil
+	} else {
+		return &extendedConn{Conn: conn, rewriting: true}, events, nil
+	}
+}
+
+// The zk package reports a read-only session as any other session, so its event is translated to StateConnectedReadOnly
+func translateReadOnlyEvents(events <-chan zk.Event, readOnly *AtomicBool) <-chan zk.Event {
+	translated := make(chan zk.Event, cap(events))
+
+	go func() {
+		defer close(translated)
+
+		for event := range events {
+			if event.Type == zk.EventSession && event.State == zk.StateHasSession && readOnly.Load() {
+				event.State = zk.StateConnectedReadOnly
+			}
+
+			translated <- event
+		}
+	}()
+
+	return translated
+}
+
+// Wrap the dialer of the zk package, so the connections are secured with TLS,
+// the handshake completes within the timeout of the connection.
+func newTLSDialer(dialer zk.Dialer, config *tls.Config) zk.Dialer {
+	if dialer == nil {
+		dialer = net.DialTimeout
+	}
+
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		started := time.Now()
+
+		conn, err := dialer(network, address, timeout)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config := config
+
+		if config.ServerName == "" {
+			if host, _, err := net.SplitHostPort(address); err == nil {
+				config = config.Clone()
+				config.ServerName = host
+			}
+		}
+
+		tlsConn := tls.Client(conn, config)
+
+		if timeout > 0 {
+			tlsConn.SetDeadline(started.Add(timeout))
+		}
+
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+
+			return nil, err
+		}
+
+		tlsConn.SetDeadline(time.Time{})
+
+		return tlsConn, nil
+	}
 }
 
 // A wrapper around Zookeeper that takes care of some low-level housekeeping
@@ -101,9 +247,21 @@ type CuratorZookeeperClient interface {
 	// Return a new retry loop. All operations should be performed in a retry loop
 	NewRetryLoop() RetryLoop
 
+	// Return a new retry loop of the operation, which is reported to the retry listeners
+	NewRetryLoopFor(operation string) RetryLoop
+
+	// Returns the listenable interface for the retries of the operations
+	RetryListenable() RetryListenable
+
+	// Return the policy of how the connection timeouts and the retries interact
+	ConnectionHandlingPolicy() ConnectionHandlingPolicy
+
 	// Returns true if the client is current connected
 	Connected() bool
 
+	// Returns true if the client is connected to a read-only server, which rejects the writes
+	ReadOnly() bool
+
 	// This method blocks until the connection to ZK succeeds.
 	BlockUntilConnectedOrTimedOut() error
 
@@ -115,14 +273,26 @@ type CuratorZookeeperClient interface {
 
 	// Start a new tracer
 	StartTracer(name string) Tracer
+
+	// Start the trace of an operation, e.g. a custom operation of a recipe, with the session of the client
+	StartAdvancedTracer(name string) *AdvancedTracer
 }
 
 type curatorZookeeperClient struct {
-	state        *connectionState
-	watcher      Watcher
-	started      AtomicBool
-	TracerDriver TracerDriver
-	retryPolicy  RetryPolicy
+	state          *connectionState
+	watcher        Watcher
+	started        AtomicBool
+	TracerDriver   TracerDriver
+	retryPolicy    RetryPolicy
+	retryListeners *retryListenerContainer
+	unhandledError func(err error) // report the panics of the retry listeners, which are logged by default
+
+	readRateLimiter        RateLimiter           // limit the attempts of the read operations, not limited if nil
+	writeRateLimiter       RateLimiter           // limit the attempts of the write operations, not limited if nil
+	operationTracer        OperationTracer       // trace the operations and their retries, not traced if nil
+	slowOperationThreshold time.Duration         // report the operations taking longer, disabled if not positive
+	slowOperationListener  SlowOperationListener // receive the slow operations, which are logged if nil
+	tracedPathPrefixes     []string              // break down the traces of the operations per path prefix
 }
 
 func NewCuratorZookeeperClient(zookeeperDialer ZookeeperDialer, ensembleProvider EnsembleProvider, sessionTimeout, connectionTimeout time.Duration,
@@ -153,11 +323,17 @@ func NewCuratorZookeeperClient(zookeeperDialer ZookeeperDialer, ensembleProvider
 	})
 
 	tracer := newDefaultTracerDriver()
+	state := newConnectionState(dialer, ensembleProvider, sessionTimeout, connectionTimeout, watcher, tracer, canReadOnly)
+
+	if provider, ok := ensembleProvider.(changeNotifyingEnsembleProvider); ok {
+		provider.setChangeListener(state.checkNewConnectionString)
+	}
 
 	return &curatorZookeeperClient{
-		state:        newConnectionState(dialer, ensembleProvider, sessionTimeout, connectionTimeout, watcher, tracer, canReadOnly),
-		TracerDriver: tracer,
-		retryPolicy:  retryPolicy,
+		state:          state,
+		TracerDriver:   tracer,
+		retryPolicy:    retryPolicy,
+		retryListeners: new(retryListenerContainer),
 	}
 }
 
@@ -179,6 +355,10 @@ func (c *curatorZookeeperClient) Connected() bool {
 	return c.state.Connected()
 }
 
+func (c *curatorZookeeperClient) ReadOnly() bool {
+	return c.state.ReadOnly()
+}
+
 func (c *curatorZookeeperClient) CurrentConnectionString() string {
 	return c.state.ensembleProvider.ConnectionString()
 }
@@ -188,13 +368,135 @@ func (c *curatorZookeeperClient) RetryPolicy() RetryPolicy {
 }
 
 func (c *curatorZookeeperClient) NewRetryLoop() RetryLoop {
-	return newRetryLoop(c.retryPolicy, c.TracerDriver)
+	return c.NewRetryLoopFor("")
+}
+
+func (c *curatorZookeeperClient) NewRetryLoopFor(operation string) RetryLoop {
+	return &handlingRetryLoop{client: c, operation: operation}
+}
+
+func (c *curatorZookeeperClient) RetryListenable() RetryListenable {
+	return c.retryListeners
+}
+
+func (c *curatorZookeeperClient) ConnectionHandlingPolicy() ConnectionHandlingPolicy {
+	return c.state.handlingPolicy
+}
+
+// The retry loop of the client, which calls the operations through its ConnectionHandlingPolicy
+type handlingRetryLoop struct {
+	client    *curatorZookeeperClient
+	ctx       context.Context
+	operation string
+	path      string // the path of the operation, for its trace
+}
+
+// Return the retry loop of the operation on the path, the path is only known to the retry loops of the client
+func newRetryLoopForPath(client CuratorZookeeperClient, operation, path string) RetryLoop {
+	retryLoop := client.NewRetryLoopFor(operation)
+
+	if l, ok := retryLoop.(*handlingRetryLoop); ok {
+		l.path = path
+	}
+
+	return retryLoop
+}
+
+func (l *handlingRetryLoop) CallWithRetry(proc func() (interface{}, error)) (interface{}, error) {
+	retryLoop := newRetryLoop(l.client.retryPolicy, l.client.TracerDriver)
+	retryLoop.operation = l.operation
+	retryLoop.listeners = l.client.retryListeners
+	retryLoop.unhandledError = l.client.unhandledError
+
+	if l.ctx != nil {
+		retryLoop.ctx = l.ctx
+		retryLoop.retrySleeper = &contextRetrySleeper{l.ctx}
+	}
+
+	if limiter := l.client.rateLimiterFor(l.operation); limiter != nil {
+		ctx, call := l.ctx, proc
+
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		// each attempt takes a token, so a retry storm is limited too
+		proc = func() (interface{}, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+
+			return call()
+		}
+	}
+
+	if threshold := l.client.slowOperationThreshold; threshold > 0 && l.operation != "" {
+		started := time.Now()
+
+		defer func() {
+			if latency := time.Since(started); latency > threshold {
+				l.client.slowOperation(l.operation, l.path, latency)
+			}
+		}()
+	}
+
+	if l.client.operationTracer != nil && l.operation != "" {
+		trace := l.client.operationTracer.StartOperation(l.ctx, l.operation, l.path)
+		attempts, call := 0, proc
+
+		var lastErr error
+
+		proc = func() (interface{}, error) {
+			if attempts > 0 {
+				trace.RetryAttempted(attempts, lastErr)
+			}
+
+			attempts++
+
+			ret, err := call()
+
+			lastErr = err
+
+			return ret, err
+		}
+
+		ret, err := l.client.state.handlingPolicy.CallWithRetry(l.client, retryLoop, proc)
+
+		trace.Finish(attempts, err)
+
+		return ret, err
+	}
+
+	return l.client.state.handlingPolicy.CallWithRetry(l.client, retryLoop, proc)
+}
+
+// The client bound to a context, whose retry loops stop retrying once the context is done
+type contextZookeeperClient struct {
+	*curatorZookeeperClient
+
+	ctx context.Context
+}
+
+func (c *contextZookeeperClient) NewRetryLoop() RetryLoop {
+	return c.NewRetryLoopFor("")
+}
+
+func (c *contextZookeeperClient) NewRetryLoopFor(operation string) RetryLoop {
+	return &handlingRetryLoop{client: c.curatorZookeeperClient, ctx: c.ctx, operation: operation}
 }
 
 func (c *curatorZookeeperClient) StartTracer(name string) Tracer {
 	return newTimeTracer(name, c.TracerDriver)
 }
 
+func (c *curatorZookeeperClient) StartAdvancedTracer(name string) *AdvancedTracer {
+	tracer := NewAdvancedTracer(name, c.TracerDriver)
+	tracer.state = c.state
+	tracer.prefixes = c.tracedPathPrefixes
+
+	return tracer
+}
+
 func (c *curatorZookeeperClient) Conn() (ZookeeperConnection, error) {
 	if !c.started.Load() {
 		return nil, errors.New("Client is not started")
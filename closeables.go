@@ -0,0 +1,90 @@
+package curator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrDuplicateCloseable = errors.New("a closeable is already registered with the name")
+	ErrUnknownDependency  = errors.New("the dependency of the closeable is not registered")
+)
+
+// The resources closed when the client is closed, before its connection is closed.
+//
+// A resource is closed before the resources it depends on, e.g. a cache before the lock it informs,
+// so the recipes don't have to be closed by hand in the right order.
+type CloseableRegistry interface {
+	// Register a resource closed with the client, the dependencies must be registered before
+	Register(name string, closeable Closeable, dependsOn ...string) error
+
+	// Unregister a resource, e.g. after it has been closed by hand
+	Unregister(name string)
+}
+
+type closeableEntry struct {
+	name      string
+	closeable Closeable
+}
+
+type closeableRegistry struct {
+	lock    sync.Mutex
+	entries []closeableEntry // in the order of the registration, so after their dependencies
+}
+
+func (r *closeableRegistry) Register(name string, closeable Closeable, dependsOn ...string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.indexOf(name) >= 0 {
+		return ErrDuplicateCloseable
+	}
+
+	for _, dependency := range dependsOn {
+		if r.indexOf(dependency) < 0 {
+			return ErrUnknownDependency
+		}
+	}
+
+	r.entries = append(r.entries, closeableEntry{name, closeable})
+
+	return nil
+}
+
+func (r *closeableRegistry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if i := r.indexOf(name); i >= 0 {
+		r.entries = append(r.entries[:i], r.entries[i+1:]...)
+	}
+}
+
+func (r *closeableRegistry) indexOf(name string) int {
+	for i, entry := range r.entries {
+		if entry.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Close the registered resources in the reverse order of the registration, the dependents first.
+// The resources failed to close are reported to the callback, the others are still closed.
+func (r *closeableRegistry) closeAll(callback func(err error)) {
+	r.lock.Lock()
+
+	entries := r.entries
+
+	r.entries = nil
+
+	r.lock.Unlock()
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := entries[i].closeable.Close(); err != nil {
+			callback(fmt.Errorf("Trying to close %s, %s", entries[i].name, err))
+		}
+	}
+}
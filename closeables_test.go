@@ -0,0 +1,52 @@
+package curator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeableFunc func() error
+
+func (f closeableFunc) Close() error { return f() }
+
+func TestCloseables(t *testing.T) {
+	newMockContainer().Test(t, func(client CuratorFramework, conn *mockConn) {
+		var closed []string
+		var errs []error
+
+		closer := func(name string, err error) Closeable {
+			return closeableFunc(func() error {
+				closed = append(closed, name)
+
+				return err
+			})
+		}
+
+		registry := client.Closeables()
+
+		assert.NoError(t, registry.Register("lock", closeableFunc(func() error {
+			// the dependents are closed first, while the client can still be used
+			assert.Equal(t, []string{"membership", "cache"}, closed)
+			assert.Equal(t, STARTED, client.State())
+			assert.EqualError(t, errs[0], "Trying to close cache, close failed")
+
+			conn.On("Delete", "/lock/node", AnyVersion).Return(nil).Once()
+
+			return client.Delete().ForPath("/lock/node")
+		})))
+		assert.NoError(t, registry.Register("cache", closer("cache", errors.New("close failed")), "lock"))
+		assert.NoError(t, registry.Register("discovery", closer("discovery", nil), "lock"))
+		assert.NoError(t, registry.Register("membership", closer("membership", nil), "discovery"))
+
+		assert.Equal(t, ErrDuplicateCloseable, registry.Register("cache", closer("cache", nil)))
+		assert.Equal(t, ErrUnknownDependency, registry.Register("leader", closer("leader", nil), "election"))
+
+		registry.Unregister("discovery") // closed by hand, the client is closed by the container
+
+		client.UnhandledErrorListenable().AddListener(NewUnhandledErrorListener(func(err error) {
+			errs = append(errs, err)
+		}))
+	})
+}
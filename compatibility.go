@@ -0,0 +1,205 @@
+package curator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The version of a ZooKeeper server, e.g. 3.5.3
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// The versions of the servers required by the features
+var (
+	CONTAINER_SERVER_VERSION        = ServerVersion{3, 5, 1}
+	TTL_SERVER_VERSION              = ServerVersion{3, 5, 3}
+	PERSISTENT_WATCH_SERVER_VERSION = ServerVersion{3, 6, 0}
+)
+
+// Parse the version reported by a server, e.g. "3.5.3-beta-8ce24f9e675cbefffb8f21a47e06b42864475a60"
+func ParseServerVersion(version string) (ServerVersion, error) {
+	if pos := strings.IndexFunc(version, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); pos >= 0 {
+		version = version[:pos]
+	}
+
+	parts := strings.Split(version, ".")
+
+	if len(parts) < 2 || len(parts) > 3 {
+		return ServerVersion{}, fmt.Errorf("invalid server version: %s", version)
+	}
+
+	var numbers [3]int
+
+	for i, part := range parts {
+		if n, err := strconv.Atoi(part); err != nil {
+			return ServerVersion{}, fmt.Errorf("invalid server version: %s", version)
+		} else {
+			numbers[i] = n
+		}
+	}
+
+	return ServerVersion{numbers[0], numbers[1], numbers[2]}, nil
+}
+
+// Return true if the version is the same or newer than the other one
+func (v ServerVersion) AtLeast(other ServerVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+
+	return v.Patch >= other.Patch
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// The error of a feature the connected servers are too old to support
+type IncompatibleServerError struct {
+	Feature  string        // the feature, e.g. "TTL nodes"
+	Required ServerVersion // the version of the servers required by the feature
+	Version  ServerVersion // the version of the connected servers
+}
+
+func (e *IncompatibleServerError) Error() string {
+	return fmt.Sprintf("%s require ZooKeeper %s+, but the server version is %s", e.Feature, e.Required, e.Version)
+}
+
+// The features supported by the connected servers.
+//
+// All the features are assumed to be supported until the version of the servers is detected,
+// the operations then fail with an IncompatibleServerError instead of an error of the server.
+type Compatibility struct {
+	lock    sync.RWMutex
+	version *ServerVersion
+}
+
+// Return the detected version of the servers, false if it isn't detected yet
+func (c *Compatibility) ServerVersion() (ServerVersion, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.version == nil {
+		return ServerVersion{}, false
+	}
+
+	return *c.version, true
+}
+
+// Return true unless the servers are known to not support the container nodes
+func (c *Compatibility) HasContainerSupport() bool {
+	return c.supports(CONTAINER_SERVER_VERSION)
+}
+
+// Return true unless the servers are known to not support the TTL nodes
+func (c *Compatibility) HasTTLSupport() bool {
+	return c.supports(TTL_SERVER_VERSION)
+}
+
+// Return true unless the servers are known to not support the persistent watches
+func (c *Compatibility) HasPersistentWatchSupport() bool {
+	return c.supports(PERSISTENT_WATCH_SERVER_VERSION)
+}
+
+func (c *Compatibility) supports(required ServerVersion) bool {
+	version, detected := c.ServerVersion()
+
+	return !detected || version.AtLeast(required)
+}
+
+// Return an IncompatibleServerError if the servers are known to not support the feature
+func (c *Compatibility) check(feature string, required ServerVersion) error {
+	if version, detected := c.ServerVersion(); detected && !version.AtLeast(required) {
+		return &IncompatibleServerError{feature, required, version}
+	}
+
+	return nil
+}
+
+// Return an IncompatibleServerError if the servers are known to not support the nodes of the create mode
+func (c *Compatibility) checkCreateMode(mode CreateMode) error {
+	if mode.IsTTL() {
+		return c.check("TTL nodes", TTL_SERVER_VERSION)
+	} else if mode.IsContainer() {
+		return c.check("Container nodes", CONTAINER_SERVER_VERSION)
+	}
+
+	return nil
+}
+
+func (c *Compatibility) setServerVersion(version ServerVersion) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.version = &version
+}
+
+// Detect the version of the servers of a connection string
+type ServerVersionDetector interface {
+	DetectServerVersion(connectString string) (ServerVersion, error)
+}
+
+type serverVersionDetectorCallback struct {
+	callback func(connectString string) (ServerVersion, error)
+}
+
+func NewServerVersionDetector(callback func(connectString string) (ServerVersion, error)) ServerVersionDetector {
+	return &serverVersionDetectorCallback{callback}
+}
+
+func (d *serverVersionDetectorCallback) DetectServerVersion(connectString string) (ServerVersion, error) {
+	return d.callback(connectString)
+}
+
+type flwServerVersionDetector struct {
+	timeout time.Duration
+}
+
+// Detect the version of the servers with the srvr four letter word,
+// the oldest version is used since the client may connect to any of the servers.
+func NewFLWServerVersionDetector(timeout time.Duration) ServerVersionDetector {
+	return &flwServerVersionDetector{timeout}
+}
+
+func (d *flwServerVersionDetector) DetectServerVersion(connectString string) (ServerVersion, error) {
+	if pos := strings.Index(connectString, PATH_SEPARATOR); pos >= 0 {
+		connectString = connectString[:pos] // the chroot
+	}
+
+	stats, _ := zk.FLWSrvr(strings.Split(connectString, ","), d.timeout)
+
+	var oldest *ServerVersion
+	var lastErr error
+
+	for _, stat := range stats {
+		if stat.Error != nil {
+			lastErr = stat.Error
+		} else if version, err := ParseServerVersion(stat.Version); err != nil {
+			lastErr = err
+		} else if oldest == nil || !version.AtLeast(*oldest) {
+			oldest = &version
+		}
+	}
+
+	if oldest == nil {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no server to detect the version: %s", connectString)
+		}
+
+		return ServerVersion{}, lastErr
+	}
+
+	return *oldest, nil
+}
@@ -0,0 +1,96 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	version, err := ParseServerVersion("3.5.3-beta-8ce24f9e675cbefffb8f21a47e06b42864475a60")
+
+	assert.Equal(t, ServerVersion{3, 5, 3}, version)
+	assert.NoError(t, err)
+
+	version, err = ParseServerVersion("3.6")
+
+	assert.Equal(t, ServerVersion{3, 6, 0}, version)
+	assert.NoError(t, err)
+
+	_, err = ParseServerVersion("unknown")
+
+	assert.EqualError(t, err, "invalid server version: ")
+
+	assert.True(t, ServerVersion{3, 6, 0}.AtLeast(TTL_SERVER_VERSION))
+	assert.True(t, ServerVersion{3, 5, 3}.AtLeast(TTL_SERVER_VERSION))
+	assert.False(t, ServerVersion{3, 5, 1}.AtLeast(TTL_SERVER_VERSION))
+	assert.False(t, ServerVersion{2, 9, 9}.AtLeast(TTL_SERVER_VERSION))
+}
+
+func TestCompatibility(t *testing.T) {
+	compatibility := &Compatibility{}
+
+	// all the features are assumed until the version is detected
+	_, detected := compatibility.ServerVersion()
+
+	assert.False(t, detected)
+	assert.True(t, compatibility.HasContainerSupport())
+	assert.True(t, compatibility.HasTTLSupport())
+	assert.True(t, compatibility.HasPersistentWatchSupport())
+
+	compatibility.setServerVersion(ServerVersion{3, 5, 3})
+
+	version, detected := compatibility.ServerVersion()
+
+	assert.Equal(t, ServerVersion{3, 5, 3}, version)
+	assert.True(t, detected)
+	assert.True(t, compatibility.HasContainerSupport())
+	assert.True(t, compatibility.HasTTLSupport())
+	assert.False(t, compatibility.HasPersistentWatchSupport())
+
+	assert.NoError(t, compatibility.checkCreateMode(PERSISTENT_WITH_TTL))
+	assert.EqualError(t, compatibility.check("Persistent watches", PERSISTENT_WATCH_SERVER_VERSION),
+		"Persistent watches require ZooKeeper 3.6.0+, but the server version is 3.5.3")
+}
+
+type CompatibilityTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestCompatibilityGating(t *testing.T) {
+	suite.Run(t, new(CompatibilityTestSuite))
+}
+
+func (s *CompatibilityTestSuite) TestOldServer() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.ServerVersionDetector = NewServerVersionDetector(func(connectString string) (ServerVersion, error) {
+			assert.Equal(s.T(), "connStr", connectString)
+
+			return ServerVersion{3, 4, 14}, nil
+		})
+	}, func(client CuratorFramework, ensembleProvider *mockEnsembleProvider, data []byte) {
+		ensembleProvider.On("ConnectionString").Return("connStr").Once()
+
+		client.(*curatorFramework).detectServerVersion()
+
+		version, detected := client.Compatibility().ServerVersion()
+
+		assert.Equal(s.T(), ServerVersion{3, 4, 14}, version)
+		assert.True(s.T(), detected)
+
+		// the unsupported features fail fast without a request
+		_, err := client.Create().WithMode(PERSISTENT_WITH_TTL).WithTTL(time.Minute).ForPathWithData("/node", data)
+
+		assert.EqualError(s.T(), err, "TTL nodes require ZooKeeper 3.5.3+, but the server version is 3.4.14")
+
+		_, err = client.Create().WithMode(CONTAINER).ForPathWithData("/node", data)
+
+		assert.IsType(s.T(), &IncompatibleServerError{}, err)
+
+		err = client.Watches().Add().ForPath("/node")
+
+		assert.IsType(s.T(), &IncompatibleServerError{}, err)
+	})
+}
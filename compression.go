@@ -72,3 +72,51 @@ func (c *LZ4CompressionProvider) Compress(path string, data []byte) ([]byte, err
 func (c *LZ4CompressionProvider) Decompress(path string, compressedData []byte) ([]byte, error) {
 	return lz4.Decode(nil, compressedData)
 }
+
+// Decide whether the data of a node is compressed
+type CompressionPolicy interface {
+	ShouldCompress(path string, data []byte) bool
+}
+
+type compressionPolicy struct {
+	minSize int
+	paths   func(path string) bool
+}
+
+// Compress the data of at least minSize bytes, at the paths matching the predicate if any
+func NewCompressionPolicy(minSize int, paths func(path string) bool) CompressionPolicy {
+	return &compressionPolicy{minSize, paths}
+}
+
+func (p *compressionPolicy) ShouldCompress(path string, data []byte) bool {
+	return len(data) >= p.minSize && (p.paths == nil || p.paths(path))
+}
+
+// Only compress the data accepted by the policy.
+// The data left uncompressed is read back as is, when it can't be decompressed and the policy still rejects it.
+type policyCompressionProvider struct {
+	provider CompressionProvider
+	policy   CompressionPolicy
+}
+
+func NewPolicyCompressionProvider(provider CompressionProvider, policy CompressionPolicy) CompressionProvider {
+	return &policyCompressionProvider{provider, policy}
+}
+
+func (c *policyCompressionProvider) Compress(path string, data []byte) ([]byte, error) {
+	if !c.policy.ShouldCompress(path, data) {
+		return data, nil
+	}
+
+	return c.provider.Compress(path, data)
+}
+
+func (c *policyCompressionProvider) Decompress(path string, compressedData []byte) ([]byte, error) {
+	if data, err := c.provider.Decompress(path, compressedData); err == nil {
+		return data, nil
+	} else if !c.policy.ShouldCompress(path, compressedData) {
+		return compressedData, nil
+	} else {
+		return nil, err
+	}
+}
@@ -1,6 +1,7 @@
 package curator
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,3 +38,49 @@ func TestLZ4CompressionProvider(t *testing.T) {
 	assert.Equal(t, "data", string(data))
 	assert.NoError(t, err)
 }
+
+func TestPolicyCompressionProvider(t *testing.T) {
+	p := NewPolicyCompressionProvider(NewGzipCompressionProvider(), NewCompressionPolicy(16, func(path string) bool {
+		return path != "/raw"
+	}))
+
+	payload := bytes.Repeat([]byte("data"), 8)
+
+	// the tiny payloads are left uncompressed
+	data, err := p.Compress("/node", []byte("data"))
+
+	assert.Equal(t, "data", string(data))
+	assert.NoError(t, err)
+
+	data, err = p.Decompress("/node", data)
+
+	assert.Equal(t, "data", string(data))
+	assert.NoError(t, err)
+
+	// and the paths rejected by the policy
+	data, err = p.Compress("/raw", payload)
+
+	assert.Equal(t, payload, data)
+	assert.NoError(t, err)
+
+	data, err = p.Decompress("/raw", data)
+
+	assert.Equal(t, payload, data)
+	assert.NoError(t, err)
+
+	// the other payloads are compressed
+	data, err = p.Compress("/node", payload)
+
+	assert.NotEqual(t, payload, data)
+	assert.NoError(t, err)
+
+	data, err = p.Decompress("/node", data)
+
+	assert.Equal(t, payload, data)
+	assert.NoError(t, err)
+
+	// the corrupted data is still an error
+	_, err = p.Decompress("/node", payload)
+
+	assert.Error(t, err)
+}
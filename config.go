@@ -0,0 +1,271 @@
+package curator
+
+import (
+	"errors"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The node of the ensemble config, which isn't in the namespace of the client
+const CONFIG_NODE = "/zookeeper/config"
+
+var (
+	ErrReconfigNotSupported = errors.New("the connection doesn't support the reconfiguration")
+	ErrConflictingMembers   = errors.New("the new members can't be combined with the joining or leaving servers")
+)
+
+type getConfigBuilder struct {
+	client        *curatorFramework
+	backgrounding backgrounding
+	stat          *zk.Stat
+	watching      watching
+}
+
+func (b *getConfigBuilder) ForEnsemble() ([]byte, error) {
+	if b.backgrounding.inBackground {
+		b.client.runSafe(b.pathInBackground)
+
+		return nil, nil
+	}
+
+	return b.pathInForeground()
+}
+
+func (b *getConfigBuilder) pathInBackground() {
+	tracer := b.client.ZookeeperClient().StartTracer("getConfigBuilder.pathInBackground")
+
+	defer tracer.Commit()
+
+	data, err := b.pathInForeground()
+
+	event := &curatorEvent{
+		eventType: GET_CONFIG,
+		err:       err,
+		path:      CONFIG_NODE,
+		name:      GetNodeFromPath(CONFIG_NODE),
+		data:      data,
+		stat:      b.stat,
+		context:   b.backgrounding.context,
+	}
+
+	b.client.processBackgroundEvent(b.backgrounding, event)
+}
+
+func (b *getConfigBuilder) pathInForeground() ([]byte, error) {
+	zkClient := b.client.ZookeeperClient()
+
+	result, err := zkClient.NewRetryLoopFor(GET_CONFIG.String()).CallWithRetry(func() (interface{}, error) {
+		if conn, err := zkClient.Conn(); err != nil {
+			return nil, err
+		} else {
+			var data []byte
+			var stat *zk.Stat
+			var events <-chan zk.Event
+			var err error
+
+			if b.watching.watched || b.watching.watcher != nil {
+				data, stat, events, err = conn.GetW(CONFIG_NODE)
+
+				if events != nil && b.watching.watcher != nil {
+					go newWatchers(b.client.dispatcher, b.watching.watcher).Watch(events)
+				}
+			} else {
+				data, stat, err = conn.Get(CONFIG_NODE)
+			}
+
+			if stat != nil {
+				if b.stat != nil {
+					*b.stat = *stat
+				} else {
+					b.stat = stat
+				}
+			}
+
+			return data, err
+		}
+	})
+
+	data, _ := result.([]byte)
+
+	return data, err
+}
+
+func (b *getConfigBuilder) StoringStatIn(stat *zk.Stat) GetConfigBuilder {
+	b.stat = stat
+
+	return b
+}
+
+func (b *getConfigBuilder) Watched() GetConfigBuilder {
+	b.watching.watched = true
+
+	return b
+}
+
+func (b *getConfigBuilder) UsingWatcher(watcher Watcher) GetConfigBuilder {
+	b.watching.watcher = b.client.getNamespaceWatcher(watcher)
+
+	return b
+}
+
+func (b *getConfigBuilder) UsingWatcherFunc(fn func(event zk.Event)) GetConfigBuilder {
+	b.watching.watcher = b.client.newNamespaceWatcher(fn)
+
+	return b
+}
+
+func (b *getConfigBuilder) UsingWatcherChannel(events chan<- zk.Event) GetConfigBuilder {
+	return b.UsingWatcherFunc(func(event zk.Event) { events <- event })
+}
+
+func (b *getConfigBuilder) InBackground() GetConfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true}
+
+	return b
+}
+
+func (b *getConfigBuilder) InBackgroundWithContext(context interface{}) GetConfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context}
+
+	return b
+}
+
+func (b *getConfigBuilder) InBackgroundWithCallback(callback BackgroundCallback) GetConfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, callback: callback}
+
+	return b
+}
+
+func (b *getConfigBuilder) InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) GetConfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context, callback: callback}
+
+	return b
+}
+
+type reconfigBuilder struct {
+	client        *curatorFramework
+	backgrounding backgrounding
+	joining       []string
+	leaving       []string
+	members       []string
+	fromConfig    int64
+	stat          *zk.Stat
+}
+
+func (b *reconfigBuilder) ForEnsemble() ([]byte, error) {
+	if b.members != nil && (b.joining != nil || b.leaving != nil) {
+		return nil, ErrConflictingMembers
+	} else if err := b.client.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if b.backgrounding.inBackground {
+		b.client.runSafe(b.pathInBackground)
+
+		return nil, nil
+	}
+
+	return b.pathInForeground()
+}
+
+func (b *reconfigBuilder) pathInBackground() {
+	tracer := b.client.ZookeeperClient().StartTracer("reconfigBuilder.pathInBackground")
+
+	defer tracer.Commit()
+
+	data, err := b.pathInForeground()
+
+	event := &curatorEvent{
+		eventType: RECONFIG,
+		err:       err,
+		path:      CONFIG_NODE,
+		name:      GetNodeFromPath(CONFIG_NODE),
+		data:      data,
+		stat:      b.stat,
+		context:   b.backgrounding.context,
+	}
+
+	b.client.processBackgroundEvent(b.backgrounding, event)
+}
+
+func (b *reconfigBuilder) pathInForeground() ([]byte, error) {
+	zkClient := b.client.ZookeeperClient()
+
+	result, err := zkClient.NewRetryLoopFor(RECONFIG.String()).CallWithRetry(func() (interface{}, error) {
+		if conn, err := zkClient.Conn(); err != nil {
+			return nil, err
+		} else if reconfigConn, ok := conn.(ReconfigZookeeperConnection); !ok {
+			return nil, ErrReconfigNotSupported
+		} else {
+			data, stat, err := reconfigConn.Reconfig(b.joining, b.leaving, b.members, b.fromConfig)
+
+			if stat != nil {
+				if b.stat != nil {
+					*b.stat = *stat
+				} else {
+					b.stat = stat
+				}
+			}
+
+			return data, err
+		}
+	})
+
+	data, _ := result.([]byte)
+
+	return data, err
+}
+
+func (b *reconfigBuilder) Joining(servers ...string) ReconfigBuilder {
+	b.joining = append(b.joining, servers...)
+
+	return b
+}
+
+func (b *reconfigBuilder) Leaving(ids ...string) ReconfigBuilder {
+	b.leaving = append(b.leaving, ids...)
+
+	return b
+}
+
+func (b *reconfigBuilder) WithNewMembers(servers ...string) ReconfigBuilder {
+	b.members = append(b.members, servers...)
+
+	return b
+}
+
+func (b *reconfigBuilder) FromConfig(config int64) ReconfigBuilder {
+	b.fromConfig = config
+
+	return b
+}
+
+func (b *reconfigBuilder) StoringStatIn(stat *zk.Stat) ReconfigBuilder {
+	b.stat = stat
+
+	return b
+}
+
+func (b *reconfigBuilder) InBackground() ReconfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true}
+
+	return b
+}
+
+func (b *reconfigBuilder) InBackgroundWithContext(context interface{}) ReconfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context}
+
+	return b
+}
+
+func (b *reconfigBuilder) InBackgroundWithCallback(callback BackgroundCallback) ReconfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, callback: callback}
+
+	return b
+}
+
+func (b *reconfigBuilder) InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) ReconfigBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context, callback: callback}
+
+	return b
+}
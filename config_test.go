@@ -0,0 +1,89 @@
+package curator
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigBuilderTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestConfigBuilder(t *testing.T) {
+	suite.Run(t, new(ConfigBuilderTestSuite))
+}
+
+func (s *ConfigBuilderTestSuite) TestGetConfig() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, stat *zk.Stat) {
+		config := []byte("server.1=host1:2888:3888:participant;0.0.0.0:2181\nversion=100000000")
+		events := make(chan zk.Event)
+
+		defer close(events)
+
+		conn.On("GetW", CONFIG_NODE).Return(config, stat, events, nil).Once()
+
+		var configStat zk.Stat
+
+		data, err := client.GetConfig().StoringStatIn(&configStat).Watched().ForEnsemble()
+
+		assert.Equal(s.T(), config, data)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), *stat, configStat)
+	})
+}
+
+func (s *ConfigBuilderTestSuite) TestReconfig() {
+	s.With(func(client CuratorFramework, conn *mockConn, stat *zk.Stat) {
+		config := []byte("server.1=host1:2888:3888:participant;0.0.0.0:2181\nversion=100000001")
+
+		conn.On("Reconfig", []string{"server.4=host4:2888:3888;2181"}, []string{"2"}, []string(nil), int64(100000000)).Return(config, stat, nil).Once()
+
+		data, err := client.Reconfig().Joining("server.4=host4:2888:3888;2181").Leaving("2").FromConfig(100000000).ForEnsemble()
+
+		assert.Equal(s.T(), config, data)
+		assert.NoError(s.T(), err)
+
+		conn.On("Reconfig", []string(nil), []string(nil), []string{"server.1=host1:2888:3888;2181"}, int64(-1)).Return(nil, nil, zk.ErrBadVersion).Once()
+
+		data, err = client.Reconfig().WithNewMembers("server.1=host1:2888:3888;2181").ForEnsemble()
+
+		assert.Nil(s.T(), data)
+		assert.EqualError(s.T(), err, zk.ErrBadVersion.Error())
+
+		// the new members replace all the servers
+		data, err = client.Reconfig().Joining("server.4=host4:2888:3888;2181").WithNewMembers("server.1=host1:2888:3888;2181").ForEnsemble()
+
+		assert.Nil(s.T(), data)
+		assert.Equal(s.T(), ErrConflictingMembers, err)
+	})
+}
+
+func (s *ConfigBuilderTestSuite) TestBackground() {
+	s.With(func(client CuratorFramework, conn *mockConn, stat *zk.Stat, wg *sync.WaitGroup) {
+		config := []byte("version=100000000")
+		ctxt := "context"
+
+		conn.On("Get", CONFIG_NODE).Return(config, stat, nil).Once()
+
+		data, err := client.GetConfig().InBackgroundWithCallbackAndContext(
+			func(client CuratorFramework, event CuratorEvent) error {
+				defer wg.Done()
+
+				assert.Equal(s.T(), GET_CONFIG, event.Type())
+				assert.Equal(s.T(), CONFIG_NODE, event.Path())
+				assert.Equal(s.T(), config, event.Data())
+				assert.Equal(s.T(), stat, event.Stat())
+				assert.NoError(s.T(), event.Err())
+				assert.Equal(s.T(), ctxt, event.Context())
+
+				return nil
+			}, ctxt).ForEnsemble()
+
+		assert.Nil(s.T(), data)
+		assert.NoError(s.T(), err)
+	})
+}
@@ -0,0 +1,28 @@
+package curator
+
+import (
+	"context"
+	"errors"
+)
+
+type contextFacade struct {
+	curatorFramework
+}
+
+func (c *curatorFramework) WithContext(ctx context.Context) CuratorFramework {
+	facade := &contextFacade{
+		curatorFramework: *c,
+	}
+
+	facade.ctx = ctx
+
+	return facade
+}
+
+func (f *contextFacade) Start() error {
+	return errors.New("the requested operation is not supported")
+}
+
+func (f *contextFacade) Close() error {
+	return errors.New("the requested operation is not supported")
+}
@@ -0,0 +1,72 @@
+package curator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextFacadeTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestContextFacade(t *testing.T) {
+	suite.Run(t, new(ContextFacadeTestSuite))
+}
+
+func (s *ContextFacadeTestSuite) TestWithContext() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		facade := client.WithContext(ctx)
+
+		assert.EqualError(s.T(), facade.Start(), "the requested operation is not supported")
+		assert.EqualError(s.T(), facade.Close(), "the requested operation is not supported")
+
+		conn.On("Set", "/node", data, AnyVersion).Return(stat, nil).Once()
+
+		stat2, err := facade.SetData().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), stat, stat2)
+		assert.NoError(s.T(), err)
+
+		// the operation isn't tried once the context is done
+		cancel()
+
+		_, err = facade.SetData().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), context.Canceled, err)
+	})
+}
+
+func (s *ContextFacadeTestSuite) TestRetrySleepAborted() {
+	s.With(func(client CuratorFramework, conn *mockConn, retryPolicy *mockRetryPolicy) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+		defer cancel()
+
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrSessionExpired).Once()
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(false).Once().Run(func(args mock.Arguments) {
+			assert.Equal(s.T(), context.DeadlineExceeded, args.Get(2).(RetrySleeper).SleepFor(time.Minute))
+		})
+
+		assert.Equal(s.T(), context.DeadlineExceeded, client.WithContext(ctx).Delete().ForPath("/node"))
+	})
+}
+
+func (s *ContextFacadeTestSuite) TestNamespace() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn) {
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("Delete", "/parent/child", AnyVersion).Return(nil).Once()
+
+		facade := client.WithContext(context.Background())
+
+		assert.Equal(s.T(), "parent", facade.Namespace())
+		assert.NoError(s.T(), facade.Delete().ForPath("/child"))
+	})
+}
@@ -1,23 +1,56 @@
 package curator
 
 import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+// The prefix of the protected node names, followed by the GUID and a dash
+const PROTECTED_PREFIX = "_c_"
+
+var (
+	ErrTTLNotSupported = errors.New("the connection doesn't support TTL nodes")
+	ErrInvalidTTL      = errors.New("the TTL must be positive and less than MAX_TTL")
+)
+
 type createBuilder struct {
-	client                *curatorFramework
-	createMode            CreateMode
-	backgrounding         backgrounding
-	createParentsIfNeeded bool
-	compress              bool
-	acling                acling
+	client                    *curatorFramework
+	createMode                CreateMode
+	backgrounding             backgrounding
+	createParentsIfNeeded     bool
+	createParentsAsContainers bool
+	compress                  bool
+	acling                    acling
+	protectedId               string
+	ttl                       time.Duration
+	setDataIfExists           bool
+	idempotent                bool
+	quietly                   bool
 }
 
 func (b *createBuilder) ForPath(path string) (string, error) {
-	return b.ForPathWithData(path, b.client.defaultData)
+	return b.ForPathWithData(path, b.client.defaultData.GetDefaultData(path))
 }
 
 func (b *createBuilder) ForPathWithData(givenPath string, payload []byte) (string, error) {
+	if b.createMode.IsTTL() && (b.ttl <= 0 || b.ttl > MAX_TTL) {
+		return "", ErrInvalidTTL
+	}
+
+	if err := b.validateSchema(givenPath, payload); err != nil {
+		return "", err
+	} else if err := b.client.checkWritable(); err != nil {
+		return "", err
+	} else if err := b.client.compatibility.checkCreateMode(b.createMode); err != nil {
+		return "", err
+	}
+
 	if b.compress {
 		if data, err := b.client.compressionProvider.Compress(givenPath, payload); err != nil {
 			return "", err
@@ -28,8 +61,12 @@ func (b *createBuilder) ForPathWithData(givenPath string, payload []byte) (strin
 
 	adjustedPath := b.client.fixForNamespace(givenPath, b.createMode.IsSequential())
 
+	if len(b.protectedId) > 0 {
+		adjustedPath = b.protectedPath(adjustedPath)
+	}
+
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, payload, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, payload, givenPath) })
 
 		return b.client.unfixForNamespace(adjustedPath), nil
 	} else {
@@ -39,6 +76,23 @@ func (b *createBuilder) ForPathWithData(givenPath string, payload []byte) (strin
 	}
 }
 
+func (b *createBuilder) validateSchema(path string, payload []byte) error {
+	schema, err := b.client.schemaSet.Schema(path)
+
+	if err != nil {
+		return err
+	}
+
+	var acls []zk.ACL
+
+	// only resolve the ACLs for the validator, the provider is asked again when the node is created
+	if schema.DataValidator != nil {
+		acls = b.acling.getAclList(path)
+	}
+
+	return schema.ValidateCreate(b.createMode, path, payload, acls)
+}
+
 func (b *createBuilder) pathInBackground(path string, payload []byte, givenPath string) {
 	tracer := b.client.ZookeeperClient().StartTracer("createBuilder.pathInBackground")
 
@@ -46,64 +100,214 @@ func (b *createBuilder) pathInBackground(path string, payload []byte, givenPath
 
 	createdPath, err := b.pathInForeground(path, payload)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: CREATE,
-			err:       err,
-			path:      createdPath,
-			data:      payload,
-			acls:      b.acling.getAclList(path),
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: CREATE,
+		err:       err,
+		path:      createdPath,
+		data:      payload,
+		acls:      b.acling.getAclList(path),
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *createBuilder) pathInForeground(path string, payload []byte) (string, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(CREATE.String(), path)
+
+	firstTime := true
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, CREATE.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
-			createdPath, err := conn.Create(path, payload, int32(b.createMode), b.acling.getAclList(path))
+			retried := !firstTime
+
+			if len(b.protectedId) > 0 && retried {
+				// the node may have been created before the connection was lost
+				if createdPath, err := b.findProtectedNode(conn, path); err != nil {
+					return nil, err
+				} else if len(createdPath) > 0 {
+					return createdPath, nil
+				}
+			}
+
+			firstTime = false
+
+			createdPath, err := b.create(conn, path, payload)
 
 			if err == zk.ErrNoNode && b.createParentsIfNeeded {
-				if err := MakeDirs(conn, path, false, b.acling.aclProvider); err != nil {
+				if err := makeDirs(conn, path, false, b.acling.aclProvider, b.createParentsAsContainers); err != nil {
 					return "", err
 				}
 
-				return conn.Create(path, payload, int32(b.createMode), b.acling.getAclList(path))
-			} else {
-				return createdPath, err
+				createdPath, err = b.create(conn, path, payload)
 			}
+
+			if err == zk.ErrNodeExists && b.setDataIfExists {
+				if _, err := conn.Set(path, payload, -1); err != nil {
+					return "", err
+				}
+
+				return path, nil
+			}
+
+			if err == zk.ErrNodeExists && b.idempotent && retried && !b.createMode.IsSequential() {
+				// the node may have been created before the connection was lost
+				if data, _, err := conn.Get(path); err != nil {
+					return "", err
+				} else if bytes.Equal(data, payload) {
+					return path, nil
+				}
+			}
+
+			if err == zk.ErrNodeExists && b.quietly && !b.createMode.IsSequential() {
+				return path, nil
+			}
+
+			return createdPath, err
 		}
 	})
 
 	createdPath, _ := result.(string)
 
+	trace.WithRequestBytes(payload).Commit(err)
+
 	return createdPath, err
 }
 
+func (b *createBuilder) create(conn ZookeeperConnection, path string, payload []byte) (string, error) {
+	if b.createMode.IsContainer() {
+		return createContainer(conn, path, payload, b.acling.getAclList(path))
+	}
+
+	if !b.createMode.IsTTL() {
+		return conn.Create(path, payload, int32(b.createMode), b.acling.getAclList(path))
+	}
+
+	if ttlConn, ok := conn.(TTLZookeeperConnection); !ok {
+		return "", ErrTTLNotSupported
+	} else {
+		return ttlConn.CreateTTL(path, payload, int32(b.createMode), b.acling.getAclList(path), b.ttl)
+	}
+}
+
+// Create a container node, or a persistent node if the server or the connection doesn't support containers
+func createContainer(conn ZookeeperConnection, path string, payload []byte, acls []zk.ACL) (string, error) {
+	if containerConn, ok := conn.(ContainerZookeeperConnection); ok {
+		if createdPath, err := containerConn.CreateContainer(path, payload, acls); err != ErrUnimplemented && err != zk.ErrBadArguments {
+			return createdPath, err
+		}
+	}
+
+	return conn.Create(path, payload, int32(PERSISTENT), acls)
+}
+
+func (b *createBuilder) protectedPath(path string) string {
+	if pathAndNode, err := SplitPath(path); err != nil {
+		return path
+	} else {
+		return JoinPath(pathAndNode.Path, getProtectedPrefix(b.protectedId)+pathAndNode.Node)
+	}
+}
+
+func (b *createBuilder) findProtectedNode(conn ZookeeperConnection, path string) (string, error) {
+	pathAndNode, err := SplitPath(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	children, _, err := conn.Children(pathAndNode.Path)
+
+	if err == zk.ErrNoNode {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	prefix := getProtectedPrefix(b.protectedId)
+
+	for _, child := range children {
+		if strings.HasPrefix(child, prefix) {
+			return JoinPath(pathAndNode.Path, child), nil
+		}
+	}
+
+	return "", nil
+}
+
+func getProtectedPrefix(protectedId string) string {
+	return PROTECTED_PREFIX + protectedId + "-"
+}
+
+func newProtectedId() string {
+	var uuid [16]byte
+
+	rand.Read(uuid[:])
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+func (b *createBuilder) WithProtection() CreateBuilder {
+	b.protectedId = newProtectedId()
+
+	return b
+}
+
+func (b *createBuilder) OrSetData() CreateBuilder {
+	b.setDataIfExists = true
+
+	return b
+}
+
+func (b *createBuilder) Quietly() CreateBuilder {
+	b.quietly = true
+
+	return b
+}
+
+func (b *createBuilder) Idempotent() CreateBuilder {
+	b.idempotent = true
+
+	return b
+}
+
 func (b *createBuilder) CreatingParentsIfNeeded() CreateBuilder {
 	b.createParentsIfNeeded = true
 
 	return b
 }
 
+func (b *createBuilder) CreatingParentContainersIfNeeded() CreateBuilder {
+	b.createParentsIfNeeded = true
+	b.createParentsAsContainers = b.client.useContainerParents
+
+	return b
+}
+
 func (b *createBuilder) WithMode(mode CreateMode) CreateBuilder {
 	b.createMode = mode
 
 	return b
 }
 
+func (b *createBuilder) WithTTL(ttl time.Duration) CreateBuilder {
+	b.ttl = ttl
+
+	return b
+}
+
 func (b *createBuilder) WithACL(acls ...zk.ACL) CreateBuilder {
 	b.acling.aclList = acls
 
@@ -116,6 +320,12 @@ func (b *createBuilder) Compressed() CreateBuilder {
 	return b
 }
 
+func (b *createBuilder) Uncompressed() CreateBuilder {
+	b.compress = false
+
+	return b
+}
+
 func (b *createBuilder) InBackground() CreateBuilder {
 	b.backgrounding = backgrounding{inBackground: true}
 
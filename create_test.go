@@ -1,11 +1,14 @@
 package curator
 
 import (
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -28,6 +31,127 @@ func (s *CreateBuilderTestSuite) TestCreate() {
 	})
 }
 
+func (s *CreateBuilderTestSuite) TestDefaultDataProvider() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.DefaultDataProvider = NewDefaultDataProvider(func(path string) []byte {
+			if strings.HasPrefix(path, "/config/") {
+				return []byte("{}")
+			}
+
+			return nil
+		})
+	}, func(client CuratorFramework, conn *mockConn, acls []zk.ACL) {
+		conn.On("Create", "/config/node", []byte("{}"), int32(PERSISTENT), acls).Return("/config/node", nil).Once()
+		conn.On("Create", "/node", []byte(nil), int32(PERSISTENT), acls).Return("/node", nil).Once()
+
+		_, err := client.Create().WithACL(acls...).ForPath("/config/node")
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.Create().WithACL(acls...).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestReadOnly() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat, acls []zk.ACL) {
+		client.(*curatorFramework).client.state.isReadOnly.Set(true)
+
+		// the writes fail fast
+		_, err := client.Create().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), ErrReadOnlyConnection, err)
+
+		_, err = client.SetData().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), ErrReadOnlyConnection, err)
+		assert.Equal(s.T(), ErrReadOnlyConnection, client.Delete().ForPath("/node"))
+
+		_, err = client.InTransaction().Create().WithACL(acls...).ForPath("/node").Commit()
+
+		assert.Equal(s.T(), ErrReadOnlyConnection, err)
+
+		// the reads proceed
+		conn.On("Get", "/node").Return(data, stat, nil).Once()
+
+		data2, err := client.GetData().ForPath("/node")
+
+		assert.Equal(s.T(), data, data2)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestOrSetData() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL) {
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+		conn.On("Set", "/node", data, int32(-1)).Return(&zk.Stat{}, nil).Once()
+
+		path, err := client.Create().OrSetData().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+
+		// the node doesn't exist
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("/node", nil).Once()
+
+		path, err = client.Create().OrSetData().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+
+		// fail without OrSetData
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+
+		_, err = client.Create().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.EqualError(s.T(), err, zk.ErrNodeExists.Error())
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestIdempotent() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL, retryPolicy *mockRetryPolicy) {
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Twice()
+
+		// the node was created before the session expired
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrSessionExpired).Once()
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+		conn.On("Get", "/node").Return(data, &zk.Stat{}, nil).Once()
+
+		path, err := client.Create().Idempotent().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+
+		// the node was created by another client with other data
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrSessionExpired).Once()
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+		conn.On("Get", "/node").Return([]byte("other"), &zk.Stat{}, nil).Once()
+
+		_, err = client.Create().Idempotent().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.EqualError(s.T(), err, zk.ErrNodeExists.Error())
+
+		// the node existed before the first try
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+
+		_, err = client.Create().Idempotent().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.EqualError(s.T(), err, zk.ErrNodeExists.Error())
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestQuietly() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL) {
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+
+		path, err := client.Create().Quietly().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
 func (s *CreateBuilderTestSuite) TestNamespace() {
 	s.WithNamespace("parent", func(builder *CuratorFrameworkBuilder, client CuratorFramework, conn *mockConn, acls []zk.ACL) {
 		conn.On("Exists", "/parent").Return(false, nil, nil).Once()
@@ -82,6 +206,42 @@ func (s *CreateBuilderTestSuite) TestCompression() {
 	})
 }
 
+func (s *CreateBuilderTestSuite) TestProtection() {
+	s.With(func(builder *CuratorFrameworkBuilder, client CuratorFramework, conn *mockConn, acls []zk.ACL) {
+		create := client.Create().WithProtection().(*createBuilder)
+
+		assert.Regexp(s.T(), "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", create.protectedId)
+
+		protectedPath := "/parent/" + PROTECTED_PREFIX + create.protectedId + "-node-"
+
+		conn.On("Create", protectedPath, builder.DefaultData, int32(EPHEMERAL_SEQUENTIAL), acls).Return(protectedPath+"0000000001", nil).Once()
+
+		path, err := create.WithMode(EPHEMERAL_SEQUENTIAL).WithACL(acls...).ForPath("/parent/node-")
+
+		assert.Equal(s.T(), protectedPath+"0000000001", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestProtectionAfterConnectionLoss() {
+	s.With(func(builder *CuratorFrameworkBuilder, client CuratorFramework, conn *mockConn, acls []zk.ACL, retryPolicy *mockRetryPolicy) {
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Once()
+
+		create := client.Create().WithProtection().(*createBuilder)
+
+		protectedNode := PROTECTED_PREFIX + create.protectedId + "-node-"
+
+		// the node was created before the session expired
+		conn.On("Create", "/parent/"+protectedNode, builder.DefaultData, int32(EPHEMERAL_SEQUENTIAL), acls).Return("", zk.ErrSessionExpired).Once()
+		conn.On("Children", "/parent").Return([]string{"node-0000000001", protectedNode + "0000000002"}, nil, nil).Once()
+
+		path, err := create.WithMode(EPHEMERAL_SEQUENTIAL).WithACL(acls...).ForPath("/parent/node-")
+
+		assert.Equal(s.T(), "/parent/"+protectedNode+"0000000002", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
 func (s *CreateBuilderTestSuite) TestCreateParents() {
 	s.With(func(builder *CuratorFrameworkBuilder, client CuratorFramework, conn *mockConn, data []byte, aclProvider *mockACLProvider, acls []zk.ACL) {
 		aclProvider.On("GetAclForPath", "/parent/child").Return(READ_ACL_UNSAFE).Once()
@@ -97,3 +257,134 @@ func (s *CreateBuilderTestSuite) TestCreateParents() {
 		assert.Equal(s.T(), err, zk.ErrAPIError)
 	})
 }
+
+func (s *CreateBuilderTestSuite) TestCreateAncestors() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, aclProvider *mockACLProvider) {
+		aclProvider.On("GetAclForPath", "/grandparent/parent/child").Return(READ_ACL_UNSAFE).Twice()
+		conn.On("Create", "/grandparent/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("", zk.ErrNoNode).Once()
+
+		conn.On("Exists", "/grandparent").Return(true, nil, nil).Once()
+		conn.On("Exists", "/grandparent/parent").Return(false, nil, nil).Once()
+		aclProvider.On("GetAclForPath", "/grandparent/parent").Return(nil).Once()
+		aclProvider.On("GetDefaultAcl").Return(CREATOR_ALL_ACL).Once()
+		conn.On("Create", "/grandparent/parent", []byte{}, int32(PERSISTENT), CREATOR_ALL_ACL).Return("/grandparent/parent", nil).Once()
+
+		conn.On("Create", "/grandparent/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("/grandparent/parent/child", nil).Once()
+
+		path, err := client.Create().CreatingParentsIfNeeded().ForPathWithData("/grandparent/parent/child", data)
+
+		assert.Equal(s.T(), "/grandparent/parent/child", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestCreateParentContainers() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, aclProvider *mockACLProvider) {
+		aclProvider.On("GetAclForPath", "/parent/child").Return(READ_ACL_UNSAFE).Twice()
+		conn.On("Create", "/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("", zk.ErrNoNode).Once()
+
+		conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+		aclProvider.On("GetAclForPath", "/parent").Return(CREATOR_ALL_ACL).Once()
+		conn.On("CreateContainer", "/parent", []byte{}, CREATOR_ALL_ACL).Return("/parent", nil).Once()
+
+		conn.On("Create", "/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("/parent/child", nil).Once()
+
+		path, err := client.Create().CreatingParentContainersIfNeeded().ForPathWithData("/parent/child", data)
+
+		assert.Equal(s.T(), "/parent/child", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestDontUseContainerParents() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.DontUseContainerParents = true
+	}, func(client CuratorFramework, conn *mockConn, data []byte, aclProvider *mockACLProvider) {
+		aclProvider.On("GetAclForPath", "/parent/child").Return(READ_ACL_UNSAFE).Twice()
+		conn.On("Create", "/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("", zk.ErrNoNode).Once()
+
+		conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+		aclProvider.On("GetAclForPath", "/parent").Return(CREATOR_ALL_ACL).Once()
+		conn.On("Create", "/parent", []byte{}, int32(PERSISTENT), CREATOR_ALL_ACL).Return("/parent", nil).Once()
+
+		conn.On("Create", "/parent/child", data, int32(PERSISTENT), READ_ACL_UNSAFE).Return("/parent/child", nil).Once()
+
+		path, err := client.Create().CreatingParentContainersIfNeeded().ForPathWithData("/parent/child", data)
+
+		assert.Equal(s.T(), "/parent/child", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestContainer() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL) {
+		conn.On("CreateContainer", "/container", data, acls).Return("/container", nil).Once()
+
+		path, err := client.Create().WithMode(CONTAINER).WithACL(acls...).ForPathWithData("/container", data)
+
+		assert.Equal(s.T(), "/container", path)
+		assert.NoError(s.T(), err)
+
+		// fallback to a persistent node if the server doesn't support containers
+		conn.On("CreateContainer", "/node", data, acls).Return("", ErrUnimplemented).Once()
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("/node", nil).Once()
+
+		path, err = client.Create().WithMode(CONTAINER).WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestTTL() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL) {
+		conn.On("CreateTTL", "/node", data, int32(PERSISTENT_WITH_TTL), acls, time.Minute).Return("/node", nil).Once()
+
+		path, err := client.Create().WithMode(PERSISTENT_WITH_TTL).WithTTL(time.Minute).WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+
+		conn.On("CreateTTL", "/seq", data, int32(PERSISTENT_SEQUENTIAL_WITH_TTL), acls, time.Second).Return("/seq0000000001", nil).Once()
+
+		path, err = client.Create().WithMode(PERSISTENT_SEQUENTIAL_WITH_TTL).WithTTL(time.Second).WithACL(acls...).ForPathWithData("/seq", data)
+
+		assert.Equal(s.T(), "/seq0000000001", path)
+		assert.NoError(s.T(), err)
+
+		path, err = client.Create().WithMode(PERSISTENT_WITH_TTL).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "", path)
+		assert.EqualError(s.T(), err, ErrInvalidTTL.Error())
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestTTLInBackground() {
+	s.With(func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup, data []byte, acls []zk.ACL) {
+		conn.On("CreateTTL", "/node", data, int32(PERSISTENT_WITH_TTL), acls, time.Minute).Return("/node", nil).Once()
+
+		_, err := client.Create().WithMode(PERSISTENT_WITH_TTL).WithTTL(time.Minute).WithACL(acls...).InBackgroundWithCallback(
+			func(client CuratorFramework, event CuratorEvent) error {
+				defer wg.Done()
+
+				assert.Equal(s.T(), CREATE, event.Type())
+				assert.Equal(s.T(), "/node", event.Path())
+				assert.NoError(s.T(), event.Err())
+
+				return nil
+			}).ForPathWithData("/node", data)
+
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *CreateBuilderTestSuite) TestTTLNotSupported() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte) {
+		create := client.Create().WithMode(PERSISTENT_WITH_TTL).WithTTL(time.Minute).(*createBuilder)
+
+		path, err := create.create(struct{ ZookeeperConnection }{conn}, "/node", data)
+
+		assert.Equal(s.T(), "", path)
+		assert.EqualError(s.T(), err, ErrTTLNotSupported.Error())
+	})
+}
@@ -0,0 +1,482 @@
+package curatortest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const (
+	DEFAULT_RECONNECT_DELAY = 100 * time.Millisecond // the time a dropped connection is disconnected
+)
+
+// The faults injected by a ChaosDialer
+type ChaosStats struct {
+	Delays      int64 // the operations delayed by the latency
+	Drops       int64 // the dropped connections
+	Duplicates  int64 // the watch events delivered twice
+	Expirations int64 // the expired sessions
+}
+
+// A ZookeeperDialer decorating another one, which injects the faults into its connections for the resilience tests of the applications.
+//
+// The faults are injected before the operations with their probabilities, or on the schedules of their intervals,
+// which are copied once a connection is dialed, so changing them only affects the following connections.
+// A dropped connection fails the operation with zk.ErrConnectionClosed, and holds the following operations until it is re-established with the same session.
+// An expired session closes the decorated connection, and fails the operations with zk.ErrSessionExpired, so the framework dials a new connection.
+//
+// The connections support the containers and the TTL nodes if the decorated ones do, the other extensions aren't forwarded.
+type ChaosDialer struct {
+	Dialer               curator.ZookeeperDialer // the decorated dialer, the DefaultZookeeperDialer if nil
+	Latency              time.Duration           // the latency added to each operation
+	LatencyJitter        time.Duration           // a random latency up to the jitter added to each operation
+	DropProbability      float64                 // the probability of dropping the connection before an operation
+	DropInterval         time.Duration           // drop the connections on a schedule
+	ReconnectDelay       time.Duration           // the time a dropped connection is disconnected, DEFAULT_RECONNECT_DELAY by default
+	DuplicateProbability float64                 // the probability of delivering a watch event twice
+	ExpireProbability    float64                 // the probability of expiring the session before an operation
+	ExpireInterval       time.Duration           // expire the sessions on a schedule
+	Seed                 int64                   // the seed of the random faults, so a failed test can be replayed, the current time by default
+
+	lock  sync.Mutex
+	rand  *rand.Rand
+	stats ChaosStats
+}
+
+// The faults of a connection, copied from the dialer once it is dialed, so the dialer can be reconfigured while the connection runs
+type chaosFaults struct {
+	latency              time.Duration
+	latencyJitter        time.Duration
+	dropProbability      float64
+	dropInterval         time.Duration
+	reconnectDelay       time.Duration
+	duplicateProbability float64
+	expireProbability    float64
+	expireInterval       time.Duration
+}
+
+func (d *ChaosDialer) Dial(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (curator.ZookeeperConnection, <-chan zk.Event, error) {
+	dialer := d.Dialer
+
+	if dialer == nil {
+		dialer = &curator.DefaultZookeeperDialer{}
+	}
+
+	if conn, events, err := dialer.Dial(connString, sessionTimeout, canBeReadOnly); err != nil {
+		return nil, nil, err
+	} else {
+		c := newChaosConn(d, d.faults(), conn, events)
+
+		return c, c.events.events, nil
+	}
+}
+
+// Return the faults injected into the connections of the dialer
+func (d *ChaosDialer) Stats() ChaosStats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.stats
+}
+
+func (d *ChaosDialer) faults() chaosFaults {
+	reconnectDelay := d.ReconnectDelay
+
+	if reconnectDelay <= 0 {
+		reconnectDelay = DEFAULT_RECONNECT_DELAY
+	}
+
+	return chaosFaults{
+		latency:              d.Latency,
+		latencyJitter:        d.LatencyJitter,
+		dropProbability:      d.DropProbability,
+		dropInterval:         d.DropInterval,
+		reconnectDelay:       reconnectDelay,
+		duplicateProbability: d.DuplicateProbability,
+		expireProbability:    d.ExpireProbability,
+		expireInterval:       d.ExpireInterval,
+	}
+}
+
+func (d *ChaosDialer) chance(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.random().Float64() < probability
+}
+
+func (d *ChaosDialer) latency(latency, jitter time.Duration) time.Duration {
+	if jitter > 0 {
+		d.lock.Lock()
+		latency += time.Duration(d.random().Int63n(int64(jitter)))
+		d.lock.Unlock()
+	}
+
+	return latency
+}
+
+// Return the random source, which is guarded by the lock of the dialer
+func (d *ChaosDialer) random() *rand.Rand {
+	if d.rand == nil {
+		seed := d.Seed
+
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+
+		d.rand = rand.New(rand.NewSource(seed))
+	}
+
+	return d.rand
+}
+
+func (d *ChaosDialer) count(counter *int64) {
+	d.lock.Lock()
+	*counter++
+	d.lock.Unlock()
+}
+
+type chaosConn struct {
+	dialer  *ChaosDialer
+	faults  chaosFaults
+	conn    curator.ZookeeperConnection
+	events  *eventQueue
+	done    chan struct{} // closed once the connection is closed
+	lock    sync.Mutex
+	cond    *sync.Cond // signaled when the connection is re-established, expired or closed
+	dropped bool
+	expired bool
+	closed  bool
+}
+
+func newChaosConn(dialer *ChaosDialer, faults chaosFaults, conn curator.ZookeeperConnection, events <-chan zk.Event) *chaosConn {
+	c := &chaosConn{
+		dialer: dialer,
+		faults: faults,
+		conn:   conn,
+		events: newEventQueue(),
+		done:   make(chan struct{}),
+	}
+
+	c.cond = sync.NewCond(&c.lock)
+
+	go c.forward(events)
+
+	if faults.dropInterval > 0 {
+		go c.schedule(faults.dropInterval, c.injectDrop)
+	}
+
+	if faults.expireInterval > 0 {
+		go c.schedule(faults.expireInterval, c.injectExpiration)
+	}
+
+	return c
+}
+
+// Forward the events of the decorated connection, until its session is expired by the dialer
+func (c *chaosConn) forward(events <-chan zk.Event) {
+	defer c.events.close()
+
+	for event := range events {
+		c.lock.Lock()
+		expired := c.expired
+		c.lock.Unlock()
+
+		if !expired {
+			c.events.enqueue(event)
+		}
+	}
+}
+
+func (c *chaosConn) schedule(interval time.Duration, fault func() bool) {
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			fault()
+		}
+	}
+}
+
+// Inject the faults before an operation, return the error of the operation if it fails
+func (c *chaosConn) before() error {
+	if latency := c.dialer.latency(c.faults.latency, c.faults.latencyJitter); latency > 0 {
+		c.dialer.count(&c.dialer.stats.Delays)
+
+		time.Sleep(latency)
+	}
+
+	c.lock.Lock()
+
+	for c.dropped && !c.expired && !c.closed {
+		c.cond.Wait() // the operations are held until the connection is re-established
+	}
+
+	closed, expired := c.closed, c.expired
+
+	c.lock.Unlock()
+
+	if closed {
+		return zk.ErrClosing
+	} else if expired {
+		return zk.ErrSessionExpired
+	} else if c.dialer.chance(c.faults.expireProbability) && c.injectExpiration() {
+		return zk.ErrSessionExpired
+	} else if c.dialer.chance(c.faults.dropProbability) && c.injectDrop() {
+		return zk.ErrConnectionClosed
+	}
+
+	return nil
+}
+
+// Drop the connection, which is re-established with the same session after the reconnect delay
+func (c *chaosConn) injectDrop() bool {
+	c.lock.Lock()
+
+	if c.dropped || c.expired || c.closed {
+		c.lock.Unlock()
+
+		return false
+	}
+
+	c.dropped = true
+	c.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateDisconnected})
+
+	c.lock.Unlock()
+
+	c.dialer.count(&c.dialer.stats.Drops)
+
+	time.AfterFunc(c.faults.reconnectDelay, c.reconnect)
+
+	return true
+}
+
+func (c *chaosConn) reconnect() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.dropped && !c.expired && !c.closed {
+		c.dropped = false
+
+		c.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateConnected})
+		c.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateHasSession})
+	}
+
+	c.cond.Broadcast()
+}
+
+// Expire the session, the decorated connection is closed so its ephemeral nodes are deleted
+func (c *chaosConn) injectExpiration() bool {
+	c.lock.Lock()
+
+	if c.expired || c.closed {
+		c.lock.Unlock()
+
+		return false
+	}
+
+	c.expired = true
+	c.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateExpired})
+	c.cond.Broadcast()
+
+	c.lock.Unlock()
+
+	c.dialer.count(&c.dialer.stats.Expirations)
+
+	c.conn.Close()
+
+	return true
+}
+
+// Deliver the events of the watch, some of them twice
+func (c *chaosConn) watch(events <-chan zk.Event) <-chan zk.Event {
+	if events == nil {
+		return nil
+	}
+
+	duplicated := make(chan zk.Event, 2)
+
+	go func() {
+		defer close(duplicated)
+
+		for event := range events {
+			duplicated <- event
+
+			if c.dialer.chance(c.faults.duplicateProbability) {
+				c.dialer.count(&c.dialer.stats.Duplicates)
+
+				duplicated <- event
+			}
+		}
+	}()
+
+	return duplicated
+}
+
+func (c *chaosConn) AddAuth(scheme string, auth []byte) error {
+	return c.conn.AddAuth(scheme, auth) // the faults aren't injected while the connection is dialed
+}
+
+func (c *chaosConn) Close() {
+	c.lock.Lock()
+
+	if c.closed {
+		c.lock.Unlock()
+
+		return
+	}
+
+	c.closed = true
+	expired := c.expired
+
+	close(c.done)
+	c.cond.Broadcast()
+
+	c.lock.Unlock()
+
+	if !expired {
+		c.conn.Close()
+	}
+}
+
+func (c *chaosConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if err := c.before(); err != nil {
+		return "", err
+	}
+
+	return c.conn.Create(path, data, flags, acl)
+}
+
+func (c *chaosConn) CreateContainer(path string, data []byte, acl []zk.ACL) (string, error) {
+	if containerConn, ok := c.conn.(curator.ContainerZookeeperConnection); !ok {
+		return "", curator.ErrUnimplemented
+	} else if err := c.before(); err != nil {
+		return "", err
+	} else {
+		return containerConn.CreateContainer(path, data, acl)
+	}
+}
+
+func (c *chaosConn) CreateTTL(path string, data []byte, flags int32, acl []zk.ACL, ttl time.Duration) (string, error) {
+	if ttlConn, ok := c.conn.(curator.TTLZookeeperConnection); !ok {
+		return "", curator.ErrTTLNotSupported
+	} else if err := c.before(); err != nil {
+		return "", err
+	} else {
+		return ttlConn.CreateTTL(path, data, flags, acl, ttl)
+	}
+}
+
+func (c *chaosConn) Exists(path string) (bool, *zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return false, nil, err
+	}
+
+	return c.conn.Exists(path)
+}
+
+func (c *chaosConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	if err := c.before(); err != nil {
+		return false, nil, nil, err
+	}
+
+	exists, stat, events, err := c.conn.ExistsW(path)
+
+	return exists, stat, c.watch(events), err
+}
+
+func (c *chaosConn) Delete(path string, version int32) error {
+	if err := c.before(); err != nil {
+		return err
+	}
+
+	return c.conn.Delete(path, version)
+}
+
+func (c *chaosConn) Get(path string) ([]byte, *zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return nil, nil, err
+	}
+
+	return c.conn.Get(path)
+}
+
+func (c *chaosConn) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	if err := c.before(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	data, stat, events, err := c.conn.GetW(path)
+
+	return data, stat, c.watch(events), err
+}
+
+func (c *chaosConn) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return nil, err
+	}
+
+	return c.conn.Set(path, data, version)
+}
+
+func (c *chaosConn) Children(path string) ([]string, *zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return nil, nil, err
+	}
+
+	return c.conn.Children(path)
+}
+
+func (c *chaosConn) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	if err := c.before(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	children, stat, events, err := c.conn.ChildrenW(path)
+
+	return children, stat, c.watch(events), err
+}
+
+func (c *chaosConn) GetACL(path string) ([]zk.ACL, *zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return nil, nil, err
+	}
+
+	return c.conn.GetACL(path)
+}
+
+func (c *chaosConn) SetACL(path string, acl []zk.ACL, version int32) (*zk.Stat, error) {
+	if err := c.before(); err != nil {
+		return nil, err
+	}
+
+	return c.conn.SetACL(path, acl, version)
+}
+
+func (c *chaosConn) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	if err := c.before(); err != nil {
+		return nil, err
+	}
+
+	return c.conn.Multi(ops...)
+}
+
+func (c *chaosConn) Sync(path string) (string, error) {
+	if err := c.before(); err != nil {
+		return "", err
+	}
+
+	return c.conn.Sync(path)
+}
@@ -0,0 +1,162 @@
+package curatortest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+func dialChaos(t *testing.T, dialer *ChaosDialer) (curator.ZookeeperConnection, <-chan zk.Event) {
+	conn, events, err := dialer.Dial(SIMULATOR_CONNECT_STRING, curator.DEFAULT_SESSION_TIMEOUT, false)
+
+	assert.NoError(t, err)
+
+	waitSessionState(t, events, zk.StateHasSession)
+
+	return conn, events
+}
+
+func waitSessionState(t *testing.T, events <-chan zk.Event, expected zk.State) {
+	timeout := time.After(timing.ForWaiting().Value())
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type == zk.EventSession && event.State == expected {
+				return
+			}
+		case <-timeout:
+			assert.Fail(t, "the session state isn't changed", "expected %s", expected)
+
+			return
+		}
+	}
+}
+
+func TestChaosDialerLatency(t *testing.T) {
+	dialer := &ChaosDialer{Dialer: NewSimulator(), Latency: 20 * time.Millisecond, LatencyJitter: 10 * time.Millisecond}
+	conn, _ := dialChaos(t, dialer)
+
+	defer conn.Close()
+
+	startTime := time.Now()
+
+	_, err := conn.Create("/node", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+	assert.True(t, time.Since(startTime) >= 20*time.Millisecond)
+	assert.Equal(t, ChaosStats{Delays: 1}, dialer.Stats())
+}
+
+func TestChaosDialerDrops(t *testing.T) {
+	simulator := NewSimulator()
+	dialer := &ChaosDialer{Dialer: simulator, DropInterval: 20 * time.Millisecond, ReconnectDelay: 10 * time.Millisecond}
+	conn, events := dialChaos(t, dialer)
+
+	defer conn.Close()
+
+	sessions := simulator.Sessions()
+
+	// the connection is re-established with the same session
+	waitSessionState(t, events, zk.StateDisconnected)
+	waitSessionState(t, events, zk.StateHasSession)
+
+	_, err := conn.Create("/node", nil, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+	assert.Equal(t, sessions, simulator.Sessions())
+	assert.True(t, dialer.Stats().Drops > 0)
+
+	// the operation fails once its connection is dropped
+	other, _ := dialChaos(t, &ChaosDialer{Dialer: simulator, DropProbability: 1})
+
+	defer other.Close()
+
+	_, _, err = other.Exists("/node")
+
+	assert.Equal(t, zk.ErrConnectionClosed, err)
+}
+
+func TestChaosDialerExpirations(t *testing.T) {
+	simulator := NewSimulator()
+	dialer := &ChaosDialer{Dialer: simulator, ExpireProbability: 1}
+	conn, events := dialChaos(t, dialer)
+
+	defer conn.Close()
+
+	_, err := conn.Create("/node", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.Equal(t, zk.ErrSessionExpired, err)
+
+	waitSessionState(t, events, zk.StateExpired)
+
+	_, _, err = conn.Exists("/node")
+
+	assert.Equal(t, zk.ErrSessionExpired, err)
+	assert.Empty(t, simulator.Sessions())
+	assert.Equal(t, ChaosStats{Expirations: 1}, dialer.Stats())
+}
+
+func TestChaosDialerExpirationsWithFramework(t *testing.T) {
+	server := NewSimulatedServer()
+
+	defer server.Close()
+
+	dialer := &ChaosDialer{Dialer: server.Simulator(), ExpireInterval: 50 * time.Millisecond}
+	builder := server.Builder()
+	builder.ZookeeperDialer = dialer
+	builder.RetryPolicy = curator.NewRetryNTimes(3, 10*time.Millisecond)
+
+	client := builder.Build()
+
+	assert.NoError(t, client.Start())
+	assert.NoError(t, client.BlockUntilConnectedTimeout(timing.ForWaiting().Value()))
+
+	defer client.Close()
+
+	states := make(chan curator.ConnectionState, 10)
+
+	client.ConnectionStateListenable().AddListener(curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		states <- newState
+	}))
+
+	// the framework dials a new connection once the session is expired
+	waitState(t, states, curator.LOST)
+	waitState(t, states, curator.RECONNECTED)
+
+	assert.NoError(t, curator.CallWithRetry(client.ZookeeperClient(), func() error {
+		_, err := client.Create().ForPath("/node")
+
+		return err
+	}))
+}
+
+func TestChaosDialerDuplicates(t *testing.T) {
+	simulator := NewSimulator()
+	dialer := &ChaosDialer{Dialer: simulator, DuplicateProbability: 1}
+	conn, _ := dialChaos(t, dialer)
+
+	defer conn.Close()
+
+	_, _, watch, err := conn.ExistsW("/node")
+
+	assert.NoError(t, err)
+
+	_, err = conn.Create("/node", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+
+	var received []zk.Event
+
+	for event := range watch {
+		received = append(received, event)
+	}
+
+	assert.Len(t, received, 2)
+	assert.Equal(t, received[0], received[1])
+	assert.Equal(t, zk.EventNodeCreated, received[0].Type)
+	assert.Equal(t, int64(1), dialer.Stats().Duplicates)
+}
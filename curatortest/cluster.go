@@ -0,0 +1,175 @@
+package curatortest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/flier/curator.go"
+)
+
+// An ensemble of the servers for the tests, e.g. of the failover or the loss of the quorum,
+// whose members can be stopped and restarted one by one.
+type TestingCluster struct {
+	simulator *Simulator // nil if the real servers are launched
+	servers   []*TestingServer
+}
+
+// Launch an ensemble of the ZooKeeper installed under $ZOOKEEPER_HOME, or run a simulator if it isn't set
+func NewTestingCluster(size int) (*TestingCluster, error) {
+	if home := os.Getenv(ZOOKEEPER_HOME_ENV); home != "" {
+		return NewZookeeperCluster(home, size)
+	}
+
+	return NewSimulatedCluster(size), nil
+}
+
+// Run an in-process simulator of the ensemble, whose servers are named simulator-1:2181, simulator-2:2181, etc.
+func NewSimulatedCluster(size int) *TestingCluster {
+	names := make([]string, size)
+
+	for i := range names {
+		names[i] = fmt.Sprintf("simulator-%d:2181", i+1)
+	}
+
+	cluster := &TestingCluster{simulator: NewEnsembleSimulator(names...)}
+
+	for _, name := range names {
+		cluster.servers = append(cluster.servers, &TestingServer{simulator: cluster.simulator, name: name})
+	}
+
+	return cluster
+}
+
+// Launch an ensemble of the ZooKeeper installation with java, on the free ports and the temporary data directories
+func NewZookeeperCluster(home string, size int) (*TestingCluster, error) {
+	cluster := &TestingCluster{}
+	peers := make([]string, size)
+
+	for i := 0; i < size; i++ {
+		server := &TestingServer{home: home}
+
+		cluster.servers = append(cluster.servers, server)
+
+		if dataDir, err := ioutil.TempDir("", "curatortest"); err != nil {
+			cluster.Close()
+
+			return nil, err
+		} else {
+			server.dataDir = dataDir
+		}
+
+		var ports [3]int // the client, the peer and the election ports
+
+		for j := range ports {
+			if port, err := freePort(); err != nil {
+				cluster.Close()
+
+				return nil, err
+			} else {
+				ports[j] = port
+			}
+		}
+
+		server.port = ports[0]
+		peers[i] = fmt.Sprintf("server.%d=127.0.0.1:%d:%d", i+1, ports[1], ports[2])
+	}
+
+	for i, server := range cluster.servers {
+		config := filepath.Join(server.dataDir, "zoo.cfg")
+		lines := append([]string{
+			"tickTime=500",
+			"initLimit=10",
+			"syncLimit=5",
+			"dataDir=" + server.dataDir,
+			"clientPort=" + strconv.Itoa(server.port),
+		}, peers...)
+
+		if err := ioutil.WriteFile(config, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			cluster.Close()
+
+			return nil, err
+		} else if err := ioutil.WriteFile(filepath.Join(server.dataDir, "myid"), []byte(strconv.Itoa(i+1)), 0644); err != nil {
+			cluster.Close()
+
+			return nil, err
+		}
+
+		server.args = []string{QUORUM_PEER_MAIN_CLASS, config}
+	}
+
+	// the servers listen once the quorum is formed, so they are all launched before waiting for them
+	for _, server := range cluster.servers {
+		if err := server.launch(); err != nil {
+			cluster.Close()
+
+			return nil, err
+		}
+	}
+
+	for _, server := range cluster.servers {
+		if err := server.awaitListening(); err != nil {
+			cluster.Close()
+
+			return nil, err
+		}
+	}
+
+	return cluster, nil
+}
+
+// Return the servers of the ensemble
+func (c *TestingCluster) Servers() []*TestingServer {
+	return c.servers
+}
+
+// Return the connection string of all the servers
+func (c *TestingCluster) ConnectString() string {
+	servers := make([]string, len(c.servers))
+
+	for i, server := range c.servers {
+		servers[i] = server.ConnectString()
+	}
+
+	return strings.Join(servers, ",")
+}
+
+// Return the simulator, nil if the real servers are launched
+func (c *TestingCluster) Simulator() *Simulator {
+	return c.simulator
+}
+
+// Return a builder of the clients connected to the ensemble
+func (c *TestingCluster) Builder() *curator.CuratorFrameworkBuilder {
+	builder := &curator.CuratorFrameworkBuilder{}
+
+	if c.simulator != nil {
+		builder.ZookeeperDialer = c.simulator
+	}
+
+	return builder.ConnectString(c.ConnectString())
+}
+
+// Create a client connected to the ensemble, which should be started
+func (c *TestingCluster) NewClient(retryPolicy curator.RetryPolicy) curator.CuratorFramework {
+	builder := c.Builder()
+	builder.RetryPolicy = retryPolicy
+
+	return builder.Build()
+}
+
+// Stop all the servers and remove their data
+func (c *TestingCluster) Close() error {
+	var err error
+
+	for _, server := range c.servers {
+		if closeErr := server.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,86 @@
+package curatortest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulatedCluster(t *testing.T) {
+	cluster := NewSimulatedCluster(3)
+
+	defer cluster.Close()
+
+	assert.Equal(t, "simulator-1:2181,simulator-2:2181,simulator-3:2181", cluster.ConnectString())
+
+	client := cluster.NewClient(curator.NewRetryNTimes(3, 10*time.Millisecond))
+
+	assert.NoError(t, client.Start())
+	assert.NoError(t, client.BlockUntilConnectedTimeout(timing.ForWaiting().Value()))
+
+	defer client.Close()
+
+	states := make(chan curator.ConnectionState, 10)
+
+	client.ConnectionStateListenable().AddListener(curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		states <- newState
+	}))
+
+	_, err := client.Create().WithMode(curator.EPHEMERAL).ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+
+	sessions := cluster.Simulator().Sessions()
+
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "simulator-1:2181", cluster.Simulator().SessionServer(sessions[0]))
+
+	// the session fails over to another server
+	assert.NoError(t, cluster.Servers()[0].Stop())
+
+	assert.Equal(t, "simulator-2:2181", cluster.Simulator().SessionServer(sessions[0]))
+
+	exists, err := client.CheckExists().ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, exists)
+
+	// the connection is suspended without a quorum
+	assert.NoError(t, cluster.Servers()[2].Stop())
+	assert.False(t, cluster.Simulator().HasQuorum())
+	assert.Equal(t, "", cluster.Simulator().SessionServer(sessions[0]))
+
+	waitState(t, states, curator.SUSPENDED)
+
+	// and reconnected with the same session once the quorum is restored
+	assert.NoError(t, cluster.Servers()[0].Restart())
+	assert.True(t, cluster.Simulator().HasQuorum())
+
+	waitState(t, states, curator.RECONNECTED)
+
+	assert.Equal(t, sessions, cluster.Simulator().Sessions())
+
+	exists, err = client.CheckExists().ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, exists)
+}
+
+func waitState(t *testing.T, states chan curator.ConnectionState, expected curator.ConnectionState) {
+	timeout := time.After(timing.ForWaiting().Value())
+
+	for {
+		select {
+		case state := <-states:
+			if state == expected {
+				return
+			}
+		case <-timeout:
+			assert.Fail(t, "the state isn't changed", "expected %s", expected)
+
+			return
+		}
+	}
+}
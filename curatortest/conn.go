@@ -0,0 +1,315 @@
+package curatortest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A fake ZookeeperConnection to the simulator, which keeps a real tree of the nodes instead of the scripted expectations of a mock,
+// so the tests exercise the versions, the ephemeral nodes of the session, the sequential counters, the watches and the multi requests.
+//
+// The state of the connection is guarded by the lock of the simulator.
+type FakeConnection struct {
+	simulator      *Simulator
+	servers        []string // the servers of the connection string
+	next           int      // the server tried first on the next connection
+	server         string   // the server of the current connection
+	timeout        time.Duration
+	sessionID      int64
+	state          zk.State
+	disconnectedAt time.Time
+	closed         bool
+	events         *eventQueue
+}
+
+func newFakeConnection(simulator *Simulator, servers []string, timeout time.Duration) *FakeConnection {
+	return &FakeConnection{
+		simulator: simulator,
+		servers:   servers,
+		server:    servers[0],
+		timeout:   timeout,
+		state:     zk.StateConnecting,
+		events:    newEventQueue(),
+	}
+}
+
+// Create a fake connection to a new simulator of a standalone server, whose session is established at once
+func NewFakeConnection() *FakeConnection {
+	conn, _ := NewSimulator().Connect(curator.DEFAULT_SESSION_TIMEOUT)
+
+	return conn
+}
+
+// Call the operation once the connection is connected
+func (c *FakeConnection) do(operation func(s *Simulator) error) error {
+	s := c.simulator
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.await(c); err != nil {
+		return err
+	}
+
+	err := operation(s)
+
+	if err != nil {
+		s.pending = nil
+	} else {
+		s.fire()
+	}
+
+	return err
+}
+
+// Return the simulator of the connection, e.g. to connect another session to the same tree
+func (c *FakeConnection) Simulator() *Simulator {
+	return c.simulator
+}
+
+// Return the events of the session, and of the watches triggered on the connection
+func (c *FakeConnection) Events() <-chan zk.Event {
+	return c.events.events
+}
+
+// Expire the session of the connection, as if it had timed out
+func (c *FakeConnection) Expire() bool {
+	return c.simulator.ExpireSession(c.SessionID())
+}
+
+func (c *FakeConnection) SessionID() int64 {
+	c.simulator.lock.Lock()
+	defer c.simulator.lock.Unlock()
+
+	return c.sessionID
+}
+
+func (c *FakeConnection) AddAuth(scheme string, auth []byte) error {
+	return c.do(func(s *Simulator) error { return nil }) // the ACLs aren't enforced
+}
+
+func (c *FakeConnection) Close() {
+	s := c.simulator
+
+	s.lock.Lock()
+
+	if !c.closed {
+		c.closed = true
+
+		if c.state != zk.StateExpired {
+			c.state = zk.StateDisconnected
+
+			s.closeSession(c, zk.ErrClosing)
+			s.fire()
+
+			c.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateDisconnected, Server: c.server})
+		}
+
+		s.cond.Broadcast()
+	}
+
+	s.lock.Unlock()
+
+	c.events.close()
+}
+
+func (c *FakeConnection) Create(path string, data []byte, flags int32, acl []zk.ACL) (createdPath string, err error) {
+	err = c.do(func(s *Simulator) (err error) {
+		createdPath, err = s.create(c.sessionID, path, data, flags, acl)
+
+		return
+	})
+
+	return
+}
+
+func (c *FakeConnection) CreateContainer(path string, data []byte, acl []zk.ACL) (string, error) {
+	return c.Create(path, data, int32(curator.CONTAINER), acl)
+}
+
+func (c *FakeConnection) Exists(path string) (exists bool, stat *zk.Stat, err error) {
+	exists, stat, _, err = c.exists(path, false)
+
+	return
+}
+
+func (c *FakeConnection) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	return c.exists(path, true)
+}
+
+func (c *FakeConnection) exists(path string, watched bool) (exists bool, stat *zk.Stat, events <-chan zk.Event, err error) {
+	err = c.do(func(s *Simulator) error {
+		if err := validatePath(path, false); err != nil {
+			return err
+		}
+
+		node, ok := s.nodes[path]
+
+		if ok {
+			exists, stat = true, copyStat(node)
+		}
+
+		if watched && ok {
+			events = s.addWatch(c, watchData, path)
+		} else if watched {
+			events = s.addWatch(c, watchExist, path)
+		}
+
+		return nil
+	})
+
+	if stat == nil {
+		stat = &zk.Stat{}
+	}
+
+	return
+}
+
+func (c *FakeConnection) Delete(path string, version int32) error {
+	return c.do(func(s *Simulator) error {
+		return s.delete(path, version)
+	})
+}
+
+func (c *FakeConnection) Get(path string) (data []byte, stat *zk.Stat, err error) {
+	data, stat, _, err = c.get(path, false)
+
+	return
+}
+
+func (c *FakeConnection) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	return c.get(path, true)
+}
+
+func (c *FakeConnection) get(path string, watched bool) (data []byte, stat *zk.Stat, events <-chan zk.Event, err error) {
+	err = c.do(func(s *Simulator) error {
+		if err := validatePath(path, false); err != nil {
+			return err
+		}
+
+		node, ok := s.nodes[path]
+
+		if !ok {
+			return zk.ErrNoNode
+		}
+
+		data, stat = append([]byte(nil), node.data...), copyStat(node)
+
+		if watched {
+			events = s.addWatch(c, watchData, path)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func (c *FakeConnection) Set(path string, data []byte, version int32) (stat *zk.Stat, err error) {
+	err = c.do(func(s *Simulator) (err error) {
+		stat, err = s.setData(path, data, version)
+
+		return
+	})
+
+	return
+}
+
+func (c *FakeConnection) Children(path string) (children []string, stat *zk.Stat, err error) {
+	children, stat, _, err = c.children(path, false)
+
+	return
+}
+
+func (c *FakeConnection) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	return c.children(path, true)
+}
+
+func (c *FakeConnection) children(path string, watched bool) (children []string, stat *zk.Stat, events <-chan zk.Event, err error) {
+	err = c.do(func(s *Simulator) error {
+		if err := validatePath(path, false); err != nil {
+			return err
+		}
+
+		node, ok := s.nodes[path]
+
+		if !ok {
+			return zk.ErrNoNode
+		}
+
+		children = make([]string, 0, len(node.children))
+
+		for child := range node.children {
+			children = append(children, child)
+		}
+
+		sort.Strings(children)
+
+		stat = copyStat(node)
+
+		if watched {
+			events = s.addWatch(c, watchChild, path)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func (c *FakeConnection) GetACL(path string) (acl []zk.ACL, stat *zk.Stat, err error) {
+	err = c.do(func(s *Simulator) error {
+		if err := validatePath(path, false); err != nil {
+			return err
+		}
+
+		node, ok := s.nodes[path]
+
+		if !ok {
+			return zk.ErrNoNode
+		}
+
+		acl, stat = append([]zk.ACL(nil), node.acl...), copyStat(node)
+
+		return nil
+	})
+
+	return
+}
+
+func (c *FakeConnection) SetACL(path string, acl []zk.ACL, version int32) (stat *zk.Stat, err error) {
+	err = c.do(func(s *Simulator) (err error) {
+		stat, err = s.setACL(path, append([]zk.ACL(nil), acl...), version)
+
+		return
+	})
+
+	return
+}
+
+func (c *FakeConnection) Multi(ops ...interface{}) (responses []zk.MultiResponse, err error) {
+	err = c.do(func(s *Simulator) (err error) {
+		responses, err = s.multi(c.sessionID, ops)
+
+		return
+	})
+
+	return
+}
+
+func (c *FakeConnection) Sync(path string) (string, error) {
+	if err := c.do(func(s *Simulator) error { return validatePath(path, false) }); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func copyStat(node *simulatedNode) *zk.Stat {
+	stat := node.stat
+
+	return &stat
+}
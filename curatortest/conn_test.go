@@ -0,0 +1,186 @@
+package curatortest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeConnection(t *testing.T) {
+	conn := NewFakeConnection()
+
+	defer conn.Close()
+
+	assert.NotZero(t, conn.SessionID())
+
+	// the versions of the nodes
+	path, err := conn.Create("/node", []byte("data"), 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/node", path)
+
+	stat, err := conn.Set("/node", []byte("new"), 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), stat.Version)
+
+	_, err = conn.Set("/node", []byte("old"), 0)
+
+	assert.Equal(t, zk.ErrBadVersion, err)
+	assert.Equal(t, zk.ErrBadVersion, conn.Delete("/node", 0))
+
+	// the sequential counters are kept by the parents, even after their children are deleted
+	first, err := conn.Create("/node/seq-", nil, zk.FlagSequence, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/node/seq-0000000000", first)
+	assert.NoError(t, conn.Delete(first, -1))
+
+	second, err := conn.Create("/node/seq-", nil, zk.FlagSequence, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/node/seq-0000000002", second)
+
+	_, stat, err = conn.Get("/node")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), stat.NumChildren)
+	assert.Equal(t, int32(3), stat.Cversion)
+}
+
+func TestFakeConnectionWatches(t *testing.T) {
+	conn := NewFakeConnection()
+
+	defer conn.Close()
+
+	_, err := conn.Create("/node", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+
+	_, _, dataEvents, err := conn.GetW("/node")
+
+	assert.NoError(t, err)
+
+	_, _, childEvents, err := conn.ChildrenW("/node")
+
+	assert.NoError(t, err)
+
+	_, err = conn.Create("/node/child", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+
+	select {
+	case event := <-childEvents:
+		assert.Equal(t, zk.EventNodeChildrenChanged, event.Type)
+		assert.Equal(t, "/node", event.Path)
+	case <-time.After(timing.ForWaiting().Value()):
+		assert.Fail(t, "the child watch isn't triggered")
+	}
+
+	select {
+	case event := <-dataEvents:
+		assert.Fail(t, "unexpected event", "%v", event)
+	default:
+	}
+
+	_, err = conn.Set("/node", []byte("data"), -1)
+
+	assert.NoError(t, err)
+
+	select {
+	case event := <-dataEvents:
+		assert.Equal(t, zk.EventNodeDataChanged, event.Type)
+		assert.Equal(t, "/node", event.Path)
+	case <-time.After(timing.ForWaiting().Value()):
+		assert.Fail(t, "the data watch isn't triggered")
+	}
+
+	// the watches are closed once triggered
+	_, ok := <-dataEvents
+
+	assert.False(t, ok)
+}
+
+func TestFakeConnectionSessions(t *testing.T) {
+	conn := NewFakeConnection()
+
+	defer conn.Close()
+
+	other, err := conn.Simulator().Connect(curator.DEFAULT_SESSION_TIMEOUT)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, conn.SessionID(), other.SessionID())
+
+	_, err = other.Create("/ephemeral", nil, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+
+	_, stat, err := conn.Exists("/ephemeral")
+
+	assert.NoError(t, err)
+	assert.Equal(t, other.SessionID(), stat.EphemeralOwner)
+
+	_, _, events, err := conn.ExistsW("/ephemeral")
+
+	assert.NoError(t, err)
+
+	// the ephemeral nodes are deleted with their session
+	assert.True(t, other.Expire())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, zk.EventNodeDeleted, event.Type)
+	case <-time.After(timing.ForWaiting().Value()):
+		assert.Fail(t, "the watch isn't triggered")
+	}
+
+	_, err = other.Create("/node", nil, 0, zk.WorldACL(zk.PermAll))
+
+	assert.Equal(t, zk.ErrSessionExpired, err)
+	assert.Equal(t, []int64{conn.SessionID()}, conn.Simulator().Sessions())
+}
+
+func TestFakeConnectionMulti(t *testing.T) {
+	conn := NewFakeConnection()
+
+	defer conn.Close()
+
+	_, err := conn.Create("/node", []byte("data"), 0, zk.WorldACL(zk.PermAll))
+
+	assert.NoError(t, err)
+
+	// none of the operations is applied if one fails
+	responses, err := conn.Multi(
+		&zk.CreateRequest{Path: "/tx", Acl: zk.WorldACL(zk.PermAll)},
+		&zk.SetDataRequest{Path: "/node", Data: []byte("new"), Version: -1},
+		&zk.CheckVersionRequest{Path: "/node", Version: 5},
+	)
+
+	assert.Equal(t, zk.ErrBadVersion, err)
+	assert.Len(t, responses, 3)
+
+	data, stat, err := conn.Get("/node")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+	assert.Equal(t, int32(0), stat.Version)
+	assert.Equal(t, []string{"/", "/node", "/zookeeper", "/zookeeper/quota"}, conn.Simulator().Paths())
+
+	// and all of them are applied otherwise
+	_, err = conn.Multi(
+		&zk.CreateRequest{Path: "/tx", Acl: zk.WorldACL(zk.PermAll)},
+		&zk.SetDataRequest{Path: "/node", Data: []byte("new"), Version: 0},
+		&zk.DeleteRequest{Path: "/tx", Version: 0},
+	)
+
+	assert.NoError(t, err)
+
+	data, _, err = conn.Get("/node")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), data)
+	assert.Equal(t, []string{"/", "/node", "/zookeeper", "/zookeeper/quota"}, conn.Simulator().Paths())
+}
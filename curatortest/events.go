@@ -0,0 +1,73 @@
+package curatortest
+
+import (
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The session events waiting to be delivered in order, so the sender never blocks on a slow reader
+type eventQueue struct {
+	events  chan zk.Event
+	lock    sync.Mutex
+	cond    *sync.Cond
+	queue   []zk.Event
+	closing bool
+}
+
+func newEventQueue() *eventQueue {
+	q := &eventQueue{events: make(chan zk.Event, 6)}
+
+	q.cond = sync.NewCond(&q.lock)
+
+	go q.deliver()
+
+	return q
+}
+
+// Queue the event, which is dropped once the queue is closed
+func (q *eventQueue) enqueue(event zk.Event) {
+	q.lock.Lock()
+
+	if !q.closing {
+		q.queue = append(q.queue, event)
+	}
+
+	q.lock.Unlock()
+
+	q.cond.Signal()
+}
+
+// Close the channel once the queued events are delivered
+func (q *eventQueue) close() {
+	q.lock.Lock()
+	q.closing = true
+	q.lock.Unlock()
+
+	q.cond.Signal()
+}
+
+func (q *eventQueue) deliver() {
+	for {
+		q.lock.Lock()
+
+		for len(q.queue) == 0 && !q.closing {
+			q.cond.Wait()
+		}
+
+		if len(q.queue) == 0 {
+			q.lock.Unlock()
+
+			close(q.events)
+
+			return
+		}
+
+		event := q.queue[0]
+		q.queue = q.queue[1:]
+
+		q.lock.Unlock()
+
+		q.events <- event
+	}
+}
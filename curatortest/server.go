@@ -0,0 +1,204 @@
+// Package curatortest provides a TestingServer and a TestingCluster for the tests of the recipes and the applications which don't require docker,
+// they launch the real ZooKeeper servers if one is installed, or run an in-process simulator of the servers otherwise.
+//
+// A FakeConnection to the simulator can also be used directly, by the tests of the code using a ZookeeperConnection,
+// a ChaosDialer injects the faults into the connections of any dialer, for the resilience tests,
+// and a Timing derives the timeouts and the sleeps of the tests from a single value, so they can be scaled on a loaded machine.
+package curatortest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/flier/curator.go"
+)
+
+const (
+	ZOOKEEPER_HOME_ENV       = "ZOOKEEPER_HOME" // the installation of ZooKeeper launched by NewTestingServer, e.g. /opt/zookeeper
+	SIMULATOR_CONNECT_STRING = "simulator:2181" // the connection string of the simulator, which is ignored by its dialer
+	DEFAULT_START_TIMEOUT    = 30 * time.Second // the time to wait for a launched server to listen
+	ZOOKEEPER_MAIN_CLASS     = "org.apache.zookeeper.server.ZooKeeperServerMain"
+	QUORUM_PEER_MAIN_CLASS   = "org.apache.zookeeper.server.quorum.QuorumPeerMain"
+)
+
+// A ZooKeeper server for the tests, either a launched standalone server, or an in-process Simulator
+type TestingServer struct {
+	simulator *Simulator // nil if a real server is launched
+	name      string     // the server of the simulator
+	home      string
+	port      int
+	dataDir   string
+	args      []string // the main class and the arguments of the launched server
+	cmd       *exec.Cmd
+}
+
+// Launch the ZooKeeper installed under $ZOOKEEPER_HOME, or run a simulator if it isn't set
+func NewTestingServer() (*TestingServer, error) {
+	if home := os.Getenv(ZOOKEEPER_HOME_ENV); home != "" {
+		return NewZookeeperServer(home)
+	}
+
+	return NewSimulatedServer(), nil
+}
+
+// Run an in-process simulator of the server
+func NewSimulatedServer() *TestingServer {
+	return &TestingServer{simulator: NewSimulator(), name: SIMULATOR_CONNECT_STRING}
+}
+
+// Launch a standalone server of the ZooKeeper installation with java, on a free port and a temporary data directory
+func NewZookeeperServer(home string) (*TestingServer, error) {
+	server := &TestingServer{home: home}
+
+	if port, err := freePort(); err != nil {
+		return nil, err
+	} else if dataDir, err := ioutil.TempDir("", "curatortest"); err != nil {
+		return nil, err
+	} else {
+		server.port, server.dataDir = port, dataDir
+		server.args = []string{ZOOKEEPER_MAIN_CLASS, strconv.Itoa(port), dataDir}
+	}
+
+	if err := server.start(); err != nil {
+		os.RemoveAll(server.dataDir)
+
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func freePort() (int, error) {
+	if l, err := net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		return 0, err
+	} else {
+		defer l.Close()
+
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+}
+
+func (s *TestingServer) start() error {
+	if err := s.launch(); err != nil {
+		return err
+	}
+
+	return s.awaitListening()
+}
+
+func (s *TestingServer) launch() error {
+	classpath := filepath.Join(s.home, "conf") + string(os.PathListSeparator) +
+		filepath.Join(s.home, "*") + string(os.PathListSeparator) +
+		filepath.Join(s.home, "lib", "*")
+
+	s.cmd = exec.Command("java", append([]string{"-cp", classpath,
+		"-Dzookeeper.admin.enableServer=false",
+		"-Dzookeeper.4lw.commands.whitelist=*",
+		"-Dzookeeper.extendedTypesEnabled=true",
+	}, s.args...)...)
+
+	if err := s.cmd.Start(); err != nil {
+		return fmt.Errorf("fail to launch ZooKeeper from %s, %s", s.home, err)
+	}
+
+	return nil
+}
+
+func (s *TestingServer) awaitListening() error {
+	for deadline := time.Now().Add(DEFAULT_START_TIMEOUT); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", s.ConnectString()); err == nil {
+			conn.Close()
+
+			return nil
+		}
+	}
+
+	s.kill()
+
+	return fmt.Errorf("ZooKeeper doesn't listen on %s in %s", s.ConnectString(), DEFAULT_START_TIMEOUT)
+}
+
+func (s *TestingServer) kill() {
+	if s.cmd != nil {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+		s.cmd = nil
+	}
+}
+
+// Return the connection string of the server
+func (s *TestingServer) ConnectString() string {
+	if s.simulator != nil {
+		return s.name
+	}
+
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(s.port))
+}
+
+// Return the simulator, nil if a real server is launched
+func (s *TestingServer) Simulator() *Simulator {
+	return s.simulator
+}
+
+// Return the dialer connecting to the server, nil for the default dialer of a real server
+func (s *TestingServer) ZookeeperDialer() curator.ZookeeperDialer {
+	if s.simulator != nil {
+		return s.simulator
+	}
+
+	return nil
+}
+
+// Return a builder of the clients connected to the server
+func (s *TestingServer) Builder() *curator.CuratorFrameworkBuilder {
+	builder := &curator.CuratorFrameworkBuilder{ZookeeperDialer: s.ZookeeperDialer()}
+
+	return builder.ConnectString(s.ConnectString())
+}
+
+// Create a client connected to the server, which should be started
+func (s *TestingServer) NewClient(retryPolicy curator.RetryPolicy) curator.CuratorFramework {
+	builder := s.Builder()
+	builder.RetryPolicy = retryPolicy
+
+	return builder.Build()
+}
+
+// Stop the server, the sessions are kept until they time out
+func (s *TestingServer) Stop() error {
+	if s.simulator != nil {
+		return s.simulator.StopServer(s.name)
+	}
+
+	s.kill()
+
+	return nil
+}
+
+// Restart the stopped server on the same port, with the same data
+func (s *TestingServer) Restart() error {
+	if s.simulator != nil {
+		return s.simulator.RestartServer(s.name)
+	} else if s.cmd != nil {
+		return nil
+	}
+
+	return s.start()
+}
+
+// Stop the server and remove its data
+func (s *TestingServer) Close() error {
+	s.Stop()
+
+	if s.dataDir != "" {
+		return os.RemoveAll(s.dataDir)
+	}
+
+	return nil
+}
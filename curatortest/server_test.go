@@ -0,0 +1,182 @@
+package curatortest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+var timing = NewTiming()
+
+func startClient(t *testing.T, server *TestingServer) curator.CuratorFramework {
+	client := server.NewClient(curator.NewRetryNTimes(3, 10*time.Millisecond))
+
+	assert.NoError(t, client.Start())
+	assert.NoError(t, client.BlockUntilConnectedTimeout(timing.ForWaiting().Value()))
+
+	return client
+}
+
+func TestTestingServer(t *testing.T) {
+	server, err := NewTestingServer()
+
+	assert.NoError(t, err)
+
+	defer server.Close()
+
+	client := startClient(t, server)
+
+	defer client.Close()
+
+	// the versions of the nodes
+	path, err := client.Create().CreatingParentsIfNeeded().ForPathWithData("/parent/node", []byte("data"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/parent/node", path)
+
+	_, err = client.Create().ForPath("/parent/node")
+
+	assert.Equal(t, zk.ErrNodeExists, err)
+
+	stat, err := client.SetData().WithVersion(0).ForPathWithData("/parent/node", []byte("new"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), stat.Version)
+
+	_, err = client.SetData().WithVersion(0).ForPathWithData("/parent/node", []byte("old"))
+
+	assert.Equal(t, zk.ErrBadVersion, err)
+
+	data, err := client.GetData().ForPath("/parent/node")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), data)
+
+	assert.Equal(t, zk.ErrNotEmpty, client.Delete().ForPath("/parent"))
+
+	// the sequential nodes are numbered by the parent
+	first, err := client.Create().WithMode(curator.PERSISTENT_SEQUENTIAL).ForPath("/parent/seq-")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/parent/seq-0000000001", first)
+
+	children, err := client.GetChildren().ForPath("/parent")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node", "seq-0000000001"}, children)
+
+	// the multi requests are atomic
+	_, err = client.InTransaction().Create().ForPath("/parent/tx").Check().WithVersion(5).ForPath("/parent/node").Commit()
+
+	assert.Equal(t, zk.ErrBadVersion, err)
+
+	exists, err := client.CheckExists().ForPath("/parent/tx")
+
+	assert.NoError(t, err)
+	assert.Nil(t, exists)
+}
+
+func TestSimulatorWatches(t *testing.T) {
+	server := NewSimulatedServer()
+
+	defer server.Close()
+
+	client := startClient(t, server)
+
+	defer client.Close()
+
+	events := make(chan *zk.Event, 10)
+	watcher := curator.NewWatcher(func(event *zk.Event) { events <- event })
+
+	_, err := client.CheckExists().UsingWatcher(watcher).ForPath("/node")
+
+	assert.NoError(t, err)
+
+	_, err = client.Create().ForPath("/node")
+
+	assert.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, zk.EventNodeCreated, event.Type)
+		assert.Equal(t, "/node", event.Path)
+	case <-time.After(timing.ForWaiting().Value()):
+		assert.Fail(t, "the watch isn't triggered")
+	}
+
+	// the watch is triggered once
+	_, err = client.SetData().ForPathWithData("/node", []byte("data"))
+
+	assert.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Fail(t, "unexpected event", "%v", event)
+	case <-time.After(timing.Value() / 4):
+	}
+}
+
+func TestSimulatorSessions(t *testing.T) {
+	server := NewSimulatedServer()
+
+	defer server.Close()
+
+	client := startClient(t, server)
+
+	defer client.Close()
+
+	other := startClient(t, server)
+
+	_, err := other.Create().WithMode(curator.EPHEMERAL).ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+
+	_, err = other.Create().WithMode(curator.EPHEMERAL).ForPath("/ephemeral/child")
+
+	assert.Equal(t, zk.ErrNoChildrenForEphemerals, err)
+
+	// the ephemeral nodes live as long as their session
+	assert.NoError(t, other.Close())
+
+	exists, err := client.CheckExists().ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+	assert.Nil(t, exists)
+
+	// the session outlives a short restart, and is expired after a longer one
+	_, err = client.Create().WithMode(curator.EPHEMERAL).ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+
+	sessions := server.Simulator().Sessions()
+
+	assert.Len(t, sessions, 1)
+
+	server.Stop()
+	server.Restart()
+
+	exists, err = client.CheckExists().ForPath("/ephemeral")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, exists)
+	assert.Equal(t, sessions, server.Simulator().Sessions())
+
+	assert.True(t, server.Simulator().ExpireSession(sessions[0]))
+
+	assert.NoError(t, curator.CallWithRetry(client.ZookeeperClient(), func() error {
+		if exists, err := client.CheckExists().ForPath("/ephemeral"); err != nil {
+			return err
+		} else {
+			assert.Nil(t, exists)
+
+			return nil
+		}
+	}))
+
+	// a new session is established
+	assert.NoError(t, client.BlockUntilConnectedTimeout(timing.ForWaiting().Value()))
+	assert.NotEqual(t, sessions, server.Simulator().Sessions())
+}
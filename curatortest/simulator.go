@@ -0,0 +1,715 @@
+package curatortest
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The error of the operations following the failed one in a multi request, as returned by go-zookeeper
+var errRuntimeInconsistency = errors.New("unknown error: -2")
+
+// The kinds of the watches set on the nodes
+type watchType int
+
+const (
+	watchData  watchType = iota // set by GetW, or ExistsW on an existing node
+	watchExist                  // set by ExistsW on a node which doesn't exist
+	watchChild                  // set by ChildrenW
+)
+
+type simulatedNode struct {
+	data      []byte
+	acl       []zk.ACL
+	stat      zk.Stat
+	children  map[string]bool
+	container bool
+}
+
+func (n *simulatedNode) clone() *simulatedNode {
+	node := *n
+
+	node.children = make(map[string]bool, len(n.children))
+
+	for child := range n.children {
+		node.children[child] = true
+	}
+
+	return &node
+}
+
+type watch struct {
+	conn   *FakeConnection
+	events chan zk.Event
+}
+
+// An in-process ZooKeeper ensemble simulating the behaviors the framework relies on:
+// the versions and the stats of the nodes, the ephemeral, sequential and container nodes,
+// the one-time watches, the atomic multi requests, and the sessions which are expired or outlive a restart.
+//
+// The connections are established to the first running server of their connection strings,
+// and move to another one once it is stopped, as long as a quorum of the servers is running.
+//
+// The ACLs are kept but not enforced, and the persistent watches, the TTL nodes and the reconfiguration aren't supported.
+type Simulator struct {
+	lock        sync.Mutex
+	cond        *sync.Cond // signaled when the connections change their states
+	servers     []string
+	stopped     map[string]bool
+	nodes       map[string]*simulatedNode
+	zxid        int64
+	lastSession int64
+	conns       map[*FakeConnection]bool
+	watches     map[watchType]map[string][]*watch
+	pending     []zk.Event // the events of the current operation, fired once it succeeds
+}
+
+// Create a simulator of a standalone server
+func NewSimulator() *Simulator {
+	return NewEnsembleSimulator(SIMULATOR_CONNECT_STRING)
+}
+
+// Create a simulator of the ensemble of the servers, e.g. simulator-1:2181
+func NewEnsembleSimulator(servers ...string) *Simulator {
+	s := &Simulator{
+		servers: servers,
+		stopped: make(map[string]bool),
+		nodes:   make(map[string]*simulatedNode),
+		conns:   make(map[*FakeConnection]bool),
+		watches: map[watchType]map[string][]*watch{
+			watchData:  make(map[string][]*watch),
+			watchExist: make(map[string][]*watch),
+			watchChild: make(map[string][]*watch),
+		},
+	}
+
+	s.cond = sync.NewCond(&s.lock)
+
+	for _, p := range []string{"/", "/zookeeper", "/zookeeper/quota"} {
+		s.nodes[p] = &simulatedNode{acl: zk.WorldACL(zk.PermAll), children: make(map[string]bool)}
+
+		if p != "/" {
+			s.nodes[path.Dir(p)].children[path.Base(p)] = true
+			s.nodes[path.Dir(p)].stat.NumChildren++
+		}
+	}
+
+	return s
+}
+
+// Connect to the simulator, which implements the ZookeeperDialer of the framework.
+//
+// The connection is established at once, or once a server of the connection string and a quorum are running.
+func (s *Simulator) Dial(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (curator.ZookeeperConnection, <-chan zk.Event, error) {
+	var servers []string
+
+	if i := strings.Index(connString, "/"); i >= 0 {
+		connString = connString[:i] // the chroot
+	}
+
+	for _, server := range strings.Split(connString, ",") {
+		if s.hasServer(server) {
+			servers = append(servers, server)
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, nil, fmt.Errorf("no simulated server in %s", connString)
+	}
+
+	conn := newFakeConnection(s, servers, sessionTimeout)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.conns[conn] = true
+
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateConnecting, Server: servers[0]})
+
+	s.reconcile()
+
+	return conn, conn.events.events, nil
+}
+
+// Connect a fake connection to the servers of the simulator, e.g. to test the code using a ZookeeperConnection without a framework
+func (s *Simulator) Connect(sessionTimeout time.Duration) (*FakeConnection, error) {
+	if conn, _, err := s.Dial(strings.Join(s.servers, ","), sessionTimeout, false); err != nil {
+		return nil, err
+	} else {
+		return conn.(*FakeConnection), nil
+	}
+}
+
+func (s *Simulator) hasServer(server string) bool {
+	for _, known := range s.servers {
+		if known == server {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stop all the servers, the sessions are kept until they time out
+func (s *Simulator) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, server := range s.servers {
+		s.stopped[server] = true
+	}
+
+	s.reconcile()
+}
+
+// Restart all the servers, the sessions disconnected for longer than their timeouts are expired
+func (s *Simulator) Restart() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stopped = make(map[string]bool)
+
+	s.reconcile()
+}
+
+// Stop a server, its connections move to the other servers of their connection strings
+func (s *Simulator) StopServer(server string) error {
+	return s.changeServer(server, true)
+}
+
+// Restart a stopped server
+func (s *Simulator) RestartServer(server string) error {
+	return s.changeServer(server, false)
+}
+
+func (s *Simulator) changeServer(server string, stopped bool) error {
+	if !s.hasServer(server) {
+		return fmt.Errorf("no simulated server %s", server)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if stopped {
+		s.stopped[server] = true
+	} else {
+		delete(s.stopped, server)
+	}
+
+	s.reconcile()
+
+	return nil
+}
+
+// Return true if a majority of the servers is running
+func (s *Simulator) HasQuorum() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.hasQuorum()
+}
+
+func (s *Simulator) hasQuorum() bool {
+	return len(s.servers)-len(s.stopped) > len(s.servers)/2
+}
+
+// Expire the session as if it had timed out, the ephemeral nodes of the session are deleted
+func (s *Simulator) ExpireSession(sessionID int64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for conn := range s.conns {
+		if conn.sessionID == sessionID && conn.state != zk.StateExpired {
+			s.expire(conn)
+			s.fire()
+			s.cond.Broadcast()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the sessions of the connections which aren't closed or expired
+func (s *Simulator) Sessions() []int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var sessions []int64
+
+	for conn := range s.conns {
+		if conn.sessionID != 0 && conn.state != zk.StateExpired {
+			sessions = append(sessions, conn.sessionID)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i] < sessions[j] })
+
+	return sessions
+}
+
+// Return the paths of the nodes in order, e.g. to check the nodes left by a recipe
+func (s *Simulator) Paths() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	paths := make([]string, 0, len(s.nodes))
+
+	for p := range s.nodes {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Return the server the session is connected to, empty if it isn't connected
+func (s *Simulator) SessionServer(sessionID int64) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for conn := range s.conns {
+		if conn.sessionID == sessionID && conn.state == zk.StateHasSession {
+			return conn.server
+		}
+	}
+
+	return ""
+}
+
+// Disconnect the connections whose servers are stopped or lost the quorum, and reconnect the other ones
+func (s *Simulator) reconcile() {
+	quorum := s.hasQuorum()
+
+	for conn := range s.conns {
+		if conn.state == zk.StateHasSession && (!quorum || s.stopped[conn.server]) {
+			s.disconnect(conn)
+		}
+
+		if quorum && (conn.state == zk.StateDisconnected || conn.state == zk.StateConnecting) {
+			if conn.sessionID != 0 && time.Since(conn.disconnectedAt) > conn.timeout {
+				s.expire(conn)
+			} else if server := s.pickServer(conn); server != "" {
+				s.connect(conn, server)
+			}
+		}
+	}
+
+	s.fire()
+	s.cond.Broadcast()
+}
+
+// Pick the next running server of the connection string
+func (s *Simulator) pickServer(conn *FakeConnection) string {
+	for i := range conn.servers {
+		if server := conn.servers[(conn.next+i)%len(conn.servers)]; !s.stopped[server] {
+			conn.next = (conn.next + i) % len(conn.servers)
+
+			return server
+		}
+	}
+
+	return ""
+}
+
+func (s *Simulator) connect(conn *FakeConnection, server string) {
+	if conn.sessionID == 0 {
+		s.lastSession++
+
+		conn.sessionID = s.lastSession
+	}
+
+	conn.state = zk.StateHasSession
+	conn.server = server
+
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateConnected, Server: server})
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateHasSession, Server: server})
+}
+
+// Disconnect the connection, whose session is expired once it times out unless the connection is re-established
+func (s *Simulator) disconnect(conn *FakeConnection) {
+	conn.state = zk.StateDisconnected
+	conn.disconnectedAt = time.Now()
+	conn.next++
+
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateDisconnected, Server: conn.server})
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateConnecting, Server: conn.server})
+
+	time.AfterFunc(conn.timeout+time.Millisecond, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.reconcile()
+	})
+}
+
+func (s *Simulator) expire(conn *FakeConnection) {
+	conn.state = zk.StateExpired
+
+	s.closeSession(conn, zk.ErrSessionExpired)
+
+	conn.events.enqueue(zk.Event{Type: zk.EventSession, State: zk.StateExpired, Server: conn.server})
+}
+
+// Delete the ephemeral nodes of the session, and stop the watches of the connection
+func (s *Simulator) closeSession(conn *FakeConnection, err error) {
+	delete(s.conns, conn)
+
+	if conn.sessionID != 0 {
+		var ephemerals []string
+
+		for p, node := range s.nodes {
+			if node.stat.EphemeralOwner == conn.sessionID {
+				ephemerals = append(ephemerals, p)
+			}
+		}
+
+		for _, p := range ephemerals {
+			s.delete(p, -1)
+		}
+	}
+
+	for _, watches := range s.watches {
+		for p, list := range watches {
+			var kept []*watch
+
+			for _, w := range list {
+				if w.conn == conn {
+					w.events <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: p, Err: err}
+					close(w.events)
+				} else {
+					kept = append(kept, w)
+				}
+			}
+
+			if len(kept) > 0 {
+				watches[p] = kept
+			} else {
+				delete(watches, p)
+			}
+		}
+	}
+}
+
+// Wait until the connection is connected, return the error of the connection if it is expired or closed.
+//
+// An expired session is reported even once its connection is closed, e.g. by the framework handling the expiration,
+// so the operations racing with the expiration fail with the retryable zk.ErrSessionExpired.
+func (s *Simulator) await(conn *FakeConnection) error {
+	for conn.state != zk.StateExpired && !conn.closed {
+		if conn.state == zk.StateHasSession {
+			return nil
+		}
+
+		s.cond.Wait() // the requests are queued until the connection is re-established
+	}
+
+	if conn.state == zk.StateExpired {
+		return zk.ErrSessionExpired
+	}
+
+	return zk.ErrClosing
+}
+
+// Fire the events of the operation to the watches, each connection receives the events on its session channel too
+func (s *Simulator) fire() {
+	for _, event := range s.pending {
+		var types []watchType
+
+		switch event.Type {
+		case zk.EventNodeCreated:
+			types = []watchType{watchExist}
+		case zk.EventNodeDeleted:
+			types = []watchType{watchData, watchExist, watchChild}
+		case zk.EventNodeDataChanged:
+			types = []watchType{watchData}
+		case zk.EventNodeChildrenChanged:
+			types = []watchType{watchChild}
+		}
+
+		notified := make(map[*FakeConnection]bool)
+
+		for _, t := range types {
+			for _, w := range s.watches[t][event.Path] {
+				w.events <- event
+				close(w.events)
+
+				if !notified[w.conn] {
+					notified[w.conn] = true
+
+					w.conn.events.enqueue(event)
+				}
+			}
+
+			delete(s.watches[t], event.Path)
+		}
+	}
+
+	s.pending = nil
+}
+
+func (s *Simulator) addWatch(conn *FakeConnection, t watchType, p string) <-chan zk.Event {
+	w := &watch{conn: conn, events: make(chan zk.Event, 1)}
+
+	s.watches[t][p] = append(s.watches[t][p], w)
+
+	return w.events
+}
+
+func (s *Simulator) trigger(eventType zk.EventType, p string) {
+	s.pending = append(s.pending, zk.Event{Type: eventType, State: zk.StateSyncConnected, Path: p})
+}
+
+func (s *Simulator) now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func validatePath(p string, sequential bool) error {
+	if p == "" || p[0] != '/' || strings.Contains(p, "//") || strings.Contains(p, "\x00") {
+		return zk.ErrInvalidPath
+	}
+
+	if len(p) > 1 && p[len(p)-1] == '/' && !sequential {
+		return zk.ErrInvalidPath
+	}
+
+	for _, name := range strings.Split(p[1:], "/") {
+		if name == "." || name == ".." {
+			return zk.ErrInvalidPath
+		}
+	}
+
+	return nil
+}
+
+func parentOf(p string) string {
+	if i := strings.LastIndex(p, "/"); i > 0 {
+		return p[:i]
+	}
+
+	return "/"
+}
+
+func (s *Simulator) create(owner int64, p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	sequential := flags&zk.FlagSequence != 0
+
+	if err := validatePath(p, sequential); err != nil {
+		return "", err
+	} else if p == "/" {
+		return "", zk.ErrNodeExists
+	}
+
+	if flags < 0 || flags > int32(curator.CONTAINER) {
+		return "", zk.ErrBadArguments // the TTL nodes aren't supported
+	} else if len(acl) == 0 {
+		return "", zk.ErrInvalidACL
+	}
+
+	parentPath := parentOf(p)
+	parent, ok := s.nodes[parentPath]
+
+	if !ok {
+		return "", zk.ErrNoNode
+	} else if parent.stat.EphemeralOwner != 0 {
+		return "", zk.ErrNoChildrenForEphemerals
+	}
+
+	if sequential {
+		p += fmt.Sprintf("%010d", parent.stat.Cversion)
+	}
+
+	if _, exists := s.nodes[p]; exists {
+		return "", zk.ErrNodeExists
+	}
+
+	s.zxid++
+
+	now := s.now()
+	node := &simulatedNode{
+		data:      append([]byte(nil), data...),
+		acl:       acl,
+		children:  make(map[string]bool),
+		container: flags == int32(curator.CONTAINER),
+		stat: zk.Stat{
+			Czxid:      s.zxid,
+			Mzxid:      s.zxid,
+			Pzxid:      s.zxid,
+			Ctime:      now,
+			Mtime:      now,
+			DataLength: int32(len(data)),
+		},
+	}
+
+	if flags&zk.FlagEphemeral != 0 {
+		node.stat.EphemeralOwner = owner
+	}
+
+	s.nodes[p] = node
+
+	parent.children[path.Base(p)] = true
+	parent.stat.Cversion++
+	parent.stat.NumChildren++
+	parent.stat.Pzxid = s.zxid
+
+	s.trigger(zk.EventNodeCreated, p)
+	s.trigger(zk.EventNodeChildrenChanged, parentPath)
+
+	return p, nil
+}
+
+func (s *Simulator) delete(p string, version int32) error {
+	if err := validatePath(p, false); err != nil {
+		return err
+	} else if p == "/" || p == "/zookeeper" || p == "/zookeeper/quota" {
+		return zk.ErrBadArguments
+	}
+
+	node, ok := s.nodes[p]
+
+	if !ok {
+		return zk.ErrNoNode
+	} else if version != -1 && version != node.stat.Version {
+		return zk.ErrBadVersion
+	} else if len(node.children) > 0 {
+		return zk.ErrNotEmpty
+	}
+
+	s.zxid++
+
+	parentPath := parentOf(p)
+	parent := s.nodes[parentPath]
+
+	delete(s.nodes, p)
+	delete(parent.children, path.Base(p))
+
+	parent.stat.Cversion++
+	parent.stat.NumChildren--
+	parent.stat.Pzxid = s.zxid
+
+	s.trigger(zk.EventNodeDeleted, p)
+	s.trigger(zk.EventNodeChildrenChanged, parentPath)
+
+	// the server deletes the containers once their last child is deleted
+	if parent.container && len(parent.children) == 0 {
+		s.delete(parentPath, -1)
+	}
+
+	return nil
+}
+
+func (s *Simulator) setData(p string, data []byte, version int32) (*zk.Stat, error) {
+	node, err := s.check(p, version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.zxid++
+
+	node.data = append([]byte(nil), data...)
+	node.stat.Version++
+	node.stat.Mzxid = s.zxid
+	node.stat.Mtime = s.now()
+	node.stat.DataLength = int32(len(data))
+
+	s.trigger(zk.EventNodeDataChanged, p)
+
+	stat := node.stat
+
+	return &stat, nil
+}
+
+func (s *Simulator) setACL(p string, acl []zk.ACL, version int32) (*zk.Stat, error) {
+	if err := validatePath(p, false); err != nil {
+		return nil, err
+	}
+
+	node, ok := s.nodes[p]
+
+	if !ok {
+		return nil, zk.ErrNoNode
+	} else if version != -1 && version != node.stat.Aversion {
+		return nil, zk.ErrBadVersion
+	} else if len(acl) == 0 {
+		return nil, zk.ErrInvalidACL
+	}
+
+	s.zxid++
+
+	node.acl = acl
+	node.stat.Aversion++
+
+	stat := node.stat
+
+	return &stat, nil
+}
+
+// Return the node if its version matches
+func (s *Simulator) check(p string, version int32) (*simulatedNode, error) {
+	if err := validatePath(p, false); err != nil {
+		return nil, err
+	}
+
+	node, ok := s.nodes[p]
+
+	if !ok {
+		return nil, zk.ErrNoNode
+	} else if version != -1 && version != node.stat.Version {
+		return nil, zk.ErrBadVersion
+	}
+
+	return node, nil
+}
+
+// Apply the operations of a multi request, none of them is applied if one fails
+func (s *Simulator) multi(owner int64, ops []interface{}) ([]zk.MultiResponse, error) {
+	nodes := make(map[string]*simulatedNode, len(s.nodes))
+
+	for p, node := range s.nodes {
+		nodes[p] = node.clone()
+	}
+
+	zxid := s.zxid
+	responses := make([]zk.MultiResponse, len(ops))
+
+	for i, op := range ops {
+		var err error
+
+		switch req := op.(type) {
+		case *zk.CreateRequest:
+			responses[i].String, err = s.create(owner, req.Path, req.Data, req.Flags, req.Acl)
+		case *zk.DeleteRequest:
+			err = s.delete(req.Path, req.Version)
+		case *zk.SetDataRequest:
+			responses[i].Stat, err = s.setData(req.Path, req.Data, req.Version)
+		case *zk.CheckVersionRequest:
+			_, err = s.check(req.Path, req.Version)
+		default:
+			err = fmt.Errorf("unknown operation type %T", op)
+		}
+
+		if err != nil {
+			s.nodes, s.zxid, s.pending = nodes, zxid, nil
+
+			responses = make([]zk.MultiResponse, len(ops))
+			responses[i].Error = err
+
+			for j := i + 1; j < len(ops); j++ {
+				responses[j].Error = errRuntimeInconsistency
+			}
+
+			return responses, err
+		}
+	}
+
+	return responses, nil
+}
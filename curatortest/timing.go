@@ -0,0 +1,113 @@
+package curatortest
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TIMING_MULTIPLE_ENV         = "CURATOR_TIMING_MULTIPLE"         // scale the timings of NewTiming, e.g. 2 on a loaded CI
+	TIMING_WAITING_MULTIPLE_ENV = "CURATOR_TIMING_WAITING_MULTIPLE" // the multiple of the timings waiting for the expected events
+	DEFAULT_TIMING              = time.Second                       // the timing of NewTiming, which is scaled by $CURATOR_TIMING_MULTIPLE
+	DEFAULT_WAITING_MULTIPLE    = 5
+	SESSION_MULTIPLE            = 1.5
+	SESSION_SLEEP_MULTIPLE      = SESSION_MULTIPLE * 1.75
+	AWAIT_CHECK_INTERVAL        = 10 * time.Millisecond // how often AwaitCondition checks its condition
+)
+
+// The timings of the tests derived from a single value, instead of the magic durations hardcoded by each test,
+// so the tests running on a loaded machine are slowed down together with $CURATOR_TIMING_MULTIPLE.
+type Timing struct {
+	value           time.Duration
+	waitingMultiple float64
+}
+
+// Create the default timing, scaled by $CURATOR_TIMING_MULTIPLE
+func NewTiming() *Timing {
+	return NewTimingWithValue(time.Duration(float64(DEFAULT_TIMING) * envMultiple(TIMING_MULTIPLE_ENV, 1)))
+}
+
+// Create a timing of the given value, which isn't scaled
+func NewTimingWithValue(value time.Duration) *Timing {
+	return &Timing{value, envMultiple(TIMING_WAITING_MULTIPLE_ENV, DEFAULT_WAITING_MULTIPLE)}
+}
+
+// Return the positive multiple of the environment variable, or the default value if it isn't set or valid
+func envMultiple(name string, defaultValue float64) float64 {
+	if multiple, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil && multiple > 0 {
+		return multiple
+	}
+
+	return defaultValue
+}
+
+// Return the value of the timing
+func (t *Timing) Value() time.Duration {
+	return t.value
+}
+
+// Return a timing of the value multiplied by n
+func (t *Timing) Multiple(n float64) *Timing {
+	return &Timing{time.Duration(float64(t.value) * n), t.waitingMultiple}
+}
+
+// Return the timing to wait for an expected event, which is much longer than the value so a loaded machine doesn't fail the test
+func (t *Timing) ForWaiting() *Timing {
+	return t.Multiple(t.waitingMultiple)
+}
+
+// Return the timing to sleep for a session to time out
+func (t *Timing) ForSessionSleep() *Timing {
+	return NewTimingWithValue(time.Duration(float64(t.Session()) * SESSION_SLEEP_MULTIPLE))
+}
+
+// Return the session timeout of the clients
+func (t *Timing) Session() time.Duration {
+	return t.Multiple(SESSION_MULTIPLE).value
+}
+
+// Return the connection timeout of the clients
+func (t *Timing) Connection() time.Duration {
+	return t.value
+}
+
+// Sleep for the value
+func (t *Timing) Sleep() {
+	time.Sleep(t.value)
+}
+
+// Sleep for a quarter of the value, e.g. to check an event doesn't happen
+func (t *Timing) SleepABit() {
+	time.Sleep(t.value / 4)
+}
+
+// Wait for the group for the waiting timing, return false if it isn't done in time
+func (t *Timing) AwaitGroup(wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(t.ForWaiting().value):
+		return false
+	}
+}
+
+// Wait for the condition to be true for the waiting timing, return false if it isn't in time
+func (t *Timing) AwaitCondition(condition func() bool) bool {
+	for deadline := time.Now().Add(t.ForWaiting().value); !condition(); time.Sleep(AWAIT_CHECK_INTERVAL) {
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package curatortest
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiming(t *testing.T) {
+	timing := NewTimingWithValue(100 * time.Millisecond)
+
+	assert.Equal(t, 100*time.Millisecond, timing.Value())
+	assert.Equal(t, 500*time.Millisecond, timing.ForWaiting().Value())
+	assert.Equal(t, 50*time.Millisecond, timing.Multiple(0.5).Value())
+	assert.Equal(t, 150*time.Millisecond, timing.Session())
+	assert.Equal(t, 100*time.Millisecond, timing.Connection())
+	assert.Equal(t, 393750*time.Microsecond, timing.ForSessionSleep().Value())
+
+	startTime := time.Now()
+
+	timing.SleepABit()
+
+	assert.True(t, time.Since(startTime) >= 25*time.Millisecond)
+
+	// the timings are scaled by the environment
+	defer os.Unsetenv(TIMING_MULTIPLE_ENV)
+	defer os.Unsetenv(TIMING_WAITING_MULTIPLE_ENV)
+
+	os.Setenv(TIMING_MULTIPLE_ENV, "2")
+	os.Setenv(TIMING_WAITING_MULTIPLE_ENV, "3")
+
+	assert.Equal(t, 2*DEFAULT_TIMING, NewTiming().Value())
+	assert.Equal(t, 6*DEFAULT_TIMING, NewTiming().ForWaiting().Value())
+
+	os.Setenv(TIMING_MULTIPLE_ENV, "invalid")
+
+	assert.Equal(t, DEFAULT_TIMING, NewTiming().Value())
+}
+
+func TestTimingAwait(t *testing.T) {
+	timing := NewTimingWithValue(10 * time.Millisecond)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	assert.False(t, timing.AwaitGroup(&wg))
+
+	go wg.Done()
+
+	assert.True(t, timing.AwaitGroup(&wg))
+
+	var lock sync.Mutex
+
+	ready := false
+
+	time.AfterFunc(timing.Value(), func() {
+		lock.Lock()
+		ready = true
+		lock.Unlock()
+	})
+
+	assert.True(t, timing.AwaitCondition(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return ready
+	}))
+
+	assert.False(t, timing.AwaitCondition(func() bool { return false }))
+}
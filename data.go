@@ -1,9 +1,42 @@
 package curator
 
 import (
+	"bytes"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+type DefaultDataProvider interface {
+	// Return the data to use when a node at the given path is created or set without data
+	GetDefaultData(path string) []byte
+}
+
+type staticDataProvider struct {
+	data []byte
+}
+
+// Provide the same data for all the paths
+func NewStaticDataProvider(data []byte) DefaultDataProvider {
+	return &staticDataProvider{data}
+}
+
+func (p *staticDataProvider) GetDefaultData(path string) []byte {
+	return p.data
+}
+
+type defaultDataProviderCallback struct {
+	callback func(path string) []byte
+}
+
+// Provide the data of each path with a function, e.g. a different payload per subtree
+func NewDefaultDataProvider(callback func(path string) []byte) DefaultDataProvider {
+	return &defaultDataProviderCallback{callback}
+}
+
+func (p *defaultDataProviderCallback) GetDefaultData(path string) []byte {
+	return p.callback(path)
+}
+
 type getDataBuilder struct {
 	client        *curatorFramework
 	backgrounding backgrounding
@@ -16,7 +49,7 @@ func (b *getDataBuilder) ForPath(givenPath string) ([]byte, error) {
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return nil, nil
 	}
@@ -35,30 +68,29 @@ func (b *getDataBuilder) pathInBackground(adjustedPath, givenPath string) {
 
 	data, err := b.pathInForeground(adjustedPath)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: GET_DATA,
-			err:       err,
-			path:      b.client.unfixForNamespace(adjustedPath),
-			data:      data,
-			stat:      b.stat,
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: GET_DATA,
+		err:       err,
+		path:      b.client.unfixForNamespace(adjustedPath),
+		data:      data,
+		stat:      b.stat,
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *getDataBuilder) pathInForeground(path string) ([]byte, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(GET_DATA.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, GET_DATA.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -71,7 +103,7 @@ func (b *getDataBuilder) pathInForeground(path string) ([]byte, error) {
 				data, stat, events, err = conn.GetW(path)
 
 				if events != nil && b.watching.watcher != nil {
-					go NewWatchers(b.watching.watcher).Watch(events)
+					go newWatchers(b.client.dispatcher, b.watching.watcher).Watch(events)
 				}
 			} else {
 				data, stat, err = conn.Get(path)
@@ -99,6 +131,8 @@ func (b *getDataBuilder) pathInForeground(path string) ([]byte, error) {
 
 	data, _ := result.([]byte)
 
+	trace.WithResponseBytes(data).WithWatcher(b.watching.watched || b.watching.watcher != nil).WithStat(b.stat).Commit(err)
+
 	return data, err
 }
 
@@ -108,6 +142,12 @@ func (b *getDataBuilder) Decompressed() GetDataBuilder {
 	return b
 }
 
+func (b *getDataBuilder) Undecompressed() GetDataBuilder {
+	b.decompress = false
+
+	return b
+}
+
 func (b *getDataBuilder) StoringStatIn(stat *zk.Stat) GetDataBuilder {
 	b.stat = stat
 
@@ -126,6 +166,16 @@ func (b *getDataBuilder) UsingWatcher(watcher Watcher) GetDataBuilder {
 	return b
 }
 
+func (b *getDataBuilder) UsingWatcherFunc(fn func(event zk.Event)) GetDataBuilder {
+	b.watching.watcher = b.client.newNamespaceWatcher(fn)
+
+	return b
+}
+
+func (b *getDataBuilder) UsingWatcherChannel(events chan<- zk.Event) GetDataBuilder {
+	return b.UsingWatcherFunc(func(event zk.Event) { events <- event })
+}
+
 func (b *getDataBuilder) InBackground() GetDataBuilder {
 	b.backgrounding = backgrounding{inBackground: true}
 
@@ -155,13 +205,23 @@ type setDataBuilder struct {
 	backgrounding backgrounding
 	version       int32
 	compress      bool
+	idempotent    bool
+	stat          *zk.Stat
 }
 
 func (b *setDataBuilder) ForPath(path string) (*zk.Stat, error) {
-	return b.ForPathWithData(path, b.client.defaultData)
+	return b.ForPathWithData(path, b.client.defaultData.GetDefaultData(path))
 }
 
 func (b *setDataBuilder) ForPathWithData(givenPath string, payload []byte) (*zk.Stat, error) {
+	if schema, err := b.client.schemaSet.Schema(givenPath); err != nil {
+		return nil, err
+	} else if err := schema.ValidateGeneral(givenPath, payload, nil); err != nil {
+		return nil, err
+	} else if err := b.client.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	if b.compress {
 		if data, err := b.client.compressionProvider.Compress(givenPath, payload); err != nil {
 			return nil, err
@@ -173,7 +233,7 @@ func (b *setDataBuilder) ForPathWithData(givenPath string, payload []byte) (*zk.
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, payload, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, payload, givenPath) })
 
 		return nil, nil
 	} else {
@@ -188,39 +248,61 @@ func (b *setDataBuilder) pathInBackground(path string, payload []byte, givenPath
 
 	stat, err := b.pathInForeground(path, payload)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: SET_DATA,
-			err:       err,
-			path:      b.client.unfixForNamespace(path),
-			data:      payload,
-			stat:      stat,
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: SET_DATA,
+		err:       err,
+		path:      b.client.unfixForNamespace(path),
+		data:      payload,
+		stat:      stat,
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *setDataBuilder) pathInForeground(path string, payload []byte) (*zk.Stat, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(SET_DATA.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	firstTime := true
+
+	result, err := newRetryLoopForPath(zkClient, SET_DATA.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
-			return conn.Set(path, payload, b.version)
+			retried := !firstTime
+
+			firstTime = false
+
+			stat, err := conn.Set(path, payload, b.version)
+
+			if err == zk.ErrBadVersion && b.idempotent && retried && b.version >= 0 {
+				// the data may have been set before the connection was lost
+				if data, stat, err := conn.Get(path); err != nil {
+					return nil, err
+				} else if stat.Version == b.version+1 && bytes.Equal(data, payload) {
+					return stat, nil
+				}
+			}
+
+			return stat, err
 		}
 	})
 
 	stat, _ := result.(*zk.Stat)
 
+	if b.stat != nil && stat != nil {
+		*b.stat = *stat
+	}
+
+	trace.WithRequestBytes(payload).WithStat(stat).Commit(err)
+
 	return stat, err
 }
 
@@ -230,12 +312,30 @@ func (b *setDataBuilder) WithVersion(version int32) SetDataBuilder {
 	return b
 }
 
+func (b *setDataBuilder) Idempotent() SetDataBuilder {
+	b.idempotent = true
+
+	return b
+}
+
+func (b *setDataBuilder) StoringStatIn(stat *zk.Stat) SetDataBuilder {
+	b.stat = stat
+
+	return b
+}
+
 func (b *setDataBuilder) Compressed() SetDataBuilder {
 	b.compress = true
 
 	return b
 }
 
+func (b *setDataBuilder) Uncompressed() SetDataBuilder {
+	b.compress = false
+
+	return b
+}
+
 func (b *setDataBuilder) InBackground() SetDataBuilder {
 	b.backgrounding = backgrounding{inBackground: true}
 
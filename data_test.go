@@ -6,6 +6,7 @@ import (
 
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -32,6 +33,44 @@ func (s *GetDataBuilderTestSuite) TestGetData() {
 	})
 }
 
+func (s *GetDataBuilderTestSuite) TestCompressionEnabled() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.EnableCompression = true
+	}, func(client CuratorFramework, conn *mockConn, compress *mockCompressionProvider, data []byte, stat *zk.Stat, acls []zk.ACL) {
+		compress.On("Compress", "/node", data).Return([]byte("compressed(data)"), nil).Twice()
+		conn.On("Create", "/node", []byte("compressed(data)"), int32(PERSISTENT), acls).Return("/node", nil).Once()
+		conn.On("Set", "/node", []byte("compressed(data)"), AnyVersion).Return(stat, nil).Once()
+		conn.On("Get", "/node").Return([]byte("compressed(data)"), stat, nil).Once()
+		compress.On("Decompress", "/node", []byte("compressed(data)")).Return(data, nil).Once()
+
+		_, err := client.Create().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.SetData().ForPathWithData("/node", data)
+
+		assert.NoError(s.T(), err)
+
+		data2, err := client.GetData().ForPath("/node")
+
+		assert.Equal(s.T(), data, data2)
+		assert.NoError(s.T(), err)
+
+		// opt out of the compression
+		conn.On("Set", "/raw", []byte("raw"), AnyVersion).Return(stat, nil).Once()
+		conn.On("Get", "/raw").Return([]byte("raw"), stat, nil).Once()
+
+		_, err = client.SetData().Uncompressed().ForPathWithData("/raw", []byte("raw"))
+
+		assert.NoError(s.T(), err)
+
+		data2, err = client.GetData().Undecompressed().ForPath("/raw")
+
+		assert.Equal(s.T(), []byte("raw"), data2)
+		assert.NoError(s.T(), err)
+	})
+}
+
 func (s *GetDataBuilderTestSuite) TestNamespace() {
 	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
 		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
@@ -95,6 +134,33 @@ func (s *GetDataBuilderTestSuite) TestWatcher() {
 	})
 }
 
+func (s *GetDataBuilderTestSuite) TestWatcherChannel() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		events := make(chan zk.Event)
+		watched := make(chan zk.Event, 1)
+
+		defer close(events)
+
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("GetW", "/parent/child").Return(data, stat, events, nil).Once()
+
+		data2, err := client.GetData().UsingWatcherChannel(watched).ForPath("/child")
+
+		assert.Equal(s.T(), data, data2)
+		assert.NoError(s.T(), err)
+
+		events <- zk.Event{
+			Type: zk.EventNodeDataChanged,
+			Path: "/parent/child",
+		}
+
+		event := <-watched
+
+		assert.Equal(s.T(), zk.EventNodeDataChanged, event.Type)
+		assert.Equal(s.T(), "/child", event.Path)
+	})
+}
+
 type SetDataBuilderTestSuite struct {
 	mockContainerTestSuite
 }
@@ -115,6 +181,44 @@ func (s *SetDataBuilderTestSuite) TestSetData() {
 	})
 }
 
+func (s *SetDataBuilderTestSuite) TestStoringStatIn() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		conn.On("Set", "/node", data, AnyVersion).Return(stat, nil).Once()
+
+		var stored zk.Stat
+
+		_, err := client.SetData().StoringStatIn(&stored).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), *stat, stored)
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *SetDataBuilderTestSuite) TestIdempotent() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, retryPolicy *mockRetryPolicy) {
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Twice()
+
+		// the data was set before the session expired
+		conn.On("Set", "/node", data, int32(3)).Return(nil, zk.ErrSessionExpired).Once()
+		conn.On("Set", "/node", data, int32(3)).Return(nil, zk.ErrBadVersion).Once()
+		conn.On("Get", "/node").Return(data, &zk.Stat{Version: 4}, nil).Once()
+
+		stat, err := client.SetData().Idempotent().WithVersion(3).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), &zk.Stat{Version: 4}, stat)
+		assert.NoError(s.T(), err)
+
+		// the data was set by another client meanwhile
+		conn.On("Set", "/node", data, int32(3)).Return(nil, zk.ErrSessionExpired).Once()
+		conn.On("Set", "/node", data, int32(3)).Return(nil, zk.ErrBadVersion).Once()
+		conn.On("Get", "/node").Return(data, &zk.Stat{Version: 5}, nil).Once()
+
+		_, err = client.SetData().Idempotent().WithVersion(3).ForPathWithData("/node", data)
+
+		assert.EqualError(s.T(), err, zk.ErrBadVersion.Error())
+	})
+}
+
 func (s *SetDataBuilderTestSuite) TestNamespace() {
 	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
 		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
@@ -9,13 +9,23 @@ type deleteBuilder struct {
 	backgrounding            backgrounding
 	deletingChildrenIfNeeded bool
 	version                  int32
+	idempotent               bool
+	quietly                  bool
 }
 
 func (b *deleteBuilder) ForPath(givenPath string) error {
+	if schema, err := b.client.schemaSet.Schema(givenPath); err != nil {
+		return err
+	} else if err := schema.ValidateDelete(givenPath); err != nil {
+		return err
+	} else if err := b.client.checkWritable(); err != nil {
+		return err
+	}
+
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return nil
 	} else {
@@ -30,41 +40,54 @@ func (b *deleteBuilder) pathInBackground(path string, givenPath string) {
 
 	err := b.pathInForeground(path, givenPath)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: DELETE,
-			err:       err,
-			path:      b.client.unfixForNamespace(path),
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: DELETE,
+		err:       err,
+		path:      b.client.unfixForNamespace(path),
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *deleteBuilder) pathInForeground(path string, givenPath string) error {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(DELETE.String(), path)
+
+	firstTime := true
 
-	_, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	_, err := newRetryLoopForPath(zkClient, DELETE.String(), path).CallWithRetry(func() (interface{}, error) {
 		conn, err := zkClient.Conn()
 
 		if err == nil {
 			err = conn.Delete(path, b.version)
 
 			if err == zk.ErrNotEmpty && b.deletingChildrenIfNeeded {
-				err = DeleteChildren(conn, path, true)
+				err = deleteTree(conn, path, b.version)
+			}
+
+			if err == zk.ErrNoNode && b.idempotent && !firstTime {
+				err = nil // the node may have been deleted before the connection was lost
 			}
+
+			firstTime = false
 		}
 
 		return nil, err
 	})
 
+	trace.Commit(err)
+
+	if err == zk.ErrNoNode && b.quietly {
+		return nil
+	}
+
 	return err
 }
 
@@ -74,6 +97,18 @@ func (b *deleteBuilder) DeletingChildrenIfNeeded() DeleteBuilder {
 	return b
 }
 
+func (b *deleteBuilder) Quietly() DeleteBuilder {
+	b.quietly = true
+
+	return b
+}
+
+func (b *deleteBuilder) Idempotent() DeleteBuilder {
+	b.idempotent = true
+
+	return b
+}
+
 func (b *deleteBuilder) WithVersion(version int32) DeleteBuilder {
 	b.version = version
 
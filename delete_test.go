@@ -6,6 +6,7 @@ import (
 
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -33,6 +34,50 @@ func (s *DeleteBuilderTestSuite) TestDeleteWithVersion() {
 	})
 }
 
+func (s *DeleteBuilderTestSuite) TestIdempotent() {
+	s.With(func(client CuratorFramework, conn *mockConn, retryPolicy *mockRetryPolicy) {
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Once()
+
+		// the node was deleted before the session expired
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrSessionExpired).Once()
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrNoNode).Once()
+
+		assert.NoError(s.T(), client.Delete().Idempotent().ForPath("/node"))
+
+		// the node didn't exist before the first try
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrNoNode).Once()
+
+		assert.EqualError(s.T(), client.Delete().Idempotent().ForPath("/node"), zk.ErrNoNode.Error())
+	})
+}
+
+func (s *DeleteBuilderTestSuite) TestRetryListener() {
+	s.With(func(client CuratorFramework, conn *mockConn, retryPolicy *mockRetryPolicy) {
+		events := make(chan *RetryEvent, 1)
+
+		client.RetryListenable().AddListener(NewRetryListenerChannel(events))
+
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrSessionExpired).Once()
+		conn.On("Delete", "/node", AnyVersion).Return(nil).Once()
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Once()
+
+		assert.NoError(s.T(), client.Delete().ForPath("/node"))
+		assert.Equal(s.T(), &RetryEvent{Operation: "DELETE", Attempt: 1, Err: zk.ErrSessionExpired}, <-events)
+	})
+}
+
+func (s *DeleteBuilderTestSuite) TestQuietly() {
+	s.With(func(client CuratorFramework, conn *mockConn) {
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrNoNode).Once()
+
+		assert.NoError(s.T(), client.Delete().Quietly().ForPath("/node"))
+
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrBadVersion).Once()
+
+		assert.EqualError(s.T(), client.Delete().Quietly().ForPath("/node"), zk.ErrBadVersion.Error())
+	})
+}
+
 func (s *DeleteBuilderTestSuite) TestNamespace() {
 	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn) {
 		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
@@ -64,6 +109,28 @@ func (s *DeleteBuilderTestSuite) TestBackground() {
 	})
 }
 
+func (s *DeleteBuilderTestSuite) TestBackgroundWithListener() {
+	s.With(func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		ctxt := "context"
+
+		conn.On("Delete", "/node", AnyVersion).Return(nil).Once()
+
+		client.CuratorListenable().AddListener(NewCuratorListener(func(client CuratorFramework, event CuratorEvent) error {
+			if event.Type() == DELETE {
+				defer wg.Done()
+
+				assert.Equal(s.T(), "/node", event.Path())
+				assert.NoError(s.T(), event.Err())
+				assert.Equal(s.T(), ctxt, event.Context())
+			}
+
+			return nil
+		}))
+
+		assert.NoError(s.T(), client.Delete().InBackgroundWithContext(ctxt).ForPath("/node"))
+	})
+}
+
 func (s *DeleteBuilderTestSuite) TestDeletingChildren() {
 	s.With(func(client CuratorFramework, conn *mockConn) {
 		conn.On("Delete", "/parent", AnyVersion).Return(zk.ErrNotEmpty).Once()
@@ -75,3 +142,29 @@ func (s *DeleteBuilderTestSuite) TestDeletingChildren() {
 		assert.NoError(s.T(), client.Delete().DeletingChildrenIfNeeded().ForPath("/parent"))
 	})
 }
+
+func (s *DeleteBuilderTestSuite) TestDeletingConcurrentChildren() {
+	s.With(func(client CuratorFramework, conn *mockConn, version int32) {
+		conn.On("Delete", "/parent", version).Return(zk.ErrNotEmpty).Once()
+		conn.On("Children", "/parent").Return([]string{"child1"}, nil, nil).Once()
+		conn.On("Children", "/parent/child1").Return([]string{}, nil, nil).Once()
+		conn.On("Delete", "/parent/child1", AnyVersion).Return(nil).Once()
+
+		// a child is created concurrently
+		conn.On("Delete", "/parent", version).Return(zk.ErrNotEmpty).Once()
+		conn.On("Children", "/parent").Return([]string{"child2"}, nil, nil).Once()
+		conn.On("Children", "/parent/child2").Return(nil, nil, zk.ErrNoNode).Once()
+		conn.On("Delete", "/parent", version).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Delete().DeletingChildrenIfNeeded().WithVersion(version).ForPath("/parent"))
+	})
+}
+
+func (s *DeleteBuilderTestSuite) TestDeletingChildrenRetries() {
+	s.With(func(client CuratorFramework, conn *mockConn) {
+		conn.On("Delete", "/parent", AnyVersion).Return(zk.ErrNotEmpty).Times(DELETE_CHILDREN_MAX_RETRIES + 2)
+		conn.On("Children", "/parent").Return([]string{}, nil, nil).Times(DELETE_CHILDREN_MAX_RETRIES + 1)
+
+		assert.EqualError(s.T(), client.Delete().DeletingChildrenIfNeeded().ForPath("/parent"), zk.ErrNotEmpty.Error())
+	})
+}
@@ -100,6 +100,16 @@ Curator provides generic API for builder
 	    ForPathWithData(path string, payload []byte) (T, error)
 	}
 
+	type Ensembleable[T] interface {
+	    // Commit the currently building operation on the ensemble
+	    ForEnsemble() (T, error)
+	}
+
+	type Configurable[T] interface {
+	    // Only change the ensemble if the version of the current config is the given version
+	    FromConfig(config int64) T
+	}
+
 	type Compressible[T] interface {
 	    // Cause the data to be compressed using the configured compression provider
 	    Compressed() T
@@ -115,6 +125,11 @@ Curator provides generic API for builder
 	    WithMode(mode CreateMode) T
 	}
 
+	type TTLable[T] interface {
+	    // Set the TTL of the node created with PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL
+	    WithTTL(ttl time.Duration) T
+	}
+
 	type ACLable[T] interface {
 	    // Set an ACL list
 	    WithACL(acl ...zk.ACL) T
@@ -125,6 +140,16 @@ Curator provides generic API for builder
 	    WithVersion(version int) T
 	}
 
+	type Quietly[T] interface {
+	    // Succeed if the operation fails because it is already done, e.g. the node to delete doesn't exist
+	    Quietly() T
+	}
+
+	type Idempotentable[T] interface {
+	    // Treat a retried operation which already took effect as success
+	    Idempotent() T
+	}
+
 	type Statable[T] interface {
 	    // Have the operation fill the provided stat object
 	    StoringStatIn(*zk.Stat) T
@@ -133,6 +158,9 @@ Curator provides generic API for builder
 	type ParentsCreatable[T] interface {
 	    // Causes any parent nodes to get created if they haven't already been
 	    CreatingParentsIfNeeded() T
+
+	    // Causes any parent nodes to get created as containers if they haven't already been
+	    CreatingParentContainersIfNeeded() T
 	}
 
 	type ChildrenDeletable[T] interface {
@@ -146,6 +174,12 @@ Curator provides generic API for builder
 
 	    // Set a watcher for the operation
 	    UsingWatcher(watcher Watcher) T
+
+	    // Deliver the watched event to the function, with the path relative to the namespace of the client
+	    UsingWatcherFunc(fn func(event zk.Event)) T
+
+	    // Send the watched event to the channel, with the path relative to the namespace of the client
+	    UsingWatcherChannel(events chan<- zk.Event) T
 	}
 
 	type Backgroundable[T] interface {
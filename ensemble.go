@@ -1,5 +1,12 @@
 package curator
 
+import (
+	"strings"
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
 // Abstraction that provides the ZooKeeper connection string
 type EnsembleProvider interface {
 	// Curator will call this method when CuratorZookeeperClient.Start() is called
@@ -12,17 +19,145 @@ type EnsembleProvider interface {
 	ConnectionString() string
 }
 
+// An ensemble provider whose connection string can be updated, e.g. by the ensemble tracker
+type UpdatableEnsembleProvider interface {
+	EnsembleProvider
+
+	// Update the connection string, the client reconnects with it on the next connection check
+	SetConnectionString(connectString string)
+}
+
 // Standard ensemble provider that wraps a fixed connection string
 type FixedEnsembleProvider struct {
+	lock          sync.RWMutex
 	connectString string // The connection string to use
 }
 
 func NewFixedEnsembleProvider(connectString string) *FixedEnsembleProvider {
-	return &FixedEnsembleProvider{connectString}
+	return &FixedEnsembleProvider{connectString: connectString}
 }
 
 func (p *FixedEnsembleProvider) Start() error { return nil }
 
 func (p *FixedEnsembleProvider) Close() error { return nil }
 
-func (p *FixedEnsembleProvider) ConnectionString() string { return p.connectString }
+func (p *FixedEnsembleProvider) ConnectionString() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.connectString
+}
+
+func (p *FixedEnsembleProvider) SetConnectionString(connectString string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.connectString = connectString
+}
+
+// Watch the ensemble config and update the connection string of the ensemble provider,
+// so the client follows the reconfigurations of the ensemble.
+type ensembleTracker struct {
+	client   *curatorFramework
+	provider UpdatableEnsembleProvider
+	closed   AtomicBool
+}
+
+func newEnsembleTracker(client *curatorFramework, provider UpdatableEnsembleProvider) *ensembleTracker {
+	return &ensembleTracker{client: client, provider: provider}
+}
+
+func (t *ensembleTracker) StateChanged(client CuratorFramework, newState ConnectionState) {
+	if newState.Connected() && newState != READ_ONLY {
+		t.reset()
+	}
+}
+
+func (t *ensembleTracker) close() {
+	t.closed.Set(true)
+}
+
+// Get the config and watch its changes again
+func (t *ensembleTracker) reset() {
+	if t.closed.Load() {
+		return
+	}
+
+	t.client.GetConfig().UsingWatcherFunc(func(event zk.Event) {
+		if event.Type == zk.EventNodeDataChanged {
+			t.reset()
+		}
+	}).InBackgroundWithCallback(func(client CuratorFramework, event CuratorEvent) error {
+		if event.Err() == nil {
+			t.processConfigData(event.Data())
+		}
+
+		return event.Err()
+	}).ForEnsemble()
+}
+
+func (t *ensembleTracker) processConfigData(data []byte) {
+	if t.closed.Load() {
+		return
+	}
+
+	if connectString := ConfigToConnectionString(data); len(connectString) > 0 && connectString != t.provider.ConnectionString() {
+		t.provider.SetConnectionString(connectString)
+	}
+}
+
+// Build the connection string of the clients from the ensemble config,
+// e.g. "host1:2181" for "server.1=host1:2888:3888:participant;0.0.0.0:2181"
+func ConfigToConnectionString(data []byte) string {
+	var servers []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if !strings.HasPrefix(line, "server.") {
+			continue
+		}
+
+		pos := strings.Index(line, "=")
+
+		if pos < 0 {
+			continue
+		}
+
+		serverAddr, clientAddr := line[pos+1:], ""
+
+		if pos = strings.Index(serverAddr, ";"); pos < 0 {
+			continue // the server doesn't accept the clients
+		} else {
+			serverAddr, clientAddr = serverAddr[:pos], serverAddr[pos+1:]
+		}
+
+		host, port := "", clientAddr
+
+		if pos = strings.LastIndex(clientAddr, ":"); pos >= 0 {
+			host, port = clientAddr[:pos], clientAddr[pos+1:]
+		}
+
+		if len(host) == 0 || host == "0.0.0.0" || host == "[::]" {
+			host = serverHost(serverAddr)
+		}
+
+		servers = append(servers, host+":"+port)
+	}
+
+	return strings.Join(servers, ",")
+}
+
+func serverHost(serverAddr string) string {
+	if strings.HasPrefix(serverAddr, "[") {
+		if pos := strings.Index(serverAddr, "]"); pos >= 0 {
+			return serverAddr[:pos+1]
+		}
+	}
+
+	if pos := strings.Index(serverAddr, ":"); pos >= 0 {
+		return serverAddr[:pos]
+	}
+
+	return serverAddr
+}
@@ -0,0 +1,252 @@
+package curator
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_ENSEMBLE_REFRESH_INTERVAL = 30 * time.Second
+
+var ErrNoServerResolved = errors.New("no server of the ensemble is resolved")
+
+// Resolve the servers of the ensemble, e.g. from the DNS records published by Consul or Kubernetes
+type EnsembleResolver interface {
+	// Return the addresses of the servers, e.g. "host1:2181"
+	ResolveServers() ([]string, error)
+}
+
+type ensembleResolverCallback struct {
+	callback func() ([]string, error)
+}
+
+func NewEnsembleResolver(callback func() ([]string, error)) EnsembleResolver {
+	return &ensembleResolverCallback{callback}
+}
+
+// An EnsembleResolver notified of the changes of the servers, which is watched instead of being polled
+type WatchingEnsembleResolver interface {
+	EnsembleResolver
+
+	// Call the callback with the servers on each change until the stop channel is closed, return the error breaking the watch
+	WatchServers(stop <-chan struct{}, callback func(servers []string)) error
+}
+
+func (r *ensembleResolverCallback) ResolveServers() ([]string, error) {
+	return r.callback()
+}
+
+type srvEnsembleResolver struct {
+	service, proto, name string
+	lookup               func(service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Resolve the servers from a DNS SRV record, e.g. _zookeeper._tcp.example.com,
+// the service and proto are empty to look up the name directly.
+func NewSrvEnsembleResolver(service, proto, name string) EnsembleResolver {
+	return &srvEnsembleResolver{service, proto, name, net.LookupSRV}
+}
+
+func (r *srvEnsembleResolver) ResolveServers() ([]string, error) {
+	_, records, err := r.lookup(r.service, r.proto, r.name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]string, 0, len(records))
+
+	for _, record := range records {
+		servers = append(servers, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+	}
+
+	return servers, nil
+}
+
+type hostEnsembleResolver struct {
+	host   string
+	port   int
+	lookup func(host string) ([]string, error)
+}
+
+// Resolve the servers from the A/AAAA records of a host, e.g. a headless service, all listening on the port
+func NewHostEnsembleResolver(host string, port int) EnsembleResolver {
+	return &hostEnsembleResolver{host, port, net.LookupHost}
+}
+
+func (r *hostEnsembleResolver) ResolveServers() ([]string, error) {
+	addrs, err := r.lookup(r.host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]string, 0, len(addrs))
+
+	for _, addr := range addrs {
+		servers = append(servers, net.JoinHostPort(addr, strconv.Itoa(r.port)))
+	}
+
+	return servers, nil
+}
+
+// An ensemble provider notifying the client when the connection string changes, so it reconnects at once
+type changeNotifyingEnsembleProvider interface {
+	setChangeListener(listener func())
+}
+
+// An ensemble provider resolving the servers on a refresh interval and rebuilding the connection string,
+// the client reconnects when the resolved servers differ.
+type ResolvingEnsembleProvider struct {
+	resolver        EnsembleResolver
+	refreshInterval time.Duration
+	lock            sync.RWMutex
+	connectString   string
+	onChange        func()
+	stop            chan struct{}
+	wg              sync.WaitGroup
+}
+
+// Create an ensemble provider resolving the servers with the resolver, every DEFAULT_ENSEMBLE_REFRESH_INTERVAL if the interval is not positive.
+//
+// The servers of a WatchingEnsembleResolver are watched instead, and a broken watch is retried after the interval.
+func NewResolvingEnsembleProvider(resolver EnsembleResolver, refreshInterval time.Duration) *ResolvingEnsembleProvider {
+	if refreshInterval <= 0 {
+		refreshInterval = DEFAULT_ENSEMBLE_REFRESH_INTERVAL
+	}
+
+	return &ResolvingEnsembleProvider{resolver: resolver, refreshInterval: refreshInterval}
+}
+
+// Create an ensemble provider resolving the servers from a DNS SRV record
+func NewDnsSrvEnsembleProvider(service, proto, name string, refreshInterval time.Duration) *ResolvingEnsembleProvider {
+	return NewResolvingEnsembleProvider(NewSrvEnsembleResolver(service, proto, name), refreshInterval)
+}
+
+// Resolve the servers, fail if none is resolved, then refresh them until the provider is closed
+func (p *ResolvingEnsembleProvider) Start() error {
+	if err := p.refresh(); err != nil {
+		return err
+	}
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+
+	if resolver, ok := p.resolver.(WatchingEnsembleResolver); ok {
+		go p.watchLoop(resolver)
+	} else {
+		go p.refreshLoop()
+	}
+
+	return nil
+}
+
+func (p *ResolvingEnsembleProvider) Close() error {
+	if p.stop != nil {
+		close(p.stop)
+
+		p.wg.Wait()
+
+		p.stop = nil
+	}
+
+	return nil
+}
+
+func (p *ResolvingEnsembleProvider) ConnectionString() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.connectString
+}
+
+func (p *ResolvingEnsembleProvider) setChangeListener(listener func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.onChange = listener
+}
+
+func (p *ResolvingEnsembleProvider) refreshLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.refreshInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.refresh(); err != nil {
+				log.Printf("fail to resolve the ensemble, keep the connection string %s, %s", p.ConnectionString(), err)
+			}
+
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *ResolvingEnsembleProvider) watchLoop(resolver WatchingEnsembleResolver) {
+	defer p.wg.Done()
+
+	for {
+		if err := resolver.WatchServers(p.stop, func(servers []string) {
+			if err := p.update(servers); err != nil {
+				log.Printf("fail to update the ensemble, keep the connection string %s, %s", p.ConnectionString(), err)
+			}
+		}); err != nil {
+			select {
+			case <-p.stop:
+				return
+			default:
+				log.Printf("fail to watch the ensemble, keep the connection string %s, %s", p.ConnectionString(), err)
+			}
+		}
+
+		select {
+		case <-time.After(p.refreshInterval):
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Resolve the servers and update the connection string
+func (p *ResolvingEnsembleProvider) refresh() error {
+	if servers, err := p.resolver.ResolveServers(); err != nil {
+		return err
+	} else {
+		return p.update(servers)
+	}
+}
+
+// Update the connection string with the servers, which are sorted so the order of the records doesn't matter
+func (p *ResolvingEnsembleProvider) update(servers []string) error {
+	if len(servers) == 0 {
+		return ErrNoServerResolved
+	}
+
+	sorted := append([]string(nil), servers...)
+
+	sort.Strings(sorted)
+
+	connectString := strings.Join(sorted, ",")
+
+	p.lock.Lock()
+	changed := p.connectString != "" && p.connectString != connectString
+	p.connectString = connectString
+	onChange := p.onChange
+	p.lock.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package curator
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSrvEnsembleResolver(t *testing.T) {
+	resolver := NewSrvEnsembleResolver("zookeeper", "tcp", "example.com").(*srvEnsembleResolver)
+
+	resolver.lookup = func(service, proto, name string) (string, []*net.SRV, error) {
+		assert.Equal(t, "zookeeper", service)
+		assert.Equal(t, "tcp", proto)
+		assert.Equal(t, "example.com", name)
+
+		return "_zookeeper._tcp.example.com.", []*net.SRV{
+			{Target: "zk2.example.com.", Port: 2181},
+			{Target: "zk1.example.com.", Port: 2182},
+		}, nil
+	}
+
+	servers, err := resolver.ResolveServers()
+
+	assert.Equal(t, []string{"zk2.example.com:2181", "zk1.example.com:2182"}, servers)
+	assert.NoError(t, err)
+}
+
+func TestHostEnsembleResolver(t *testing.T) {
+	resolver := NewHostEnsembleResolver("zk.example.com", 2181).(*hostEnsembleResolver)
+
+	resolver.lookup = func(host string) ([]string, error) {
+		return []string{"10.0.0.1", "::1"}, nil
+	}
+
+	servers, err := resolver.ResolveServers()
+
+	assert.Equal(t, []string{"10.0.0.1:2181", "[::1]:2181"}, servers)
+	assert.NoError(t, err)
+}
+
+func TestResolvingEnsembleProvider(t *testing.T) {
+	var lock sync.Mutex
+
+	servers := []string{"host2:2181", "host1:2181"}
+
+	p := NewResolvingEnsembleProvider(NewEnsembleResolver(func() ([]string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if servers == nil {
+			return nil, errors.New("lookup failed")
+		}
+
+		return servers, nil
+	}), 10*time.Millisecond)
+
+	assert.NoError(t, p.Start())
+	assert.Equal(t, "host1:2181,host2:2181", p.ConnectionString())
+
+	lock.Lock()
+	servers = []string{"host3:2181"}
+	lock.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host3:2181", p.ConnectionString())
+
+	// keep the last connection string if the servers can't be resolved
+	lock.Lock()
+	servers = nil
+	lock.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host3:2181", p.ConnectionString())
+	assert.NoError(t, p.Close())
+
+	// fail to start without any server
+	p = NewResolvingEnsembleProvider(NewEnsembleResolver(func() ([]string, error) { return nil, nil }), 0)
+
+	assert.Equal(t, ErrNoServerResolved, p.Start())
+	assert.NoError(t, p.Close())
+}
+
+type watchingEnsembleResolver struct {
+	servers []string
+	changes chan []string
+}
+
+func (r *watchingEnsembleResolver) ResolveServers() ([]string, error) {
+	return r.servers, nil
+}
+
+func (r *watchingEnsembleResolver) WatchServers(stop <-chan struct{}, callback func(servers []string)) error {
+	for {
+		select {
+		case servers := <-r.changes:
+			callback(servers)
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+func TestResolvingEnsembleProviderWatch(t *testing.T) {
+	resolver := &watchingEnsembleResolver{[]string{"host2:2181", "host1:2181"}, make(chan []string)}
+
+	// the servers are watched instead of being refreshed
+	p := NewResolvingEnsembleProvider(resolver, time.Hour)
+
+	assert.NoError(t, p.Start())
+	assert.Equal(t, "host1:2181,host2:2181", p.ConnectionString())
+
+	resolver.changes <- []string{"host3:2181"}
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host3:2181", p.ConnectionString())
+
+	// keep the last connection string if none of the servers is ready
+	resolver.changes <- nil
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host3:2181", p.ConnectionString())
+	assert.NoError(t, p.Close())
+}
@@ -0,0 +1,75 @@
+package curator
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const DEFAULT_FAILOVER_WINDOW = 30 * time.Second
+
+// An ensemble provider failing over from a primary provider to the backup connection strings,
+// when the connection can't be established within the failover window.
+//
+// The connection strings are tried in turn, and the provider stays on the one the client connected to.
+// The provider follows the connection state of the framework it is given to.
+type FailoverEnsembleProvider struct {
+	primary           EnsembleProvider
+	backups           []string
+	failoverWindow    time.Duration
+	lock              sync.Mutex
+	current           int       // the primary provider, or the backup before it
+	disconnectedSince time.Time // zero while connected
+}
+
+// Create an ensemble provider failing over between the primary provider and the backups,
+// after DEFAULT_FAILOVER_WINDOW if the window is not positive
+func NewFailoverEnsembleProvider(primary EnsembleProvider, failoverWindow time.Duration, backups ...string) *FailoverEnsembleProvider {
+	if failoverWindow <= 0 {
+		failoverWindow = DEFAULT_FAILOVER_WINDOW
+	}
+
+	return &FailoverEnsembleProvider{primary: primary, backups: backups, failoverWindow: failoverWindow}
+}
+
+func (p *FailoverEnsembleProvider) Start() error {
+	p.lock.Lock()
+	p.disconnectedSince = time.Now()
+	p.lock.Unlock()
+
+	return p.primary.Start()
+}
+
+func (p *FailoverEnsembleProvider) Close() error {
+	return p.primary.Close()
+}
+
+// Return the current connection string, the next one once the connection failed for the failover window
+func (p *FailoverEnsembleProvider) ConnectionString() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.backups) > 0 && !p.disconnectedSince.IsZero() && time.Since(p.disconnectedSince) >= p.failoverWindow {
+		p.current = (p.current + 1) % (len(p.backups) + 1)
+		p.disconnectedSince = time.Now()
+
+		log.Printf("Connection not established within %v, failover to the connection string #%d", p.failoverWindow, p.current)
+	}
+
+	if p.current == 0 {
+		return p.primary.ConnectionString()
+	}
+
+	return p.backups[p.current-1]
+}
+
+func (p *FailoverEnsembleProvider) StateChanged(client CuratorFramework, newState ConnectionState) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if newState.Connected() {
+		p.disconnectedSince = time.Time{}
+	} else if p.disconnectedSince.IsZero() {
+		p.disconnectedSince = time.Now()
+	}
+}
@@ -0,0 +1,40 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailoverEnsembleProvider(t *testing.T) {
+	p := NewFailoverEnsembleProvider(NewFixedEnsembleProvider("primary:2181"), 20*time.Millisecond, "backup1:2181", "backup2:2181")
+
+	assert.NoError(t, p.Start())
+	assert.Equal(t, "primary:2181", p.ConnectionString())
+
+	// fail over when the connection isn't established within the window
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, "backup1:2181", p.ConnectionString())
+
+	// stay on the connected one
+	p.StateChanged(nil, CONNECTED)
+
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, "backup1:2181", p.ConnectionString())
+
+	p.StateChanged(nil, SUSPENDED)
+
+	assert.Equal(t, "backup1:2181", p.ConnectionString())
+
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, "backup2:2181", p.ConnectionString())
+
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, "primary:2181", p.ConnectionString())
+	assert.NoError(t, p.Close())
+}
@@ -0,0 +1,43 @@
+package curator
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type fileEnsembleResolver struct {
+	path string
+}
+
+// Resolve the servers from a file, separated by commas, spaces or lines, the lines starting with # are ignored
+func NewFileEnsembleResolver(path string) EnsembleResolver {
+	return &fileEnsembleResolver{path}
+}
+
+func (r *fileEnsembleResolver) ResolveServers() ([]string, error) {
+	data, err := ioutil.ReadFile(r.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		servers = append(servers, strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })...)
+	}
+
+	return servers, nil
+}
+
+// Create an ensemble provider reading the servers from a file and reading it again on the poll interval,
+// the client only reconnects when the servers differ, not when the file is merely rewritten or reordered.
+func NewFileEnsembleProvider(path string, pollInterval time.Duration) *ResolvingEnsembleProvider {
+	return NewResolvingEnsembleProvider(NewFileEnsembleResolver(path), pollInterval)
+}
@@ -0,0 +1,51 @@
+package curator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileEnsembleProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ensemble")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "servers")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("# the ensemble\nhost2:2181, host1:2181\nhost3:2181\n"), 0644))
+
+	p := NewFileEnsembleProvider(path, 10*time.Millisecond)
+	changes := make(chan struct{}, 10)
+
+	p.setChangeListener(func() { changes <- struct{}{} })
+
+	assert.NoError(t, p.Start())
+	assert.Equal(t, "host1:2181,host2:2181,host3:2181", p.ConnectionString())
+
+	// the reordered servers keep the same connection string
+	assert.NoError(t, ioutil.WriteFile(path, []byte("host3:2181 host1:2181 host2:2181"), 0644))
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host1:2181,host2:2181,host3:2181", p.ConnectionString())
+	assert.Empty(t, changes)
+
+	// the client is notified to reconnect when the servers differ
+	assert.NoError(t, ioutil.WriteFile(path, []byte("host4:2181"), 0644))
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "host4:2181", p.ConnectionString())
+	assert.Len(t, changes, 1)
+	assert.NoError(t, p.Close())
+
+	// fail to start without the file
+	assert.Error(t, NewFileEnsembleProvider(filepath.Join(dir, "missing"), 0).Start())
+}
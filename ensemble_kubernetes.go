@@ -0,0 +1,323 @@
+package curator
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KUBERNETES_SERVICE_ACCOUNT_DIR = "/var/run/secrets/kubernetes.io/serviceaccount"
+	KUBERNETES_REQUEST_TIMEOUT     = 10 * time.Second
+	KUBERNETES_WATCH_TIMEOUT       = 5 * time.Minute // the API server closes the watches after the timeout, which are resumed from the last resource version
+)
+
+var ErrNotInCluster = errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not defined")
+
+var errKubernetesResourceExpired = errors.New("the resource version of the watch is expired") // 410 Gone, so the endpointslices are listed again
+
+type kubernetesObjectMeta struct {
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type kubernetesEndpointSlice struct {
+	Metadata  kubernetesObjectMeta `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name string `json:"name"`
+		Port *int   `json:"port"`
+	} `json:"ports"`
+}
+
+type kubernetesEndpointSliceList struct {
+	Metadata kubernetesObjectMeta      `json:"metadata"`
+	Items    []kubernetesEndpointSlice `json:"items"`
+}
+
+type kubernetesWatchEvent struct {
+	Type   string          `json:"type"` // ADDED, MODIFIED, DELETED, BOOKMARK or ERROR
+	Object json.RawMessage `json:"object"`
+}
+
+type kubernetesStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type kubernetesEnsembleResolver struct {
+	apiServer string // e.g. https://10.0.0.1:443
+	namespace string
+	service   string
+	portName  string
+	tokenFile string
+	client    *http.Client
+}
+
+// Resolve the ready pods of a headless service from its EndpointSlices with the in-cluster service account,
+// the namespace of the pod is used if the namespace is empty, and the first port if the port name is empty.
+//
+// The resolver is a WatchingEnsembleResolver, so the service account must be allowed to list and watch the endpointslices of the namespace.
+func NewKubernetesEnsembleResolver(namespace, service, portName string) (EnsembleResolver, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	if namespace == "" {
+		if data, err := ioutil.ReadFile(KUBERNETES_SERVICE_ACCOUNT_DIR + "/namespace"); err != nil {
+			return nil, err
+		} else {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+
+	certs := x509.NewCertPool()
+
+	if data, err := ioutil.ReadFile(KUBERNETES_SERVICE_ACCOUNT_DIR + "/ca.crt"); err != nil {
+		return nil, err
+	} else if !certs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("invalid CA certificate of the cluster: %s/ca.crt", KUBERNETES_SERVICE_ACCOUNT_DIR)
+	}
+
+	return &kubernetesEnsembleResolver{
+		apiServer: "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		service:   service,
+		portName:  portName,
+		tokenFile: KUBERNETES_SERVICE_ACCOUNT_DIR + "/token",
+		client: &http.Client{
+			Timeout:   KUBERNETES_REQUEST_TIMEOUT,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certs}},
+		},
+	}, nil
+}
+
+// Create an ensemble provider watching the ready pods of a headless service as they roll, a broken watch is retried after the refresh interval
+func NewKubernetesEnsembleProvider(namespace, service, portName string, refreshInterval time.Duration) (*ResolvingEnsembleProvider, error) {
+	if resolver, err := NewKubernetesEnsembleResolver(namespace, service, portName); err != nil {
+		return nil, err
+	} else {
+		return NewResolvingEnsembleProvider(resolver, refreshInterval), nil
+	}
+}
+
+func (r *kubernetesEnsembleResolver) ResolveServers() ([]string, error) {
+	if slices, err := r.list(context.Background()); err != nil {
+		return nil, err
+	} else {
+		return r.servers(slices.Items), nil
+	}
+}
+
+// List the endpointslices once, then watch them from the resource version of the list,
+// which is listed again if the resource version is expired.
+func (r *kubernetesEnsembleResolver) WatchServers(stop <-chan struct{}, callback func(servers []string)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		list, err := r.list(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		slices := make(map[string]kubernetesEndpointSlice)
+
+		for _, slice := range list.Items {
+			slices[slice.Metadata.Name] = slice
+		}
+
+		callback(r.servers(list.Items))
+
+		resourceVersion := list.Metadata.ResourceVersion
+
+		for err == nil {
+			resourceVersion, err = r.watch(ctx, resourceVersion, slices, callback)
+		}
+
+		if err != errKubernetesResourceExpired {
+			return err
+		}
+	}
+}
+
+func (r *kubernetesEnsembleResolver) list(ctx context.Context) (*kubernetesEndpointSliceList, error) {
+	resp, err := r.get(ctx, r.client, url.Values{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fail to list the endpointslices of service %s/%s, %s", r.namespace, r.service, resp.Status)
+	}
+
+	var slices kubernetesEndpointSliceList
+
+	if err := json.NewDecoder(resp.Body).Decode(&slices); err != nil {
+		return nil, err
+	}
+
+	return &slices, nil
+}
+
+// Watch the endpointslices from the resource version until the API server closes the watch,
+// return the last resource version to resume the watch from.
+func (r *kubernetesEnsembleResolver) watch(ctx context.Context, resourceVersion string, slices map[string]kubernetesEndpointSlice, callback func(servers []string)) (string, error) {
+	// the watch is bounded by the timeout of the API server instead of the timeout of the requests
+	client := &http.Client{Transport: r.client.Transport}
+
+	resp, err := r.get(ctx, client, url.Values{
+		"watch":               {"true"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {strconv.Itoa(int(KUBERNETES_WATCH_TIMEOUT / time.Second))},
+	})
+
+	if err != nil {
+		return resourceVersion, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return resourceVersion, errKubernetesResourceExpired
+	} else if resp.StatusCode != http.StatusOK {
+		return resourceVersion, fmt.Errorf("fail to watch the endpointslices of service %s/%s, %s", r.namespace, r.service, resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+
+	for {
+		var event kubernetesWatchEvent
+
+		if err := decoder.Decode(&event); err == io.EOF {
+			return resourceVersion, nil
+		} else if err != nil {
+			return resourceVersion, err
+		}
+
+		if event.Type == "ERROR" {
+			var status kubernetesStatus
+
+			if err := json.Unmarshal(event.Object, &status); err != nil {
+				return resourceVersion, err
+			} else if status.Code == http.StatusGone {
+				return resourceVersion, errKubernetesResourceExpired
+			} else {
+				return resourceVersion, fmt.Errorf("fail to watch the endpointslices of service %s/%s, %s", r.namespace, r.service, status.Message)
+			}
+		}
+
+		var slice kubernetesEndpointSlice
+
+		if err := json.Unmarshal(event.Object, &slice); err != nil {
+			return resourceVersion, err
+		}
+
+		resourceVersion = slice.Metadata.ResourceVersion
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			slices[slice.Metadata.Name] = slice
+		case "DELETED":
+			delete(slices, slice.Metadata.Name)
+		default:
+			continue // the bookmarks only advance the resource version
+		}
+
+		items := make([]kubernetesEndpointSlice, 0, len(slices))
+
+		for _, slice := range slices {
+			items = append(items, slice)
+		}
+
+		callback(r.servers(items))
+	}
+}
+
+func (r *kubernetesEnsembleResolver) get(ctx context.Context, client *http.Client, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", "kubernetes.io/service-name="+r.service)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s",
+		r.apiServer, url.PathEscape(r.namespace), query.Encode()), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the bound service account tokens are rotated, so the token is read for each request
+	if token, err := ioutil.ReadFile(r.tokenFile); err != nil {
+		return nil, err
+	} else {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	return client.Do(req.WithContext(ctx))
+}
+
+// Return the addresses of the ready endpoints of the slices
+func (r *kubernetesEnsembleResolver) servers(slices []kubernetesEndpointSlice) []string {
+	var servers []string
+
+	for _, slice := range slices {
+		port := 0
+
+		for _, p := range slice.Ports {
+			if p.Port != nil && (r.portName == "" || p.Name == r.portName) {
+				port = *p.Port
+
+				break
+			}
+		}
+
+		if port == 0 {
+			continue
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue // the pod is starting or terminating
+			}
+
+			for _, addr := range endpoint.Addresses {
+				servers = append(servers, net.JoinHostPort(addr, strconv.Itoa(port)))
+			}
+		}
+	}
+
+	return servers
+}
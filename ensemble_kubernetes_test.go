@@ -0,0 +1,191 @@
+package curator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesEnsembleResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/apis/discovery.k8s.io/v1/namespaces/default/endpointslices", r.URL.Path)
+		assert.Equal(t, "kubernetes.io/service-name=zk-hs", r.URL.Query().Get("labelSelector"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.Write([]byte(`{"items": [{
+			"endpoints": [
+				{"addresses": ["10.0.0.1"], "conditions": {"ready": true}},
+				{"addresses": ["10.0.0.2"], "conditions": {"ready": false}},
+				{"addresses": ["10.0.0.3"], "conditions": {}}
+			],
+			"ports": [{"name": "server", "port": 2888}, {"name": "client", "port": 2181}]
+		}]}`))
+	}))
+
+	defer server.Close()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+
+	assert.NoError(t, err)
+
+	defer os.Remove(tokenFile.Name())
+
+	tokenFile.WriteString("token\n")
+	tokenFile.Close()
+
+	resolver := &kubernetesEnsembleResolver{
+		apiServer: server.URL,
+		namespace: "default",
+		service:   "zk-hs",
+		portName:  "client",
+		tokenFile: tokenFile.Name(),
+		client:    server.Client(),
+	}
+
+	servers, err := resolver.ResolveServers()
+
+	assert.Equal(t, []string{"10.0.0.1:2181", "10.0.0.3:2181"}, servers)
+	assert.NoError(t, err)
+
+	resolver.portName = ""
+
+	servers, err = resolver.ResolveServers()
+
+	assert.Equal(t, []string{"10.0.0.1:2888", "10.0.0.3:2888"}, servers)
+	assert.NoError(t, err)
+}
+
+func kubernetesEndpointSliceJSON(name, resourceVersion, addr string) string {
+	return fmt.Sprintf(`{"metadata": {"name": "%s", "resourceVersion": "%s"}, "endpoints": [{"addresses": ["%s"]}], "ports": [{"name": "client", "port": 2181}]}`,
+		name, resourceVersion, addr)
+}
+
+func TestKubernetesEnsembleResolverWatch(t *testing.T) {
+	var lock sync.Mutex
+	var lists int
+	var watches []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "kubernetes.io/service-name=zk-hs", r.URL.Query().Get("labelSelector"))
+
+		send := func(eventType, object string) {
+			fmt.Fprintf(w, `{"type": "%s", "object": %s}`+"\n", eventType, object)
+
+			w.(http.Flusher).Flush()
+		}
+
+		if r.URL.Query().Get("watch") != "true" {
+			lock.Lock()
+			lists++
+			relisted := lists > 1
+			lock.Unlock()
+
+			if relisted {
+				fmt.Fprintf(w, `{"metadata": {"resourceVersion": "5"}, "items": [%s, %s]}`,
+					kubernetesEndpointSliceJSON("a", "3", "10.0.0.3"), kubernetesEndpointSliceJSON("b", "2", "10.0.0.2"))
+			} else {
+				fmt.Fprintf(w, `{"metadata": {"resourceVersion": "1"}, "items": [%s]}`, kubernetesEndpointSliceJSON("a", "1", "10.0.0.1"))
+			}
+
+			return
+		}
+
+		resourceVersion := r.URL.Query().Get("resourceVersion")
+
+		lock.Lock()
+		watches = append(watches, resourceVersion)
+		lock.Unlock()
+
+		switch resourceVersion {
+		case "1":
+			send("ADDED", kubernetesEndpointSliceJSON("b", "2", "10.0.0.2"))
+			send("ERROR", `{"kind": "Status", "code": 410, "reason": "Expired"}`)
+		case "5":
+			send("MODIFIED", kubernetesEndpointSliceJSON("a", "6", "10.0.0.4"))
+			send("BOOKMARK", `{"metadata": {"resourceVersion": "7"}}`)
+		default:
+			send("DELETED", kubernetesEndpointSliceJSON("b", "8", "10.0.0.2"))
+
+			<-r.Context().Done()
+		}
+	}))
+
+	defer server.Close()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+
+	assert.NoError(t, err)
+
+	defer os.Remove(tokenFile.Name())
+
+	tokenFile.WriteString("token\n")
+	tokenFile.Close()
+
+	resolver := &kubernetesEnsembleResolver{
+		apiServer: server.URL,
+		namespace: "default",
+		service:   "zk-hs",
+		portName:  "client",
+		tokenFile: tokenFile.Name(),
+		client:    server.Client(),
+	}
+
+	stop := make(chan struct{})
+	changes := make(chan []string, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- resolver.WatchServers(stop, func(servers []string) {
+			sort.Strings(servers)
+
+			changes <- servers
+		})
+	}()
+
+	for _, expected := range [][]string{
+		{"10.0.0.1:2181"},                  // listed
+		{"10.0.0.1:2181", "10.0.0.2:2181"}, // added
+		{"10.0.0.2:2181", "10.0.0.3:2181"}, // listed again once the resource version is expired
+		{"10.0.0.2:2181", "10.0.0.4:2181"}, // modified
+		{"10.0.0.4:2181"},                  // deleted, after the watch is resumed from the bookmark
+	} {
+		select {
+		case servers := <-changes:
+			assert.Equal(t, expected, servers)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the servers aren't changed", "expected %v", expected)
+		}
+	}
+
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the watch isn't stopped")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, 2, lists)
+	assert.Equal(t, []string{"1", "5", "7"}, watches)
+}
+
+func TestKubernetesEnsembleResolverNotInCluster(t *testing.T) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		t.Skip("running in a Kubernetes cluster")
+	}
+
+	_, err := NewKubernetesEnsembleResolver("default", "zk-hs", "client")
+
+	assert.Equal(t, ErrNotInCluster, err)
+}
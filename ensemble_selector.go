@@ -0,0 +1,235 @@
+package curator
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DEFAULT_LATENCY_PROBE_TIMEOUT = time.Second
+
+// Order the servers of the connection string on each connection, the client tries them in turn
+type ServerSelector interface {
+	SelectServers(servers []string) []string
+}
+
+type serverSelectorCallback struct {
+	callback func(servers []string) []string
+}
+
+func NewServerSelector(callback func(servers []string) []string) ServerSelector {
+	return &serverSelectorCallback{callback}
+}
+
+func (s *serverSelectorCallback) SelectServers(servers []string) []string {
+	return s.callback(servers)
+}
+
+type shuffleServerSelector struct{}
+
+// Shuffle the servers on each connection to spread the load of the clients over the ensemble
+func NewShuffleServerSelector() ServerSelector {
+	return shuffleServerSelector{}
+}
+
+func (shuffleServerSelector) SelectServers(servers []string) []string {
+	shuffled := append([]string(nil), servers...)
+
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	return shuffled
+}
+
+type preferredServerSelector struct {
+	prefer func(server string) bool
+}
+
+// Shuffle the servers and try the preferred ones first, e.g. the servers on the same host
+func NewPreferredServerSelector(prefer func(server string) bool) ServerSelector {
+	return &preferredServerSelector{prefer}
+}
+
+func (s *preferredServerSelector) SelectServers(servers []string) []string {
+	selected := NewShuffleServerSelector().SelectServers(servers)
+
+	sort.SliceStable(selected, func(i, j int) bool { return s.prefer(selected[i]) && !s.prefer(selected[j]) })
+
+	return selected
+}
+
+// Shuffle the servers and try the ones of the zone first, the zones of the servers are labeled by their address, e.g. "zk1:2181"
+func NewZoneServerSelector(zones map[string]string, zone string) ServerSelector {
+	return NewPreferredServerSelector(func(server string) bool { return zones[server] == zone })
+}
+
+type latencyServerSelector struct {
+	timeout time.Duration
+	dial    func(network, address string, timeout time.Duration) (net.Conn, error)
+}
+
+// Probe the servers with a TCP connection and try the fastest ones first, the unreachable ones last.
+// The probes run concurrently on each connection, up to the timeout, DEFAULT_LATENCY_PROBE_TIMEOUT if it is not positive.
+func NewLatencyServerSelector(timeout time.Duration) ServerSelector {
+	if timeout <= 0 {
+		timeout = DEFAULT_LATENCY_PROBE_TIMEOUT
+	}
+
+	return &latencyServerSelector{timeout, net.DialTimeout}
+}
+
+func (s *latencyServerSelector) SelectServers(servers []string) []string {
+	selected := NewShuffleServerSelector().SelectServers(servers)
+	latencies := make(map[string]time.Duration, len(selected))
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, server := range selected {
+		wg.Add(1)
+
+		go func(server string) {
+			defer wg.Done()
+
+			latency := time.Duration(1<<63 - 1)
+			started := time.Now()
+
+			if conn, err := s.dial("tcp", server, s.timeout); err == nil {
+				latency = time.Since(started)
+
+				conn.Close()
+			}
+
+			lock.Lock()
+			latencies[server] = latency
+			lock.Unlock()
+		}(server)
+	}
+
+	wg.Wait()
+
+	sort.SliceStable(selected, func(i, j int) bool { return latencies[selected[i]] < latencies[selected[j]] })
+
+	return selected
+}
+
+// How the hostnames of the servers are resolved by the default dialer
+type HostResolution int
+
+const (
+	RESOLVE_ONCE      HostResolution = iota // resolve the hostnames once per connection string and try the addresses in turn, as the zk package does
+	RESOLVE_ON_DIAL                         // resolve the hostnames on each connection attempt, the dialer picks one of the addresses
+	RESOLVE_EACH_PASS                       // resolve the hostnames again on each pass over the servers, and try the addresses in turn
+)
+
+// A host provider of the zk package trying the servers in the order of the selector if any,
+// the addresses of a server are tried in turn before the next server.
+//
+// The hostnames are resolved again unless the resolution is RESOLVE_ONCE,
+// so the clients follow the servers replaced behind stable hostnames.
+type hostProvider struct {
+	selector   ServerSelector
+	resolution HostResolution
+	lookupHost func(host string) ([]string, error)
+	lock       sync.Mutex
+	servers    []string // the servers of the connection string
+	addrs      []string // the addresses of the current pass
+	curr       int
+	connected  bool // connected during the current pass
+}
+
+func newHostProvider(selector ServerSelector, resolution HostResolution) *hostProvider {
+	return &hostProvider{selector: selector, resolution: resolution, lookupHost: net.LookupHost}
+}
+
+func (p *hostProvider) Init(servers []string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.servers = servers
+
+	if addrs, err := p.resolve(); err != nil {
+		return err
+	} else if len(addrs) == 0 {
+		return fmt.Errorf("no hosts found for addresses %q", servers)
+	} else {
+		p.addrs = addrs
+	}
+
+	p.curr = -1
+	p.connected = false
+
+	return nil
+}
+
+// Return the addresses of a pass over the servers, the unresolved hostnames are skipped unless resolved once
+func (p *hostProvider) resolve() ([]string, error) {
+	servers := p.servers
+
+	if p.selector != nil {
+		servers = p.selector.SelectServers(servers)
+	}
+
+	if p.resolution == RESOLVE_ON_DIAL {
+		return servers, nil
+	}
+
+	var found []string
+
+	for _, server := range servers {
+		if host, port, err := net.SplitHostPort(server); err != nil {
+			return nil, err
+		} else if addrs, err := p.lookupHost(host); err != nil {
+			if p.resolution == RESOLVE_ONCE {
+				return nil, err
+			}
+
+			log.Printf("fail to resolve the server %s, %s", server, err)
+		} else {
+			for _, addr := range addrs {
+				found = append(found, net.JoinHostPort(addr, port))
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func (p *hostProvider) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.addrs)
+}
+
+// Return the next address, and true once a whole pass over the servers failed to connect
+func (p *hostProvider) Next() (server string, retryStart bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.curr++; p.curr >= len(p.addrs) {
+		retryStart = !p.connected
+
+		p.curr = 0
+		p.connected = false
+
+		if p.resolution != RESOLVE_ONCE {
+			// keep the last addresses if none is resolved
+			if addrs, err := p.resolve(); err == nil && len(addrs) > 0 {
+				p.addrs = addrs
+			}
+		}
+	}
+
+	return p.addrs[p.curr], retryStart
+}
+
+func (p *hostProvider) Connected() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.connected = true
+}
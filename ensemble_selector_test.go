@@ -0,0 +1,154 @@
+package curator
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuffleServerSelector(t *testing.T) {
+	servers := []string{"zk1:2181", "zk2:2181", "zk3:2181"}
+
+	selected := NewShuffleServerSelector().SelectServers(servers)
+
+	assert.ElementsMatch(t, servers, selected)
+	assert.Equal(t, []string{"zk1:2181", "zk2:2181", "zk3:2181"}, servers)
+}
+
+func TestZoneServerSelector(t *testing.T) {
+	selector := NewZoneServerSelector(map[string]string{
+		"zk1:2181": "us-east-1a",
+		"zk2:2181": "us-east-1b",
+		"zk3:2181": "us-east-1b",
+	}, "us-east-1b")
+
+	for i := 0; i < 10; i++ {
+		selected := selector.SelectServers([]string{"zk1:2181", "zk2:2181", "zk3:2181"})
+
+		assert.ElementsMatch(t, []string{"zk2:2181", "zk3:2181"}, selected[:2])
+		assert.Equal(t, "zk1:2181", selected[2])
+	}
+}
+
+func TestLatencyServerSelector(t *testing.T) {
+	selector := NewLatencyServerSelector(0).(*latencyServerSelector)
+
+	assert.Equal(t, DEFAULT_LATENCY_PROBE_TIMEOUT, selector.timeout)
+
+	selector.dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		switch address {
+		case "far:2181":
+			time.Sleep(20 * time.Millisecond)
+		case "down:2181":
+			return nil, errors.New("connection refused")
+		}
+
+		client, server := net.Pipe()
+
+		server.Close()
+
+		return client, nil
+	}
+
+	selected := selector.SelectServers([]string{"down:2181", "far:2181", "near:2181"})
+
+	assert.Equal(t, []string{"near:2181", "far:2181", "down:2181"}, selected)
+}
+
+func TestSelectingHostProvider(t *testing.T) {
+	p := newHostProvider(NewServerSelector(func(servers []string) []string {
+		return []string{servers[1], servers[0]}
+	}), RESOLVE_ONCE)
+
+	p.lookupHost = func(host string) ([]string, error) {
+		if host == "zk1" {
+			return []string{"10.0.0.1", "10.0.0.2"}, nil
+		}
+
+		return []string{"10.0.0.3"}, nil
+	}
+
+	assert.NoError(t, p.Init([]string{"zk1:2181", "zk2:2181"}))
+	assert.Equal(t, 3, p.Len())
+
+	server, retryStart := p.Next()
+
+	assert.Equal(t, "10.0.0.3:2181", server)
+	assert.False(t, retryStart)
+
+	server, _ = p.Next()
+
+	assert.Equal(t, "10.0.0.1:2181", server)
+
+	server, _ = p.Next()
+
+	assert.Equal(t, "10.0.0.2:2181", server)
+
+	server, retryStart = p.Next()
+
+	assert.Equal(t, "10.0.0.3:2181", server)
+	assert.True(t, retryStart)
+
+	p.lookupHost = func(host string) ([]string, error) { return nil, nil }
+
+	assert.Error(t, p.Init([]string{"zk1:2181", "zk2:2181"}))
+}
+
+func TestHostProviderResolution(t *testing.T) {
+	addrs := []string{"10.0.0.1", "10.0.0.2"}
+
+	p := newHostProvider(nil, RESOLVE_EACH_PASS)
+
+	p.lookupHost = func(host string) ([]string, error) {
+		if addrs == nil {
+			return nil, errors.New("no such host")
+		}
+
+		return addrs, nil
+	}
+
+	assert.NoError(t, p.Init([]string{"zk:2181"}))
+
+	server, _ := p.Next()
+
+	assert.Equal(t, "10.0.0.1:2181", server)
+
+	p.Connected()
+
+	// the server is replaced behind the hostname
+	addrs = []string{"10.0.0.3"}
+
+	server, _ = p.Next()
+
+	assert.Equal(t, "10.0.0.2:2181", server)
+
+	server, retryStart := p.Next()
+
+	assert.Equal(t, "10.0.0.3:2181", server)
+	assert.False(t, retryStart)
+	assert.Equal(t, 1, p.Len())
+
+	// keep the last addresses if the hostname can't be resolved
+	addrs = nil
+
+	server, retryStart = p.Next()
+
+	assert.Equal(t, "10.0.0.3:2181", server)
+	assert.True(t, retryStart)
+
+	// leave the hostnames to the dialer
+	p = newHostProvider(nil, RESOLVE_ON_DIAL)
+
+	assert.NoError(t, p.Init([]string{"zk1:2181", "zk2:2181"}))
+
+	server, _ = p.Next()
+
+	assert.Equal(t, "zk1:2181", server)
+
+	server, _ = p.Next()
+
+	assert.Equal(t, "zk2:2181", server)
+}
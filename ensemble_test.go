@@ -2,8 +2,11 @@ package curator
 
 import (
 	"testing"
+	"time"
 
+	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
 )
 
 func TestFixedEnsembleProvider(t *testing.T) {
@@ -15,5 +18,65 @@ func TestFixedEnsembleProvider(t *testing.T) {
 
 	assert.Equal(t, "connStr", p.ConnectionString())
 
+	p.SetConnectionString("anotherStr")
+
+	assert.Equal(t, "anotherStr", p.ConnectionString())
+
 	assert.NoError(t, p.Close())
 }
+
+func TestConfigToConnectionString(t *testing.T) {
+	assert.Equal(t, "host1:2181,10.0.0.2:2182,[::1]:2181", ConfigToConnectionString([]byte(
+		"server.1=host1:2888:3888:participant;0.0.0.0:2181\n"+
+			"server.2=host2:2888:3888:participant;10.0.0.2:2182\n"+
+			"server.3=[::1]:2888:3888:observer;2181\n"+
+			"server.4=host4:2888:3888:participant\n"+
+			"version=100000000")))
+
+	assert.Empty(t, ConfigToConnectionString([]byte("version=100000000")))
+}
+
+type EnsembleTrackerTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestEnsembleTracker(t *testing.T) {
+	suite.Run(t, new(EnsembleTrackerTestSuite))
+}
+
+func (s *EnsembleTrackerTestSuite) TestTrackConfig() {
+	s.With(func(client CuratorFramework, conn *mockConn, stat *zk.Stat) {
+		provider := NewFixedEnsembleProvider("host1:2181")
+		tracker := newEnsembleTracker(client.(*curatorFramework), provider)
+
+		events := make(chan zk.Event)
+
+		defer close(events)
+
+		conn.On("GetW", CONFIG_NODE).Return([]byte("server.1=host1:2888:3888:participant;0.0.0.0:2181\nversion=100000000"), stat, events, nil).Once()
+
+		tracker.StateChanged(client, CONNECTED)
+
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Equal(s.T(), "host1:2181", provider.ConnectionString())
+
+		// the ensemble is reconfigured
+		conn.On("GetW", CONFIG_NODE).Return([]byte("server.1=host1:2888:3888:participant;0.0.0.0:2181\nserver.2=host2:2888:3888:participant;0.0.0.0:2181\nversion=100000001"), stat, events, nil).Once()
+
+		events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: CONFIG_NODE}
+
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Equal(s.T(), "host1:2181,host2:2181", provider.ConnectionString())
+
+		// the closed tracker ignores the changes
+		tracker.close()
+
+		events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: CONFIG_NODE}
+
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Equal(s.T(), "host1:2181,host2:2181", provider.ConnectionString())
+	})
+}
@@ -18,11 +18,15 @@ const (
 	SYNC                             // CuratorFramework.Sync() -> Err(), Path()
 	GET_ACL                          // CuratorFramework.GetACL() -> Err(), Path()
 	SET_ACL                          // CuratorFramework.SetACL() -> Err(), Path()
+	TRANSACTION                      // CuratorFramework.Transaction() -> Err(), OpResults()
 	WATCHED                          // Watchable.UsingWatcher() -> WatchedEvent()
 	CLOSING                          // Event sent when client is being closed
+	GET_CONFIG                       // CuratorFramework.GetConfig() -> Err(), Stat(), Data()
+	RECONFIG                         // CuratorFramework.Reconfig() -> Err(), Stat(), Data()
+	ADD_WATCH                        // CuratorFramework.Watches().Add() -> Err(), Path()
 )
 
-var CuratorEventTypeNames = []string{"CREATE", "DELETE", "EXISTS", "GET_DATA", "SET_DATA", "CHILDREN", "SYNC", "GET_ACL", "SET_ACL", "WATCHED", "CLOSING"}
+var CuratorEventTypeNames = []string{"CREATE", "DELETE", "EXISTS", "GET_DATA", "SET_DATA", "CHILDREN", "SYNC", "GET_ACL", "SET_ACL", "TRANSACTION", "WATCHED", "CLOSING", "GET_CONFIG", "RECONFIG", "ADD_WATCH"}
 
 func (t CuratorEventType) String() string {
 	if int(t) < len(CuratorEventTypeNames) {
@@ -62,6 +66,9 @@ type CuratorEvent interface {
 	ACLs() []zk.ACL
 
 	WatchedEvent() *zk.Event
+
+	// any operation results of a transaction
+	OpResults() []TransactionResult
 }
 
 type curatorEvent struct {
@@ -75,6 +82,7 @@ type curatorEvent struct {
 	data         []byte
 	watchedEvent *zk.Event
 	acls         []zk.ACL
+	opResults    []TransactionResult
 }
 
 func (e *curatorEvent) Type() CuratorEventType { return e.eventType }
@@ -96,3 +104,5 @@ func (e *curatorEvent) Children() []string { return e.children }
 func (e *curatorEvent) ACLs() []zk.ACL { return e.acls }
 
 func (e *curatorEvent) WatchedEvent() *zk.Event { return e.watchedEvent }
+
+func (e *curatorEvent) OpResults() []TransactionResult { return e.opResults }
@@ -0,0 +1,128 @@
+package curator
+
+import (
+	"sync"
+	"time"
+)
+
+// The default number of the go-routines running the background operations
+const DEFAULT_EXECUTOR_SIZE = 64
+
+// Run the background operations and their callbacks
+type Executor interface {
+	// Run the task asynchronously, without blocking the caller
+	Execute(task func())
+}
+
+type executorCallback struct {
+	callback func(task func())
+}
+
+func NewExecutor(callback func(task func())) Executor {
+	return &executorCallback{callback}
+}
+
+func (e *executorCallback) Execute(task func()) {
+	e.callback(task)
+}
+
+// Run the tasks in a bounded pool of go-routines.
+// The tasks submitted while all the go-routines are busy are queued, and run in order as the go-routines become free.
+type poolExecutor struct {
+	lock    sync.Mutex
+	size    int
+	workers int
+	queue   []func()
+}
+
+func NewPoolExecutor(size int) Executor {
+	if size <= 0 {
+		size = DEFAULT_EXECUTOR_SIZE
+	}
+
+	return &poolExecutor{size: size}
+}
+
+func (e *poolExecutor) Execute(task func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.workers < e.size {
+		e.workers++
+
+		go e.work(task)
+	} else {
+		e.queue = append(e.queue, task)
+	}
+}
+
+func (e *poolExecutor) work(task func()) {
+	for task != nil {
+		task()
+
+		e.lock.Lock()
+
+		if len(e.queue) > 0 {
+			task, e.queue = e.queue[0], e.queue[1:]
+		} else {
+			task = nil
+
+			e.workers--
+		}
+
+		e.lock.Unlock()
+	}
+}
+
+// Track the tasks in flight, so they can be waited for on close
+type taskTracker struct {
+	lock  sync.Mutex
+	count int
+	idle  chan struct{} // closed once the tasks in flight are done
+}
+
+func (t *taskTracker) add() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.count == 0 {
+		t.idle = make(chan struct{})
+	}
+
+	t.count++
+}
+
+func (t *taskTracker) done() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.count--; t.count == 0 {
+		close(t.idle)
+	}
+}
+
+// Wait for the tasks in flight until the timeout, return false if they aren't done
+func (t *taskTracker) wait(timeout time.Duration) bool {
+	t.lock.Lock()
+
+	if t.count == 0 {
+		t.lock.Unlock()
+
+		return true
+	}
+
+	idle := t.idle
+
+	t.lock.Unlock()
+
+	timer := time.NewTimer(timeout)
+
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,139 @@
+package curator
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPoolExecutor(t *testing.T) {
+	executor := NewPoolExecutor(2)
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+
+	blocked := make(chan struct{})
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		executor.Execute(func() {
+			defer wg.Done()
+
+			n := atomic.AddInt32(&running, 1)
+
+			for {
+				if max := atomic.LoadInt32(&maxRunning); n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+
+			<-blocked
+
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	close(blocked)
+
+	wg.Wait()
+
+	assert.True(t, atomic.LoadInt32(&maxRunning) <= 2)
+
+	// the workers exit once the tasks returned, which may be after the group is done
+	pool := executor.(*poolExecutor)
+
+	workers := func() int {
+		pool.lock.Lock()
+		defer pool.lock.Unlock()
+
+		return pool.workers
+	}
+
+	for deadline := time.Now().Add(time.Second); workers() > 0 && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+	}
+
+	assert.Equal(t, 0, workers())
+}
+
+type ExecutorTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestExecutor(t *testing.T) {
+	suite.Run(t, new(ExecutorTestSuite))
+}
+
+func (s *ExecutorTestSuite) TestBackground() {
+	var executed int32
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.Executor = NewExecutor(func(task func()) {
+			atomic.AddInt32(&executed, 1)
+
+			go task()
+		})
+	}, func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		conn.On("Delete", "/node", AnyVersion).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Delete().InBackgroundWithCallback(func(client CuratorFramework, event CuratorEvent) error {
+			defer wg.Done()
+
+			assert.Equal(s.T(), int32(1), atomic.LoadInt32(&executed))
+
+			return nil
+		}).ForPath("/node"))
+	})
+}
+
+func (s *ExecutorTestSuite) TestPanic() {
+	s.With(func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		client.UnhandledErrorListenable().AddListener(NewUnhandledErrorListener(func(err error) {
+			defer wg.Done()
+
+			assert.EqualError(s.T(), err, "Background task panicked, boom")
+		}))
+
+		client.(*curatorFramework).runSafe(func() {
+			panic(errors.New("boom"))
+		})
+	})
+}
+
+func (s *ExecutorTestSuite) TestWaitForShutdown() {
+	var deleted AtomicBool
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.WaitForShutdownTimeout = time.Second
+	}, func(client CuratorFramework, conn *mockConn) {
+		conn.On("Delete", "/node", AnyVersion).After(10 * time.Millisecond).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Delete().InBackgroundWithCallback(func(client CuratorFramework, event CuratorEvent) error {
+			deleted.Set(true)
+
+			return nil
+		}).ForPath("/node"))
+	})
+
+	// the client waited for the background operation when closed
+	assert.True(s.T(), deleted.Load())
+}
+
+func TestTaskTracker(t *testing.T) {
+	tracker := &taskTracker{}
+
+	assert.True(t, tracker.wait(time.Millisecond))
+
+	tracker.add()
+
+	assert.False(t, tracker.wait(time.Millisecond))
+
+	tracker.done()
+
+	assert.True(t, tracker.wait(time.Millisecond))
+}
@@ -8,13 +8,14 @@ type checkExistsBuilder struct {
 	client        *curatorFramework
 	backgrounding backgrounding
 	watching      watching
+	stat          *zk.Stat
 }
 
 func (b *checkExistsBuilder) ForPath(givenPath string) (*zk.Stat, error) {
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath) })
 
 		return nil, nil
 	} else {
@@ -29,24 +30,23 @@ func (b *checkExistsBuilder) pathInBackground(path string) {
 
 	stat, err := b.pathInForeground(path)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: EXISTS,
-			err:       err,
-			path:      b.client.unfixForNamespace(path),
-			stat:      stat,
-			name:      GetNodeFromPath(path),
-			context:   b.backgrounding.context,
-		}
-
-		b.backgrounding.callback(b.client, event)
+	event := &curatorEvent{
+		eventType: EXISTS,
+		err:       err,
+		path:      b.client.unfixForNamespace(path),
+		stat:      stat,
+		name:      GetNodeFromPath(path),
+		context:   b.backgrounding.context,
 	}
+
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *checkExistsBuilder) pathInForeground(path string) (*zk.Stat, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(EXISTS.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, EXISTS.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -59,7 +59,7 @@ func (b *checkExistsBuilder) pathInForeground(path string) (*zk.Stat, error) {
 				exists, stat, events, err = conn.ExistsW(path)
 
 				if events != nil && b.watching.watcher != nil {
-					go NewWatchers(b.watching.watcher).Watch(events)
+					go newWatchers(b.client.dispatcher, b.watching.watcher).Watch(events)
 				}
 			} else {
 				exists, stat, err = conn.Exists(path)
@@ -70,6 +70,10 @@ func (b *checkExistsBuilder) pathInForeground(path string) (*zk.Stat, error) {
 			} else if !exists {
 				return nil, nil
 			} else {
+				if b.stat != nil && stat != nil {
+					*b.stat = *stat
+				}
+
 				return stat, nil
 			}
 		}
@@ -77,9 +81,17 @@ func (b *checkExistsBuilder) pathInForeground(path string) (*zk.Stat, error) {
 
 	stat, _ := result.(*zk.Stat)
 
+	trace.WithWatcher(b.watching.watched || b.watching.watcher != nil).WithStat(stat).Commit(err)
+
 	return stat, err
 }
 
+func (b *checkExistsBuilder) StoringStatIn(stat *zk.Stat) CheckExistsBuilder {
+	b.stat = stat
+
+	return b
+}
+
 func (b *checkExistsBuilder) Watched() CheckExistsBuilder {
 	b.watching.watched = true
 
@@ -92,6 +104,16 @@ func (b *checkExistsBuilder) UsingWatcher(watcher Watcher) CheckExistsBuilder {
 	return b
 }
 
+func (b *checkExistsBuilder) UsingWatcherFunc(fn func(event zk.Event)) CheckExistsBuilder {
+	b.watching.watcher = b.client.newNamespaceWatcher(fn)
+
+	return b
+}
+
+func (b *checkExistsBuilder) UsingWatcherChannel(events chan<- zk.Event) CheckExistsBuilder {
+	return b.UsingWatcherFunc(func(event zk.Event) { events <- event })
+}
+
 func (b *checkExistsBuilder) InBackground() CheckExistsBuilder {
 	b.backgrounding = backgrounding{inBackground: true}
 
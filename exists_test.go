@@ -27,6 +27,17 @@ func (s *CheckExistsBuilderTestSuite) TestCheckExists() {
 		assert.NoError(s.T(), err)
 	})
 
+	s.With(func(client CuratorFramework, conn *mockConn, stat *zk.Stat) {
+		conn.On("Exists", "/node").Return(true, stat, nil).Once()
+
+		var stored zk.Stat
+
+		_, err := client.CheckExists().StoringStatIn(&stored).ForPath("/node")
+
+		assert.Equal(s.T(), *stat, stored)
+		assert.NoError(s.T(), err)
+	})
+
 	s.With(func(client CuratorFramework, conn *mockConn) {
 		conn.On("Exists", "/node").Return(false, nil, nil).Once()
 
@@ -0,0 +1,131 @@
+package curator
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// The stats of an operation published via expvar
+type expvarOperationStats struct {
+	Count  int `json:"count"`
+	Errors int `json:"errors"`
+}
+
+// The stats of a client published via expvar, e.g. {"state":"CONNECTED","operations":{"GET_DATA":{"count":2,"errors":0}},"retries":0,"watches":1}
+type expvarSnapshot struct {
+	State      string                          `json:"state"`
+	Operations map[string]expvarOperationStats `json:"operations"`
+	Retries    int                             `json:"retries"` // the retries allowed by the retry policy
+	Watches    int                             `json:"watches"` // the operations which set a watch
+}
+
+// A TracerDriver recording the stats of a client before passing the traces to the driver of the client
+type expvarTracerDriver struct {
+	TracerDriver
+
+	state      func() ConnectionState
+	lock       sync.Mutex
+	operations map[string]*expvarOperationStats
+	retries    int
+	watches    int
+}
+
+func newExpvarTracerDriver(driver TracerDriver, state func() ConnectionState) *expvarTracerDriver {
+	return &expvarTracerDriver{
+		TracerDriver: driver,
+		state:        state,
+		operations:   make(map[string]*expvarOperationStats),
+	}
+}
+
+func (d *expvarTracerDriver) AddCount(name string, increment int) {
+	if name == "retries-allowed" {
+		d.lock.Lock()
+		d.retries += increment
+		d.lock.Unlock()
+	}
+
+	d.TracerDriver.AddCount(name, increment)
+}
+
+func (d *expvarTracerDriver) AddTrace(trace *TracedOperation) {
+	d.lock.Lock()
+
+	stats, ok := d.operations[trace.Name]
+
+	if !ok {
+		stats = &expvarOperationStats{}
+
+		d.operations[trace.Name] = stats
+	}
+
+	stats.Count++
+
+	if trace.Err != nil {
+		stats.Errors++
+	}
+
+	if trace.WithWatcher {
+		d.watches++
+	}
+
+	d.lock.Unlock()
+
+	addTrace(d.TracerDriver, trace)
+}
+
+func (d *expvarTracerDriver) AddEvent(trace *TracedEvent) {
+	if driver, ok := d.TracerDriver.(AdvancedTracerDriver); ok {
+		driver.AddEvent(trace)
+	}
+}
+
+// Take a snapshot of the stats, which is encoded as JSON by expvar
+func (d *expvarTracerDriver) snapshot() interface{} {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	snapshot := &expvarSnapshot{
+		State:      d.state().String(),
+		Operations: make(map[string]expvarOperationStats, len(d.operations)),
+		Retries:    d.retries,
+		Watches:    d.watches,
+	}
+
+	for name, stats := range d.operations {
+		snapshot.Operations[name] = *stats
+	}
+
+	return snapshot
+}
+
+var (
+	expvarLock    sync.Mutex
+	expvarDrivers = make(map[string]*expvarTracerDriver)
+)
+
+// Publish the stats of the driver via expvar under the name, which replaces the stats of a previous client published under it,
+// since the variables of expvar can't be removed.
+func publishExpvar(name string, driver *expvarTracerDriver) error {
+	expvarLock.Lock()
+	defer expvarLock.Unlock()
+
+	if _, published := expvarDrivers[name]; !published {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("expvar %s is already published", name)
+		}
+
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			expvarLock.Lock()
+			driver := expvarDrivers[name]
+			expvarLock.Unlock()
+
+			return driver.snapshot()
+		}))
+	}
+
+	expvarDrivers[name] = driver
+
+	return nil
+}
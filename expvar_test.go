@@ -0,0 +1,62 @@
+package curator
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExpvarTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestExpvar(t *testing.T) {
+	suite.Run(t, new(ExpvarTestSuite))
+}
+
+func (s *ExpvarTestSuite) TestPublish() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.ExpvarName = "curator-test"
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat, acls []zk.ACL) {
+		conn.On("GetW", "/node").Return(data, stat, nil, nil).Once()
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("", zk.ErrNodeExists).Once()
+
+		_, err := client.GetData().Watched().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.Create().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), zk.ErrNodeExists, err)
+
+		var snapshot expvarSnapshot
+
+		assert.NoError(s.T(), json.Unmarshal([]byte(expvar.Get("curator-test").String()), &snapshot))
+		assert.Equal(s.T(), map[string]expvarOperationStats{
+			"GET_DATA": {Count: 1},
+			"CREATE":   {Count: 1, Errors: 1},
+		}, snapshot.Operations)
+		assert.Equal(s.T(), 1, snapshot.Watches)
+		assert.NotEmpty(s.T(), snapshot.State)
+	})
+}
+
+func TestPublishExpvar(t *testing.T) {
+	expvar.NewInt("curator-taken")
+
+	driver := newExpvarTracerDriver(newDefaultTracerDriver(), func() ConnectionState { return CONNECTED })
+
+	assert.EqualError(t, publishExpvar("curator-taken", driver), "expvar curator-taken is already published")
+
+	// the latest driver published under the name replaces the previous one
+	assert.NoError(t, publishExpvar("curator-replaced", newExpvarTracerDriver(newDefaultTracerDriver(), func() ConnectionState { return LOST })))
+	assert.NoError(t, publishExpvar("curator-replaced", driver))
+
+	driver.AddCount("retries-allowed", 2)
+
+	assert.JSONEq(t, `{"state":"CONNECTED","operations":{},"retries":2,"watches":0}`, expvar.Get("curator-replaced").String())
+}
@@ -1,8 +1,11 @@
 package curator
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
@@ -12,6 +15,7 @@ const (
 	DEFAULT_SESSION_TIMEOUT    = 60 * time.Second
 	DEFAULT_CONNECTION_TIMEOUT = 15 * time.Second
 	DEFAULT_CLOSE_WAIT         = 1 * time.Second
+	DEFAULT_MAX_PACKET_SIZE    = 0xfffff // the default jute.maxbuffer of the servers
 )
 
 // Zookeeper framework-style client
@@ -53,9 +57,24 @@ type CuratorFramework interface {
 	// Start a set ACL builder
 	SetACL() SetACLBuilder
 
+	// Start a get config builder, the config of the ensemble requires ZooKeeper 3.5.0+
+	GetConfig() GetConfigBuilder
+
+	// Start a reconfig builder, which changes the servers of the ensemble with ZooKeeper 3.5.0+
+	Reconfig() ReconfigBuilder
+
+	// Start a watches builder, which manages the persistent watches
+	Watches() WatchesBuilder
+
 	// Start a transaction builder
 	InTransaction() Transaction
 
+	// Allocate an operation that can be used with Transaction().
+	TransactionOp() TransactionOp
+
+	// Start a transaction builder that commits the given operations
+	Transaction() CuratorMultiTransaction
+
 	// Perform a sync on the given path - syncs are always in the background
 	DoSync(path string, backgroundContextObject interface{})
 
@@ -65,19 +84,51 @@ type CuratorFramework interface {
 	// Returns the listenable interface for the Connect State
 	ConnectionStateListenable() ConnectionStateListenable
 
+	// Returns the policy deciding which connection states are errors for the recipes
+	ConnectionStateErrorPolicy() ConnectionStateErrorPolicy
+
+	// Returns the schemas enforced for the nodes
+	SchemaSet() *SchemaSet
+
+	// Returns the features supported by the connected servers
+	Compatibility() *Compatibility
+
+	// Returns the stats of the connection and the session, which are reported through the TracerDriver too
+	Stats() *ConnectionStats
+
 	// Returns the listenable interface for events
 	CuratorListenable() CuratorListenable
 
 	// Returns the listenable interface for unhandled errors
 	UnhandledErrorListenable() UnhandledErrorListenable
 
+	// Returns the listenable interface for the retries of the operations
+	RetryListenable() RetryListenable
+
+	// Returns the registry of the resources closed with the client, in the reverse order of their dependencies
+	Closeables() CloseableRegistry
+
 	// Returns a facade of the current instance that does _not_ automatically pre-pend the namespace to all paths
 	NonNamespaceView() CuratorFramework
 
+	// Returns a facade of the current instance whose operations stop retrying once the context is done,
+	// their errors are the error of the context then.
+	// The operation sent to the server isn't aborted, since ZooKeeper can't cancel a submitted operation.
+	WithContext(ctx context.Context) CuratorFramework
+
 	// Returns a facade of the current instance that uses the specified namespace
 	// or no namespace if newNamespace is empty.
 	UsingNamespace(newNamespace string) CuratorFramework
 
+	// Returns a facade sharing the session of the current instance, with its own namespace and ACL provider,
+	// or the ACL provider of the instance if aclProvider is nil.
+	// The session is closed once the instance and all the shared facades are closed.
+	Share(namespace string, aclProvider ACLProvider) CuratorFramework
+
+	// Returns a facade of the current instance that records the watchers set through it,
+	// so they can be removed at once with RemoveWatchers()
+	NewWatcherRemoveCuratorFramework() WatcherRemoveCuratorFramework
+
 	// Return the current namespace or "" if none
 	Namespace() string
 
@@ -92,6 +143,9 @@ type CuratorFramework interface {
 
 	// Block until a connection to ZooKeeper is available or the maxWaitTime has been exceeded
 	BlockUntilConnectedTimeout(maxWaitTime time.Duration) error
+
+	// Block until a connection to ZooKeeper is available or the context is done, return the error of the context if it is done
+	BlockUntilConnectedContext(ctx context.Context) error
 }
 
 // Create a new client with default session timeout and default connection timeout
@@ -111,18 +165,52 @@ func NewClientTimeout(connString string, sessionTimeout, connectionTimeout time.
 }
 
 type CuratorFrameworkBuilder struct {
-	AuthInfos           []AuthInfo          // the connection authorization
-	ZookeeperDialer     ZookeeperDialer     // the zookeeper dialer to use
-	EnsembleProvider    EnsembleProvider    // the list ensemble provider.
-	DefaultData         []byte              // the data to use when PathAndBytesable.ForPath(String) is used.
-	Namespace           string              // as ZooKeeper is a shared space, users of a given cluster should stay within a pre-defined namespace
-	SessionTimeout      time.Duration       // the session timeout
-	ConnectionTimeout   time.Duration       // the connection timeout
-	MaxCloseWait        time.Duration       // the time to wait during close to wait background tasks
-	RetryPolicy         RetryPolicy         // the retry policy to use
-	CompressionProvider CompressionProvider // the compression provider
-	AclProvider         ACLProvider         // the provider for ACLs
-	CanBeReadOnly       bool                // allow ZooKeeper client to enter read only mode in case of a network partition.
+	AuthInfos                         []AuthInfo                 // the connection authorizations, applied in order on every new connection, e.g. after the session expired
+	ZookeeperDialer                   ZookeeperDialer            // the zookeeper dialer to use
+	EnsembleProvider                  EnsembleProvider           // the list ensemble provider.
+	DefaultData                       []byte                     // the data to use when PathAndBytesable.ForPath(String) is used.
+	DefaultDataProvider               DefaultDataProvider        // the data to use per path when PathAndBytesable.ForPath(String) is used, DefaultData for all the paths by default
+	Namespace                         string                     // as ZooKeeper is a shared space, users of a given cluster should stay within a pre-defined namespace
+	SessionTimeout                    time.Duration              // the session timeout
+	ConnectionTimeout                 time.Duration              // the connection timeout
+	MaxCloseWait                      time.Duration              // the time to wait during close to wait background tasks
+	RetryPolicy                       RetryPolicy                // the retry policy to use
+	CompressionProvider               CompressionProvider        // the compression provider
+	CompressionPolicy                 CompressionPolicy          // decide which data is compressed by the provider, e.g. to leave the tiny payloads uncompressed, all the data by default
+	AclProvider                       ACLProvider                // the provider for ACLs
+	CanBeReadOnly                     bool                       // allow ZooKeeper client to enter read only mode in case of a network partition, the writes fail with ErrReadOnlyConnection meanwhile
+	SuperUserPassword                 string                     // the password to authenticate as the super user, for maintenance such as ACL repair
+	AllowSuperUser                    bool                       // explicitly opt in to authenticating as the super user
+	WatcherDispatchMode               DispatchMode               // the ordering of the events delivered to the watchers, in order per path by default
+	MaxPacketSize                     int                        // the max size of a request accepted by the servers, their jute.maxbuffer
+	EnableCompression                 bool                       // compress and de-compress the data of all the calls, unless they opt out
+	ListenAllEvents                   bool                       // deliver the events of the background calls with a callback to the CuratorListeners too
+	StateQueueSize                    int                        // the size of the queue of the connection state changes, the oldest changes are dropped when it is full
+	SimulatedSessionExpirationPercent int                        // expire the session of a SUSPENDED connection after the percent of the session timeout, 100 by default, disabled if negative
+	StateErrorPolicy                  ConnectionStateErrorPolicy // the connection states treated as errors by the recipes, the SUSPENDED and LOST states by default
+	ConnectionHandlingPolicy          ConnectionHandlingPolicy   // how the connection timeouts and the retries interact, the classic handling by default
+	ConnectionAcquisition             ConnectionAcquisition      // how the operations issued while not connected acquire the connection, e.g. ACQUIRE_FAIL_FAST, queued by default
+	ConnectionWaitTimeout             time.Duration              // the time an operation waits for the connection with ACQUIRE_WAIT before the retry policy handles its failure, the connection timeout by default
+	SchemaSet                         *SchemaSet                 // the schemas enforced for the nodes, allowing everything by default
+	EnableEnsembleTracker             bool                       // watch the ensemble config and update the connection string of an UpdatableEnsembleProvider
+	DontUseContainerParents           bool                       // create the parents as PERSISTENT nodes even when the parent containers are asked for
+	Executor                          Executor                   // run the background operations and their callbacks, a bounded pool of go-routines by default
+	WaitForShutdownTimeout            time.Duration              // the time to wait during close for the background operations in flight, don't wait by default
+	ServerVersionDetector             ServerVersionDetector      // detect the version of the servers on connect to fail fast the features they don't support, e.g. NewFLWServerVersionDetector, no detection by default
+	ServerSelector                    ServerSelector             // the order of the servers on each connection of the default dialer, e.g. NewZoneServerSelector, shuffled by default
+	HostResolution                    HostResolution             // how the default dialer resolves the hostnames of the servers, e.g. RESOLVE_EACH_PASS to follow the replaced servers, once by default
+	TLSConfig                         *tls.Config                // connect the default dialer to the secure client port of the servers, e.g. 2281, plaintext by default
+	SASLMechanism                     SASLMechanism              // authenticate the connections of the default dialer with SASL, e.g. NewDigestSASLMechanism or a Kerberos GSSAPI client
+	RequestTimeout                    time.Duration              // abandon a request of the default dialer without a response in time, and retry it on another server, disabled by default
+	ReadRateLimiter                   RateLimiter                // limit the rate of the read operations, e.g. NewTokenBucketRateLimiter, not limited by default
+	WriteRateLimiter                  RateLimiter                // limit the rate of the write operations, e.g. NewTokenBucketRateLimiter, not limited by default
+	TracerDriver                      TracerDriver               // record the times and the counters of the client, e.g. metrics.NewPrometheusTracerDriver or metrics.NewStatsdTracerDriver, only kept in memory by default
+	OperationTracer                   OperationTracer            // trace the operations and their retries, e.g. with tracing.NewOpenTelemetryTracer, not traced by default
+	CircuitBreakingRetryPolicy        RetryPolicy                // wrap the connection state listeners with the circuit breakers kept open while the policy retries, e.g. NewRetryNTimes(10, time.Second), disabled by default
+	ExpvarName                        string                     // publish the stats of the client via expvar under the name, e.g. "curator" on /debug/vars, not published by default
+	SlowOperationThreshold            time.Duration              // report the operations taking longer, including their retries, to catch the latency regressions of the ensemble, disabled by default
+	SlowOperationListener             SlowOperationListener      // receive the operations exceeding the slow operation threshold, which are logged by default
+	TracedPathPrefixes                []string                   // break down the traces of the operations per path prefix, including the namespace, e.g. "/locks" to find the recipe hammering the ensemble
 }
 
 // Apply the current values and build a new CuratorFramework
@@ -139,22 +227,69 @@ func (b *CuratorFrameworkBuilder) Build() CuratorFramework {
 	if builder.ConnectionTimeout == 0 {
 		builder.ConnectionTimeout = DEFAULT_CONNECTION_TIMEOUT
 	}
+	if builder.ConnectionWaitTimeout == 0 {
+		builder.ConnectionWaitTimeout = builder.ConnectionTimeout
+	}
 	if builder.MaxCloseWait == 0 {
 		builder.MaxCloseWait = DEFAULT_CLOSE_WAIT
 	}
+	if builder.MaxPacketSize == 0 {
+		builder.MaxPacketSize = DEFAULT_MAX_PACKET_SIZE
+	}
+	if builder.StateQueueSize == 0 {
+		builder.StateQueueSize = STATE_QUEUE_SIZE
+	}
+	if builder.SimulatedSessionExpirationPercent == 0 {
+		builder.SimulatedSessionExpirationPercent = SIMULATED_SESSION_EXPIRATION_PERCENT
+	}
+	if builder.StateErrorPolicy == nil {
+		builder.StateErrorPolicy = StandardConnectionStateErrorPolicy{}
+	}
+	if builder.ConnectionHandlingPolicy == nil {
+		builder.ConnectionHandlingPolicy = ClassicConnectionHandlingPolicy{}
+	}
+	if builder.DefaultDataProvider == nil {
+		builder.DefaultDataProvider = NewStaticDataProvider(builder.DefaultData)
+	}
+	if builder.Executor == nil {
+		builder.Executor = NewPoolExecutor(DEFAULT_EXECUTOR_SIZE)
+	}
+	if builder.SchemaSet == nil {
+		builder.SchemaSet = DefaultSchemaSet()
+	}
 	if builder.CompressionProvider == nil {
 		builder.CompressionProvider = NewGzipCompressionProvider()
 	}
+	if builder.CompressionPolicy != nil {
+		builder.CompressionProvider = NewPolicyCompressionProvider(builder.CompressionProvider, builder.CompressionPolicy)
+	}
 	if builder.AclProvider == nil {
 		builder.AclProvider = NewDefaultACLProvider()
 	}
+	if builder.ZookeeperDialer == nil && (builder.ServerSelector != nil || builder.HostResolution != RESOLVE_ONCE ||
+		builder.TLSConfig != nil || builder.SASLMechanism != nil || builder.RequestTimeout > 0) {
+		builder.ZookeeperDialer = &DefaultZookeeperDialer{
+			ServerSelector: builder.ServerSelector,
+			HostResolution: builder.HostResolution,
+			TLSConfig:      builder.TLSConfig,
+			SASLMechanism:  builder.SASLMechanism,
+			RequestTimeout: builder.RequestTimeout,
+		}
+	}
+	if len(builder.SuperUserPassword) > 0 {
+		if !builder.AllowSuperUser {
+			panic("super user authorization requires AllowSuperUser")
+		}
+
+		builder.AuthInfos = append(append([]AuthInfo(nil), builder.AuthInfos...), NewDigestAuthInfo(SUPER_USER, builder.SuperUserPassword))
+	}
 
 	return newCuratorFramework(&builder)
 }
 
 // Set the list of servers to connect to.
 func (b *CuratorFrameworkBuilder) ConnectString(connectString string) *CuratorFrameworkBuilder {
-	b.EnsembleProvider = &FixedEnsembleProvider{connectString}
+	b.EnsembleProvider = NewFixedEnsembleProvider(connectString)
 
 	return b
 }
@@ -166,6 +301,28 @@ func (b *CuratorFrameworkBuilder) Authorization(scheme string, auth []byte) *Cur
 	return b
 }
 
+// Add the digest authorization of the given user and password, matching the ACL of NewDigestACL
+func (b *CuratorFrameworkBuilder) DigestAuthorization(user, password string) *CuratorFrameworkBuilder {
+	b.AuthInfos = append(b.AuthInfos, NewDigestAuthInfo(user, password))
+
+	return b
+}
+
+// Add a list of connection authorizations
+func (b *CuratorFrameworkBuilder) Authorizations(authInfos ...AuthInfo) *CuratorFrameworkBuilder {
+	b.AuthInfos = append(b.AuthInfos, authInfos...)
+
+	return b
+}
+
+// Authenticate as the super user with the given password, AllowSuperUser must also be set.
+// The super user bypasses all ACL checks, so it should only be used by maintenance clients.
+func (b *CuratorFrameworkBuilder) SuperUser(password string) *CuratorFrameworkBuilder {
+	b.SuperUserPassword = password
+
+	return b
+}
+
 // Add compression provider
 func (b *CuratorFrameworkBuilder) Compression(name string) *CuratorFrameworkBuilder {
 	if provider, exists := CompressionProviders[name]; exists {
@@ -178,10 +335,10 @@ func (b *CuratorFrameworkBuilder) Compression(name string) *CuratorFrameworkBuil
 type curatorFramework struct {
 	client                  *curatorZookeeperClient
 	stateManager            *connectionStateManager
-	state                   State
+	state                   *State // shared with the namespace facades
 	listeners               CuratorListenable
 	unhandledErrorListeners UnhandledErrorListenable
-	defaultData             []byte
+	defaultData             DefaultDataProvider
 	namespace               *namespaceImpl
 	namespaceFacadeCache    *namespaceFacadeCache
 	fixForNamespace         func(path string, isSequential bool) string
@@ -189,18 +346,57 @@ type curatorFramework struct {
 	retryPolicy             RetryPolicy
 	compressionProvider     CompressionProvider
 	aclProvider             ACLProvider
+	dispatcher              *eventDispatcher
+	maxPacketSize           int
+	closeables              *closeableRegistry
+	compressionEnabled      bool
+	persistentWatches       *persistentWatches
+	listenAllEvents         bool
+	stateErrorPolicy        ConnectionStateErrorPolicy
+	schemaSet               *SchemaSet
+	ctx                     context.Context        // the context of the operations, set by the context facades
+	watcherRemoval          *watcherRemovalManager // the watchers set through the facade, set by the watcher removal facades
+	ensembleTracker         *ensembleTracker
+	useContainerParents     bool
+	executor                Executor
+	backgroundTasks         *taskTracker
+	waitForShutdownTimeout  time.Duration
+	compatibility           *Compatibility
+	serverVersionDetector   ServerVersionDetector
+	root                    *curatorFramework // the built instance, which the facades are copied from
+	references              *int32            // the instance and its shared facades not closed yet
+	released                AtomicBool
 }
 
 func newCuratorFramework(b *CuratorFrameworkBuilder) *curatorFramework {
 	c := &curatorFramework{
+		state:                   new(State),
 		listeners:               &curatorListenerContainer{},
 		unhandledErrorListeners: &unhandledErrorListenerContainer{},
-		defaultData:             b.DefaultData,
+		defaultData:             b.DefaultDataProvider,
 		retryPolicy:             b.RetryPolicy,
 		compressionProvider:     b.CompressionProvider,
 		aclProvider:             b.AclProvider,
+		dispatcher:              newEventDispatcher(b.WatcherDispatchMode),
+		maxPacketSize:           b.MaxPacketSize,
+		closeables:              &closeableRegistry{},
+		compressionEnabled:      b.EnableCompression,
+		persistentWatches:       &persistentWatches{},
+		listenAllEvents:         b.ListenAllEvents,
+		stateErrorPolicy:        b.StateErrorPolicy,
+		schemaSet:               b.SchemaSet,
+		useContainerParents:     !b.DontUseContainerParents,
+		executor:                b.Executor,
+		backgroundTasks:         &taskTracker{},
+		waitForShutdownTimeout:  b.WaitForShutdownTimeout,
+		compatibility:           &Compatibility{},
+		serverVersionDetector:   b.ServerVersionDetector,
+		references:              new(int32),
 	}
 
+	*c.references = 1
+	c.root = c
+
 	watcher := NewWatcher(func(event *zk.Event) {
 		c.processEvent(&curatorEvent{
 			eventType:    WATCHED,
@@ -208,10 +404,70 @@ func newCuratorFramework(b *CuratorFrameworkBuilder) *curatorFramework {
 			path:         c.unfixForNamespace(event.Path),
 			watchedEvent: event,
 		})
+
+		c.firePersistentWatches(event)
 	})
 
 	c.client = NewCuratorZookeeperClient(b.ZookeeperDialer, b.EnsembleProvider, b.SessionTimeout, b.ConnectionTimeout, watcher, b.RetryPolicy, b.CanBeReadOnly, b.AuthInfos)
+	c.client.state.handlingPolicy = b.ConnectionHandlingPolicy
+	c.client.state.acquisition = b.ConnectionAcquisition
+	c.client.state.waitTimeout = b.ConnectionWaitTimeout
+	c.client.unhandledError = c.logError
+	c.client.readRateLimiter = b.ReadRateLimiter
+	c.client.writeRateLimiter = b.WriteRateLimiter
+	c.client.operationTracer = b.OperationTracer
+	c.client.slowOperationThreshold = b.SlowOperationThreshold
+	c.client.slowOperationListener = b.SlowOperationListener
+	c.client.tracedPathPrefixes = b.TracedPathPrefixes
+
+	if b.TracerDriver != nil {
+		c.client.TracerDriver = b.TracerDriver
+		c.client.state.tracer = b.TracerDriver
+	}
+
+	c.dispatcher.unhandledError = c.logError
 	c.stateManager = newConnectionStateManager(c)
+	c.stateManager.QueueSize = b.StateQueueSize
+	c.stateManager.SessionTimeout = b.SessionTimeout
+	c.stateManager.SimulatedSessionExpirationPercent = b.SimulatedSessionExpirationPercent
+	c.stateManager.injectSessionExpiration = c.client.state.injectSessionExpiration
+
+	if b.CircuitBreakingRetryPolicy != nil {
+		c.stateManager.listeners = newCircuitBreakingListenerContainer(b.CircuitBreakingRetryPolicy, c.logError)
+	}
+
+	if b.ExpvarName != "" {
+		driver := newExpvarTracerDriver(c.client.TracerDriver, c.stateManager.currentState)
+
+		if err := publishExpvar(b.ExpvarName, driver); err != nil {
+			c.logError(err)
+		} else {
+			c.client.TracerDriver = driver
+			c.client.state.tracer = driver
+		}
+	}
+
+	c.stateManager.tracer = c.client.TracerDriver
+
+	c.stateManager.Listenable().AddListener(NewConnectionStateListener(func(client CuratorFramework, newState ConnectionState) {
+		if newState == RECONNECTED {
+			c.restorePersistentWatches()
+		}
+
+		if (newState == CONNECTED || newState == RECONNECTED) && c.serverVersionDetector != nil {
+			c.runSafe(c.detectServerVersion)
+		}
+	}))
+
+	if listener, ok := b.EnsembleProvider.(ConnectionStateListener); ok {
+		c.stateManager.Listenable().AddListener(listener) // e.g. the failover provider
+	}
+
+	if provider, ok := b.EnsembleProvider.(UpdatableEnsembleProvider); ok && b.EnableEnsembleTracker {
+		c.ensembleTracker = newEnsembleTracker(c, provider)
+		c.stateManager.Listenable().AddListener(c.ensembleTracker)
+	}
+
 	c.namespace = newNamespace(c, b.Namespace)
 	c.namespaceFacadeCache = newNamespaceFacadeCache(c)
 	c.fixForNamespace = c.namespace.fixForNamespace
@@ -233,6 +489,21 @@ func (c *curatorFramework) Start() error {
 }
 
 func (c *curatorFramework) Close() error {
+	if c.State() != STARTED || !c.released.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	return c.release()
+}
+
+// Release a reference to the session, and close it once the instance and all the shared facades are closed
+func (c *curatorFramework) release() error {
+	if atomic.AddInt32(c.references, -1) > 0 {
+		return nil
+	}
+
+	c.closeables.closeAll(c.logError) // while the client can still be used by the resources
+
 	if !c.state.Change(STARTED, STOPPED) {
 		return nil
 	}
@@ -243,6 +514,15 @@ func (c *curatorFramework) Close() error {
 		listener.(CuratorListener).EventReceived(c, evt)
 	})
 
+	if c.ensembleTracker != nil {
+		c.ensembleTracker.close()
+	}
+
+	// the background operations in flight may still deliver their events to the listeners
+	if c.waitForShutdownTimeout > 0 && !c.backgroundTasks.wait(c.waitForShutdownTimeout) {
+		log.Printf("Timed out after %v waiting for the background operations to finish", c.waitForShutdownTimeout)
+	}
+
 	c.listeners.Clear()
 	c.unhandledErrorListeners.Clear()
 	c.stateManager.Close()
@@ -261,7 +541,7 @@ func (c *curatorFramework) Started() bool {
 func (c *curatorFramework) Create() CreateBuilder {
 	c.state.Check(STARTED, "instance must be started before calling this method")
 
-	return &createBuilder{client: c, acling: acling{aclProvider: c.aclProvider}}
+	return &createBuilder{client: c, acling: acling{aclProvider: c.aclProvider}, compress: c.compressionEnabled}
 }
 
 func (c *curatorFramework) Delete() DeleteBuilder {
@@ -279,13 +559,13 @@ func (c *curatorFramework) CheckExists() CheckExistsBuilder {
 func (c *curatorFramework) GetData() GetDataBuilder {
 	c.state.Check(STARTED, "instance must be started before calling this method")
 
-	return &getDataBuilder{client: c}
+	return &getDataBuilder{client: c, decompress: c.compressionEnabled}
 }
 
 func (c *curatorFramework) SetData() SetDataBuilder {
 	c.state.Check(STARTED, "instance must be started before calling this method")
 
-	return &setDataBuilder{client: c, version: AnyVersion}
+	return &setDataBuilder{client: c, version: AnyVersion, compress: c.compressionEnabled}
 }
 
 func (c *curatorFramework) GetChildren() GetChildrenBuilder {
@@ -306,12 +586,40 @@ func (c *curatorFramework) SetACL() SetACLBuilder {
 	return &setACLBuilder{client: c, version: AnyVersion, acling: acling{aclProvider: c.aclProvider}}
 }
 
+func (c *curatorFramework) GetConfig() GetConfigBuilder {
+	c.state.Check(STARTED, "instance must be started before calling this method")
+
+	return &getConfigBuilder{client: c}
+}
+
+func (c *curatorFramework) Reconfig() ReconfigBuilder {
+	c.state.Check(STARTED, "instance must be started before calling this method")
+
+	return &reconfigBuilder{client: c, fromConfig: -1}
+}
+
+func (c *curatorFramework) Watches() WatchesBuilder {
+	c.state.Check(STARTED, "instance must be started before calling this method")
+
+	return &watchesBuilder{client: c}
+}
+
 func (c *curatorFramework) InTransaction() Transaction {
 	c.state.Check(STARTED, "instance must be started before calling this method")
 
 	return &curatorTransaction{client: c}
 }
 
+func (c *curatorFramework) TransactionOp() TransactionOp {
+	return &transactionOp{client: c}
+}
+
+func (c *curatorFramework) Transaction() CuratorMultiTransaction {
+	c.state.Check(STARTED, "instance must be started before calling this method")
+
+	return &curatorMultiTransaction{client: c}
+}
+
 func (c *curatorFramework) DoSync(path string, context interface{}) {
 	c.Sync().InBackgroundWithContext(context).ForPath(path)
 }
@@ -334,6 +642,10 @@ func (c *curatorFramework) UnhandledErrorListenable() UnhandledErrorListenable {
 	return c.unhandledErrorListeners
 }
 
+func (c *curatorFramework) RetryListenable() RetryListenable {
+	return c.client.RetryListenable()
+}
+
 func (c *curatorFramework) processEvent(event CuratorEvent) {
 	if event.Type() == WATCHED {
 		c.validateConnection(event.WatchedEvent().State)
@@ -343,6 +655,7 @@ func (c *curatorFramework) processEvent(event CuratorEvent) {
 		tracer := c.client.StartTracer("EventListener")
 
 		defer tracer.Commit()
+		defer recoverPanic("Event listener", c.logError)
 
 		if err := l.(CuratorListener).EventReceived(c, event); err != nil {
 			c.logError(fmt.Errorf("Event listener threw exception, %s", err))
@@ -350,6 +663,28 @@ func (c *curatorFramework) processEvent(event CuratorEvent) {
 	})
 }
 
+// Deliver the event of a background operation to its callback, or to the CuratorListeners if no callback is given.
+// With ListenAllEvents, the CuratorListeners also receive the events delivered to the callbacks, e.g. for auditing.
+func (c *curatorFramework) processBackgroundEvent(backgrounding backgrounding, event CuratorEvent) {
+	if backgrounding.callback == nil {
+		c.processEvent(event)
+
+		return
+	}
+
+	func() {
+		defer recoverPanic("Background operation callback", c.logError)
+
+		if err := backgrounding.callback(c, event); err != nil {
+			c.logError(fmt.Errorf("Background operation callback threw exception, %s", err))
+		}
+	}()
+
+	if c.listenAllEvents {
+		c.processEvent(event)
+	}
+}
+
 func (c *curatorFramework) validateConnection(state zk.State) {
 	switch state {
 	case zk.StateDisconnected:
@@ -358,8 +693,8 @@ func (c *curatorFramework) validateConnection(state zk.State) {
 	case zk.StateExpired:
 		c.stateManager.AddStateChange(LOST)
 
-	case zk.StateSyncConnected:
-		c.stateManager.AddStateChange(RECONNECTED)
+	case zk.StateSyncConnected, zk.StateHasSession:
+		c.stateManager.AddStateChange(RECONNECTED) // e.g. after the read-only session
 
 	case zk.StateConnectedReadOnly:
 		c.stateManager.AddStateChange(READ_ONLY)
@@ -371,7 +706,9 @@ func (c *curatorFramework) suspendConnection() {
 		return
 	}
 
-	go c.doSyncForSuspendedConnection(c.client.InstanceIndex())
+	instanceIndex := c.client.InstanceIndex()
+
+	c.runSafe(func() { c.doSyncForSuspendedConnection(instanceIndex) })
 }
 
 func (c *curatorFramework) doSyncForSuspendedConnection(instanceIndex int64) {
@@ -394,10 +731,22 @@ func (c *curatorFramework) doSyncForSuspendedConnection(instanceIndex int64) {
 	if instanceIndex < 0 || instanceIndex == c.client.InstanceIndex() {
 		c.stateManager.AddStateChange(LOST)
 	} else {
-		go c.doSyncForSuspendedConnection(-1)
+		c.runSafe(func() { c.doSyncForSuspendedConnection(-1) })
 	}
 }
 
+// Run the task with the executor, a panic of the task is reported to the unhandled error listeners
+func (c *curatorFramework) runSafe(task func()) {
+	c.backgroundTasks.add()
+
+	c.executor.Execute(func() {
+		defer c.backgroundTasks.done()
+		defer recoverPanic("Background task", c.logError)
+
+		task()
+	})
+}
+
 func (c *curatorFramework) logError(err error) {
 	log.Printf("error: %s", err)
 
@@ -406,6 +755,39 @@ func (c *curatorFramework) logError(err error) {
 	})
 }
 
+func (c *curatorFramework) ConnectionStateErrorPolicy() ConnectionStateErrorPolicy {
+	return c.stateErrorPolicy
+}
+
+func (c *curatorFramework) checkWritable() error {
+	if c.client.ReadOnly() {
+		return ErrReadOnlyConnection
+	}
+
+	return nil
+}
+
+func (c *curatorFramework) SchemaSet() *SchemaSet {
+	return c.schemaSet
+}
+
+func (c *curatorFramework) Compatibility() *Compatibility {
+	return c.compatibility
+}
+
+// Detect the version of the servers of the current connection string, the ensemble may have been upgraded
+func (c *curatorFramework) detectServerVersion() {
+	if version, err := c.serverVersionDetector.DetectServerVersion(c.client.CurrentConnectionString()); err != nil {
+		c.logError(fmt.Errorf("fail to detect the server version, %s", err))
+	} else {
+		c.compatibility.setServerVersion(version)
+	}
+}
+
+func (c *curatorFramework) Closeables() CloseableRegistry {
+	return c.closeables
+}
+
 func (c *curatorFramework) NonNamespaceView() CuratorFramework {
 	return c.UsingNamespace("")
 }
@@ -421,10 +803,22 @@ func (c *curatorFramework) Namespace() string {
 }
 
 func (c *curatorFramework) getNamespaceWatcher(watcher Watcher) Watcher {
+	if c.watcherRemoval != nil {
+		return c.watcherRemoval.wrap(watcher)
+	}
+
 	return watcher
 }
 
+func (c *curatorFramework) newNamespaceWatcher(fn func(event zk.Event)) Watcher {
+	return c.getNamespaceWatcher(&namespaceWatcher{fn, c.unfixForNamespace})
+}
+
 func (c *curatorFramework) ZookeeperClient() CuratorZookeeperClient {
+	if c.ctx != nil {
+		return &contextZookeeperClient{c.client, c.ctx}
+	}
+
 	return c.client
 }
 
@@ -439,3 +833,7 @@ func (c *curatorFramework) BlockUntilConnected() error {
 func (c *curatorFramework) BlockUntilConnectedTimeout(maxWaitTime time.Duration) error {
 	return c.stateManager.BlockUntilConnected(maxWaitTime)
 }
+
+func (c *curatorFramework) BlockUntilConnectedContext(ctx context.Context) error {
+	return c.stateManager.BlockUntilConnectedContext(ctx)
+}
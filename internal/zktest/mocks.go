@@ -0,0 +1,307 @@
+// Package zktest provides the mocked ZooKeeper connection and dialer shared by the tests of the subpackages.
+package zktest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/mock"
+)
+
+type LogFunc func(format string, args ...interface{})
+
+type MockZookeeperConnection struct {
+	mock.Mock
+
+	Log        LogFunc
+	Operations []interface{} // the operations of the committed transactions
+}
+
+func (c *MockZookeeperConnection) AddAuth(scheme string, auth []byte) error {
+	args := c.Called(scheme, auth)
+	err := args.Error(0)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.AddAuth(scheme=\"%s\", auth=[]byte(\"%s\")) error=%v", scheme, auth, err)
+	}
+
+	return err
+}
+
+func (c *MockZookeeperConnection) Close() {
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Close()")
+	}
+
+	c.Called()
+}
+
+func (c *MockZookeeperConnection) Create(path string, data []byte, flags int32, acls []zk.ACL) (string, error) {
+	args := c.Called(path, data, flags, acls)
+
+	createPath := args.String(0)
+	err := args.Error(1)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Create(path=\"%s\", data=[]byte(\"%s\"), flags=%d, alcs=%v) (createdPath=\"%s\", error=%v)", path, data, flags, acls, createPath, err)
+	}
+
+	return createPath, err
+}
+
+func (c *MockZookeeperConnection) Exists(path string) (bool, *zk.Stat, error) {
+	args := c.Called(path)
+
+	exists := args.Bool(0)
+	stat, _ := args.Get(1).(*zk.Stat)
+	err := args.Error(2)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Exists(path=\"%s\")(exists=%v, stat=%v, error=%v)", path, exists, stat, err)
+	}
+
+	return exists, stat, err
+}
+
+func (c *MockZookeeperConnection) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	args := c.Called(path)
+
+	exists := args.Bool(0)
+	stat, _ := args.Get(1).(*zk.Stat)
+	events, _ := args.Get(2).(chan zk.Event)
+	err := args.Error(3)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.ExistsW(path=\"%s\")(exists=%v, stat=%v, events=%v, error=%v)", path, exists, stat, events, err)
+	}
+
+	return exists, stat, events, err
+}
+
+func (c *MockZookeeperConnection) Delete(path string, version int32) error {
+	args := c.Called(path, version)
+
+	err := args.Error(0)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Delete(path=\"%s\", version=%d) error=%v", path, version, err)
+	}
+
+	return err
+}
+
+func (c *MockZookeeperConnection) Get(path string) ([]byte, *zk.Stat, error) {
+	args := c.Called(path)
+
+	data, _ := args.Get(0).([]byte)
+	stat, _ := args.Get(1).(*zk.Stat)
+	err := args.Error(2)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Get(path=\"%s\")(data=%v, stat=%v, error=%v)", path, data, stat, err)
+	}
+
+	return data, stat, err
+}
+
+func (c *MockZookeeperConnection) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	args := c.Called(path)
+
+	data, _ := args.Get(0).([]byte)
+	stat, _ := args.Get(1).(*zk.Stat)
+	events, _ := args.Get(2).(chan zk.Event)
+	err := args.Error(3)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.GetW(path=\"%s\")(data=%v, stat=%v, events=%v, error=%v)", path, data, stat, events, err)
+	}
+
+	return data, stat, events, err
+}
+
+func (c *MockZookeeperConnection) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	args := c.Called(path, data, version)
+
+	stat, _ := args.Get(0).(*zk.Stat)
+	err := args.Error(1)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Set(path=\"%s\", data=%v, version=%d) (stat=%v, error=%v)", path, data, version, stat, err)
+	}
+
+	return stat, err
+}
+
+func (c *MockZookeeperConnection) Children(path string) ([]string, *zk.Stat, error) {
+	args := c.Called(path)
+
+	children, _ := args.Get(0).([]string)
+	stat, _ := args.Get(1).(*zk.Stat)
+	err := args.Error(2)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Children(path=\"%s\")(children=%v, stat=%v, error=%v)", path, children, stat, err)
+	}
+
+	return children, stat, err
+}
+
+func (c *MockZookeeperConnection) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	args := c.Called(path)
+
+	children, _ := args.Get(0).([]string)
+	stat, _ := args.Get(1).(*zk.Stat)
+	events, _ := args.Get(2).(chan zk.Event)
+	err := args.Error(3)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.ChildrenW(path=\"%s\")(children=%v, stat=%v, events=%v, error=%v)", path, children, stat, events, err)
+	}
+
+	return children, stat, events, err
+}
+
+func (c *MockZookeeperConnection) GetACL(path string) ([]zk.ACL, *zk.Stat, error) {
+	args := c.Called(path)
+
+	acls, _ := args.Get(0).([]zk.ACL)
+	stat, _ := args.Get(1).(*zk.Stat)
+	err := args.Error(2)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.GetACL(path=\"%s\")(acls=%v, stat=%v, error=%v)", path, acls, stat, err)
+	}
+
+	return acls, stat, err
+}
+
+func (c *MockZookeeperConnection) SetACL(path string, acls []zk.ACL, version int32) (*zk.Stat, error) {
+	args := c.Called(path, acls, version)
+
+	stat, _ := args.Get(0).(*zk.Stat)
+	err := args.Error(1)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.SetACL(path=\"%s\", acls=%v, version=%d) (stat=%v, error=%v)", path, acls, version, stat, err)
+	}
+
+	return stat, err
+}
+
+func (c *MockZookeeperConnection) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	c.Operations = append(c.Operations, ops...)
+
+	args := c.Called(ops)
+
+	res, _ := args.Get(0).([]zk.MultiResponse)
+	err := args.Error(1)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Multi(ops=%v)(responses=%v, error=%v)", ops, res, err)
+	}
+
+	return res, err
+}
+
+func (c *MockZookeeperConnection) Sync(path string) (string, error) {
+	args := c.Called(path)
+	p := args.String(0)
+	err := args.Error(1)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.Sync(path=\"%s\")(path=\"%s\", error=%v)", path, p, err)
+	}
+
+	return path, err
+}
+
+func (c *MockZookeeperConnection) AddPersistentWatch(path string, recursive bool) error {
+	args := c.Called(path, recursive)
+
+	err := args.Error(0)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.AddPersistentWatch(path=\"%s\", recursive=%v) error=%v", path, recursive, err)
+	}
+
+	return err
+}
+
+func (c *MockZookeeperConnection) RemovePersistentWatch(path string, recursive bool) error {
+	args := c.Called(path, recursive)
+
+	err := args.Error(0)
+
+	if c.Log != nil {
+		c.Log("ZookeeperConnection.RemovePersistentWatch(path=\"%s\", recursive=%v) error=%v", path, recursive, err)
+	}
+
+	return err
+}
+
+type MockZookeeperDialer struct {
+	mock.Mock
+
+	Log LogFunc
+}
+
+func (d *MockZookeeperDialer) Dial(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (curator.ZookeeperConnection, <-chan zk.Event, error) {
+	args := d.Called(connString, sessionTimeout, canBeReadOnly)
+
+	conn, _ := args.Get(0).(curator.ZookeeperConnection)
+	events, _ := args.Get(1).(chan zk.Event)
+	err := args.Error(2)
+
+	if d.Log != nil {
+		d.Log("ZookeeperDialer.Dial(connectString=\"%s\", sessionTimeout=%v, canBeReadOnly=%v)(conn=%p, events=%v, error=%v)", connString, sessionTimeout, canBeReadOnly, conn, events, err)
+	}
+
+	return conn, events, err
+}
+
+// Builds a CuratorFramework connected through the mocked dialer
+type MockBuilder struct {
+	Conn    *MockZookeeperConnection
+	Events  chan zk.Event
+	Dialer  *MockZookeeperDialer
+	Builder *curator.CuratorFrameworkBuilder
+}
+
+func NewMockBuilder(t *testing.T) *MockBuilder {
+	conn := &MockZookeeperConnection{Log: t.Logf}
+
+	dialer := &MockZookeeperDialer{Log: t.Logf}
+	builder := &curator.CuratorFrameworkBuilder{ZookeeperDialer: dialer}
+
+	return &MockBuilder{
+		Conn:    conn,
+		Events:  make(chan zk.Event),
+		Dialer:  dialer,
+		Builder: builder,
+	}
+}
+
+func (b *MockBuilder) Build() curator.CuratorFramework {
+	b.Builder.ConnectString("connStr")
+
+	b.Dialer.On("Dial", "connStr", curator.DEFAULT_SESSION_TIMEOUT, b.Builder.CanBeReadOnly).Return(b.Conn, b.Events, nil).Once()
+
+	return b.Builder.Build()
+}
+
+func (b *MockBuilder) Check(t *testing.T) {
+	b.Conn.AssertExpectations(t)
+	b.Dialer.AssertExpectations(t)
+}
+
+// Match the protected path of the node in the parent path
+func ProtectedPath(parent, node string) interface{} {
+	prefix := curator.JoinPath(parent, curator.PROTECTED_PREFIX)
+
+	return mock.MatchedBy(func(path string) bool {
+		return strings.HasPrefix(path, prefix) && strings.HasSuffix(path, "-"+node)
+	})
+}
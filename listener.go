@@ -2,6 +2,7 @@ package curator
 
 import (
 	"sync"
+	"time"
 )
 
 type ConnectionStateListener interface {
@@ -20,6 +21,20 @@ type UnhandledErrorListener interface {
 	UnhandledError(err error)
 }
 
+// The retry of an operation which failed with a retryable error
+type RetryEvent struct {
+	Operation string        // the retried operation, e.g. CREATE, or empty if it isn't known
+	Attempt   int           // the number of the retry, starting from 1
+	Err       error         // the error of the failed attempt
+	SleepTime time.Duration // the time to sleep before the retry
+}
+
+// Receives the retries of the operations, e.g. to alert on a sustained retry behavior
+type RetryListener interface {
+	// Called before sleeping between the attempts of an operation
+	RetryAttempted(event *RetryEvent)
+}
+
 type connectionStateListenerCallback func(client CuratorFramework, newState ConnectionState)
 
 type connectionStateListenerStub struct {
@@ -62,6 +77,25 @@ func (l *unhandledErrorListenerStub) UnhandledError(err error) {
 	l.callback(err)
 }
 
+type retryListenerCallback func(event *RetryEvent)
+
+type retryListenerStub struct {
+	callback retryListenerCallback
+}
+
+func NewRetryListener(callback retryListenerCallback) RetryListener {
+	return &retryListenerStub{callback}
+}
+
+// Send the retries to the channel, the retry loops block until it receives them
+func NewRetryListenerChannel(events chan<- *RetryEvent) RetryListener {
+	return &retryListenerStub{func(event *RetryEvent) { events <- event }}
+}
+
+func (l *retryListenerStub) RetryAttempted(event *RetryEvent) {
+	l.callback(event)
+}
+
 // Abstracts a listenable object
 type Listenable /* [T] */ interface {
 	Len() int
@@ -95,6 +129,14 @@ type UnhandledErrorListenable interface {
 	RemoveListener(listener UnhandledErrorListener)
 }
 
+type RetryListenable interface {
+	Listenable /* [T] */
+
+	AddListener(listener RetryListener)
+
+	RemoveListener(listener RetryListener)
+}
+
 type ListenerContainer struct {
 	lock      sync.RWMutex
 	listeners []interface{}
@@ -196,3 +238,15 @@ func (c *unhandledErrorListenerContainer) AddListener(listener UnhandledErrorLis
 func (c *unhandledErrorListenerContainer) RemoveListener(listener UnhandledErrorListener) {
 	c.Remove(listener)
 }
+
+type retryListenerContainer struct {
+	ListenerContainer
+}
+
+func (c *retryListenerContainer) AddListener(listener RetryListener) {
+	c.Add(listener)
+}
+
+func (c *retryListenerContainer) RemoveListener(listener RetryListener) {
+	c.Remove(listener)
+}
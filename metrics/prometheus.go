@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The namespace of the metrics if none is given
+const DEFAULT_NAMESPACE = "curator"
+
+// A TracerDriver exporting the traced times as histograms and the counters as counters,
+// labeled with the name of the traced operation, e.g. "createBuilder.pathInBackground" or "retries-allowed".
+type PrometheusTracerDriver struct {
+	durations *prometheus.HistogramVec
+	counters  *prometheus.CounterVec
+}
+
+// Create a TracerDriver registering its metrics with the registerer, or the default registerer if it is nil.
+//
+// The metrics already registered by another driver of the same namespace are shared,
+// so the clients of a process may each have their own driver.
+func NewPrometheusTracerDriver(registerer prometheus.Registerer, namespace string) (*PrometheusTracerDriver, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	if namespace == "" {
+		namespace = DEFAULT_NAMESPACE
+	}
+
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operation_duration_seconds",
+		Help:      "The time of the traced operations of the ZooKeeper client.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	counters := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operation_count_total",
+		Help:      "The counters of the ZooKeeper client, e.g. the retries and the connection timeouts.",
+	}, []string{"operation"})
+
+	if err := registerer.Register(durations); err != nil {
+		if registered, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return nil, err
+		} else if durations, ok = registered.ExistingCollector.(*prometheus.HistogramVec); !ok {
+			return nil, err
+		}
+	}
+
+	if err := registerer.Register(counters); err != nil {
+		if registered, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return nil, err
+		} else if counters, ok = registered.ExistingCollector.(*prometheus.CounterVec); !ok {
+			return nil, err
+		}
+	}
+
+	return &PrometheusTracerDriver{durations, counters}, nil
+}
+
+func (d *PrometheusTracerDriver) AddTime(name string, duration time.Duration) {
+	d.durations.WithLabelValues(name).Observe(duration.Seconds())
+}
+
+func (d *PrometheusTracerDriver) AddCount(name string, increment int) {
+	if increment > 0 {
+		d.counters.WithLabelValues(name).Add(float64(increment)) // a counter only goes up
+	}
+}
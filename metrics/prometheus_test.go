@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusTracerDriver(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	driver, err := NewPrometheusTracerDriver(registry, "")
+
+	assert.NoError(t, err)
+
+	var _ curator.TracerDriver = driver
+
+	driver.AddTime("createBuilder.pathInBackground", 100*time.Millisecond)
+	driver.AddTime("createBuilder.pathInBackground", 300*time.Millisecond)
+	driver.AddCount("retries-allowed", 2)
+	driver.AddCount("retries-allowed", 1)
+
+	// the metrics are shared with another driver of the same namespace
+	other, err := NewPrometheusTracerDriver(registry, DEFAULT_NAMESPACE)
+
+	assert.NoError(t, err)
+
+	other.AddCount("retries-allowed", 1)
+
+	families, err := registry.Gather()
+
+	assert.NoError(t, err)
+	assert.Len(t, families, 2)
+
+	for _, family := range families {
+		assert.Len(t, family.Metric, 1)
+
+		metric := family.Metric[0]
+
+		assert.Equal(t, "operation", metric.Label[0].GetName())
+
+		switch family.GetName() {
+		case "curator_operation_duration_seconds":
+			assert.Equal(t, "createBuilder.pathInBackground", metric.Label[0].GetValue())
+			assert.Equal(t, uint64(2), metric.Histogram.GetSampleCount())
+			assert.InDelta(t, 0.4, metric.Histogram.GetSampleSum(), 0.0001)
+
+		case "curator_operation_count_total":
+			assert.Equal(t, "retries-allowed", metric.Label[0].GetValue())
+			assert.Equal(t, float64(4), metric.Counter.GetValue())
+
+		default:
+			t.Errorf("unexpected metric %s", family.GetName())
+		}
+	}
+}
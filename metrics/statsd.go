@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The format of the tags of the metrics, which isn't part of the original StatsD protocol
+type StatsdTagFormat int
+
+const (
+	DOGSTATSD_TAGS StatsdTagFormat = iota // e.g. curator.retries-allowed:1|c|#env:prod, understood by Datadog and Telegraf
+	INFLUX_TAGS                           // e.g. curator.retries-allowed,env=prod:1|c, understood by Telegraf
+)
+
+// A TracerDriver sending the traced times as timers and the counters as counters to a StatsD server over UDP,
+// the names of the traced operations are the names of the metrics, after the prefix.
+//
+// The metrics are sent one per datagram and the failed writes are dropped, so a client never waits for the server.
+type StatsdTracerDriver struct {
+	conn   net.Conn
+	prefix string
+	tags   string // the encoded tags appended to the metrics
+	format StatsdTagFormat
+}
+
+// Create a TracerDriver sending the metrics to the StatsD server at the address, e.g. "localhost:8125",
+// the names of the metrics are prefixed with the prefix and a dot unless it is empty, and tagged with the tags.
+func NewStatsdTracerDriver(address, prefix string, format StatsdTagFormat, tags map[string]string) (*StatsdTracerDriver, error) {
+	conn, err := net.Dial("udp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &StatsdTracerDriver{conn, prefix, encodeTags(format, tags), format}, nil
+}
+
+// Encode the tags sorted by their keys, so the metrics are stable
+func encodeTags(format StatsdTagFormat, tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+
+	for key := range tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	encoded := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if format == INFLUX_TAGS {
+			encoded = append(encoded, sanitize(key)+"="+sanitize(tags[key]))
+		} else if tags[key] == "" {
+			encoded = append(encoded, sanitize(key))
+		} else {
+			encoded = append(encoded, sanitize(key)+":"+sanitize(tags[key]))
+		}
+	}
+
+	if format == INFLUX_TAGS {
+		return "," + strings.Join(encoded, ",")
+	}
+
+	return "|#" + strings.Join(encoded, ",")
+}
+
+// Replace the characters delimiting the fields of the protocol
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '=', ' ', '\n':
+			return '_'
+		}
+
+		return r
+	}, s)
+}
+
+func (d *StatsdTracerDriver) AddTime(name string, duration time.Duration) {
+	d.send(name, strconv.FormatFloat(duration.Seconds()*1000, 'f', -1, 64), "ms")
+}
+
+func (d *StatsdTracerDriver) AddCount(name string, increment int) {
+	d.send(name, strconv.Itoa(increment), "c")
+}
+
+func (d *StatsdTracerDriver) send(name, value, metricType string) {
+	var metric string
+
+	if d.format == INFLUX_TAGS {
+		metric = d.prefix + sanitize(name) + d.tags + ":" + value + "|" + metricType
+	} else {
+		metric = d.prefix + sanitize(name) + ":" + value + "|" + metricType + d.tags
+	}
+
+	d.conn.Write([]byte(metric))
+}
+
+// Close the connection to the server
+func (d *StatsdTracerDriver) Close() error {
+	return d.conn.Close()
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsdTracerDriver(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	defer server.Close()
+
+	receive := func() string {
+		buf := make([]byte, 1024)
+
+		server.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := server.ReadFrom(buf)
+
+		assert.NoError(t, err)
+
+		return string(buf[:n])
+	}
+
+	driver, err := NewStatsdTracerDriver(server.LocalAddr().String(), "curator", DOGSTATSD_TAGS, map[string]string{"env": "prod", "app": "web"})
+
+	assert.NoError(t, err)
+
+	var _ curator.TracerDriver = driver
+
+	driver.AddTime("createBuilder.pathInBackground", 1500*time.Microsecond)
+
+	assert.Equal(t, "curator.createBuilder.pathInBackground:1.5|ms|#app:web,env:prod", receive())
+
+	driver.AddCount("retries-allowed", 2)
+
+	assert.Equal(t, "curator.retries-allowed:2|c|#app:web,env:prod", receive())
+	assert.NoError(t, driver.Close())
+
+	// the tags of Telegraf, without a prefix
+	driver, err = NewStatsdTracerDriver(server.LocalAddr().String(), "", INFLUX_TAGS, map[string]string{"env": "prod"})
+
+	assert.NoError(t, err)
+
+	driver.AddCount("session:expired", 1)
+
+	assert.Equal(t, "session_expired,env=prod:1|c", receive())
+	assert.NoError(t, driver.Close())
+
+	// without any tag
+	driver, err = NewStatsdTracerDriver(server.LocalAddr().String(), "zk.", DOGSTATSD_TAGS, nil)
+
+	assert.NoError(t, err)
+
+	driver.AddCount("retries-disallowed", 1)
+
+	assert.Equal(t, "zk.retries-disallowed:1|c", receive())
+	assert.NoError(t, driver.Close())
+}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/recipes"
+)
+
+var (
+	ErrLockTimeout = errors.New("timeout waiting for the migration lock")
+)
+
+// Manages migrations, ensuring each migration of a set is applied exactly once across the cluster.
+//
+//	manager, err := migrations.NewMigrationManager(client, "/migrations/lock", "/migrations/meta", time.Minute)
+//
+//	err = manager.Migrate(migrations.NewMigrationSet("people", migrations.NewMigration(func(transaction curator.Transaction) {
+//		transaction.Create().ForPath("/people")
+//	})))
+type MigrationManager struct {
+	client       curator.CuratorFramework
+	lock         recipes.InterProcessLock
+	metaDataPath string
+	lockMax      time.Duration
+}
+
+// Create a migration manager that serializes migrations with a lock at the lock path
+// and records the applied migrations under the meta data path
+func NewMigrationManager(client curator.CuratorFramework, lockPath, metaDataPath string, lockMax time.Duration) (*MigrationManager, error) {
+	if err := curator.ValidatePath(metaDataPath); err != nil {
+		return nil, err
+	} else if lock, err := recipes.NewInterProcessMutex(client, lockPath); err != nil {
+		return nil, err
+	} else {
+		return &MigrationManager{
+			client:       client,
+			lock:         lock,
+			metaDataPath: metaDataPath,
+			lockMax:      lockMax,
+		}, nil
+	}
+}
+
+// Process the given migration set, applying the migrations that have not been applied yet
+func (m *MigrationManager) Migrate(set *MigrationSet) error {
+	if acquired, err := m.lock.AcquireTimeout(m.lockMax); err != nil {
+		return err
+	} else if !acquired {
+		return ErrLockTimeout
+	}
+
+	defer m.lock.Release()
+
+	setPath := curator.JoinPath(m.metaDataPath, set.Id)
+
+	if err := m.client.NewNamespaceAwareEnsurePath(setPath).Ensure(m.client.ZookeeperClient()); err != nil {
+		return err
+	}
+
+	applied, err := m.client.GetChildren().ForPath(setPath)
+
+	if err != nil {
+		return err
+	}
+
+	if len(applied) > len(set.Migrations) {
+		return fmt.Errorf("migration set %s has %d applied migrations, but only %d defined", set.Id, len(applied), len(set.Migrations))
+	}
+
+	for i := len(applied); i < len(set.Migrations); i++ {
+		transaction := m.client.InTransaction()
+
+		set.Migrations[i].Operations(transaction)
+
+		if _, err := transaction.Create().ForPath(curator.JoinPath(setPath, migrationNodeName(i))).Commit(); err != nil {
+			return fmt.Errorf("fail to apply migration %d of set %s, %s", i, set.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func migrationNodeName(index int) string {
+	return fmt.Sprintf("%010d", index)
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMigrationManager(t *testing.T) {
+	Convey("Given a MigrationManager", t, func() {
+		mocks := zktest.NewMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("base on an invalid meta data path", func() {
+			manager, err := NewMigrationManager(client, "/migrations/lock", "meta", time.Second)
+
+			So(manager, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("base on validated paths", func() {
+			manager, err := NewMigrationManager(client, "/migrations/lock", "/migrations/meta", time.Second)
+
+			So(manager, ShouldNotBeNil)
+			So(err, ShouldBeNil)
+
+			var applied []int
+
+			set := NewMigrationSet("people",
+				NewMigration(func(transaction curator.Transaction) {
+					applied = append(applied, 0)
+
+					transaction.Create().ForPath("/people")
+				}),
+				NewMigration(func(transaction curator.Transaction) {
+					applied = append(applied, 1)
+
+					transaction.Create().ForPathWithData("/people/alice", []byte("alice"))
+				}))
+
+			mocks.Conn.On("Create", zktest.ProtectedPath("/migrations/lock", "lock-"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/migrations/lock/lock-0000000001", nil).Once()
+			mocks.Conn.On("Children", "/migrations/lock").Return([]string{"lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("Exists", "/migrations").Return(true, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Exists", "/migrations/meta").Return(true, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Exists", "/migrations/meta/people").Return(true, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Delete", "/migrations/lock/lock-0000000001", curator.AnyVersion).Return(nil).Once()
+
+			Convey("When some migrations have been applied", func() {
+				mocks.Conn.On("Children", "/migrations/meta/people").Return([]string{"0000000000"}, nil, nil).Once()
+				mocks.Conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{String: "/people/alice"}, {String: "/migrations/meta/people/0000000001"}}, nil).Once()
+
+				So(manager.Migrate(set), ShouldBeNil)
+
+				Convey("Only the remaining migrations are applied with the meta data", func() {
+					So(applied, ShouldResemble, []int{1})
+					So(mocks.Conn.Operations, ShouldHaveLength, 2)
+					So(mocks.Conn.Operations[0].(*zk.CreateRequest).Path, ShouldEqual, "/people/alice")
+					So(mocks.Conn.Operations[1].(*zk.CreateRequest).Path, ShouldEqual, "/migrations/meta/people/0000000001")
+
+					mocks.Check(t)
+				})
+			})
+
+			Convey("When more migrations have been applied than defined", func() {
+				mocks.Conn.On("Children", "/migrations/meta/people").Return([]string{"0000000000", "0000000001", "0000000002"}, nil, nil).Once()
+
+				So(manager.Migrate(set), ShouldNotBeNil)
+				So(applied, ShouldBeEmpty)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/flier/curator.go"
+)
+
+// Models a single migration/transition
+type Migration interface {
+	// Add the operations of the migration to the given transaction
+	Operations(transaction curator.Transaction)
+}
+
+type migrationCallback func(transaction curator.Transaction)
+
+type migrationStub struct {
+	callback migrationCallback
+}
+
+func NewMigration(callback migrationCallback) Migration {
+	return &migrationStub{callback}
+}
+
+func (m *migrationStub) Operations(transaction curator.Transaction) {
+	m.callback(transaction)
+}
+
+// Models a set of migrations. Each individual migration is applied in a transaction.
+//
+// Migrations are applied in order and only once, new migrations may be appended to an existing set
+// but already applied migrations must not be changed or removed.
+type MigrationSet struct {
+	Id         string      // the unique id of the set, used as node name under the meta data path
+	Migrations []Migration // the migrations in the set, in order
+}
+
+func NewMigrationSet(id string, migrations ...Migration) *MigrationSet {
+	return &MigrationSet{Id: id, Migrations: migrations}
+}
@@ -1,6 +1,7 @@
 package curator
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"reflect"
@@ -155,6 +156,32 @@ func (c *mockConn) Create(path string, data []byte, flags int32, acls []zk.ACL)
 	return createPath, err
 }
 
+func (c *mockConn) CreateContainer(path string, data []byte, acls []zk.ACL) (string, error) {
+	args := c.Called(path, data, acls)
+
+	createPath := args.String(0)
+	err := args.Error(1)
+
+	if c.log != nil {
+		c.log("ZookeeperConnection.CreateContainer(path=\"%s\", data=[]byte(\"%s\"), alcs=%v) (createdPath=\"%s\", error=%v)", path, data, acls, createPath, err)
+	}
+
+	return createPath, err
+}
+
+func (c *mockConn) CreateTTL(path string, data []byte, flags int32, acls []zk.ACL, ttl time.Duration) (string, error) {
+	args := c.Called(path, data, flags, acls, ttl)
+
+	createPath := args.String(0)
+	err := args.Error(1)
+
+	if c.log != nil {
+		c.log("ZookeeperConnection.CreateTTL(path=\"%s\", data=[]byte(\"%s\"), flags=%d, alcs=%v, ttl=%v) (createdPath=\"%s\", error=%v)", path, data, flags, acls, ttl, createPath, err)
+	}
+
+	return createPath, err
+}
+
 func (c *mockConn) Exists(path string) (bool, *zk.Stat, error) {
 	args := c.Called(path)
 
@@ -219,7 +246,7 @@ func (c *mockConn) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
 	err := args.Error(3)
 
 	if c.log != nil {
-		c.log("ZookeeperConnection.GetW(path=\"%s\")(data=%v, stat=%v, events=%p, error=%v)", path, data, stat, err)
+		c.log("ZookeeperConnection.GetW(path=\"%s\")(data=%v, stat=%v, events=%v, error=%v)", path, data, stat, events, err)
 	}
 
 	return data, stat, events, err
@@ -294,6 +321,40 @@ func (c *mockConn) SetACL(path string, acls []zk.ACL, version int32) (*zk.Stat,
 	return stat, err
 }
 
+func (c *mockConn) AddPersistentWatch(path string, recursive bool) error {
+	err := c.Called(path, recursive).Error(0)
+
+	if c.log != nil {
+		c.log("ZookeeperConnection.AddPersistentWatch(path=\"%s\", recursive=%v) error=%v", path, recursive, err)
+	}
+
+	return err
+}
+
+func (c *mockConn) RemovePersistentWatch(path string, recursive bool) error {
+	err := c.Called(path, recursive).Error(0)
+
+	if c.log != nil {
+		c.log("ZookeeperConnection.RemovePersistentWatch(path=\"%s\", recursive=%v) error=%v", path, recursive, err)
+	}
+
+	return err
+}
+
+func (c *mockConn) Reconfig(joining, leaving, members []string, fromConfig int64) ([]byte, *zk.Stat, error) {
+	args := c.Called(joining, leaving, members, fromConfig)
+
+	config, _ := args.Get(0).([]byte)
+	stat, _ := args.Get(1).(*zk.Stat)
+	err := args.Error(2)
+
+	if c.log != nil {
+		c.log("ZookeeperConnection.Reconfig(joining=%v, leaving=%v, members=%v, fromConfig=%d)(config=%v, stat=%v, error=%v)", joining, leaving, members, fromConfig, config, stat, err)
+	}
+
+	return config, stat, err
+}
+
 func (c *mockConn) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
 	c.operations = append(c.operations, ops...)
 
@@ -490,6 +551,36 @@ func (c *mockCuratorZookeeperClient) NewRetryLoop() RetryLoop {
 	return retryLoop
 }
 
+func (c *mockCuratorZookeeperClient) NewRetryLoopFor(operation string) RetryLoop {
+	retryLoop, _ := c.Called(operation).Get(0).(RetryLoop)
+
+	if c.log != nil {
+		c.log("CuratorZookeeperClient.NewRetryLoopFor(operation=%s) retryLoop=%v", operation, retryLoop)
+	}
+
+	return retryLoop
+}
+
+func (c *mockCuratorZookeeperClient) RetryListenable() RetryListenable {
+	listenable, _ := c.Called().Get(0).(RetryListenable)
+
+	if c.log != nil {
+		c.log("CuratorZookeeperClient.RetryListenable() listenable=%v", listenable)
+	}
+
+	return listenable
+}
+
+func (c *mockCuratorZookeeperClient) ConnectionHandlingPolicy() ConnectionHandlingPolicy {
+	policy, _ := c.Called().Get(0).(ConnectionHandlingPolicy)
+
+	if c.log != nil {
+		c.log("CuratorZookeeperClient.ConnectionHandlingPolicy() policy=%v", policy)
+	}
+
+	return policy
+}
+
 func (c *mockCuratorZookeeperClient) Connected() bool {
 	connected := c.Called().Bool(0)
 
@@ -500,6 +591,16 @@ func (c *mockCuratorZookeeperClient) Connected() bool {
 	return connected
 }
 
+func (c *mockCuratorZookeeperClient) ReadOnly() bool {
+	readOnly := c.Called().Bool(0)
+
+	if c.log != nil {
+		c.log("CuratorZookeeperClient.ReadOnly() readOnly=%v", readOnly)
+	}
+
+	return readOnly
+}
+
 func (c *mockCuratorZookeeperClient) BlockUntilConnectedOrTimedOut() error {
 	err := c.Called().Error(0)
 
@@ -540,6 +641,16 @@ func (c *mockCuratorZookeeperClient) StartTracer(name string) Tracer {
 	return tracer
 }
 
+func (c *mockCuratorZookeeperClient) StartAdvancedTracer(name string) *AdvancedTracer {
+	tracer, _ := c.Called(name).Get(0).(*AdvancedTracer)
+
+	if c.log != nil {
+		c.log("CuratorZookeeperClient.StartAdvancedTracer(name=\"%s\") tracer=%v", name, tracer)
+	}
+
+	return tracer
+}
+
 type mockCuratorFramework struct {
 	mock.Mock
 
@@ -666,6 +777,36 @@ func (c *mockCuratorFramework) SetACL() SetACLBuilder {
 	return builder
 }
 
+func (c *mockCuratorFramework) GetConfig() GetConfigBuilder {
+	builder, _ := c.Called().Get(0).(GetConfigBuilder)
+
+	if c.log != nil {
+		c.log("CuratorFramework.GetConfig() GetConfigBuilder=%v", builder)
+	}
+
+	return builder
+}
+
+func (c *mockCuratorFramework) Reconfig() ReconfigBuilder {
+	builder, _ := c.Called().Get(0).(ReconfigBuilder)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Reconfig() ReconfigBuilder=%v", builder)
+	}
+
+	return builder
+}
+
+func (c *mockCuratorFramework) Watches() WatchesBuilder {
+	builder, _ := c.Called().Get(0).(WatchesBuilder)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Watches() WatchesBuilder=%v", builder)
+	}
+
+	return builder
+}
+
 func (c *mockCuratorFramework) InTransaction() Transaction {
 	transaction, _ := c.Called().Get(0).(Transaction)
 
@@ -676,6 +817,26 @@ func (c *mockCuratorFramework) InTransaction() Transaction {
 	return transaction
 }
 
+func (c *mockCuratorFramework) TransactionOp() TransactionOp {
+	op, _ := c.Called().Get(0).(TransactionOp)
+
+	if c.log != nil {
+		c.log("CuratorFramework.TransactionOp() TransactionOp=%v", op)
+	}
+
+	return op
+}
+
+func (c *mockCuratorFramework) Transaction() CuratorMultiTransaction {
+	transaction, _ := c.Called().Get(0).(CuratorMultiTransaction)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Transaction() CuratorMultiTransaction=%v", transaction)
+	}
+
+	return transaction
+}
+
 func (c *mockCuratorFramework) DoSync(path string, backgroundContextObject interface{}) {
 	c.Called(path, backgroundContextObject)
 
@@ -724,6 +885,66 @@ func (c *mockCuratorFramework) UnhandledErrorListenable() UnhandledErrorListenab
 	return listenable
 }
 
+func (c *mockCuratorFramework) RetryListenable() RetryListenable {
+	listenable, _ := c.Called().Get(0).(RetryListenable)
+
+	if c.log != nil {
+		c.log("CuratorFramework.RetryListenable() Listenable=%v", listenable)
+	}
+
+	return listenable
+}
+
+func (c *mockCuratorFramework) ConnectionStateErrorPolicy() ConnectionStateErrorPolicy {
+	policy, _ := c.Called().Get(0).(ConnectionStateErrorPolicy)
+
+	if c.log != nil {
+		c.log("CuratorFramework.ConnectionStateErrorPolicy() ConnectionStateErrorPolicy=%v", policy)
+	}
+
+	return policy
+}
+
+func (c *mockCuratorFramework) Stats() *ConnectionStats {
+	stats, _ := c.Called().Get(0).(*ConnectionStats)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Stats() ConnectionStats=%v", stats)
+	}
+
+	return stats
+}
+
+func (c *mockCuratorFramework) SchemaSet() *SchemaSet {
+	schemaSet, _ := c.Called().Get(0).(*SchemaSet)
+
+	if c.log != nil {
+		c.log("CuratorFramework.SchemaSet() SchemaSet=%v", schemaSet)
+	}
+
+	return schemaSet
+}
+
+func (c *mockCuratorFramework) Compatibility() *Compatibility {
+	compatibility, _ := c.Called().Get(0).(*Compatibility)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Compatibility() Compatibility=%v", compatibility)
+	}
+
+	return compatibility
+}
+
+func (c *mockCuratorFramework) Closeables() CloseableRegistry {
+	registry, _ := c.Called().Get(0).(CloseableRegistry)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Closeables() Registry=%v", registry)
+	}
+
+	return registry
+}
+
 func (c *mockCuratorFramework) NonNamespaceView() CuratorFramework {
 	framework, _ := c.Called().Get(0).(CuratorFramework)
 
@@ -734,6 +955,36 @@ func (c *mockCuratorFramework) NonNamespaceView() CuratorFramework {
 	return framework
 }
 
+func (c *mockCuratorFramework) WithContext(ctx context.Context) CuratorFramework {
+	framework, _ := c.Called(ctx).Get(0).(CuratorFramework)
+
+	if c.log != nil {
+		c.log("CuratorFramework.WithContext(ctx=%v) Framework=%v", ctx, framework)
+	}
+
+	return framework
+}
+
+func (c *mockCuratorFramework) NewWatcherRemoveCuratorFramework() WatcherRemoveCuratorFramework {
+	framework, _ := c.Called().Get(0).(WatcherRemoveCuratorFramework)
+
+	if c.log != nil {
+		c.log("CuratorFramework.NewWatcherRemoveCuratorFramework() Framework=%v", framework)
+	}
+
+	return framework
+}
+
+func (c *mockCuratorFramework) Share(namespace string, aclProvider ACLProvider) CuratorFramework {
+	framework, _ := c.Called(namespace, aclProvider).Get(0).(CuratorFramework)
+
+	if c.log != nil {
+		c.log("CuratorFramework.Share(namespace=\"%s\", aclProvider=%v) Framework=%v", namespace, aclProvider, framework)
+	}
+
+	return framework
+}
+
 func (c *mockCuratorFramework) UsingNamespace(newNamespace string) CuratorFramework {
 	framework, _ := c.Called(newNamespace).Get(0).(CuratorFramework)
 
@@ -794,6 +1045,16 @@ func (c *mockCuratorFramework) BlockUntilConnectedTimeout(maxWaitTime time.Durat
 	return err
 }
 
+func (c *mockCuratorFramework) BlockUntilConnectedContext(ctx context.Context) error {
+	err := c.Called(ctx).Error(0)
+
+	if c.log != nil {
+		c.log("CuratorFramework.BlockUntilConnectedContext(ctx=%v) error=%v", ctx, err)
+	}
+
+	return err
+}
+
 type mockContainer struct {
 	builder *CuratorFrameworkBuilder
 }
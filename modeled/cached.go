@@ -0,0 +1,163 @@
+package modeled
+
+import (
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/recipes"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Listener for changes to a cached model
+type ModeledCacheListener interface {
+	// Called when the cached model has changed, the model and stat are nil if the node was deleted
+	ModelChanged(path *ZPath, stat *zk.Stat, model interface{}) error
+}
+
+type modeledCacheListenerCallback func(path *ZPath, stat *zk.Stat, model interface{}) error
+
+type modeledCacheListenerStub struct {
+	callback modeledCacheListenerCallback
+}
+
+func NewModeledCacheListener(callback modeledCacheListenerCallback) ModeledCacheListener {
+	return &modeledCacheListenerStub{callback}
+}
+
+func (l *modeledCacheListenerStub) ModelChanged(path *ZPath, stat *zk.Stat, model interface{}) error {
+	return l.callback(path, stat, model)
+}
+
+type ModeledCacheListenable interface {
+	curator.Listenable /* [T] */
+
+	AddListener(listener ModeledCacheListener)
+
+	RemoveListener(listener ModeledCacheListener)
+}
+
+type ModeledCacheListenerContainer struct {
+	*curator.ListenerContainer
+}
+
+func (c *ModeledCacheListenerContainer) AddListener(listener ModeledCacheListener) {
+	c.Add(listener)
+}
+
+func (c *ModeledCacheListenerContainer) RemoveListener(listener ModeledCacheListener) {
+	c.Remove(listener)
+}
+
+// Modeled client that keeps the model node locally cached.
+// Reads are served from the cache and only fall back to ZooKeeper when the node is not cached, writes always go to ZooKeeper.
+//
+// Only the single node of the resolved path is cached, Children, UpdateAll and the other operations on the children
+// are always served by ZooKeeper.
+//
+//	cached, err := client.Cached()
+//
+//	cached.Listenable().AddListener(modeled.NewModeledCacheListener(func(path *modeled.ZPath, stat *zk.Stat, model interface{}) error {
+//		...
+//	}))
+//
+//	cached.Start()
+//
+//	defer cached.Close()
+//
+//	model, err := cached.Read()
+type CachedModeledFramework /* [T] */ interface {
+	ModeledFramework
+
+	// Start the cache, the cache is not started automatically
+	Start() error
+
+	// Close the cache
+	Close() error
+
+	// Return the listenable for the cached model changes
+	Listenable() ModeledCacheListenable
+
+	// Return the currently cached model and its stat, or nil if the node is not cached
+	CurrentData() (interface{}, *zk.Stat, error)
+}
+
+type cachedModeledFramework struct {
+	*modeledFramework
+
+	cache     *recipes.NodeCache
+	listeners *ModeledCacheListenerContainer
+}
+
+func newCachedModeledFramework(client *modeledFramework, cache *recipes.NodeCache) *cachedModeledFramework {
+	f := &cachedModeledFramework{
+		modeledFramework: client,
+		cache:            cache,
+		listeners:        &ModeledCacheListenerContainer{&curator.ListenerContainer{}},
+	}
+
+	cache.NodeCacheListenable().AddListener(recipes.NewNodeCacheListener(f.nodeChanged))
+
+	return f
+}
+
+func (f *cachedModeledFramework) Start() error {
+	return f.cache.StartAndInitalize(true)
+}
+
+func (f *cachedModeledFramework) Close() error {
+	f.listeners.Clear()
+
+	return f.cache.Close()
+}
+
+func (f *cachedModeledFramework) Listenable() ModeledCacheListenable {
+	return f.listeners
+}
+
+func (f *cachedModeledFramework) CurrentData() (interface{}, *zk.Stat, error) {
+	data := f.cache.CurrentData()
+
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	if model, err := f.spec.Serializer.Deserialize(data.Data); err != nil {
+		return nil, nil, err
+	} else {
+		return model, data.Stat, nil
+	}
+}
+
+func (f *cachedModeledFramework) Read() (interface{}, error) {
+	return f.ReadStoringStatIn(nil)
+}
+
+func (f *cachedModeledFramework) ReadStoringStatIn(stat *zk.Stat) (interface{}, error) {
+	if model, cachedStat, err := f.CurrentData(); err != nil {
+		return nil, err
+	} else if model == nil {
+		return f.modeledFramework.ReadStoringStatIn(stat)
+	} else {
+		if stat != nil && cachedStat != nil {
+			*stat = *cachedStat
+		}
+
+		return model, nil
+	}
+}
+
+func (f *cachedModeledFramework) Versioned() VersionedModeledFramework {
+	return &versionedModeledFramework{client: f.modeledFramework, reader: f}
+}
+
+func (f *cachedModeledFramework) nodeChanged() error {
+	model, stat, err := f.CurrentData()
+
+	if err != nil {
+		return err
+	}
+
+	f.listeners.ForEach(func(listener interface{}) {
+		listener.(ModeledCacheListener).ModelChanged(f.spec.Path, stat, model)
+	})
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package modeled
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCachedModeledFramework(t *testing.T) {
+	Convey("Given a CachedModeledFramework", t, func() {
+		mocks := zktest.NewMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		spec := NewModelSpec(MustParseZPath("/people/alice"), NewJSONModelSerializer(&testModel{}))
+		data := []byte(`{"name":"alice","age":20}`)
+		newData := []byte(`{"name":"alice","age":21}`)
+
+		Convey("base on an unresolved path", func() {
+			cached, err := NewModeledFramework(client, spec.WithPath(MustParseZPath("/people/{name}"))).Cached()
+
+			So(cached, ShouldBeNil)
+			So(err, ShouldEqual, ErrUnresolvedPath)
+		})
+
+		Convey("base on a resolved path", func() {
+			cached, err := NewModeledFramework(client, spec).Cached()
+
+			So(cached, ShouldNotBeNil)
+			So(err, ShouldBeNil)
+
+			changes := make(chan interface{}, 1)
+			paths := make(chan string, 1)
+
+			cached.Listenable().AddListener(NewModeledCacheListener(func(path *ZPath, stat *zk.Stat, model interface{}) error {
+				paths <- path.String()
+				changes <- model
+
+				return nil
+			}))
+
+			Convey("When the cached node has been changed", func() {
+				mocks.Conn.On("Exists", "/people").Return(true, &zk.Stat{}, nil).Once()
+				mocks.Conn.On("Get", "/people/alice").Return(data, &zk.Stat{Version: 3}, nil).Once()
+				mocks.Conn.On("ExistsW", "/people/alice").Return(true, &zk.Stat{Version: 4}, nil, nil).Once()
+				mocks.Conn.On("GetW", "/people/alice").Return(newData, &zk.Stat{Version: 4}, nil, nil).Once()
+
+				So(cached.Start(), ShouldBeNil)
+
+				So(<-paths, ShouldEqual, "/people/alice")
+				So(<-changes, ShouldResemble, &testModel{"alice", 21})
+
+				var stat zk.Stat
+
+				model, err := cached.ReadStoringStatIn(&stat)
+
+				So(model, ShouldResemble, &testModel{"alice", 21})
+				So(err, ShouldBeNil)
+				So(stat.Version, ShouldEqual, 4)
+
+				v, err := cached.Versioned().Read()
+
+				So(v, ShouldResemble, NewVersioned(&testModel{"alice", 21}, 4))
+				So(err, ShouldBeNil)
+
+				So(cached.Close(), ShouldBeNil)
+			})
+
+			Convey("When the node is not cached", func() {
+				mocks.Conn.On("Get", "/people/alice").Return(data, &zk.Stat{Version: 3}, nil).Once()
+
+				model, err := cached.Read()
+
+				So(model, ShouldResemble, &testModel{"alice", 20})
+				So(err, ShouldBeNil)
+			})
+		})
+
+		mocks.Check(t)
+	})
+}
@@ -0,0 +1,358 @@
+package modeled
+
+import (
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/recipes"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The maximum number of node updates committed in one transaction by UpdateAll()
+const MAX_UPDATE_BATCH_SIZE = 100
+
+// Return true if the model at the given path should be updated
+type ModelPredicate func(path *ZPath, model interface{}) bool
+
+// Return the new model for the given model
+type ModelTransform func(model interface{}) (interface{}, error)
+
+// A modeled client that serializes and deserializes the model stored at the ModelSpec's path.
+//
+//	spec := modeled.NewModelSpec(modeled.MustParseZPath("/people/{name}"), modeled.NewJSONModelSerializer(&Person{}))
+//
+//	client, err := modeled.NewModeledFramework(curatorClient, spec).Resolved("alice")
+//
+//	client.Set(&Person{Name: "alice"})
+//
+//	model, err := client.Read()
+//
+//	alice := model.(*Person)
+type ModeledFramework /* [T] */ interface {
+	// Return the client that was originally passed to NewModeledFramework()
+	Client() curator.CuratorFramework
+
+	// Return the model spec being used
+	ModelSpec() *ModelSpec
+
+	// Return a new modeled client for a child of the current path
+	Child(name string) (ModeledFramework, error)
+
+	// Return a new modeled client for the parent of the current path
+	Parent() (ModeledFramework, error)
+
+	// Return a new modeled client with the path parameters resolved by the given values
+	Resolved(parameters ...interface{}) (ModeledFramework, error)
+
+	// Create (or update depending on build options) a ZNode at this instance's path with a serialized version of the given model
+	Set(model interface{}) (string, error)
+
+	// Read the ZNode at this instance's path and deserialize into a model
+	Read() (interface{}, error)
+
+	// Read the ZNode at this instance's path and deserialize into a model, filling the given stat
+	ReadStoringStatIn(stat *zk.Stat) (interface{}, error)
+
+	// Update the ZNode at this instance's path with a serialized form of the given model
+	Update(model interface{}) (*zk.Stat, error)
+
+	// Update the ZNode at this instance's path with a serialized form of the given model,
+	// failing with ErrBadVersion if the node's version doesn't match the given version
+	UpdateWithVersion(model interface{}, version int32) (*zk.Stat, error)
+
+	// Delete the ZNode at this instance's path
+	Delete() error
+
+	// Delete the ZNode at this instance's path if the node's version matches the given version
+	DeleteWithVersion(version int32) error
+
+	// Check to see if the ZNode at this instance's path exists
+	CheckExists() (*zk.Stat, error)
+
+	// Return the child paths of this instance's path
+	Children() ([]*ZPath, error)
+
+	// Read the children of this instance's path, transform the models matching the predicate
+	// and write them back in batched transactions, each node is checked against the version it was read at.
+	// Return the number of updated nodes, batches committed before an error are not rolled back.
+	UpdateAll(predicate ModelPredicate, transform ModelTransform) (int, error)
+
+	// Return a view of this client that reads and writes versioned models
+	Versioned() VersionedModeledFramework
+
+	// Return a view of this client that serves reads from a local cache of the node, the path must be resolved.
+	// Only the node itself is cached, not its children.
+	Cached() (CachedModeledFramework, error)
+}
+
+type modeledFramework struct {
+	client curator.CuratorFramework
+	spec   *ModelSpec
+}
+
+// Create a modeled client for the given model spec
+func NewModeledFramework(client curator.CuratorFramework, spec *ModelSpec) ModeledFramework {
+	return &modeledFramework{client: client, spec: spec}
+}
+
+func (f *modeledFramework) Client() curator.CuratorFramework { return f.client }
+
+func (f *modeledFramework) ModelSpec() *ModelSpec { return f.spec }
+
+func (f *modeledFramework) Child(name string) (ModeledFramework, error) {
+	return f.withSpec(f.spec.Child(name))
+}
+
+func (f *modeledFramework) Parent() (ModeledFramework, error) {
+	return f.withSpec(f.spec.Parent())
+}
+
+func (f *modeledFramework) Resolved(parameters ...interface{}) (ModeledFramework, error) {
+	return f.withSpec(f.spec.Resolved(parameters...))
+}
+
+func (f *modeledFramework) withSpec(spec *ModelSpec, err error) (ModeledFramework, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return &modeledFramework{client: f.client, spec: spec}, nil
+}
+
+func (f *modeledFramework) Set(model interface{}) (string, error) {
+	return f.set(model, curator.AnyVersion)
+}
+
+func (f *modeledFramework) set(model interface{}, version int32) (string, error) {
+	fullPath, err := f.spec.Path.FullPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := f.spec.Serializer.Serialize(model)
+
+	if err != nil {
+		return "", err
+	}
+
+	builder := f.client.Create().WithMode(f.spec.CreateMode)
+
+	if f.spec.ACLs != nil {
+		builder = builder.WithACL(f.spec.ACLs...)
+	}
+
+	if f.spec.hasCreateOption(CREATE_PARENTS_IF_NEEDED) {
+		builder = builder.CreatingParentsIfNeeded()
+	}
+
+	if f.spec.hasCreateOption(COMPRESS) {
+		builder = builder.Compressed()
+	}
+
+	path, err := builder.ForPathWithData(fullPath, data)
+
+	if err == zk.ErrNodeExists {
+		if _, err := f.setData(fullPath, data, version); err != nil {
+			return "", err
+		}
+
+		return fullPath, nil
+	}
+
+	return path, err
+}
+
+func (f *modeledFramework) Read() (interface{}, error) {
+	return f.ReadStoringStatIn(nil)
+}
+
+func (f *modeledFramework) ReadStoringStatIn(stat *zk.Stat) (interface{}, error) {
+	fullPath, err := f.spec.Path.FullPath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return f.read(fullPath, stat)
+}
+
+func (f *modeledFramework) read(fullPath string, stat *zk.Stat) (interface{}, error) {
+	builder := f.client.GetData()
+
+	if stat != nil {
+		builder = builder.StoringStatIn(stat)
+	}
+
+	if f.spec.hasCreateOption(COMPRESS) {
+		builder = builder.Decompressed()
+	}
+
+	if data, err := builder.ForPath(fullPath); err != nil {
+		return nil, err
+	} else {
+		return f.spec.Serializer.Deserialize(data)
+	}
+}
+
+func (f *modeledFramework) Update(model interface{}) (*zk.Stat, error) {
+	return f.UpdateWithVersion(model, curator.AnyVersion)
+}
+
+func (f *modeledFramework) UpdateWithVersion(model interface{}, version int32) (*zk.Stat, error) {
+	if fullPath, err := f.spec.Path.FullPath(); err != nil {
+		return nil, err
+	} else if data, err := f.spec.Serializer.Serialize(model); err != nil {
+		return nil, err
+	} else {
+		return f.setData(fullPath, data, version)
+	}
+}
+
+func (f *modeledFramework) setData(fullPath string, data []byte, version int32) (*zk.Stat, error) {
+	builder := f.client.SetData().WithVersion(version)
+
+	if f.spec.hasCreateOption(COMPRESS) {
+		builder = builder.Compressed()
+	}
+
+	return builder.ForPathWithData(fullPath, data)
+}
+
+func (f *modeledFramework) Delete() error {
+	return f.DeleteWithVersion(curator.AnyVersion)
+}
+
+func (f *modeledFramework) DeleteWithVersion(version int32) error {
+	fullPath, err := f.spec.Path.FullPath()
+
+	if err != nil {
+		return err
+	}
+
+	builder := f.client.Delete().WithVersion(version)
+
+	if f.spec.hasDeleteOption(DELETE_CHILDREN_IF_NEEDED) {
+		builder = builder.DeletingChildrenIfNeeded()
+	}
+
+	return builder.ForPath(fullPath)
+}
+
+func (f *modeledFramework) CheckExists() (*zk.Stat, error) {
+	if fullPath, err := f.spec.Path.FullPath(); err != nil {
+		return nil, err
+	} else {
+		return f.client.CheckExists().ForPath(fullPath)
+	}
+}
+
+func (f *modeledFramework) Children() ([]*ZPath, error) {
+	fullPath, err := f.spec.Path.FullPath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := f.client.GetChildren().ForPath(fullPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]*ZPath, len(children))
+
+	for i, child := range children {
+		if paths[i], err = f.spec.Path.Child(child); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+func (f *modeledFramework) UpdateAll(predicate ModelPredicate, transform ModelTransform) (int, error) {
+	children, err := f.Children()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var transaction curator.TransactionFinal
+
+	updated, batched := 0, 0
+
+	for _, child := range children {
+		var stat zk.Stat
+
+		fullPath := child.String()
+
+		model, err := f.read(fullPath, &stat)
+
+		if err == zk.ErrNoNode {
+			continue // deleted since the children were listed
+		} else if err != nil {
+			return updated, err
+		}
+
+		if predicate != nil && !predicate(child, model) {
+			continue
+		}
+
+		if model, err = transform(model); err != nil {
+			return updated, err
+		}
+
+		data, err := f.spec.Serializer.Serialize(model)
+
+		if err != nil {
+			return updated, err
+		}
+
+		var builder curator.TransactionSetDataBuilder
+
+		if transaction == nil {
+			builder = f.client.InTransaction().SetData()
+		} else {
+			builder = transaction.SetData()
+		}
+
+		builder = builder.WithVersion(stat.Version)
+
+		if f.spec.hasCreateOption(COMPRESS) {
+			builder = builder.Compressed()
+		}
+
+		transaction = builder.ForPathWithData(fullPath, data)
+
+		if batched++; batched == MAX_UPDATE_BATCH_SIZE {
+			if _, err := transaction.Commit(); err != nil {
+				return updated, err
+			}
+
+			updated += batched
+			transaction = nil
+			batched = 0
+		}
+	}
+
+	if transaction != nil {
+		if _, err := transaction.Commit(); err != nil {
+			return updated, err
+		}
+
+		updated += batched
+	}
+
+	return updated, nil
+}
+
+func (f *modeledFramework) Versioned() VersionedModeledFramework {
+	return &versionedModeledFramework{client: f, reader: f}
+}
+
+func (f *modeledFramework) Cached() (CachedModeledFramework, error) {
+	if fullPath, err := f.spec.Path.FullPath(); err != nil {
+		return nil, err
+	} else {
+		return newCachedModeledFramework(f, recipes.NewNodeCache(f.client, fullPath, f.spec.hasCreateOption(COMPRESS))), nil
+	}
+}
@@ -0,0 +1,123 @@
+package modeled
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestModeledFramework(t *testing.T) {
+	Convey("Given a ModeledFramework", t, func() {
+		mocks := zktest.NewMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("base on an unresolved path", func() {
+			modeled := NewModeledFramework(client, NewModelSpec(MustParseZPath("/people/{name}"), NewJSONModelSerializer(&testModel{})))
+
+			Convey("Operations fail until the path is resolved", func() {
+				_, err := modeled.Read()
+
+				So(err, ShouldEqual, ErrUnresolvedPath)
+			})
+		})
+
+		Convey("base on a resolved path", func() {
+			spec := NewModelSpec(MustParseZPath("/people/{name}"), NewJSONModelSerializer(&testModel{}))
+			data := []byte(`{"name":"alice","age":20}`)
+
+			modeled, err := NewModeledFramework(client, spec).Resolved("alice")
+
+			So(modeled, ShouldNotBeNil)
+			So(err, ShouldBeNil)
+
+			Convey("When set a new model", func() {
+				mocks.Conn.On("Create", "/people/alice", data, int32(curator.PERSISTENT), curator.OPEN_ACL_UNSAFE).Return("/people/alice", nil).Once()
+
+				path, err := modeled.Set(&testModel{"alice", 20})
+
+				So(path, ShouldEqual, "/people/alice")
+				So(err, ShouldBeNil)
+			})
+
+			Convey("When set an existing model", func() {
+				mocks.Conn.On("Create", "/people/alice", data, int32(curator.PERSISTENT), curator.OPEN_ACL_UNSAFE).Return("", zk.ErrNodeExists).Once()
+				mocks.Conn.On("Set", "/people/alice", data, curator.AnyVersion).Return(&zk.Stat{}, nil).Once()
+
+				path, err := modeled.Set(&testModel{"alice", 20})
+
+				So(path, ShouldEqual, "/people/alice")
+				So(err, ShouldBeNil)
+			})
+
+			Convey("When read the model", func() {
+				var stat zk.Stat
+
+				mocks.Conn.On("Get", "/people/alice").Return(data, &zk.Stat{Version: 3}, nil).Once()
+
+				model, err := modeled.ReadStoringStatIn(&stat)
+
+				So(model, ShouldResemble, &testModel{"alice", 20})
+				So(err, ShouldBeNil)
+				So(stat.Version, ShouldEqual, 3)
+			})
+
+			Convey("When delete the model", func() {
+				mocks.Conn.On("Delete", "/people/alice", curator.AnyVersion).Return(nil).Once()
+
+				So(modeled.Delete(), ShouldBeNil)
+			})
+
+			Convey("When list the children", func() {
+				mocks.Conn.On("Children", "/people").Return([]string{"alice", "bob"}, nil, nil).Once()
+
+				parent, err := modeled.Parent()
+
+				So(err, ShouldBeNil)
+
+				children, err := parent.Children()
+
+				So(err, ShouldBeNil)
+				So(children, ShouldHaveLength, 2)
+				So(children[0].String(), ShouldEqual, "/people/alice")
+				So(children[1].String(), ShouldEqual, "/people/bob")
+			})
+
+			Convey("When update all the matched children", func() {
+				mocks.Conn.On("Children", "/people").Return([]string{"alice", "bob", "carol"}, nil, nil).Once()
+				mocks.Conn.On("Get", "/people/alice").Return(data, &zk.Stat{Version: 3}, nil).Once()
+				mocks.Conn.On("Get", "/people/bob").Return([]byte(`{"name":"bob","age":30}`), &zk.Stat{Version: 5}, nil).Once()
+				mocks.Conn.On("Get", "/people/carol").Return(nil, nil, zk.ErrNoNode).Once()
+				mocks.Conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: &zk.Stat{Version: 4}}}, nil).Once()
+
+				parent, err := modeled.Parent()
+
+				So(err, ShouldBeNil)
+
+				updated, err := parent.UpdateAll(func(path *ZPath, model interface{}) bool {
+					return model.(*testModel).Age < 30
+				}, func(model interface{}) (interface{}, error) {
+					m := *model.(*testModel)
+
+					m.Age++
+
+					return &m, nil
+				})
+
+				So(updated, ShouldEqual, 1)
+				So(err, ShouldBeNil)
+				So(mocks.Conn.Operations, ShouldResemble, []interface{}{
+					&zk.SetDataRequest{Path: "/people/alice", Data: []byte(`{"name":"alice","age":21}`), Version: 3},
+				})
+			})
+		})
+
+		mocks.Check(t)
+	})
+}
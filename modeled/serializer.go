@@ -0,0 +1,74 @@
+package modeled
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Serializing models to/from byte arrays
+type ModelSerializer interface {
+	// Given a model return the serialized bytes
+	Serialize(model interface{}) ([]byte, error)
+
+	// Given bytes serialized via Serialize() return the model
+	Deserialize(data []byte) (interface{}, error)
+}
+
+// Model serializer that uses encoding/json for serializing.
+type JSONModelSerializer struct {
+	modelType reflect.Type
+	isPointer bool
+}
+
+// Create a serializer for the type of the given prototype model,
+// i.e. NewJSONModelSerializer(&Person{}) deserializes to *Person.
+func NewJSONModelSerializer(prototype interface{}) *JSONModelSerializer {
+	modelType := reflect.TypeOf(prototype)
+
+	s := &JSONModelSerializer{modelType: modelType}
+
+	if modelType.Kind() == reflect.Ptr {
+		s.modelType = modelType.Elem()
+		s.isPointer = true
+	}
+
+	return s
+}
+
+func (s *JSONModelSerializer) Serialize(model interface{}) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+func (s *JSONModelSerializer) Deserialize(data []byte) (interface{}, error) {
+	model := reflect.New(s.modelType)
+
+	if err := json.Unmarshal(data, model.Interface()); err != nil {
+		return nil, err
+	}
+
+	if s.isPointer {
+		return model.Interface(), nil
+	}
+
+	return model.Elem().Interface(), nil
+}
+
+// Model serializer that passes raw byte arrays through unchanged.
+type RawModelSerializer struct{}
+
+func NewRawModelSerializer() *RawModelSerializer {
+	return &RawModelSerializer{}
+}
+
+func (s *RawModelSerializer) Serialize(model interface{}) ([]byte, error) {
+	if data, ok := model.([]byte); ok {
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("raw model must be []byte, got %T", model)
+}
+
+func (s *RawModelSerializer) Deserialize(data []byte) (interface{}, error) {
+	return data, nil
+}
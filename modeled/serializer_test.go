@@ -0,0 +1,70 @@
+package modeled
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testModel struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestJSONModelSerializer(t *testing.T) {
+	Convey("Given a JSONModelSerializer for a pointer model", t, func() {
+		serializer := NewJSONModelSerializer(&testModel{})
+
+		Convey("When serialize a model", func() {
+			data, err := serializer.Serialize(&testModel{"alice", 20})
+
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"name":"alice","age":20}`)
+
+			Convey("Deserialize returns a pointer to the model", func() {
+				model, err := serializer.Deserialize(data)
+
+				So(err, ShouldBeNil)
+				So(model, ShouldResemble, &testModel{"alice", 20})
+			})
+		})
+
+		Convey("When deserialize invalid data", func() {
+			model, err := serializer.Deserialize([]byte("invalid"))
+
+			So(model, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a JSONModelSerializer for a value model", t, func() {
+		serializer := NewJSONModelSerializer(testModel{})
+
+		Convey("Deserialize returns the model value", func() {
+			model, err := serializer.Deserialize([]byte(`{"name":"bob","age":30}`))
+
+			So(err, ShouldBeNil)
+			So(model, ShouldResemble, testModel{"bob", 30})
+		})
+	})
+}
+
+func TestRawModelSerializer(t *testing.T) {
+	Convey("Given a RawModelSerializer", t, func() {
+		serializer := NewRawModelSerializer()
+
+		Convey("Serialize passes bytes through", func() {
+			data, err := serializer.Serialize([]byte("data"))
+
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, []byte("data"))
+		})
+
+		Convey("Serialize rejects other models", func() {
+			data, err := serializer.Serialize("data")
+
+			So(data, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
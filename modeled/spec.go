@@ -0,0 +1,97 @@
+package modeled
+
+import (
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Options applied when a model node is created
+type CreateOption int
+
+const (
+	CREATE_PARENTS_IF_NEEDED CreateOption = iota // Create any missing parent nodes
+	COMPRESS                                     // Compress the serialized model with the configured compression provider
+)
+
+// Options applied when a model node is deleted
+type DeleteOption int
+
+const (
+	DELETE_CHILDREN_IF_NEEDED DeleteOption = iota // Delete any children of the node first
+)
+
+// A full specification for dealing with a portion of the ZooKeeper tree.
+// ModelSpec's are used by ModeledFramework to serialize and deserialize the models stored at the path.
+type ModelSpec struct {
+	Path          *ZPath             // the path of the model node, may contain parameters
+	Serializer    ModelSerializer    // the serializer used to (de)serialize the model
+	CreateMode    curator.CreateMode // the create mode used when the node is created
+	ACLs          []zk.ACL           // the ACL list used when the node is created, or nil to use the ACL provider
+	CreateOptions []CreateOption     // the options used when the node is created
+	DeleteOptions []DeleteOption     // the options used when the node is deleted
+}
+
+// Create a model spec for the given path and serializer
+func NewModelSpec(path *ZPath, serializer ModelSerializer) *ModelSpec {
+	return &ModelSpec{
+		Path:       path,
+		Serializer: serializer,
+		CreateMode: curator.PERSISTENT,
+	}
+}
+
+// Return a new spec that is a copy of this one but uses the given path
+func (s *ModelSpec) WithPath(path *ZPath) *ModelSpec {
+	spec := *s
+
+	spec.Path = path
+
+	return &spec
+}
+
+// Return a new spec that is a copy of this one but uses a child of the current path
+func (s *ModelSpec) Child(name string) (*ModelSpec, error) {
+	if path, err := s.Path.Child(name); err != nil {
+		return nil, err
+	} else {
+		return s.WithPath(path), nil
+	}
+}
+
+// Return a new spec that is a copy of this one but uses the parent of the current path
+func (s *ModelSpec) Parent() (*ModelSpec, error) {
+	if path, err := s.Path.Parent(); err != nil {
+		return nil, err
+	} else {
+		return s.WithPath(path), nil
+	}
+}
+
+// Return a new spec that is a copy of this one but with the path parameters resolved
+func (s *ModelSpec) Resolved(parameters ...interface{}) (*ModelSpec, error) {
+	if path, err := s.Path.Resolved(parameters...); err != nil {
+		return nil, err
+	} else {
+		return s.WithPath(path), nil
+	}
+}
+
+func (s *ModelSpec) hasCreateOption(option CreateOption) bool {
+	for _, o := range s.CreateOptions {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *ModelSpec) hasDeleteOption(option DeleteOption) bool {
+	for _, o := range s.DeleteOptions {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package modeled
+
+import (
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A container for a model and its version
+type Versioned /* [T] */ struct {
+	Model   interface{} // the model
+	Version int32       // the version of the node the model was read from, or the version required to write it
+}
+
+func NewVersioned(model interface{}, version int32) *Versioned {
+	return &Versioned{Model: model, Version: version}
+}
+
+// Modeled client that reads and writes models together with their node versions,
+// so updates can require that the node has not changed since it was read.
+//
+//	v, err := client.Versioned().Read()
+//
+//	v.Model.(*Person).Age++
+//
+//	_, err = client.Versioned().Update(v) // fails with ErrBadVersion if the node has been changed
+type VersionedModeledFramework /* [T] */ interface {
+	// Create a ZNode at this instance's path, or update it if the node's version matches the given version
+	Set(versioned *Versioned) (string, error)
+
+	// Read the ZNode at this instance's path and return the model along with the node's version
+	Read() (*Versioned, error)
+
+	// Update the ZNode at this instance's path if the node's version matches the given version
+	Update(versioned *Versioned) (*zk.Stat, error)
+}
+
+type versionedModeledFramework struct {
+	client *modeledFramework
+	reader ModeledFramework // serves the reads, may be a cached view of the client
+}
+
+func (f *versionedModeledFramework) Set(versioned *Versioned) (string, error) {
+	return f.client.set(versioned.Model, versioned.Version)
+}
+
+func (f *versionedModeledFramework) Read() (*Versioned, error) {
+	var stat zk.Stat
+
+	if model, err := f.reader.ReadStoringStatIn(&stat); err != nil {
+		return nil, err
+	} else {
+		return NewVersioned(model, stat.Version), nil
+	}
+}
+
+func (f *versionedModeledFramework) Update(versioned *Versioned) (*zk.Stat, error) {
+	return f.client.UpdateWithVersion(versioned.Model, versioned.Version)
+}
@@ -0,0 +1,64 @@
+package modeled
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVersionedModeledFramework(t *testing.T) {
+	Convey("Given a VersionedModeledFramework", t, func() {
+		mocks := zktest.NewMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		spec := NewModelSpec(MustParseZPath("/people/alice"), NewJSONModelSerializer(&testModel{}))
+		data := []byte(`{"name":"alice","age":20}`)
+
+		versioned := NewModeledFramework(client, spec).Versioned()
+
+		Convey("When read the model", func() {
+			mocks.Conn.On("Get", "/people/alice").Return(data, &zk.Stat{Version: 3}, nil).Once()
+
+			v, err := versioned.Read()
+
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, NewVersioned(&testModel{"alice", 20}, 3))
+		})
+
+		Convey("When update the model with the matched version", func() {
+			mocks.Conn.On("Set", "/people/alice", data, int32(3)).Return(&zk.Stat{Version: 4}, nil).Once()
+
+			stat, err := versioned.Update(NewVersioned(&testModel{"alice", 20}, 3))
+
+			So(err, ShouldBeNil)
+			So(stat.Version, ShouldEqual, 4)
+		})
+
+		Convey("When update the model with a stale version", func() {
+			mocks.Conn.On("Set", "/people/alice", data, int32(2)).Return(nil, zk.ErrBadVersion).Once()
+
+			stat, err := versioned.Update(NewVersioned(&testModel{"alice", 20}, 2))
+
+			So(err, ShouldEqual, zk.ErrBadVersion)
+			So(stat, ShouldBeNil)
+		})
+
+		Convey("When set an existing model with a version", func() {
+			mocks.Conn.On("Create", "/people/alice", data, int32(curator.PERSISTENT), curator.OPEN_ACL_UNSAFE).Return("", zk.ErrNodeExists).Once()
+			mocks.Conn.On("Set", "/people/alice", data, int32(3)).Return(&zk.Stat{Version: 4}, nil).Once()
+
+			path, err := versioned.Set(NewVersioned(&testModel{"alice", 20}, 3))
+
+			So(path, ShouldEqual, "/people/alice")
+			So(err, ShouldBeNil)
+		})
+
+		mocks.Check(t)
+	})
+}
@@ -0,0 +1,217 @@
+package modeled
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/flier/curator.go"
+)
+
+const (
+	PARAMETER_OPENING_DELIMITER = "{"
+	PARAMETER_CLOSING_DELIMITER = "}"
+)
+
+var (
+	ErrUnresolvedPath = errors.New("path has not been resolved")
+	ErrNoParent       = errors.New("the root path has no parent")
+)
+
+// Returns the name to use for a node, used when resolving parameterized paths
+type NodeName interface {
+	NodeName() string
+}
+
+// Abstracts a ZooKeeper path, optionally with parameters to be resolved.
+//
+//	path, err := modeled.ParseZPath("/services/{service}/{instance}")
+//
+//	resolved, err := path.Resolved("web", "instance-1") // "/services/web/instance-1"
+type ZPath struct {
+	nodes []string
+}
+
+// The root path: "/"
+var ROOT = &ZPath{}
+
+// Parse the given path, nodes of the form "{name}" are treated as parameters
+func ParseZPath(path string) (*ZPath, error) {
+	if err := validateZPath(path); err != nil {
+		return nil, err
+	}
+
+	if path == curator.PATH_SEPARATOR {
+		return ROOT, nil
+	}
+
+	return &ZPath{strings.Split(path[1:], curator.PATH_SEPARATOR)}, nil
+}
+
+// Parse the given path or panic if the path is invalid
+func MustParseZPath(path string) *ZPath {
+	if p, err := ParseZPath(path); err != nil {
+		panic(fmt.Errorf("invalid path %q, %s", path, err))
+	} else {
+		return p
+	}
+}
+
+func validateZPath(path string) error {
+	if err := curator.ValidatePath(path); err != nil {
+		return err
+	}
+
+	for _, node := range strings.Split(path[1:], curator.PATH_SEPARATOR) {
+		if isParameter(node) {
+			continue
+		}
+
+		if strings.Contains(node, PARAMETER_OPENING_DELIMITER) || strings.Contains(node, PARAMETER_CLOSING_DELIMITER) {
+			return fmt.Errorf("invalid parameter node %q", node)
+		}
+	}
+
+	return nil
+}
+
+func validateNodeName(name string) error {
+	if len(name) == 0 {
+		return errors.New("node name cannot be empty")
+	} else if strings.Contains(name, curator.PATH_SEPARATOR) {
+		return fmt.Errorf("node name %q must not contain %s", name, curator.PATH_SEPARATOR)
+	}
+
+	return validateZPath(curator.PATH_SEPARATOR + name)
+}
+
+func isParameter(node string) bool {
+	return strings.HasPrefix(node, PARAMETER_OPENING_DELIMITER) && strings.HasSuffix(node, PARAMETER_CLOSING_DELIMITER)
+}
+
+// Return true if this is the root path
+func (p *ZPath) IsRoot() bool { return len(p.nodes) == 0 }
+
+// Return the last node of the path
+func (p *ZPath) NodeName() string {
+	if p.IsRoot() {
+		return ""
+	}
+
+	return p.nodes[len(p.nodes)-1]
+}
+
+// Return true if the path contains no unresolved parameters
+func (p *ZPath) IsResolved() bool {
+	for _, node := range p.nodes {
+		if isParameter(node) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Return the names of the unresolved parameters, in order
+func (p *ZPath) Parameters() []string {
+	var names []string
+
+	for _, node := range p.nodes {
+		if isParameter(node) {
+			names = append(names, node[len(PARAMETER_OPENING_DELIMITER):len(node)-len(PARAMETER_CLOSING_DELIMITER)])
+		}
+	}
+
+	return names
+}
+
+// Return the parent path
+func (p *ZPath) Parent() (*ZPath, error) {
+	if p.IsRoot() {
+		return nil, ErrNoParent
+	}
+
+	n := len(p.nodes) - 1
+
+	return &ZPath{p.nodes[:n:n]}, nil
+}
+
+// Return a path with the given child node appended, the child may be a parameter
+func (p *ZPath) Child(name string) (*ZPath, error) {
+	if err := validateNodeName(name); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]string, len(p.nodes), len(p.nodes)+1)
+
+	copy(nodes, p.nodes)
+
+	return &ZPath{append(nodes, name)}, nil
+}
+
+// Return true if this path starts with the given path, parameters match any node
+func (p *ZPath) StartsWith(other *ZPath) bool {
+	if len(other.nodes) > len(p.nodes) {
+		return false
+	}
+
+	for i, node := range other.nodes {
+		if node != p.nodes[i] && !isParameter(node) && !isParameter(p.nodes[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Return a path where the parameters are replaced, in order, by the given values.
+//
+// Values implementing NodeName use NodeName(), any others are formatted with fmt.Sprint.
+func (p *ZPath) Resolved(parameters ...interface{}) (*ZPath, error) {
+	nodes := make([]string, len(p.nodes))
+
+	i := 0
+
+	for n, node := range p.nodes {
+		if !isParameter(node) {
+			nodes[n] = node
+		} else if i < len(parameters) {
+			var name string
+
+			if nodeName, ok := parameters[i].(NodeName); ok {
+				name = nodeName.NodeName()
+			} else {
+				name = fmt.Sprint(parameters[i])
+			}
+
+			if err := validateNodeName(name); err != nil || isParameter(name) {
+				return nil, fmt.Errorf("invalid value %q for parameter %s", name, node)
+			}
+
+			nodes[n] = name
+
+			i++
+		} else {
+			nodes[n] = node
+		}
+	}
+
+	if i < len(parameters) {
+		return nil, fmt.Errorf("too many parameters, expected %d got %d", i, len(parameters))
+	}
+
+	return &ZPath{nodes}, nil
+}
+
+// Return the full path, the path must be resolved
+func (p *ZPath) FullPath() (string, error) {
+	if !p.IsResolved() {
+		return "", ErrUnresolvedPath
+	}
+
+	return p.String(), nil
+}
+
+func (p *ZPath) String() string {
+	return curator.PATH_SEPARATOR + strings.Join(p.nodes, curator.PATH_SEPARATOR)
+}
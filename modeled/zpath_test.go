@@ -0,0 +1,98 @@
+package modeled
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testNodeName string
+
+func (n testNodeName) NodeName() string { return "node-" + string(n) }
+
+func TestZPath(t *testing.T) {
+	Convey("Given a parameterized path", t, func() {
+		path, err := ParseZPath("/services/{service}/{instance}")
+
+		So(err, ShouldBeNil)
+		So(path.String(), ShouldEqual, "/services/{service}/{instance}")
+		So(path.IsRoot(), ShouldBeFalse)
+		So(path.IsResolved(), ShouldBeFalse)
+		So(path.NodeName(), ShouldEqual, "{instance}")
+		So(path.Parameters(), ShouldResemble, []string{"service", "instance"})
+
+		Convey("Full path requires the path to be resolved", func() {
+			_, err := path.FullPath()
+
+			So(err, ShouldEqual, ErrUnresolvedPath)
+		})
+
+		Convey("When resolve all the parameters", func() {
+			resolved, err := path.Resolved("web", testNodeName("1"))
+
+			So(err, ShouldBeNil)
+			So(resolved.IsResolved(), ShouldBeTrue)
+
+			fullPath, err := resolved.FullPath()
+
+			So(err, ShouldBeNil)
+			So(fullPath, ShouldEqual, "/services/web/node-1")
+			So(resolved.StartsWith(path), ShouldBeTrue)
+		})
+
+		Convey("When resolve some of the parameters", func() {
+			resolved, err := path.Resolved("web")
+
+			So(err, ShouldBeNil)
+			So(resolved.String(), ShouldEqual, "/services/web/{instance}")
+			So(resolved.IsResolved(), ShouldBeFalse)
+		})
+
+		Convey("When resolve with too many or invalid parameters", func() {
+			_, err := path.Resolved("web", "1", "2")
+
+			So(err, ShouldNotBeNil)
+
+			_, err = path.Resolved("a/b")
+
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("When navigate to parent and child", func() {
+			parent, err := path.Parent()
+
+			So(err, ShouldBeNil)
+			So(parent.String(), ShouldEqual, "/services/{service}")
+
+			child, err := parent.Child("status")
+
+			So(err, ShouldBeNil)
+			So(child.String(), ShouldEqual, "/services/{service}/status")
+			So(path.String(), ShouldEqual, "/services/{service}/{instance}")
+
+			_, err = parent.Child("")
+
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given the root path", t, func() {
+		path, err := ParseZPath("/")
+
+		So(err, ShouldBeNil)
+		So(path.IsRoot(), ShouldBeTrue)
+		So(path.String(), ShouldEqual, "/")
+
+		_, err = path.Parent()
+
+		So(err, ShouldEqual, ErrNoParent)
+	})
+
+	Convey("Given invalid paths", t, func() {
+		for _, p := range []string{"", "relative", "/trailing/", "/a//b", "/bad{param", "/bad}param"} {
+			_, err := ParseZPath(p)
+
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -55,12 +55,10 @@ func (n *namespaceImpl) unfixForNamespace(path string) string {
 	if len(n.namespace) > 0 && len(path) > 0 {
 		prefix := JoinPath(n.namespace)
 
-		if strings.HasPrefix(path, prefix) {
-			if len(prefix) < len(path) {
-				return path[len(prefix):]
-			} else {
-				return PATH_SEPARATOR
-			}
+		if path == prefix {
+			return PATH_SEPARATOR
+		} else if strings.HasPrefix(path, prefix+PATH_SEPARATOR) {
+			return path[len(prefix):]
 		}
 	}
 
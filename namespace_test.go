@@ -0,0 +1,100 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NamespaceFacadeTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestNamespaceFacade(t *testing.T) {
+	suite.Run(t, new(NamespaceFacadeTestSuite))
+}
+
+func (s *NamespaceFacadeTestSuite) TestUsingNamespace() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		facade := client.UsingNamespace("tenant")
+
+		assert.Equal(s.T(), "tenant", facade.Namespace())
+		assert.Equal(s.T(), facade, client.UsingNamespace("tenant"))
+		assert.Equal(s.T(), client.ZookeeperClient(), facade.ZookeeperClient())
+
+		conn.On("Exists", "/tenant").Return(true, nil, nil).Once()
+		conn.On("Set", "/tenant/node", data, AnyVersion).Return(stat, nil).Once()
+
+		stat2, err := facade.SetData().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), stat, stat2)
+		assert.NoError(s.T(), err)
+
+		// the facade of the facade uses its own namespace
+		conn.On("Exists", "/other").Return(true, nil, nil).Once()
+		conn.On("Delete", "/other/node", AnyVersion).Return(nil).Once()
+
+		assert.NoError(s.T(), facade.UsingNamespace("other").Delete().ForPath("/node"))
+	})
+}
+
+func (s *NamespaceFacadeTestSuite) TestUnfixForNamespace() {
+	n := &namespaceImpl{namespace: "tenant"}
+
+	assert.Equal(s.T(), "/", n.unfixForNamespace("/tenant"))
+	assert.Equal(s.T(), "/node", n.unfixForNamespace("/tenant/node"))
+	assert.Equal(s.T(), "/tenants/node", n.unfixForNamespace("/tenants/node"))
+	assert.Equal(s.T(), "", n.unfixForNamespace(""))
+}
+
+func TestNamespaceFacadeState(t *testing.T) {
+	conn := &mockConn{log: t.Logf}
+
+	client := (&CuratorFrameworkBuilder{
+		ZookeeperDialer: NewZookeeperDialer(func(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (ZookeeperConnection, <-chan zk.Event, error) {
+			return conn, make(chan zk.Event), nil
+		}),
+	}).ConnectString("connStr").Build()
+
+	assert.NoError(t, client.Start())
+
+	facade := client.UsingNamespace("tenant")
+
+	assert.True(t, facade.Started())
+
+	conn.On("Close").Return().Once()
+
+	assert.NoError(t, client.Close())
+
+	// the facade shares the lifecycle of the client
+	assert.False(t, facade.Started())
+	assert.Equal(t, STOPPED, facade.State())
+
+	conn.AssertExpectations(t)
+}
+
+func (s *NamespaceFacadeTestSuite) TestNonNamespaceView() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		view := client.NonNamespaceView()
+
+		assert.Equal(s.T(), "", view.Namespace())
+
+		// the absolute paths are used without ensuring the namespace
+		conn.On("Get", CONFIG_NODE).Return(data, stat, nil).Once()
+
+		data2, err := view.GetData().ForPath(CONFIG_NODE)
+
+		assert.Equal(s.T(), data, data2)
+		assert.NoError(s.T(), err)
+
+		conn.On("Children", "/parent").Return([]string{"child"}, stat, nil).Once()
+
+		children, err := view.GetChildren().ForPath("/parent")
+
+		assert.Equal(s.T(), []string{"child"}, children)
+		assert.NoError(s.T(), err)
+	})
+}
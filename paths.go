@@ -15,6 +15,9 @@ const (
 	PATH_SEPARATOR = "/"
 )
 
+// The max number of times a node is emptied again when its children are created concurrently while it is deleted
+const DELETE_CHILDREN_MAX_RETRIES = 10
+
 type PathAndNode struct {
 	Path, Node string
 }
@@ -138,6 +141,17 @@ func ValidatePath(path string) error {
 
 // Make sure all the nodes in the path are created
 func MakeDirs(conn ZookeeperConnection, path string, makeLastNode bool, aclProvider ACLProvider) error {
+	return makeDirs(conn, path, makeLastNode, aclProvider, false)
+}
+
+// Make sure all the nodes in the path are created, the missing nodes are created as containers,
+// which are deleted by the server when they become empty (requires ZooKeeper 3.5.1+).
+// Falls back to persistent nodes if the server or the connection doesn't support containers.
+func MakeContainerDirs(conn ZookeeperConnection, path string, makeLastNode bool, aclProvider ACLProvider) error {
+	return makeDirs(conn, path, makeLastNode, aclProvider, true)
+}
+
+func makeDirs(conn ZookeeperConnection, path string, makeLastNode bool, aclProvider ACLProvider, asContainers bool) error {
 	if err := ValidatePath(path); err != nil {
 		return err
 	}
@@ -172,6 +186,16 @@ func MakeDirs(conn ZookeeperConnection, path string, makeLastNode bool, aclProvi
 				acls = OPEN_ACL_UNSAFE
 			}
 
+			if containerConn, ok := conn.(ContainerZookeeperConnection); ok && asContainers {
+				if _, err := containerConn.CreateContainer(subPath, []byte{}, acls); err == nil || err == zk.ErrNodeExists {
+					continue
+				} else if err != ErrUnimplemented && err != zk.ErrBadArguments {
+					return err
+				}
+
+				asContainers = false // the server doesn't support containers
+			}
+
 			if _, err := conn.Create(subPath, []byte{}, int32(PERSISTENT), acls); err != nil && err != zk.ErrNodeExists {
 				return err
 			}
@@ -187,30 +211,50 @@ func DeleteChildren(conn ZookeeperConnection, path string, deleteSelf bool) erro
 		return err
 	}
 
+	if deleteSelf {
+		return deleteTree(conn, path, AnyVersion)
+	}
+
+	return deleteChildren(conn, path)
+}
+
+func deleteChildren(conn ZookeeperConnection, path string) error {
 	if children, _, err := conn.Children(path); err != nil {
 		return err
 	} else {
 		for _, child := range children {
-			if err := DeleteChildren(conn, JoinPath(path, child), true); err != nil {
+			if err := deleteTree(conn, JoinPath(path, child), AnyVersion); err != nil {
 				return err
 			}
 		}
 	}
 
-	if deleteSelf {
-		if err := conn.Delete(path, -1); err != nil {
-			switch err {
-			case zk.ErrNotEmpty:
-				return DeleteChildren(conn, path, true)
-			case zk.ErrNoNode:
-				return nil
-			default:
-				return err
+	return nil
+}
+
+// Delete the node with its children, the node is emptied again if children are created concurrently,
+// up to DELETE_CHILDREN_MAX_RETRIES times before failing with zk.ErrNotEmpty
+func deleteTree(conn ZookeeperConnection, path string, version int32) error {
+	for retries := 0; ; retries++ {
+		if err := deleteChildren(conn, path); err == zk.ErrNoNode {
+			return nil // deleted concurrently
+		} else if err != nil {
+			return err
+		}
+
+		switch err := conn.Delete(path, version); err {
+		case zk.ErrNotEmpty:
+			if retries < DELETE_CHILDREN_MAX_RETRIES {
+				continue
 			}
+
+			return err
+		case zk.ErrNoNode:
+			return nil
+		default:
+			return err
 		}
 	}
-
-	return nil
 }
 
 type EnsurePath interface {
@@ -120,6 +120,64 @@ func TestMakeDirs(t *testing.T) {
 	acls.AssertExpectations(t)
 }
 
+func TestMakeContainerDirs(t *testing.T) {
+	// create `parent` and `child` as containers
+	conn := &mockConn{}
+
+	conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+	conn.On("CreateContainer", "/parent", []byte{}, OPEN_ACL_UNSAFE).Return("/parent", nil).Once()
+	conn.On("Exists", "/parent/child").Return(false, nil, nil).Once()
+	conn.On("CreateContainer", "/parent/child", []byte{}, OPEN_ACL_UNSAFE).Return("/parent/child", nil).Once()
+
+	assert.NoError(t, MakeContainerDirs(conn, "/parent/child/node", false, nil))
+
+	conn.AssertExpectations(t)
+
+	// fall back to persistent nodes if the server doesn't support containers
+	conn = &mockConn{}
+
+	conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+	conn.On("CreateContainer", "/parent", []byte{}, OPEN_ACL_UNSAFE).Return("", zk.ErrBadArguments).Once()
+	conn.On("Create", "/parent", []byte{}, int32(PERSISTENT), OPEN_ACL_UNSAFE).Return("/parent", nil).Once()
+	conn.On("Exists", "/parent/child").Return(false, nil, nil).Once()
+	conn.On("Create", "/parent/child", []byte{}, int32(PERSISTENT), OPEN_ACL_UNSAFE).Return("/parent/child", nil).Once()
+
+	assert.NoError(t, MakeContainerDirs(conn, "/parent/child/node", false, nil))
+
+	conn.AssertExpectations(t)
+
+	// fall back to persistent nodes if the server doesn't implement the createContainer request
+	conn = &mockConn{}
+
+	conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+	conn.On("CreateContainer", "/parent", []byte{}, OPEN_ACL_UNSAFE).Return("", ErrUnimplemented).Once()
+	conn.On("Create", "/parent", []byte{}, int32(PERSISTENT), OPEN_ACL_UNSAFE).Return("/parent", nil).Once()
+
+	assert.NoError(t, MakeContainerDirs(conn, "/parent/node", false, nil))
+
+	conn.AssertExpectations(t)
+
+	// create persistent nodes if the connection doesn't support containers
+	conn = &mockConn{}
+
+	conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+	conn.On("Create", "/parent", []byte{}, int32(PERSISTENT), OPEN_ACL_UNSAFE).Return("/parent", nil).Once()
+
+	assert.NoError(t, MakeContainerDirs(struct{ ZookeeperConnection }{conn}, "/parent/node", false, nil))
+
+	conn.AssertExpectations(t)
+
+	// fail to create `parent`
+	conn = &mockConn{}
+
+	conn.On("Exists", "/parent").Return(false, nil, nil).Once()
+	conn.On("CreateContainer", "/parent", []byte{}, OPEN_ACL_UNSAFE).Return("", zk.ErrNoAuth).Once()
+
+	assert.EqualError(t, MakeContainerDirs(conn, "/parent/child/node", false, nil), zk.ErrNoAuth.Error())
+
+	conn.AssertExpectations(t)
+}
+
 func TestDeleteChildren(t *testing.T) {
 	// Delete children
 	conn := &mockConn{}
@@ -0,0 +1,938 @@
+package curator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The opcodes of the requests, the zk package only sends the opCreate requests
+const (
+	opError           = -1
+	opCreate          = 1
+	opDelete          = 2
+	opGetData         = 4
+	opSetData         = 5
+	opSync            = 9
+	opCheck           = 13
+	opMulti           = 14
+	opCreate2         = 15
+	opReconfig        = 16
+	opRemoveWatches   = 18
+	opCreateContainer = 19
+	opCreateTTL       = 21
+	opAddWatch        = 106
+)
+
+// The modes of the addWatch requests and the watcher types of the removeWatches requests
+const (
+	addWatchModePersistent          = 0
+	addWatchModePersistentRecursive = 1
+	watcherTypePersistent           = 4
+	watcherTypePersistentRecursive  = 5
+)
+
+// The error code of an operation the server doesn't implement, e.g. a createContainer request sent to ZooKeeper 3.4
+const errUnimplemented = -6
+
+// The error code of an operation abandoned by protocolConn, once it waited for its response longer than the request timeout
+const errOperationTimeout = -7
+
+// The length of an encoded zk.Stat
+const statLength = 68
+
+// The ACL scheme marking the create requests sent as createTTL requests, the ID of the ACL is the TTL in milliseconds
+const ttlScheme = "_curator_ttl"
+
+// The path prefix marking the sync requests sent as addWatch or removeWatches requests,
+// followed by the opcode, the mode and the watched path, e.g. /_curator_watch_106_1/path
+const watchPrefix = "/_curator_watch_"
+
+// The path prefix marking the getData requests sent as reconfig requests, followed by the encoded servers and version,
+// e.g. /_curator_reconfig_joining=server.4%3Dhost4%3A2888%3A3888&version=-1
+const reconfigPrefix = "/_curator_reconfig_"
+
+var (
+	ErrUnimplemented  = errors.New("zk: unimplemented operation")
+	ErrRequestTimeout = errors.New("zk: request timed out") // the request is abandoned, and retried after the connection moves to another server
+	ErrNotRewritten   = errors.New("zk: the extended request requires a connection dialed by DefaultZookeeperDialer")
+)
+
+// The connection created by DefaultZookeeperDialer, which sends the create requests of ZooKeeper 3.5+
+//
+// The zk package only sends the create opcode, so the requests are rewritten on the wire by protocolConn,
+// e.g. a create request of a CONTAINER node is sent as a createContainer request.
+//
+// Without protocolConn the marked requests would reach the server as plain requests of the marked paths,
+// so they fail with ErrNotRewritten, or ErrTTLNotSupported for the TTL nodes, unless the connection is dialed through it.
+type extendedConn struct {
+	*zk.Conn
+
+	rewriting bool // the connection is dialed through protocolConn, which rewrites the marked requests
+}
+
+func (c *extendedConn) CreateContainer(path string, data []byte, acl []zk.ACL) (string, error) {
+	createdPath, err := c.Conn.Create(path, data, int32(CONTAINER), acl)
+
+	return createdPath, translateError(err)
+}
+
+func (c *extendedConn) CreateTTL(path string, data []byte, flags int32, acl []zk.ACL, ttl time.Duration) (string, error) {
+	if !c.rewriting {
+		return "", ErrTTLNotSupported // the marked ACL would be rejected as an invalid ACL
+	}
+
+	createdPath, err := c.Conn.Create(path, data, flags, withTTL(acl, ttl))
+
+	return createdPath, translateError(err)
+}
+
+func (c *extendedConn) AddPersistentWatch(path string, recursive bool) error {
+	if !c.rewriting {
+		return ErrNotRewritten
+	}
+
+	mode := addWatchModePersistent
+
+	if recursive {
+		mode = addWatchModePersistentRecursive
+	}
+
+	_, err := c.Conn.Sync(watchPath(opAddWatch, mode, path))
+
+	return translateError(err)
+}
+
+func (c *extendedConn) RemovePersistentWatch(path string, recursive bool) error {
+	if !c.rewriting {
+		return ErrNotRewritten
+	}
+
+	watcherType := watcherTypePersistent
+
+	if recursive {
+		watcherType = watcherTypePersistentRecursive
+	}
+
+	_, err := c.Conn.Sync(watchPath(opRemoveWatches, watcherType, path))
+
+	return translateError(err)
+}
+
+func (c *extendedConn) Reconfig(joining, leaving, members []string, fromConfig int64) ([]byte, *zk.Stat, error) {
+	if !c.rewriting {
+		return nil, nil, ErrNotRewritten
+	}
+
+	config, stat, err := c.Conn.Get(reconfigPath(joining, leaving, members, fromConfig))
+
+	return config, stat, translateError(err)
+}
+
+func (c *extendedConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	createdPath, err := c.Conn.Create(path, data, flags, acl)
+
+	return createdPath, translateError(err)
+}
+
+func (c *extendedConn) Exists(path string) (bool, *zk.Stat, error) {
+	exists, stat, err := c.Conn.Exists(path)
+
+	return exists, stat, translateError(err)
+}
+
+func (c *extendedConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	exists, stat, events, err := c.Conn.ExistsW(path)
+
+	return exists, stat, events, translateError(err)
+}
+
+func (c *extendedConn) Delete(path string, version int32) error {
+	return translateError(c.Conn.Delete(path, version))
+}
+
+func (c *extendedConn) Get(path string) ([]byte, *zk.Stat, error) {
+	data, stat, err := c.Conn.Get(path)
+
+	return data, stat, translateError(err)
+}
+
+func (c *extendedConn) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	data, stat, events, err := c.Conn.GetW(path)
+
+	return data, stat, events, translateError(err)
+}
+
+func (c *extendedConn) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	stat, err := c.Conn.Set(path, data, version)
+
+	return stat, translateError(err)
+}
+
+func (c *extendedConn) Children(path string) ([]string, *zk.Stat, error) {
+	children, stat, err := c.Conn.Children(path)
+
+	return children, stat, translateError(err)
+}
+
+func (c *extendedConn) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	children, stat, events, err := c.Conn.ChildrenW(path)
+
+	return children, stat, events, translateError(err)
+}
+
+func (c *extendedConn) GetACL(path string) ([]zk.ACL, *zk.Stat, error) {
+	acl, stat, err := c.Conn.GetACL(path)
+
+	return acl, stat, translateError(err)
+}
+
+func (c *extendedConn) SetACL(path string, acl []zk.ACL, version int32) (*zk.Stat, error) {
+	stat, err := c.Conn.SetACL(path, acl, version)
+
+	return stat, translateError(err)
+}
+
+func (c *extendedConn) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	if !c.rewriting && hasTTL(ops) {
+		return nil, ErrTTLNotSupported
+	}
+
+	responses, err := c.Conn.Multi(ops...)
+
+	return responses, translateError(err)
+}
+
+func (c *extendedConn) Sync(path string) (string, error) {
+	syncedPath, err := c.Conn.Sync(path)
+
+	return syncedPath, translateError(err)
+}
+
+// The zk package doesn't know the error codes of the unimplemented and the abandoned operations
+func translateError(err error) error {
+	if err != nil {
+		switch err.Error() {
+		case fmt.Sprintf("unknown error: %d", errUnimplemented):
+			return ErrUnimplemented
+		case fmt.Sprintf("unknown error: %d", errOperationTimeout):
+			return ErrRequestTimeout
+		}
+	}
+
+	return err
+}
+
+// Append the ACL marking the create request to be sent as a createTTL request
+func withTTL(acls []zk.ACL, ttl time.Duration) []zk.ACL {
+	marked := make([]zk.ACL, len(acls), len(acls)+1)
+
+	copy(marked, acls)
+
+	return append(marked, zk.ACL{Scheme: ttlScheme, ID: strconv.FormatInt(int64(ttl/time.Millisecond), 10)})
+}
+
+// Return the path marking the sync request to be sent as an addWatch or removeWatches request
+func watchPath(opcode, mode int, path string) string {
+	if path == PATH_SEPARATOR {
+		path = ""
+	}
+
+	return fmt.Sprintf("%s%d_%d%s", watchPrefix, opcode, mode, path)
+}
+
+// Parse the opcode, the mode and the watched path of a marked sync request
+func parseWatchPath(markedPath string) (int32, int32, string, bool) {
+	if !strings.HasPrefix(markedPath, watchPrefix) {
+		return 0, 0, "", false
+	}
+
+	rest := markedPath[len(watchPrefix):]
+	path := PATH_SEPARATOR
+
+	if idx := strings.Index(rest, PATH_SEPARATOR); idx != -1 {
+		rest, path = rest[:idx], rest[idx:]
+	}
+
+	var opcode, mode int32
+
+	if n, err := fmt.Sscanf(rest, "%d_%d", &opcode, &mode); err != nil || n != 2 {
+		return 0, 0, "", false
+	} else if opcode != opAddWatch && opcode != opRemoveWatches {
+		return 0, 0, "", false
+	}
+
+	return opcode, mode, path, true
+}
+
+// Return the path marking the getData request to be sent as a reconfig request
+func reconfigPath(joining, leaving, members []string, fromConfig int64) string {
+	values := url.Values{"version": {strconv.FormatInt(fromConfig, 10)}}
+
+	if joining != nil {
+		values.Set("joining", strings.Join(joining, ","))
+	}
+
+	if leaving != nil {
+		values.Set("leaving", strings.Join(leaving, ","))
+	}
+
+	if members != nil {
+		values.Set("members", strings.Join(members, ","))
+	}
+
+	return reconfigPrefix + values.Encode()
+}
+
+// Parse the servers and the version of a marked getData request, the servers not given are nil
+func parseReconfigPath(markedPath string) (joining, leaving, members []byte, fromConfig int64, ok bool) {
+	if !strings.HasPrefix(markedPath, reconfigPrefix) {
+		return nil, nil, nil, 0, false
+	}
+
+	values, err := url.ParseQuery(markedPath[len(reconfigPrefix):])
+
+	if err != nil {
+		return nil, nil, nil, 0, false
+	}
+
+	if fromConfig, err = strconv.ParseInt(values.Get("version"), 10, 64); err != nil {
+		return nil, nil, nil, 0, false
+	}
+
+	servers := func(key string) []byte {
+		if _, exists := values[key]; exists {
+			return []byte(values.Get(key))
+		}
+
+		return nil
+	}
+
+	return servers("joining"), servers("leaving"), servers("members"), fromConfig, true
+}
+
+// Wrap the dialer of the zk package, so the connections rewrite the create requests,
+// and authenticate with the SASL mechanism if it is given.
+//
+// The connections ask for a read-only session if readOnly is given, which is set when the server grants it,
+// and abandon the requests waiting for their responses longer than the request timeout if it is positive.
+func newProtocolDialer(dialer zk.Dialer, sasl SASLMechanism, readOnly *AtomicBool, requestTimeout time.Duration) zk.Dialer {
+	if dialer == nil {
+		dialer = net.DialTimeout
+	}
+
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		if conn, err := dialer(network, address, timeout); err != nil {
+			return nil, err
+		} else {
+			return &protocolConn{Conn: conn, sasl: sasl, readOnly: readOnly, requestTimeout: requestTimeout, responses: make(map[int32]func([]byte) []byte)}, nil
+		}
+	}
+}
+
+// A network connection rewriting the create and the marked sync and getData requests,
+// the zk package writes a whole packet at a time.
+//
+// The results of the rewritten creates in a transaction are create2 results with the stat of the node,
+// which the zk package can't decode, so they are rewritten to the create results.
+// The results of the rewritten sync requests are rewritten to the sync results,
+// and the results of the reconfig requests have the layout of the getData results, so they are passed through.
+//
+// The SASL authentication runs when the connect response is read, before the zk package sends any request.
+// The zk package doesn't know the read-only flag of the connect request and response, so it is appended and read here.
+//
+// The zk package only gives up on a request when the connection stays silent for 2/3 of the session timeout,
+// so with a request timeout the read deadline is shortened while a request waits for its response.
+// Once it passes, the waiting requests fail with ErrRequestTimeout and the connection is dropped,
+// so the zk package reconnects the session to the next server.
+type protocolConn struct {
+	net.Conn
+
+	sasl           SASLMechanism
+	readOnly       *AtomicBool // the session is read-only, nil unless a read-only session is allowed
+	requestTimeout time.Duration
+	handshaked     bool
+	connected      bool
+	lock           sync.Mutex
+	responses      map[int32]func([]byte) []byte // rewrite the responses of the rewritten requests by xid
+	response       []byte                        // the rest of the response being read
+	pending        map[int32]time.Time           // the time the requests waiting for their responses were sent, by xid
+	readDeadline   time.Time                     // the read deadline set by the zk package
+	err            error                         // the error returned once the responses of the abandoned requests are read
+}
+
+func (c *protocolConn) SetReadDeadline(t time.Time) error {
+	if c.requestTimeout <= 0 {
+		return c.Conn.SetReadDeadline(t)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.readDeadline = t
+
+	return c.Conn.SetReadDeadline(c.deadline())
+}
+
+// Return the earlier of the read deadline of the zk package and the request timeout of the oldest waiting request
+func (c *protocolConn) deadline() time.Time {
+	deadline := c.readDeadline
+
+	for _, sent := range c.pending {
+		if expires := sent.Add(c.requestTimeout); deadline.IsZero() || expires.Before(deadline) {
+			deadline = expires
+		}
+	}
+
+	return deadline
+}
+
+// Track the request waiting for its response, the pings and the internal requests have negative xids
+func (c *protocolConn) trackRequest(b []byte) {
+	if c.requestTimeout <= 0 || len(b) < 8 {
+		return
+	}
+
+	if xid := int32(binary.BigEndian.Uint32(b[4:])); xid > 0 {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.pending == nil {
+			c.pending = make(map[int32]time.Time)
+		}
+
+		c.pending[xid] = time.Now()
+
+		c.Conn.SetReadDeadline(c.deadline())
+	}
+}
+
+// Fail the waiting requests with the errOperationTimeout responses if one of them timed out,
+// the connection is then dropped, since its responses may still arrive.
+func (c *protocolConn) abandonRequests(err error) ([]byte, error) {
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() || c.requestTimeout <= 0 {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now, timedOut := time.Now(), false
+
+	for _, sent := range c.pending {
+		if !now.Before(sent.Add(c.requestTimeout)) {
+			timedOut = true
+		}
+	}
+
+	if !timedOut {
+		return nil, err
+	}
+
+	var responses []byte
+
+	code := int32(errOperationTimeout)
+
+	for xid := range c.pending {
+		response := make([]byte, 20)
+
+		binary.BigEndian.PutUint32(response, 16)
+		binary.BigEndian.PutUint32(response[4:], uint32(xid))
+		binary.BigEndian.PutUint32(response[16:], uint32(code))
+
+		responses = append(responses, response...)
+
+		delete(c.responses, xid)
+	}
+
+	c.pending = nil
+
+	return responses, nil
+}
+
+func (c *protocolConn) Write(b []byte) (int, error) {
+	if !c.handshaked {
+		c.handshaked = true // the connect request has no header
+
+		if c.readOnly == nil || len(b) < 4 || int(binary.BigEndian.Uint32(b)) != len(b)-4 {
+			return c.Conn.Write(b)
+		}
+
+		packet := append(append([]byte(nil), b...), 1)
+
+		binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+
+		if _, err := c.Conn.Write(packet); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	c.trackRequest(b)
+
+	packet, opcode, rewritten := rewriteRequest(b)
+
+	if !rewritten {
+		return c.Conn.Write(b)
+	}
+
+	var rewriteResponse func([]byte) []byte
+
+	switch opcode {
+	case opMulti:
+		rewriteResponse = rewriteMultiResponse
+	case opSync:
+		rewriteResponse = func(packet []byte) []byte {
+			return rewriteSyncResponse(packet, b[12:])
+		}
+	}
+
+	if rewriteResponse != nil {
+		c.lock.Lock()
+		c.responses[int32(binary.BigEndian.Uint32(b[4:]))] = rewriteResponse
+		c.lock.Unlock()
+	}
+
+	if _, err := c.Conn.Write(packet); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (c *protocolConn) Read(b []byte) (int, error) {
+	if len(c.response) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		} else if response, err := c.readResponse(); err == nil {
+			c.response = response
+		} else if responses, err := c.abandonRequests(err); err != nil {
+			return 0, err
+		} else {
+			c.response, c.err = responses, ErrRequestTimeout
+		}
+	}
+
+	n := copy(b, c.response)
+
+	c.response = c.response[n:]
+
+	return n, nil
+}
+
+// Read a whole response packet, so the packets stay aligned when a transaction result is rewritten
+func (c *protocolConn) readResponse() ([]byte, error) {
+	var header [4]byte
+
+	if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+		return nil, err
+	}
+
+	packet := make([]byte, 4+binary.BigEndian.Uint32(header[:]))
+
+	copy(packet, header[:])
+
+	if _, err := io.ReadFull(c.Conn, packet[4:]); err != nil {
+		return nil, err
+	}
+
+	if (c.sasl != nil || c.readOnly != nil) && !c.connected {
+		c.connected = true // the connect response has no header
+
+		if c.readOnly != nil {
+			c.readOnly.Set(connectResponseReadOnly(packet))
+		}
+
+		// an expired session has no session id
+		if c.sasl != nil && len(packet) >= 20 && binary.BigEndian.Uint64(packet[12:]) != 0 {
+			if err := authenticateSASL(c.Conn, c.sasl); err != nil {
+				return nil, err
+			}
+		}
+
+		return packet, nil
+	}
+
+	if len(packet) < 20 {
+		return packet, nil
+	}
+
+	xid := int32(binary.BigEndian.Uint32(packet[4:]))
+
+	c.lock.Lock()
+	rewriteResponse := c.responses[xid]
+	delete(c.responses, xid)
+	delete(c.pending, xid)
+	c.lock.Unlock()
+
+	if rewriteResponse != nil {
+		return rewriteResponse(packet), nil
+	}
+
+	return packet, nil
+}
+
+// Return the read-only flag following the length, protocol version, timeout, session id and password of a connect response
+func connectResponseReadOnly(packet []byte) bool {
+	if len(packet) < 24 {
+		return false
+	}
+
+	pos := 24 + int(int32(binary.BigEndian.Uint32(packet[20:])))
+
+	return pos >= 24 && pos < len(packet) && packet[pos] != 0
+}
+
+// Rewrite a request packet with the length, xid, opcode and the request body
+func rewriteRequest(b []byte) ([]byte, int32, bool) {
+	if len(b) < 12 || int(binary.BigEndian.Uint32(b)) != len(b)-4 {
+		return nil, 0, false
+	}
+
+	opcode := int32(binary.BigEndian.Uint32(b[8:]))
+
+	var body bytes.Buffer
+
+	switch opcode {
+	case opCreate:
+		if n, rewrittenOpcode, ok := rewriteCreateRequest(&body, b[12:]); !ok || n != len(b)-12 || rewrittenOpcode == opCreate {
+			return nil, 0, false
+		} else {
+			return newPacket(b[4:8], rewrittenOpcode, body.Bytes()), opcode, true
+		}
+
+	case opMulti:
+		if rewritten := rewriteMultiRequest(&body, b[12:]); !rewritten {
+			return nil, 0, false
+		} else {
+			return newPacket(b[4:8], opcode, body.Bytes()), opcode, true
+		}
+
+	case opSync:
+		r := bytes.NewReader(b[12:])
+
+		if markedPath := readBuffer(r); markedPath == nil || r.Len() != 0 {
+			return nil, 0, false
+		} else if rewrittenOpcode, mode, path, ok := parseWatchPath(string(markedPath)); !ok {
+			return nil, 0, false
+		} else {
+			writeBuffer(&body, []byte(path))
+			binary.Write(&body, binary.BigEndian, mode)
+
+			return newPacket(b[4:8], rewrittenOpcode, body.Bytes()), opcode, true
+		}
+
+	case opGetData:
+		r := bytes.NewReader(b[12:])
+
+		if markedPath := readBuffer(r); markedPath == nil || r.Len() != 1 {
+			return nil, 0, false
+		} else if joining, leaving, members, fromConfig, ok := parseReconfigPath(string(markedPath)); !ok {
+			return nil, 0, false
+		} else {
+			writeBuffer(&body, joining)
+			writeBuffer(&body, leaving)
+			writeBuffer(&body, members)
+			binary.Write(&body, binary.BigEndian, fromConfig)
+
+			return newPacket(b[4:8], opReconfig, body.Bytes()), opcode, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+func newPacket(xid []byte, opcode int32, body []byte) []byte {
+	packet := make([]byte, 12+len(body))
+
+	binary.BigEndian.PutUint32(packet, uint32(8+len(body)))
+	copy(packet[4:], xid)
+	binary.BigEndian.PutUint32(packet[8:], uint32(opcode))
+	copy(packet[12:], body)
+
+	return packet
+}
+
+// Rewrite the create requests in a transaction, which are followed by a header with the done flag
+func rewriteMultiRequest(w *bytes.Buffer, b []byte) bool {
+	rewritten := false
+
+	for pos := 0; ; {
+		if len(b)-pos < 9 {
+			return false
+		}
+
+		opcode := int32(binary.BigEndian.Uint32(b[pos:]))
+		done := b[pos+4] != 0
+		header := b[pos : pos+9]
+
+		pos += 9
+
+		if done {
+			w.Write(header)
+
+			return rewritten && pos == len(b)
+		}
+
+		if opcode == opCreate {
+			var body bytes.Buffer
+
+			if n, rewrittenOpcode, ok := rewriteCreateRequest(&body, b[pos:]); !ok {
+				return false
+			} else {
+				binary.Write(w, binary.BigEndian, rewrittenOpcode)
+				w.Write(header[4:])
+				w.Write(body.Bytes())
+
+				rewritten = rewritten || rewrittenOpcode != opCreate
+				pos += n
+			}
+
+			continue
+		}
+
+		r := bytes.NewReader(b[pos:])
+
+		switch opcode {
+		case opDelete, opCheck:
+			if !skipBuffer(r) || r.Len() < 4 {
+				return false
+			}
+		case opSetData:
+			if !skipBuffer(r) || !skipBuffer(r) || r.Len() < 4 {
+				return false
+			}
+		default:
+			return false
+		}
+
+		n := len(b) - pos - r.Len() + 4 // the version
+
+		w.Write(header)
+		w.Write(b[pos : pos+n])
+
+		pos += n
+	}
+}
+
+// Rewrite a create request with the path, data, ACL and flags, return the length of the scanned request.
+//
+// A CONTAINER node is created with the createContainer opcode, and a node marked with the TTL ACL
+// is created with the createTTL opcode, which appends the TTL to the request.
+func rewriteCreateRequest(w *bytes.Buffer, b []byte) (int, int32, bool) {
+	r := bytes.NewReader(b)
+
+	if !skipBuffer(r) || !skipBuffer(r) {
+		return 0, 0, false
+	}
+
+	var count, flags int32
+
+	if binary.Read(r, binary.BigEndian, &count) != nil {
+		return 0, 0, false
+	}
+
+	aclsStart := len(b) - r.Len()
+	lastAclStart := aclsStart
+
+	var lastScheme, lastID []byte
+
+	for i := int32(0); i < count; i++ {
+		var perms int32
+
+		lastAclStart = len(b) - r.Len()
+
+		if binary.Read(r, binary.BigEndian, &perms) != nil {
+			return 0, 0, false
+		}
+
+		lastScheme = readBuffer(r)
+		lastID = readBuffer(r)
+
+		if lastScheme == nil || lastID == nil {
+			return 0, 0, false
+		}
+	}
+
+	aclsEnd := len(b) - r.Len()
+
+	if binary.Read(r, binary.BigEndian, &flags) != nil {
+		return 0, 0, false
+	}
+
+	n := len(b) - r.Len()
+
+	if count > 0 && string(lastScheme) == ttlScheme {
+		ttl, err := strconv.ParseInt(string(lastID), 10, 64)
+
+		if err != nil {
+			return 0, 0, false
+		}
+
+		w.Write(b[:aclsStart-4])
+		binary.Write(w, binary.BigEndian, count-1)
+		w.Write(b[aclsStart:lastAclStart])
+		binary.Write(w, binary.BigEndian, flags)
+		binary.Write(w, binary.BigEndian, ttl)
+
+		return n, opCreateTTL, true
+	}
+
+	w.Write(b[:aclsEnd])
+	binary.Write(w, binary.BigEndian, flags)
+
+	if flags == int32(CONTAINER) {
+		return n, opCreateContainer, true
+	}
+
+	return n, opCreate, true
+}
+
+// Rewrite the result of an addWatch or removeWatches request to the result of the marked sync request,
+// the body of a failed response isn't decoded by the zk package.
+func rewriteSyncResponse(packet []byte, request []byte) []byte {
+	if len(packet) < 20 || binary.BigEndian.Uint32(packet[16:]) != 0 {
+		return packet
+	}
+
+	var w bytes.Buffer
+
+	w.Write(packet[:20]) // the length, xid, zxid and error
+	w.Write(request)     // the marked path
+
+	rewritten := w.Bytes()
+
+	binary.BigEndian.PutUint32(rewritten, uint32(len(rewritten)-4))
+
+	return rewritten
+}
+
+// Rewrite the create2 results of a transaction response to the create results without the stat
+func rewriteMultiResponse(packet []byte) []byte {
+	var w bytes.Buffer
+
+	w.Write(packet[:20]) // the length, xid, zxid and error
+
+	for pos := 20; ; {
+		if len(packet)-pos < 9 {
+			return packet
+		}
+
+		opcode := int32(binary.BigEndian.Uint32(packet[pos:]))
+		done := packet[pos+4] != 0
+		header := packet[pos : pos+9]
+
+		pos += 9
+
+		if done {
+			w.Write(header)
+			w.Write(packet[pos:])
+
+			break
+		}
+
+		r := bytes.NewReader(packet[pos:])
+
+		switch opcode {
+		case opCreate, opCreate2:
+			if !skipBuffer(r) {
+				return packet
+			}
+		case opSetData:
+			if r.Len() < statLength {
+				return packet
+			}
+
+			r.Seek(statLength, io.SeekCurrent)
+		case opError:
+			if r.Len() < 4 {
+				return packet
+			}
+
+			r.Seek(4, io.SeekCurrent)
+		case opDelete, opCheck:
+		default:
+			return packet
+		}
+
+		n := len(packet) - pos - r.Len()
+
+		if opcode == opCreate2 {
+			if r.Len() < statLength {
+				return packet
+			}
+
+			binary.Write(&w, binary.BigEndian, int32(opCreate))
+			w.Write(header[4:])
+			w.Write(packet[pos : pos+n])
+
+			pos += n + statLength
+		} else {
+			w.Write(header)
+			w.Write(packet[pos : pos+n])
+
+			pos += n
+		}
+	}
+
+	rewritten := w.Bytes()
+
+	binary.BigEndian.PutUint32(rewritten, uint32(len(rewritten)-4))
+
+	return rewritten
+}
+
+// Skip a string or a buffer prefixed with its length, a nil one has the -1 length
+func skipBuffer(r *bytes.Reader) bool {
+	var size int32
+
+	if binary.Read(r, binary.BigEndian, &size) != nil || int(size) > r.Len() {
+		return false
+	}
+
+	if size > 0 {
+		r.Seek(int64(size), io.SeekCurrent)
+	}
+
+	return true
+}
+
+// Write a string or a buffer prefixed with its length, a nil one has the -1 length
+func writeBuffer(w *bytes.Buffer, data []byte) {
+	if data == nil {
+		binary.Write(w, binary.BigEndian, int32(-1))
+	} else {
+		binary.Write(w, binary.BigEndian, int32(len(data)))
+		w.Write(data)
+	}
+}
+
+// Read a string or a buffer prefixed with its length, return nil if it is malformed
+func readBuffer(r *bytes.Reader) []byte {
+	var size int32
+
+	if binary.Read(r, binary.BigEndian, &size) != nil || int(size) > r.Len() {
+		return nil
+	}
+
+	if size <= 0 {
+		return []byte{}
+	}
+
+	buf := make([]byte, size)
+
+	r.Read(buf)
+
+	return buf
+}
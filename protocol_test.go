@@ -0,0 +1,523 @@
+package curator
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferConn struct {
+	net.Conn
+
+	written bytes.Buffer
+	read    bytes.Buffer
+}
+
+func (c *bufferConn) Read(b []byte) (int, error) {
+	return c.read.Read(b)
+}
+
+func (c *bufferConn) Write(b []byte) (int, error) {
+	return c.written.Write(b)
+}
+
+func encodeCreateRequest(body *bytes.Buffer, path string, data []byte, acls []zk.ACL, flags int32) {
+	writeBuffer(body, []byte(path))
+	writeBuffer(body, data)
+	binary.Write(body, binary.BigEndian, int32(len(acls)))
+
+	for _, acl := range acls {
+		binary.Write(body, binary.BigEndian, acl.Perms)
+		writeBuffer(body, []byte(acl.Scheme))
+		writeBuffer(body, []byte(acl.ID))
+	}
+
+	binary.Write(body, binary.BigEndian, flags)
+}
+
+func encodePacket(xid, opcode int32, body []byte) []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int32(8+len(body)))
+	binary.Write(&buf, binary.BigEndian, xid)
+	binary.Write(&buf, binary.BigEndian, opcode)
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+func TestRewriteCreateContainer(t *testing.T) {
+	var body bytes.Buffer
+
+	encodeCreateRequest(&body, "/parent", []byte{}, OPEN_ACL_UNSAFE, int32(CONTAINER))
+
+	packet, _, rewritten := rewriteRequest(encodePacket(3, opCreate, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, encodePacket(3, opCreateContainer, body.Bytes()), packet)
+
+	// keep the other create requests
+	body.Reset()
+
+	encodeCreateRequest(&body, "/node", nil, OPEN_ACL_UNSAFE, int32(EPHEMERAL))
+
+	_, _, rewritten = rewriteRequest(encodePacket(4, opCreate, body.Bytes()))
+
+	assert.False(t, rewritten)
+
+	// keep the other requests
+	_, _, rewritten = rewriteRequest(encodePacket(5, 2, body.Bytes()))
+
+	assert.False(t, rewritten)
+
+	// keep the malformed requests
+	_, _, rewritten = rewriteRequest(encodePacket(6, opCreate, []byte{0, 0, 0, 10, 'a'}))
+
+	assert.False(t, rewritten)
+}
+
+func TestProtocolConn(t *testing.T) {
+	var body bytes.Buffer
+
+	encodeCreateRequest(&body, "/parent", []byte("data"), CREATOR_ALL_ACL, int32(CONTAINER))
+
+	conn := &bufferConn{}
+	protocolConn := &protocolConn{Conn: conn}
+
+	// pass the connect request through
+	connect := encodePacket(0, opCreate, body.Bytes())
+
+	n, err := protocolConn.Write(connect)
+
+	assert.Equal(t, len(connect), n)
+	assert.NoError(t, err)
+	assert.Equal(t, connect, conn.written.Bytes())
+
+	conn.written.Reset()
+
+	n, err = protocolConn.Write(encodePacket(1, opCreate, body.Bytes()))
+
+	assert.Equal(t, len(connect), n)
+	assert.NoError(t, err)
+	assert.Equal(t, encodePacket(1, opCreateContainer, body.Bytes()), conn.written.Bytes())
+}
+
+func TestRewriteCreateTTL(t *testing.T) {
+	var body, expected bytes.Buffer
+
+	encodeCreateRequest(&body, "/node", []byte("data"), withTTL(OPEN_ACL_UNSAFE, time.Minute), int32(PERSISTENT_WITH_TTL))
+	encodeCreateRequest(&expected, "/node", []byte("data"), OPEN_ACL_UNSAFE, int32(PERSISTENT_WITH_TTL))
+	binary.Write(&expected, binary.BigEndian, int64(60000))
+
+	packet, _, rewritten := rewriteRequest(encodePacket(3, opCreate, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, encodePacket(3, opCreateTTL, expected.Bytes()), packet)
+}
+
+func encodeMultiHeader(buf *bytes.Buffer, opcode int32, done bool, err int32) {
+	binary.Write(buf, binary.BigEndian, opcode)
+	binary.Write(buf, binary.BigEndian, done)
+	binary.Write(buf, binary.BigEndian, err)
+}
+
+func TestRewriteMultiRequest(t *testing.T) {
+	var body, expected bytes.Buffer
+
+	encodeMultiHeader(&body, opCreate, false, -1)
+	encodeMultiHeader(&expected, opCreateTTL, false, -1)
+	encodeCreateRequest(&body, "/node1", nil, withTTL(OPEN_ACL_UNSAFE, time.Second), int32(PERSISTENT_SEQUENTIAL_WITH_TTL))
+	encodeCreateRequest(&expected, "/node1", nil, OPEN_ACL_UNSAFE, int32(PERSISTENT_SEQUENTIAL_WITH_TTL))
+	binary.Write(&expected, binary.BigEndian, int64(1000))
+
+	for _, buf := range []*bytes.Buffer{&body, &expected} {
+		encodeMultiHeader(buf, opDelete, false, -1)
+		writeBuffer(buf, []byte("/node2"))
+		binary.Write(buf, binary.BigEndian, int32(3))
+
+		encodeMultiHeader(buf, opSetData, false, -1)
+		writeBuffer(buf, []byte("/node3"))
+		writeBuffer(buf, []byte("data"))
+		binary.Write(buf, binary.BigEndian, int32(-1))
+	}
+
+	encodeMultiHeader(&body, opCreate, false, -1)
+	encodeMultiHeader(&expected, opCreateContainer, false, -1)
+	encodeCreateRequest(&body, "/node4", []byte{}, OPEN_ACL_UNSAFE, int32(CONTAINER))
+	encodeCreateRequest(&expected, "/node4", []byte{}, OPEN_ACL_UNSAFE, int32(CONTAINER))
+
+	for _, buf := range []*bytes.Buffer{&body, &expected} {
+		encodeMultiHeader(buf, opCheck, false, -1)
+		writeBuffer(buf, []byte("/node5"))
+		binary.Write(buf, binary.BigEndian, int32(1))
+
+		encodeMultiHeader(buf, -1, true, -1)
+	}
+
+	packet, opcode, rewritten := rewriteRequest(encodePacket(7, opMulti, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, int32(opMulti), opcode)
+	assert.Equal(t, encodePacket(7, opMulti, expected.Bytes()), packet)
+}
+
+func encodeResponse(xid int32, body []byte) []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int32(16+len(body)))
+	binary.Write(&buf, binary.BigEndian, xid)
+	binary.Write(&buf, binary.BigEndian, int64(100)) // zxid
+	binary.Write(&buf, binary.BigEndian, int32(0))
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+func TestProtocolConnMulti(t *testing.T) {
+	conn := &bufferConn{}
+	protocolConn := &protocolConn{Conn: conn, handshaked: true, responses: make(map[int32]func([]byte) []byte)}
+
+	var request bytes.Buffer
+
+	encodeMultiHeader(&request, opCreate, false, -1)
+	encodeCreateRequest(&request, "/node", nil, withTTL(OPEN_ACL_UNSAFE, time.Second), int32(PERSISTENT_WITH_TTL))
+	encodeMultiHeader(&request, -1, true, -1)
+
+	_, err := protocolConn.Write(encodePacket(7, opMulti, request.Bytes()))
+
+	assert.NoError(t, err)
+
+	var response, expected bytes.Buffer
+
+	encodeMultiHeader(&response, opCreate2, false, 0)
+	encodeMultiHeader(&expected, opCreate, false, 0)
+	writeBuffer(&response, []byte("/node"))
+	writeBuffer(&expected, []byte("/node"))
+	response.Write(make([]byte, statLength))
+
+	for _, buf := range []*bytes.Buffer{&response, &expected} {
+		encodeMultiHeader(buf, opSetData, false, 0)
+		buf.Write(make([]byte, statLength))
+		encodeMultiHeader(buf, -1, true, -1)
+	}
+
+	// the other responses are read as is
+	conn.read.Write(encodeResponse(6, []byte("other")))
+	conn.read.Write(encodeResponse(7, response.Bytes()))
+
+	var header [4]byte
+
+	_, err = io.ReadFull(protocolConn, header[:])
+
+	assert.NoError(t, err)
+
+	other := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	_, err = io.ReadFull(protocolConn, other)
+
+	assert.NoError(t, err)
+	assert.Equal(t, encodeResponse(6, []byte("other"))[4:], other)
+
+	_, err = io.ReadFull(protocolConn, header[:])
+
+	assert.NoError(t, err)
+
+	multi := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	_, err = io.ReadFull(protocolConn, multi)
+
+	assert.NoError(t, err)
+	assert.Equal(t, encodeResponse(7, expected.Bytes())[4:], multi)
+	assert.Empty(t, protocolConn.responses)
+}
+
+func TestRewriteWatchRequest(t *testing.T) {
+	var body, expected bytes.Buffer
+
+	writeBuffer(&body, []byte(watchPath(opAddWatch, addWatchModePersistentRecursive, "/config")))
+	writeBuffer(&expected, []byte("/config"))
+	binary.Write(&expected, binary.BigEndian, int32(addWatchModePersistentRecursive))
+
+	packet, opcode, rewritten := rewriteRequest(encodePacket(3, opSync, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, int32(opSync), opcode)
+	assert.Equal(t, encodePacket(3, opAddWatch, expected.Bytes()), packet)
+
+	// watch the root path
+	body.Reset()
+	expected.Reset()
+
+	writeBuffer(&body, []byte(watchPath(opRemoveWatches, watcherTypePersistent, "/")))
+	writeBuffer(&expected, []byte("/"))
+	binary.Write(&expected, binary.BigEndian, int32(watcherTypePersistent))
+
+	packet, _, rewritten = rewriteRequest(encodePacket(4, opSync, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, encodePacket(4, opRemoveWatches, expected.Bytes()), packet)
+
+	// keep the other sync requests
+	body.Reset()
+
+	writeBuffer(&body, []byte("/config"))
+
+	_, _, rewritten = rewriteRequest(encodePacket(5, opSync, body.Bytes()))
+
+	assert.False(t, rewritten)
+}
+
+func TestProtocolConnWatch(t *testing.T) {
+	conn := &bufferConn{}
+	protocolConn := &protocolConn{Conn: conn, handshaked: true, responses: make(map[int32]func([]byte) []byte)}
+
+	var request bytes.Buffer
+
+	markedPath := watchPath(opAddWatch, addWatchModePersistent, "/config")
+
+	writeBuffer(&request, []byte(markedPath))
+
+	_, err := protocolConn.Write(encodePacket(7, opSync, request.Bytes()))
+
+	assert.NoError(t, err)
+
+	// the addWatch response only has the error code
+	conn.read.Write(encodeResponse(7, []byte{0, 0, 0, 0}))
+
+	var header [4]byte
+
+	_, err = io.ReadFull(protocolConn, header[:])
+
+	assert.NoError(t, err)
+
+	response := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	_, err = io.ReadFull(protocolConn, response)
+
+	assert.NoError(t, err)
+	assert.Equal(t, encodeResponse(7, request.Bytes())[4:], response)
+	assert.Empty(t, protocolConn.responses)
+}
+
+func TestRewriteReconfigRequest(t *testing.T) {
+	var body, expected bytes.Buffer
+
+	writeBuffer(&body, []byte(reconfigPath([]string{"server.4=host4:2888:3888;2181", "server.5=host5:2888:3888;2181"}, nil, nil, 100000000)))
+	binary.Write(&body, binary.BigEndian, false)
+
+	writeBuffer(&expected, []byte("server.4=host4:2888:3888;2181,server.5=host5:2888:3888;2181"))
+	writeBuffer(&expected, nil)
+	writeBuffer(&expected, nil)
+	binary.Write(&expected, binary.BigEndian, int64(100000000))
+
+	packet, opcode, rewritten := rewriteRequest(encodePacket(3, opGetData, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, int32(opGetData), opcode)
+	assert.Equal(t, encodePacket(3, opReconfig, expected.Bytes()), packet)
+
+	// replace all the servers
+	body.Reset()
+	expected.Reset()
+
+	writeBuffer(&body, []byte(reconfigPath(nil, nil, []string{"server.1=host1:2888:3888;2181"}, -1)))
+	binary.Write(&body, binary.BigEndian, false)
+
+	writeBuffer(&expected, nil)
+	writeBuffer(&expected, nil)
+	writeBuffer(&expected, []byte("server.1=host1:2888:3888;2181"))
+	binary.Write(&expected, binary.BigEndian, int64(-1))
+
+	packet, _, rewritten = rewriteRequest(encodePacket(4, opGetData, body.Bytes()))
+
+	assert.True(t, rewritten)
+	assert.Equal(t, encodePacket(4, opReconfig, expected.Bytes()), packet)
+
+	// keep the other getData requests
+	body.Reset()
+
+	writeBuffer(&body, []byte(CONFIG_NODE))
+	binary.Write(&body, binary.BigEndian, true)
+
+	_, _, rewritten = rewriteRequest(encodePacket(5, opGetData, body.Bytes()))
+
+	assert.False(t, rewritten)
+}
+
+func TestExtendedConnNotRewritten(t *testing.T) {
+	conn := &extendedConn{} // not dialed through protocolConn
+
+	_, _, err := conn.Reconfig(nil, nil, []string{"server.1=host1:2888:3888;2181"}, -1)
+
+	assert.Equal(t, ErrNotRewritten, err)
+
+	assert.Equal(t, ErrNotRewritten, conn.AddPersistentWatch("/config", true))
+	assert.Equal(t, ErrNotRewritten, conn.RemovePersistentWatch("/config", true))
+
+	_, err = conn.CreateTTL("/node", nil, int32(PERSISTENT_WITH_TTL), OPEN_ACL_UNSAFE, time.Minute)
+
+	assert.Equal(t, ErrTTLNotSupported, err)
+
+	_, err = conn.Multi(&zk.CreateRequest{Path: "/node", Acl: withTTL(OPEN_ACL_UNSAFE, time.Minute), Flags: int32(PERSISTENT_WITH_TTL)})
+
+	assert.Equal(t, ErrTTLNotSupported, err)
+}
+
+func TestTLSDialer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	defer server.Close()
+
+	config := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	// the server name is the host of the server by default
+	conn, err := newTLSDialer(nil, config)("tcp", server.Listener.Addr().String(), time.Second)
+
+	assert.NoError(t, err)
+	assert.True(t, conn.(*tls.Conn).ConnectionState().HandshakeComplete)
+	assert.Empty(t, config.ServerName)
+
+	conn.Close()
+
+	// fail the handshake with an unknown CA
+	_, err = newTLSDialer(nil, &tls.Config{})("tcp", server.Listener.Addr().String(), time.Second)
+
+	assert.Error(t, err)
+}
+
+func TestProtocolConnReadOnly(t *testing.T) {
+	conn := &bufferConn{}
+	readOnly := new(AtomicBool)
+	protocolConn := &protocolConn{Conn: conn, readOnly: readOnly, responses: make(map[int32]func([]byte) []byte)}
+
+	// ask for a read-only session
+	var connect bytes.Buffer
+
+	binary.Write(&connect, binary.BigEndian, int32(28+16))
+	binary.Write(&connect, binary.BigEndian, int32(0)) // protocol version
+	binary.Write(&connect, binary.BigEndian, int64(0)) // last zxid seen
+	binary.Write(&connect, binary.BigEndian, int32(4000))
+	binary.Write(&connect, binary.BigEndian, int64(0)) // session id
+	writeBuffer(&connect, make([]byte, 16))
+
+	n, err := protocolConn.Write(connect.Bytes())
+
+	assert.Equal(t, connect.Len(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, connect.Len()+1, conn.written.Len())
+	assert.Equal(t, uint32(connect.Len()-3), binary.BigEndian.Uint32(conn.written.Bytes()))
+	assert.Equal(t, byte(1), conn.written.Bytes()[connect.Len()])
+
+	// the server grants a read-only session
+	var response bytes.Buffer
+
+	binary.Write(&response, binary.BigEndian, int32(36+1))
+	binary.Write(&response, binary.BigEndian, int32(0))    // protocol version
+	binary.Write(&response, binary.BigEndian, int32(4000)) // timeout
+	binary.Write(&response, binary.BigEndian, int64(1))    // session id
+	writeBuffer(&response, make([]byte, 16))
+	response.WriteByte(1)
+
+	conn.read.Write(response.Bytes())
+
+	buf := make([]byte, response.Len())
+
+	_, err = io.ReadFull(protocolConn, buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, response.Bytes(), buf)
+	assert.True(t, readOnly.Load())
+
+	// a server of the older versions doesn't send the flag
+	assert.False(t, connectResponseReadOnly(response.Bytes()[:response.Len()-1]))
+	assert.False(t, connectResponseReadOnly(response.Bytes()[:10]))
+}
+
+func TestProtocolConnRequestTimeout(t *testing.T) {
+	client, server := net.Pipe()
+
+	defer server.Close()
+
+	protocolConn := &protocolConn{Conn: client, handshaked: true, requestTimeout: 20 * time.Millisecond, responses: make(map[int32]func([]byte) []byte)}
+
+	request := func(xid int32) []byte {
+		return newPacket([]byte{0, 0, 0, byte(xid)}, opGetData, []byte{0, 0, 0, 0, 0})
+	}
+
+	go func() {
+		buf := make([]byte, len(request(1)))
+
+		// answer the first request in time, and leave the second one waiting
+		io.ReadFull(server, buf)
+		server.Write(newPacket([]byte{0, 0, 0, 1}, 0, make([]byte, 8)))
+		io.ReadFull(server, buf)
+	}()
+
+	assert.NoError(t, protocolConn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	_, err := protocolConn.Write(request(1))
+
+	assert.NoError(t, err)
+
+	buf := make([]byte, 20)
+
+	_, err = io.ReadFull(protocolConn, buf)
+
+	assert.NoError(t, err)
+	assert.Empty(t, protocolConn.pending)
+
+	// the second request is abandoned once it times out
+	_, err = protocolConn.Write(request(2))
+
+	assert.NoError(t, err)
+
+	started := time.Now()
+
+	_, err = io.ReadFull(protocolConn, buf)
+
+	assert.NoError(t, err)
+	assert.True(t, time.Since(started) < time.Second)
+	assert.Equal(t, int32(2), int32(binary.BigEndian.Uint32(buf[4:])))
+	assert.Equal(t, int32(errOperationTimeout), int32(binary.BigEndian.Uint32(buf[16:])))
+
+	// then the connection is dropped
+	_, err = protocolConn.Read(buf)
+
+	assert.Equal(t, ErrRequestTimeout, err)
+	assert.Equal(t, ErrRequestTimeout, translateError(fmt.Errorf("unknown error: %d", errOperationTimeout)))
+}
+
+func TestTranslateReadOnlyEvents(t *testing.T) {
+	events := make(chan zk.Event, 3)
+	readOnly := NewAtomicBool(true)
+
+	translated := translateReadOnlyEvents(events, &readOnly)
+
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	events <- zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession, Path: "/node"}
+
+	assert.Equal(t, zk.Event{Type: zk.EventSession, State: zk.StateConnectedReadOnly}, <-translated)
+	assert.Equal(t, zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession, Path: "/node"}, <-translated)
+
+	readOnly.Set(false)
+
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	close(events)
+
+	assert.Equal(t, zk.Event{Type: zk.EventSession, State: zk.StateHasSession}, <-translated)
+
+	_, ok := <-translated
+
+	assert.False(t, ok)
+}
@@ -0,0 +1,116 @@
+package curator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit the rate of the operations, e.g. to protect an ensemble shared by many clients from a runaway caller
+type RateLimiter interface {
+	// Wait until the operation may be sent, or fail with the error of the context once it is done
+	Wait(ctx context.Context) error
+}
+
+type rateLimiterCallback struct {
+	callback func(ctx context.Context) error
+}
+
+func NewRateLimiter(callback func(ctx context.Context) error) RateLimiter {
+	return &rateLimiterCallback{callback}
+}
+
+func (l *rateLimiterCallback) Wait(ctx context.Context) error {
+	return l.callback(ctx)
+}
+
+// A token bucket refilled with the rate of operations per second, holding up to the burst of tokens
+type tokenBucketRateLimiter struct {
+	rate   float64
+	burst  float64
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Create a token bucket allowing the operations per second on average, and the burst of operations at once,
+// the operations aren't limited if the rate is not positive.
+func NewTokenBucketRateLimiter(opsPerSecond float64, burst int) RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucketRateLimiter{rate: opsPerSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (l *tokenBucketRateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+
+	case <-ctx.Done():
+		l.lock.Lock()
+		l.tokens++ // give back the reserved token
+		l.lock.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// Take a token, the bucket goes into debt when it is empty, so the waiting operations are served in order
+func (l *tokenBucketRateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+
+	if l.tokens += now.Sub(l.last).Seconds() * l.rate; l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+
+	l.last = now
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// The operations reading the nodes, the other operations of the framework are the writes
+var readOperations = map[string]bool{
+	EXISTS.String():     true,
+	GET_DATA.String():   true,
+	CHILDREN.String():   true,
+	SYNC.String():       true,
+	GET_ACL.String():    true,
+	GET_CONFIG.String(): true,
+	ADD_WATCH.String():  true,
+	"REMOVE_WATCH":      true,
+}
+
+// Return the rate limiter of the operation, nil for the retry loops without an operation, e.g. the ones of the recipes
+func (c *curatorZookeeperClient) rateLimiterFor(operation string) RateLimiter {
+	if operation == "" {
+		return nil
+	} else if readOperations[operation] {
+		return c.readRateLimiter
+	}
+
+	return c.writeRateLimiter
+}
@@ -0,0 +1,86 @@
+package curator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTokenBucketRateLimiter(t *testing.T) {
+	limiter := NewTokenBucketRateLimiter(50, 2)
+
+	// the burst is allowed at once
+	started := time.Now()
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(started) < 10*time.Millisecond)
+
+	// then the operations wait for the refilled tokens
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(started) >= 15*time.Millisecond)
+
+	// fail with the error of the context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+
+	// not limited without a rate
+	limiter = NewTokenBucketRateLimiter(0, 0)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+}
+
+type RateLimiterTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestRateLimiter(t *testing.T) {
+	suite.Run(t, new(RateLimiterTestSuite))
+}
+
+func (s *RateLimiterTestSuite) TestReadsAndWrites() {
+	var reads, writes int
+
+	errLimited := errors.New("limited")
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.ReadRateLimiter = NewRateLimiter(func(ctx context.Context) error {
+			reads++
+
+			return nil
+		})
+		builder.WriteRateLimiter = NewRateLimiter(func(ctx context.Context) error {
+			writes++
+
+			return errLimited
+		})
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		conn.On("Get", "/node").Return(data, stat, nil).Once()
+		conn.On("Exists", "/node").Return(true, stat, nil).Once()
+
+		_, err := client.GetData().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.CheckExists().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+
+		// the writes fail without a request
+		_, err = client.SetData().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), errLimited, err)
+		assert.Equal(s.T(), 2, reads)
+		assert.Equal(s.T(), 1, writes)
+	})
+}
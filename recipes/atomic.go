@@ -195,7 +195,7 @@ func (v *distributedAtomicValue) TrySet(newValue []byte) (AtomicValue, error) {
 }
 
 func (v *distributedAtomicValue) Initialize(value []byte) (bool, error) {
-	if _, err := v.client.Create().ForPath(v.path); err == nil {
+	if _, err := v.client.Create().ForPathWithData(v.path, value); err == nil {
 		return true, nil
 	} else if err == zk.ErrNodeExists {
 		return false, nil
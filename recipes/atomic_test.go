@@ -2,12 +2,67 @@ package recipes
 
 import (
 	"testing"
+	"time"
 
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/curatortest"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestDistributedAtomicValue(t *testing.T) {
 	Convey("Given a DistributedAtomicValue base on path", t, func() {
+		server := curatortest.NewSimulatedServer()
 
+		defer server.Close()
+
+		client := server.NewClient(curator.NewRetryNTimes(3, 10*time.Millisecond))
+
+		So(client.Start(), ShouldBeNil)
+
+		defer client.Close()
+
+		value, err := NewDistributedAtomicValue(client, "/value", curator.NewRetryNTimes(3, 10*time.Millisecond))
+
+		So(err, ShouldBeNil)
+
+		Convey("When the value is initialized", func() {
+			initialized, err := value.Initialize([]byte("first"))
+
+			So(initialized, ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			initialized, err = value.Initialize([]byte("second"))
+
+			So(initialized, ShouldBeFalse)
+			So(err, ShouldBeNil)
+
+			Convey("The value should be compared and set", func() {
+				result, err := value.CompareAndSet([]byte("second"), []byte("third"))
+
+				So(err, ShouldBeNil)
+				So(result.Succeeded(), ShouldBeFalse)
+
+				result, err = value.CompareAndSet([]byte("first"), []byte("third"))
+
+				So(err, ShouldBeNil)
+				So(result.Succeeded(), ShouldBeTrue)
+				So(result.PreValue(), ShouldResemble, []byte("first"))
+				So(result.PostValue(), ShouldResemble, []byte("third"))
+
+				result, err = value.Get()
+
+				So(err, ShouldBeNil)
+				So(result.PostValue(), ShouldResemble, []byte("third"))
+			})
+
+			Convey("The value should be tried to set", func() {
+				result, err := value.TrySet([]byte("second"))
+
+				So(err, ShouldBeNil)
+				So(result.Succeeded(), ShouldBeTrue)
+				So(result.PreValue(), ShouldResemble, []byte("first"))
+				So(result.PostValue(), ShouldResemble, []byte("second"))
+			})
+		})
 	})
 }
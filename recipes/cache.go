@@ -3,6 +3,7 @@ package recipes
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 
@@ -42,6 +43,20 @@ type NodeCacheListener interface {
 	NodeChanged() error
 }
 
+type nodeCacheListenerCallback func() error
+
+type nodeCacheListenerStub struct {
+	callback nodeCacheListenerCallback
+}
+
+func NewNodeCacheListener(callback nodeCacheListenerCallback) NodeCacheListener {
+	return &nodeCacheListenerStub{callback}
+}
+
+func (l *nodeCacheListenerStub) NodeChanged() error {
+	return l.callback()
+}
+
 type NodeCacheListenable interface {
 	curator.Listenable /* [T] */
 
@@ -77,6 +92,8 @@ func (c *NodeCacheListenerContainer) RemoveListener(listener NodeCacheListener)
 // A utility that attempts to keep the data from a node locally cached.
 // This class will watch the node, respond to update/create/delete events, pull down the data, etc.
 // You can register a listener that will get notified when changes occur.
+//
+// The listeners are notified one at a time in the order of the changes, a stale read of the node is ignored.
 type NodeCache struct {
 	client                  curator.CuratorFramework
 	path                    string
@@ -85,6 +102,7 @@ type NodeCache struct {
 	state                   curator.State
 	isConnected             curator.AtomicBool
 	data                    *ChildData
+	dataLock                sync.Mutex
 	connectionStateListener curator.ConnectionStateListener
 	watcher                 curator.Watcher
 	backgroundCallback      curator.BackgroundCallback
@@ -97,7 +115,8 @@ func NewNodeCache(client curator.CuratorFramework, path string, dataIsCompressed
 		path:             path,
 		dataIsCompressed: dataIsCompressed,
 		ensurePath:       client.NewNamespaceAwareEnsurePath(path).ExcludingLast(),
-		listeners:        &NodeCacheListenerContainer{},
+		isConnected:      curator.TRUE,
+		listeners:        &NodeCacheListenerContainer{&curator.ListenerContainer{}},
 	}
 
 	c.connectionStateListener = curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
@@ -161,6 +180,12 @@ func (c *NodeCache) NodeCacheListenable() NodeCacheListenable {
 	return c.listeners
 }
 
+// Return the current data. There are no guarantees of accuracy. This is merely the most recent view of the data.
+// If the node does not exist, this returns nil
+func (c *NodeCache) CurrentData() *ChildData {
+	return (*ChildData)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&c.data))))
+}
+
 func (c *NodeCache) internalRebuild() error {
 	var stat zk.Stat
 
@@ -195,10 +220,10 @@ func (c *NodeCache) processBackgroundResult(event curator.CuratorEvent) error {
 	switch event.Type() {
 	case curator.GET_DATA:
 		if event.Err() == nil {
-			c.setNewData(&ChildData{c.path, event.Stat(), event.Data()})
+			c.setNewData(&ChildData{c.path, event.Stat(), compactData(event.Data())})
 		}
 	case curator.EXISTS:
-		if event.Err() == zk.ErrNoNode {
+		if event.Err() == zk.ErrNoNode || (event.Err() == nil && event.Stat() == nil) {
 			c.setNewData(nil)
 		} else if event.Err() == nil {
 			builder := c.client.GetData()
@@ -215,6 +240,13 @@ func (c *NodeCache) processBackgroundResult(event curator.CuratorEvent) error {
 }
 
 func (c *NodeCache) setNewData(newData *ChildData) {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	if isStaleData(c.CurrentData(), newData) {
+		return
+	}
+
 	previousData := (*ChildData)(atomic.SwapPointer((*unsafe.Pointer)(unsafe.Pointer(&c.data)), unsafe.Pointer(newData)))
 
 	if !reflect.DeepEqual(previousData, newData) {
@@ -224,6 +256,15 @@ func (c *NodeCache) setNewData(newData *ChildData) {
 	}
 }
 
+// Return true if the new data of the node was read before the current data, e.g. by an earlier background read
+func isStaleData(currentData, newData *ChildData) bool {
+	if currentData == nil || newData == nil || currentData.Stat == nil || newData.Stat == nil {
+		return false
+	}
+
+	return newData.Stat.Czxid == currentData.Stat.Czxid && newData.Stat.Mzxid < currentData.Stat.Mzxid
+}
+
 type RefreshMode int
 
 const (
@@ -282,3 +323,17 @@ func (c *PathChildrenCache) RefreshMode(mode RefreshMode) {
 		}).ForPath(c.path)
 	*/
 }
+
+// Return the data without the spare capacity of its backing array, e.g. the buffer of a decompressed payload,
+// so a cached node doesn't retain more memory than its data. The data is shared if there is no spare capacity.
+func compactData(data []byte) []byte {
+	if data == nil || len(data) == cap(data) {
+		return data
+	}
+
+	compacted := make([]byte, len(data))
+
+	copy(compacted, data)
+
+	return compacted
+}
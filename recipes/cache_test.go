@@ -0,0 +1,55 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStaleData(t *testing.T) {
+	Convey("Given the current data of a node", t, func() {
+		currentData := &ChildData{Path: "/node", Stat: &zk.Stat{Czxid: 1, Mzxid: 5}}
+
+		Convey("An earlier read should be stale", func() {
+			So(isStaleData(currentData, &ChildData{Path: "/node", Stat: &zk.Stat{Czxid: 1, Mzxid: 3}}), ShouldBeTrue)
+		})
+
+		Convey("A later read should not be stale", func() {
+			So(isStaleData(currentData, &ChildData{Path: "/node", Stat: &zk.Stat{Czxid: 1, Mzxid: 7}}), ShouldBeFalse)
+		})
+
+		Convey("A read of the recreated node should not be stale", func() {
+			So(isStaleData(currentData, &ChildData{Path: "/node", Stat: &zk.Stat{Czxid: 6, Mzxid: 6}}), ShouldBeFalse)
+		})
+
+		Convey("The deletion should not be stale", func() {
+			So(isStaleData(currentData, nil), ShouldBeFalse)
+			So(isStaleData(nil, currentData), ShouldBeFalse)
+		})
+	})
+}
+
+func TestCompactData(t *testing.T) {
+	Convey("Given some data", t, func() {
+		Convey("The nil data should be kept", func() {
+			So(compactData(nil), ShouldBeNil)
+		})
+
+		Convey("The data without spare capacity should be shared", func() {
+			data := []byte("data")
+
+			So(&compactData(data)[0] == &data[0], ShouldBeTrue)
+		})
+
+		Convey("The data with spare capacity should be copied", func() {
+			data := make([]byte, 4, 1024)
+
+			compacted := compactData(data)
+
+			So(compacted, ShouldResemble, data)
+			So(cap(compacted), ShouldEqual, 4)
+			So(&compacted[0] == &data[0], ShouldBeFalse)
+		})
+	})
+}
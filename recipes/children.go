@@ -0,0 +1,141 @@
+package recipes
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The max number of the children read concurrently by GetChildrenWithData
+const GET_CHILDREN_PARALLELISM = 8
+
+// Return the children of the given path by name, with the data and stat of each child.
+//
+// The data of the children are read concurrently, the children deleted before they are read are skipped.
+func GetChildrenWithData(client curator.CuratorFramework, path string) (map[string]*ChildData, error) {
+	children, err := client.GetChildren().ForPath(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var firstErr error
+
+	result := make(map[string]*ChildData, len(children))
+	tokens := make(chan struct{}, GET_CHILDREN_PARALLELISM)
+
+	for _, child := range children {
+		wg.Add(1)
+
+		tokens <- struct{}{}
+
+		go func(child string) {
+			defer func() {
+				<-tokens
+
+				wg.Done()
+			}()
+
+			var stat zk.Stat
+
+			childPath := curator.JoinPath(path, child)
+
+			data, err := client.GetData().StoringStatIn(&stat).ForPath(childPath)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err == nil {
+				result[child] = &ChildData{childPath, &stat, data}
+			} else if err != zk.ErrNoNode && firstErr == nil {
+				firstErr = err
+			}
+		}(child)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return result, nil
+}
+
+// Make the children of the given path match the desired child names and data.
+//
+// Missing children are created, children with different data are updated and the others are deleted,
+// all the changes are committed in one transaction where updates and deletes are checked against
+// the versions read, so the transaction fails if the children are changed concurrently.
+func SyncChildren(client curator.CuratorFramework, path string, desired map[string][]byte) ([]curator.TransactionResult, error) {
+	if err := client.NewNamespaceAwareEnsurePath(path).Ensure(client.ZookeeperClient()); err != nil {
+		return nil, err
+	}
+
+	children, err := client.GetChildren().ForPath(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(children)
+
+	var transaction curator.TransactionFinal
+
+	existing := make(map[string]bool)
+
+	for _, child := range children {
+		var stat zk.Stat
+
+		childPath := curator.JoinPath(path, child)
+
+		data, err := client.GetData().StoringStatIn(&stat).ForPath(childPath)
+
+		if err == zk.ErrNoNode {
+			continue // the transaction will fail if it is recreated
+		} else if err != nil {
+			return nil, err
+		}
+
+		existing[child] = true
+
+		if desiredData, exists := desired[child]; !exists {
+			transaction = syncTransaction(client, transaction).Delete().WithVersion(stat.Version).ForPath(childPath)
+		} else if !bytes.Equal(data, desiredData) {
+			transaction = syncTransaction(client, transaction).SetData().WithVersion(stat.Version).ForPathWithData(childPath, desiredData)
+		}
+	}
+
+	var names []string
+
+	for name := range desired {
+		if !existing[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		transaction = syncTransaction(client, transaction).Create().ForPathWithData(curator.JoinPath(path, name), desired[name])
+	}
+
+	if transaction == nil {
+		return nil, nil
+	}
+
+	return transaction.Commit()
+}
+
+func syncTransaction(client curator.CuratorFramework, transaction curator.TransactionFinal) curator.Transaction {
+	if transaction == nil {
+		return client.InTransaction()
+	}
+
+	return transaction
+}
@@ -0,0 +1,108 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetChildrenWithData(t *testing.T) {
+	Convey("Given the children of a path", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		mocks.Conn.On("Children", "/members").Return([]string{"alice", "bob", "carol"}, nil, nil).Once()
+		mocks.Conn.On("Get", "/members/alice").Return([]byte("alice"), &zk.Stat{Version: 1}, nil).Once()
+
+		Convey("When a child is deleted before it is read", func() {
+			mocks.Conn.On("Get", "/members/bob").Return([]byte("bob"), &zk.Stat{Version: 2}, nil).Once()
+			mocks.Conn.On("Get", "/members/carol").Return(nil, nil, zk.ErrNoNode).Once()
+
+			children, err := GetChildrenWithData(client, "/members")
+
+			Convey("Return the data and stat of the remaining children", func() {
+				So(err, ShouldBeNil)
+				So(children, ShouldResemble, map[string]*ChildData{
+					"alice": {"/members/alice", &zk.Stat{Version: 1}, []byte("alice")},
+					"bob":   {"/members/bob", &zk.Stat{Version: 2}, []byte("bob")},
+				})
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When a child fails to be read", func() {
+			mocks.Conn.On("Get", "/members/bob").Return(nil, nil, zk.ErrNoAuth).Once()
+			mocks.Conn.On("Get", "/members/carol").Return([]byte("carol"), &zk.Stat{Version: 3}, nil).Once()
+
+			children, err := GetChildrenWithData(client, "/members")
+
+			Convey("Return the error", func() {
+				So(children, ShouldBeNil)
+				So(err, ShouldEqual, zk.ErrNoAuth)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
+
+func TestSyncChildren(t *testing.T) {
+	Convey("Given the children of a path", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		mocks.Conn.On("Exists", "/members").Return(true, &zk.Stat{}, nil).Once()
+		mocks.Conn.On("Children", "/members").Return([]string{"bob", "alice", "carol"}, nil, nil).Once()
+		mocks.Conn.On("Get", "/members/alice").Return([]byte("alice"), &zk.Stat{Version: 1}, nil).Once()
+		mocks.Conn.On("Get", "/members/bob").Return([]byte("bob"), &zk.Stat{Version: 2}, nil).Once()
+		mocks.Conn.On("Get", "/members/carol").Return([]byte("carol"), &zk.Stat{Version: 3}, nil).Once()
+
+		Convey("When the children match the desired children", func() {
+			results, err := SyncChildren(client, "/members", map[string][]byte{
+				"alice": []byte("alice"),
+				"bob":   []byte("bob"),
+				"carol": []byte("carol"),
+			})
+
+			Convey("Nothing should be changed", func() {
+				So(results, ShouldBeNil)
+				So(err, ShouldBeNil)
+				So(mocks.Conn.Operations, ShouldBeEmpty)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the children differ from the desired children", func() {
+			mocks.Conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{}, {Stat: &zk.Stat{}}, {String: "/members/dave"}}, nil).Once()
+
+			results, err := SyncChildren(client, "/members", map[string][]byte{
+				"alice": []byte("alice"),
+				"bob":   []byte("robert"),
+				"dave":  []byte("dave"),
+			})
+
+			Convey("Apply the changes with version checks in one transaction", func() {
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 3)
+				So(mocks.Conn.Operations, ShouldResemble, []interface{}{
+					&zk.SetDataRequest{Path: "/members/bob", Data: []byte("robert"), Version: 2},
+					&zk.DeleteRequest{Path: "/members/carol", Version: 3},
+					&zk.CreateRequest{Path: "/members/dave", Data: []byte("dave"), Acl: curator.OPEN_ACL_UNSAFE, Flags: int32(curator.PERSISTENT)},
+				})
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
@@ -0,0 +1,419 @@
+package recipes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The child of the flags path holding the audit trail of the changes
+const FEATURE_FLAGS_AUDIT_NODE = "_audit"
+
+// The prefix of the sequential nodes of the audit trail
+const FEATURE_FLAGS_CHANGE_NODE_NAME = "change-"
+
+type FeatureFlagType string
+
+const (
+	BOOL_FLAG       FeatureFlagType = "bool"
+	INT_FLAG        FeatureFlagType = "int"
+	STRING_FLAG     FeatureFlagType = "string"
+	PERCENTAGE_FLAG FeatureFlagType = "percentage" // The flag is enabled for the given percentage of the keys
+)
+
+var (
+	ErrInvalidFlagName = errors.New("invalid feature flag name")
+	ErrInvalidFlag     = errors.New("invalid feature flag value")
+)
+
+// A feature flag, stored as JSON in the child of the flags path named by the flag
+type FeatureFlag struct {
+	Type  FeatureFlagType `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func NewBoolFlag(value bool) *FeatureFlag { return newFeatureFlag(BOOL_FLAG, value) }
+
+func NewIntFlag(value int64) *FeatureFlag { return newFeatureFlag(INT_FLAG, value) }
+
+func NewStringFlag(value string) *FeatureFlag { return newFeatureFlag(STRING_FLAG, value) }
+
+// Create a flag enabled for the given percentage of the keys, from 0 to 100
+func NewPercentageFlag(percentage int) *FeatureFlag {
+	return newFeatureFlag(PERCENTAGE_FLAG, percentage)
+}
+
+func newFeatureFlag(flagType FeatureFlagType, value interface{}) *FeatureFlag {
+	data, _ := json.Marshal(value)
+
+	return &FeatureFlag{Type: flagType, Value: data}
+}
+
+func (f *FeatureFlag) validate() error {
+	var value interface{}
+
+	switch f.Type {
+	case BOOL_FLAG:
+		value = new(bool)
+	case INT_FLAG:
+		value = new(int64)
+	case STRING_FLAG:
+		value = new(string)
+	case PERCENTAGE_FLAG:
+		var percentage int
+
+		if err := json.Unmarshal(f.Value, &percentage); err != nil || percentage < 0 || percentage > 100 {
+			return ErrInvalidFlag
+		}
+
+		return nil
+	default:
+		return ErrInvalidFlag
+	}
+
+	if err := json.Unmarshal(f.Value, value); err != nil {
+		return ErrInvalidFlag
+	}
+
+	return nil
+}
+
+// A change of a feature flag recorded in the audit trail
+type FeatureFlagChange struct {
+	Name     string       `json:"name"`
+	Previous *FeatureFlag `json:"previous,omitempty"` // nil if the flag was added
+	Current  *FeatureFlag `json:"current,omitempty"`  // nil if the flag was removed
+	Author   string       `json:"author,omitempty"`
+	Time     time.Time    `json:"time"`
+}
+
+// Listener for the changes of the feature flags
+type FeatureFlagsListener interface {
+	// Called when a flag is added, changed or removed, the flag is nil if it was removed.
+	// The flags loaded when the cache starts are added.
+	FlagChanged(name string, flag *FeatureFlag) error
+}
+
+type featureFlagsListenerCallback func(name string, flag *FeatureFlag) error
+
+type featureFlagsListenerStub struct {
+	callback featureFlagsListenerCallback
+}
+
+func NewFeatureFlagsListener(callback featureFlagsListenerCallback) FeatureFlagsListener {
+	return &featureFlagsListenerStub{callback}
+}
+
+func (l *featureFlagsListenerStub) FlagChanged(name string, flag *FeatureFlag) error {
+	return l.callback(name, flag)
+}
+
+type FeatureFlagsListenable interface {
+	curator.Listenable /* [T] */
+
+	AddListener(listener FeatureFlagsListener)
+
+	RemoveListener(listener FeatureFlagsListener)
+}
+
+type FeatureFlagsListenerContainer struct {
+	*curator.ListenerContainer
+}
+
+func (c *FeatureFlagsListenerContainer) AddListener(listener FeatureFlagsListener) {
+	c.Add(listener)
+}
+
+func (c *FeatureFlagsListenerContainer) RemoveListener(listener FeatureFlagsListener) {
+	c.Remove(listener)
+}
+
+// Feature flags shared by the cooperating clients.
+//
+// The flags are stored as the children of a path and cached locally, so reading a flag doesn't hit ZooKeeper.
+// Each change is committed in one transaction with a sequential node of the audit trail,
+// which records the author and the previous value of the flag.
+type FeatureFlags struct {
+	client    curator.CuratorFramework
+	path      string
+	cache     *TreeCache
+	listeners *FeatureFlagsListenerContainer
+}
+
+func NewFeatureFlags(client curator.CuratorFramework, path string) *FeatureFlags {
+	f := &FeatureFlags{
+		client:    client,
+		path:      path,
+		cache:     NewTreeCache(client, path, true, false).SetMaxDepth(1),
+		listeners: &FeatureFlagsListenerContainer{&curator.ListenerContainer{}},
+	}
+
+	f.cache.Listenable().AddListener(NewTreeCacheListener(func(client curator.CuratorFramework, event TreeCacheEvent) error {
+		return f.processEvent(event)
+	}))
+
+	return f
+}
+
+// Start caching the flags. You must call this method.
+func (f *FeatureFlags) Start() error {
+	if err := f.client.NewNamespaceAwareEnsurePath(curator.JoinPath(f.path, FEATURE_FLAGS_AUDIT_NODE)).Ensure(f.client.ZookeeperClient()); err != nil {
+		return err
+	}
+
+	return f.cache.Start()
+}
+
+func (f *FeatureFlags) Close() error {
+	f.listeners.Clear()
+
+	return f.cache.Close()
+}
+
+func (f *FeatureFlags) Listenable() FeatureFlagsListenable {
+	return f.listeners
+}
+
+func (f *FeatureFlags) processEvent(event TreeCacheEvent) error {
+	if parentOf(event.Data.Path) != f.path {
+		return nil
+	}
+
+	name := curator.GetNodeFromPath(event.Data.Path)
+
+	if name == FEATURE_FLAGS_AUDIT_NODE {
+		return nil
+	}
+
+	var flag *FeatureFlag
+
+	switch event.Type {
+	case CHILD_ADDED, CHILD_UPDATED:
+		if flag = parseFeatureFlag(event.Data.Data); flag == nil {
+			return nil
+		}
+	case CHILD_REMOVED:
+	default:
+		return nil
+	}
+
+	f.listeners.ForEach(func(listener interface{}) {
+		if err := listener.(FeatureFlagsListener).FlagChanged(name, flag); err != nil {
+			f.unhandledError(err)
+		}
+	})
+
+	return nil
+}
+
+func (f *FeatureFlags) unhandledError(err error) {
+	f.client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+		listener.(curator.UnhandledErrorListener).UnhandledError(err)
+	})
+}
+
+func parseFeatureFlag(data []byte) *FeatureFlag {
+	var flag FeatureFlag
+
+	if err := json.Unmarshal(data, &flag); err != nil || flag.validate() != nil {
+		return nil
+	}
+
+	return &flag
+}
+
+// Return the cached flag, or nil if it doesn't exist or can't be parsed
+func (f *FeatureFlags) Flag(name string) *FeatureFlag {
+	if data := f.cache.CurrentData(curator.JoinPath(f.path, name)); data != nil {
+		return parseFeatureFlag(data.Data)
+	}
+
+	return nil
+}
+
+// Return the names of the cached flags
+func (f *FeatureFlags) Names() []string {
+	var names []string
+
+	for name := range f.cache.CurrentChildren(f.path) {
+		if name != FEATURE_FLAGS_AUDIT_NODE {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (f *FeatureFlags) value(name string, flagType FeatureFlagType, value interface{}) bool {
+	if flag := f.Flag(name); flag != nil && flag.Type == flagType {
+		return json.Unmarshal(flag.Value, value) == nil
+	}
+
+	return false
+}
+
+// Return the value of a BOOL_FLAG, or the default value if it doesn't exist or has another type
+func (f *FeatureFlags) Bool(name string, defaultValue bool) bool {
+	f.value(name, BOOL_FLAG, &defaultValue)
+
+	return defaultValue
+}
+
+// Return the value of an INT_FLAG, or the default value if it doesn't exist or has another type
+func (f *FeatureFlags) Int(name string, defaultValue int64) int64 {
+	f.value(name, INT_FLAG, &defaultValue)
+
+	return defaultValue
+}
+
+// Return the value of a STRING_FLAG, or the default value if it doesn't exist or has another type
+func (f *FeatureFlags) String(name string, defaultValue string) string {
+	f.value(name, STRING_FLAG, &defaultValue)
+
+	return defaultValue
+}
+
+// Return if the flag is enabled for the key, e.g. a user id.
+//
+// A PERCENTAGE_FLAG is enabled for the keys hashed into its percentage, so a key keeps its result
+// while the percentage grows, and the keys are spread differently by each flag. A BOOL_FLAG is enabled
+// for all the keys or none, the other flags are never enabled.
+func (f *FeatureFlags) IsEnabledFor(name string, key string) bool {
+	var percentage int
+
+	if f.value(name, PERCENTAGE_FLAG, &percentage) {
+		return RolloutBucket(name, key) < percentage
+	}
+
+	return f.Bool(name, false)
+}
+
+// Return the bucket from 0 to 99 of the key in the percentage rollout of a flag
+func RolloutBucket(name string, key string) int {
+	hash := fnv.New32a()
+
+	hash.Write([]byte(name))
+	hash.Write([]byte{0})
+	hash.Write([]byte(key))
+
+	return int(hash.Sum32() % 100)
+}
+
+// Add or change a flag, the change is recorded in the audit trail with the author.
+//
+// The flag is read before it is written, so the change fails with zk.ErrBadVersion or zk.ErrNodeExists
+// if the flag is changed concurrently.
+func (f *FeatureFlags) Set(name string, flag *FeatureFlag, author string) error {
+	if err := flag.validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(flag)
+
+	if err != nil {
+		return err
+	}
+
+	return f.change(name, flag, author, func(transaction curator.Transaction, flagPath string, stat *zk.Stat) curator.TransactionBridge {
+		if stat == nil {
+			return transaction.Create().ForPathWithData(flagPath, data)
+		}
+
+		return transaction.SetData().WithVersion(stat.Version).ForPathWithData(flagPath, data)
+	})
+}
+
+// Remove a flag, the removal is recorded in the audit trail with the author
+func (f *FeatureFlags) Remove(name string, author string) error {
+	return f.change(name, nil, author, func(transaction curator.Transaction, flagPath string, stat *zk.Stat) curator.TransactionBridge {
+		if stat == nil {
+			return nil
+		}
+
+		return transaction.Delete().WithVersion(stat.Version).ForPath(flagPath)
+	})
+}
+
+func (f *FeatureFlags) change(name string, flag *FeatureFlag, author string, write func(transaction curator.Transaction, flagPath string, stat *zk.Stat) curator.TransactionBridge) error {
+	if len(name) == 0 || name == FEATURE_FLAGS_AUDIT_NODE || strings.Contains(name, curator.PATH_SEPARATOR) {
+		return ErrInvalidFlagName
+	}
+
+	flagPath := curator.JoinPath(f.path, name)
+
+	var stat zk.Stat
+	var current *zk.Stat
+
+	change := FeatureFlagChange{Name: name, Current: flag, Author: author, Time: time.Now()}
+
+	if data, err := f.client.GetData().StoringStatIn(&stat).ForPath(flagPath); err == nil {
+		current = &stat
+		change.Previous = parseFeatureFlag(data)
+	} else if err != zk.ErrNoNode {
+		return err
+	}
+
+	bridge := write(f.client.InTransaction(), flagPath, current)
+
+	if bridge == nil {
+		return nil // nothing to remove
+	}
+
+	if data, err := json.Marshal(&change); err != nil {
+		return err
+	} else {
+		_, err := bridge.And().Create().WithMode(curator.PERSISTENT_SEQUENTIAL).ForPathWithData(f.changePath(), data).Commit()
+
+		return err
+	}
+}
+
+func (f *FeatureFlags) changePath() string {
+	return curator.JoinPath(curator.JoinPath(f.path, FEATURE_FLAGS_AUDIT_NODE), FEATURE_FLAGS_CHANGE_NODE_NAME)
+}
+
+// Return the audit trail of the changes, oldest first
+func (f *FeatureFlags) AuditTrail() ([]*FeatureFlagChange, error) {
+	auditPath := curator.JoinPath(f.path, FEATURE_FLAGS_AUDIT_NODE)
+
+	children, err := f.client.GetChildren().ForPath(auditPath)
+
+	if err == zk.ErrNoNode {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(children)
+
+	changes := make([]*FeatureFlagChange, 0, len(children))
+
+	for _, child := range children {
+		data, err := f.client.GetData().ForPath(curator.JoinPath(auditPath, child))
+
+		if err == zk.ErrNoNode {
+			continue // pruned meanwhile
+		} else if err != nil {
+			return nil, err
+		}
+
+		var change FeatureFlagChange
+
+		if err := json.Unmarshal(data, &change); err != nil {
+			return nil, fmt.Errorf("Trying to parse the change %s, %s", child, err)
+		}
+
+		changes = append(changes, &change)
+	}
+
+	return changes, nil
+}
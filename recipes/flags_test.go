@@ -0,0 +1,153 @@
+package recipes
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func flagData(flag *FeatureFlag) []byte {
+	data, _ := json.Marshal(flag)
+
+	return data
+}
+
+func TestFeatureFlags(t *testing.T) {
+	Convey("Given some feature flags", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		flags := NewFeatureFlags(client, "/flags")
+
+		changes := make(chan string, 16)
+
+		flags.Listenable().AddListener(NewFeatureFlagsListener(func(name string, flag *FeatureFlag) error {
+			changes <- name
+
+			return nil
+		}))
+
+		mocks.Conn.On("Exists", "/flags").Return(true, &zk.Stat{}, nil).Once()
+		mocks.Conn.On("Exists", "/flags/_audit").Return(true, &zk.Stat{}, nil).Once()
+
+		mocks.Conn.On("AddPersistentWatch", "/flags", true).Return(nil).Once()
+		mocks.Conn.On("Exists", "/flags").Return(true, &zk.Stat{}, nil).Once()
+		mocks.Conn.On("Get", "/flags").Return([]byte{}, &zk.Stat{}, nil).Once()
+		mocks.Conn.On("Children", "/flags").Return([]string{"_audit", "dark-mode", "limit", "rollout", "broken"}, &zk.Stat{}, nil).Once()
+
+		for name, data := range map[string][]byte{
+			"_audit":    {},
+			"dark-mode": flagData(NewBoolFlag(true)),
+			"limit":     flagData(NewIntFlag(42)),
+			"rollout":   flagData(NewPercentageFlag(30)),
+			"broken":    []byte("broken"),
+		} {
+			mocks.Conn.On("Exists", "/flags/"+name).Return(true, &zk.Stat{Version: 1}, nil).Once()
+			mocks.Conn.On("Get", "/flags/"+name).Return(data, &zk.Stat{Version: 1}, nil).Once()
+		}
+
+		So(flags.Start(), ShouldBeNil)
+
+		Convey("The flags should be read from the cache", func() {
+			So(flags.Names(), ShouldResemble, []string{"broken", "dark-mode", "limit", "rollout"})
+
+			So(flags.Bool("dark-mode", false), ShouldBeTrue)
+			So(flags.Int("limit", 0), ShouldEqual, 42)
+			So(flags.String("limit", "default"), ShouldEqual, "default")
+			So(flags.Bool("broken", true), ShouldBeTrue)
+			So(flags.Flag("missing"), ShouldBeNil)
+
+			So(flags.IsEnabledFor("dark-mode", "alice"), ShouldBeTrue)
+			So(flags.IsEnabledFor("limit", "alice"), ShouldBeFalse)
+
+			enabled := 0
+
+			for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+				So(flags.IsEnabledFor("rollout", key), ShouldEqual, RolloutBucket("rollout", key) < 30)
+
+				if flags.IsEnabledFor("rollout", key) {
+					enabled++
+				}
+			}
+
+			So(enabled, ShouldBeLessThan, 10)
+
+			mocks.Conn.On("RemovePersistentWatch", "/flags", true).Return(nil).Once()
+
+			So(flags.Close(), ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("The changes should be committed with the audit trail", func() {
+			So(flags.Set("limit", &FeatureFlag{Type: INT_FLAG, Value: json.RawMessage(`"many"`)}, "bob"), ShouldEqual, ErrInvalidFlag)
+			So(flags.Set("_audit", NewBoolFlag(true), "bob"), ShouldEqual, ErrInvalidFlagName)
+
+			mocks.Conn.On("Get", "/flags/limit").Return(flagData(NewIntFlag(42)), &zk.Stat{Version: 1}, nil).Once()
+			mocks.Conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: &zk.Stat{}}, {String: "/flags/_audit/change-0000000001"}}, nil).Once()
+
+			So(flags.Set("limit", NewIntFlag(100), "bob"), ShouldBeNil)
+
+			So(mocks.Conn.Operations, ShouldHaveLength, 2)
+			So(mocks.Conn.Operations[0], ShouldResemble, &zk.SetDataRequest{Path: "/flags/limit", Data: flagData(NewIntFlag(100)), Version: 1})
+
+			audit := mocks.Conn.Operations[1].(*zk.CreateRequest)
+
+			So(audit.Path, ShouldEqual, "/flags/_audit/change-")
+			So(audit.Flags, ShouldEqual, int32(curator.PERSISTENT_SEQUENTIAL))
+
+			mocks.Conn.On("Children", "/flags/_audit").Return([]string{"change-0000000001"}, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Get", "/flags/_audit/change-0000000001").Return(audit.Data, &zk.Stat{}, nil).Once()
+
+			trail, err := flags.AuditTrail()
+
+			So(err, ShouldBeNil)
+			So(trail, ShouldHaveLength, 1)
+			So(trail[0].Name, ShouldEqual, "limit")
+			So(trail[0].Author, ShouldEqual, "bob")
+			So(trail[0].Previous, ShouldResemble, NewIntFlag(42))
+			So(trail[0].Current, ShouldResemble, NewIntFlag(100))
+
+			Convey("The listeners should be notified of the changes", func() {
+				// the loaded flags are added
+				initial := []string{<-changes, <-changes, <-changes}
+
+				sort.Strings(initial)
+
+				So(initial, ShouldResemble, []string{"dark-mode", "limit", "rollout"})
+
+				mocks.Conn.On("Exists", "/flags/limit").Return(true, &zk.Stat{Version: 2, Mzxid: 2}, nil).Once()
+				mocks.Conn.On("Get", "/flags/limit").Return(flagData(NewIntFlag(100)), &zk.Stat{Version: 2, Mzxid: 2}, nil).Once()
+
+				mocks.Events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: "/flags/limit"}
+
+				So(<-changes, ShouldEqual, "limit")
+				So(flags.Int("limit", 0), ShouldEqual, 100)
+
+				mocks.Conn.On("Get", "/flags/broken").Return([]byte("broken"), &zk.Stat{Version: 1}, nil).Once()
+				mocks.Conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{}, {String: "/flags/_audit/change-0000000002"}}, nil).Once()
+
+				So(flags.Remove("broken", "alice"), ShouldBeNil)
+
+				mocks.Events <- zk.Event{Type: zk.EventNodeDeleted, State: zk.StateHasSession, Path: "/flags/broken"}
+
+				So(<-changes, ShouldEqual, "broken")
+				So(flags.Names(), ShouldNotContain, "broken")
+
+				mocks.Conn.On("RemovePersistentWatch", "/flags", true).Return(nil).Once()
+
+				So(flags.Close(), ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
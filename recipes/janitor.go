@@ -0,0 +1,214 @@
+package recipes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A recipe path scanned by the Janitor
+type JanitorPath struct {
+	Path      string        // the parent path whose children are scanned, e.g. the base path of locks or queues
+	MinAge    time.Duration // the children modified more recently than this are left alone
+	Ephemeral bool          // also delete the ephemeral children whose owner session is verified to be dead
+
+	// Decide whether an old child without children is orphaned, e.g. a consumed queue marker or an expired lease.
+	// The default treats only the children without data as orphaned, so the unconsumed queue items are kept.
+	IsOrphaned func(path string, stat *zk.Stat) bool
+}
+
+// Verifies whether the session owning an ephemeral node is still alive
+type SessionOwnerVerifier interface {
+	// Return true if the owner session of the ephemeral node with the given stat is alive
+	IsOwnerAlive(client curator.CuratorFramework, path string, stat *zk.Stat) (bool, error)
+}
+
+// Verifies the owner session against the live sessions of a SessionRegistry.
+//
+// ZooKeeper keeps an ephemeral node as long as its owner session, so the session of an existing node is alive
+// on the server. The owner is treated as dead only if the node still exists unchanged and its session isn't
+// registered, i.e. the owner is not a live cooperating client, however slow it is.
+type RegistryOwnerVerifier struct {
+	Registry *SessionRegistry
+}
+
+func NewRegistryOwnerVerifier(registry *SessionRegistry) *RegistryOwnerVerifier {
+	return &RegistryOwnerVerifier{registry}
+}
+
+func (v *RegistryOwnerVerifier) IsOwnerAlive(client curator.CuratorFramework, path string, stat *zk.Stat) (bool, error) {
+	if stat.EphemeralOwner == 0 {
+		return false, nil
+	}
+
+	if sessions, err := v.Registry.LiveSessions(); err != nil {
+		return true, err
+	} else if sessions[stat.EphemeralOwner] {
+		return true, nil
+	}
+
+	if current, err := client.CheckExists().ForPath(path); err != nil {
+		return true, err
+	} else if current == nil {
+		return false, nil // the node has gone, the owner session has been closed or expired
+	} else if current.Czxid != stat.Czxid || current.EphemeralOwner != stat.EphemeralOwner {
+		return true, nil // the node has been recreated by another owner, leave it to the next scan
+	} else {
+		return false, nil
+	}
+}
+
+// A utility that periodically cleans up the debris left behind by recipes,
+// such as the parent nodes of locks and queues which are no longer used.
+//
+// Only the old persistent children without children and data of the configured paths are deleted,
+// unless the path decides with its IsOrphaned predicate, so the unconsumed queue items are kept.
+// Ephemeral nodes are left alone unless the path allows it and the OwnerVerifier, if any, reports the owner session is dead.
+// The nodes are deleted with a version check, so a node changed during the scan is kept.
+// Only the Janitor holding the leader lock cleans up, so several processes may run a Janitor on the same paths.
+type Janitor struct {
+	client        curator.CuratorFramework
+	lock          InterProcessLock
+	interval      time.Duration
+	paths         []JanitorPath
+	state         curator.State
+	done          chan struct{}
+	OwnerVerifier SessionOwnerVerifier // verifies the owner session before an ephemeral node is deleted
+}
+
+func NewJanitor(client curator.CuratorFramework, leaderPath string, interval time.Duration, paths ...JanitorPath) (*Janitor, error) {
+	for _, path := range paths {
+		if err := curator.ValidatePath(path.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if lock, err := NewInterProcessMutex(client, leaderPath); err != nil {
+		return nil, err
+	} else {
+		return &Janitor{
+			client:   client,
+			lock:     lock,
+			interval: interval,
+			paths:    paths,
+			done:     make(chan struct{}),
+		}, nil
+	}
+}
+
+// Start the janitor. The janitor is not started automatically. You must call this method.
+func (j *Janitor) Start() error {
+	if !j.state.Change(curator.LATENT, curator.STARTED) {
+		return fmt.Errorf("Cannot be started more than once")
+	}
+
+	go j.run()
+
+	return nil
+}
+
+func (j *Janitor) Close() error {
+	if j.state.Change(curator.STARTED, curator.STOPPED) {
+		close(j.done)
+	}
+
+	return nil
+}
+
+func (j *Janitor) run() {
+	t := time.NewTicker(j.interval)
+
+	defer t.Stop()
+
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-t.C:
+			if err := j.cleanAsLeader(); err != nil {
+				j.client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+					listener.(curator.UnhandledErrorListener).UnhandledError(err)
+				})
+			}
+		}
+	}
+}
+
+func (j *Janitor) cleanAsLeader() error {
+	if acquired, err := j.lock.AcquireTimeout(0); err != nil {
+		return err
+	} else if !acquired {
+		return nil // another janitor is running
+	}
+
+	defer j.lock.Release()
+
+	_, err := j.Clean()
+
+	return err
+}
+
+// Scan the configured paths once and delete the orphaned nodes, return the number of deleted nodes.
+// The leader lock is not used, the caller is responsible for the coordination.
+func (j *Janitor) Clean() (int, error) {
+	deleted := 0
+
+	for _, path := range j.paths {
+		children, err := j.client.GetChildren().ForPath(path.Path)
+
+		if err == zk.ErrNoNode {
+			continue
+		} else if err != nil {
+			return deleted, err
+		}
+
+		for _, child := range children {
+			childPath := curator.JoinPath(path.Path, child)
+
+			stat, err := j.client.CheckExists().ForPath(childPath)
+
+			if err != nil {
+				return deleted, err
+			} else if stat == nil || !isOrphaned(path, childPath, stat) {
+				continue
+			} else if stat.EphemeralOwner != 0 {
+				if !path.Ephemeral || j.OwnerVerifier == nil {
+					continue
+				} else if alive, err := j.OwnerVerifier.IsOwnerAlive(j.client, childPath, stat); err != nil {
+					return deleted, err
+				} else if alive {
+					continue
+				}
+			}
+
+			switch err := j.client.Delete().WithVersion(stat.Version).ForPath(childPath); err {
+			case nil:
+				deleted++
+			case zk.ErrNoNode, zk.ErrBadVersion, zk.ErrNotEmpty:
+				// ignore - changed since the scan
+			default:
+				return deleted, err
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+func isOrphaned(path JanitorPath, childPath string, stat *zk.Stat) bool {
+	if stat.NumChildren > 0 {
+		return false
+	}
+
+	if modified := time.Unix(0, stat.Mtime*int64(time.Millisecond)); time.Since(modified) < path.MinAge {
+		return false
+	}
+
+	if path.IsOrphaned != nil {
+		return path.IsOrphaned(childPath, stat)
+	}
+
+	return stat.DataLength == 0
+}
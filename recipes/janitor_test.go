@@ -0,0 +1,220 @@
+package recipes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJanitor(t *testing.T) {
+	Convey("Given a Janitor", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("base on an invalid path", func() {
+			janitor, err := NewJanitor(client, "/janitor", time.Minute, JanitorPath{Path: "locks"})
+
+			So(janitor, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("base on the lock paths", func() {
+			janitor, err := NewJanitor(client, "/janitor", time.Minute, JanitorPath{Path: "/locks", MinAge: time.Hour}, JanitorPath{Path: "/queues"})
+
+			So(janitor, ShouldNotBeNil)
+			So(err, ShouldBeNil)
+
+			old := time.Now().Add(-2*time.Hour).UnixNano() / int64(time.Millisecond)
+			recent := time.Now().UnixNano() / int64(time.Millisecond)
+
+			Convey("When clean the orphaned nodes", func() {
+				mocks.Conn.On("Children", "/locks").Return([]string{"ephemeral", "busy", "orphaned", "recent", "changed", "item"}, nil, nil).Once()
+				mocks.Conn.On("Exists", "/locks/ephemeral").Return(true, &zk.Stat{EphemeralOwner: 123, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/busy").Return(true, &zk.Stat{NumChildren: 1, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/orphaned").Return(true, &zk.Stat{Version: 3, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/recent").Return(true, &zk.Stat{Mtime: recent}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/changed").Return(true, &zk.Stat{Version: 1, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/item").Return(true, &zk.Stat{DataLength: 5, Mtime: old}, nil).Once()
+				mocks.Conn.On("Delete", "/locks/orphaned", int32(3)).Return(nil).Once()
+				mocks.Conn.On("Delete", "/locks/changed", int32(1)).Return(zk.ErrBadVersion).Once()
+				mocks.Conn.On("Children", "/queues").Return(nil, nil, zk.ErrNoNode).Once()
+
+				deleted, err := janitor.Clean()
+
+				Convey("Only the orphaned nodes should be deleted", func() {
+					So(deleted, ShouldEqual, 1)
+					So(err, ShouldBeNil)
+
+					mocks.Check(t)
+				})
+			})
+
+			Convey("When clean the expired leases with a predicate", func() {
+				janitor.paths = []JanitorPath{{Path: "/leases", MinAge: time.Hour, IsOrphaned: func(path string, stat *zk.Stat) bool {
+					return path == "/leases/expired"
+				}}}
+
+				mocks.Conn.On("Children", "/leases").Return([]string{"expired", "active"}, nil, nil).Once()
+				mocks.Conn.On("Exists", "/leases/expired").Return(true, &zk.Stat{DataLength: 8, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/leases/active").Return(true, &zk.Stat{DataLength: 8, Mtime: old}, nil).Once()
+				mocks.Conn.On("Delete", "/leases/expired", int32(0)).Return(nil).Once()
+
+				deleted, err := janitor.Clean()
+
+				Convey("Only the nodes matched by the predicate should be deleted", func() {
+					So(deleted, ShouldEqual, 1)
+					So(err, ShouldBeNil)
+
+					mocks.Check(t)
+				})
+			})
+
+			Convey("When clean the orphaned ephemeral nodes", func() {
+				registry, err := NewSessionRegistry(client, "/sessions")
+
+				So(err, ShouldBeNil)
+
+				janitor.paths = []JanitorPath{{Path: "/locks", MinAge: time.Hour, Ephemeral: true}}
+				janitor.OwnerVerifier = NewRegistryOwnerVerifier(registry)
+
+				mocks.Conn.On("Children", "/locks").Return([]string{"alive", "dead", "recreated"}, nil, nil).Once()
+				mocks.Conn.On("Children", "/sessions").Return([]string{"session-0000000001"}, nil, nil).Times(3)
+				mocks.Conn.On("Exists", "/sessions/session-0000000001").Return(true, &zk.Stat{EphemeralOwner: 123}, nil).Times(3)
+				mocks.Conn.On("Exists", "/locks/alive").Return(true, &zk.Stat{Czxid: 1, EphemeralOwner: 123, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/dead").Return(true, &zk.Stat{Czxid: 2, EphemeralOwner: 456, Mtime: old}, nil).Twice()
+				mocks.Conn.On("Delete", "/locks/dead", int32(0)).Return(nil).Once()
+				mocks.Conn.On("Exists", "/locks/recreated").Return(true, &zk.Stat{Czxid: 3, EphemeralOwner: 789, Mtime: old}, nil).Once()
+				mocks.Conn.On("Exists", "/locks/recreated").Return(true, &zk.Stat{Czxid: 4, EphemeralOwner: 790, Mtime: recent}, nil).Once()
+
+				deleted, err := janitor.Clean()
+
+				Convey("Only the nodes of the unregistered sessions should be deleted", func() {
+					So(deleted, ShouldEqual, 1)
+					So(err, ShouldBeNil)
+
+					mocks.Check(t)
+				})
+			})
+
+			Convey("When clean the ephemeral nodes without an owner verifier", func() {
+				janitor.paths = []JanitorPath{{Path: "/locks", MinAge: time.Hour, Ephemeral: true}}
+
+				mocks.Conn.On("Children", "/locks").Return([]string{"ephemeral"}, nil, nil).Once()
+				mocks.Conn.On("Exists", "/locks/ephemeral").Return(true, &zk.Stat{EphemeralOwner: 123, Mtime: old}, nil).Once()
+
+				deleted, err := janitor.Clean()
+
+				Convey("The ephemeral nodes should be kept", func() {
+					So(deleted, ShouldEqual, 0)
+					So(err, ShouldBeNil)
+
+					mocks.Check(t)
+				})
+			})
+
+			Convey("When another janitor is the leader", func() {
+				mocks.Conn.On("Create", zktest.ProtectedPath("/janitor", "lock-"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/janitor/lock-0000000002", nil).Once()
+				mocks.Conn.On("Children", "/janitor").Return([]string{"lock-0000000002", "lock-0000000001"}, nil, nil).Once()
+				mocks.Conn.On("GetW", "/janitor/lock-0000000001").Return([]byte{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("Delete", "/janitor/lock-0000000002", curator.AnyVersion).Return(nil).Once()
+
+				Convey("Nothing should be cleaned", func() {
+					So(janitor.cleanAsLeader(), ShouldBeNil)
+
+					mocks.Check(t)
+				})
+			})
+		})
+	})
+}
+
+func TestRegistryOwnerVerifier(t *testing.T) {
+	Convey("Given a RegistryOwnerVerifier", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		registry, err := NewSessionRegistry(client, "/sessions")
+
+		So(err, ShouldBeNil)
+
+		verifier := NewRegistryOwnerVerifier(registry)
+
+		stat := &zk.Stat{Czxid: 1, EphemeralOwner: 123}
+
+		Convey("When the node is persistent", func() {
+			alive, err := verifier.IsOwnerAlive(client, "/node", &zk.Stat{})
+
+			So(alive, ShouldBeFalse)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("When the owner session is registered", func() {
+			mocks.Conn.On("Children", "/sessions").Return([]string{"session-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("Exists", "/sessions/session-0000000001").Return(true, &zk.Stat{EphemeralOwner: 123}, nil).Once()
+
+			alive, err := verifier.IsOwnerAlive(client, "/node", stat)
+
+			So(alive, ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When the owner session isn't registered", func() {
+			mocks.Conn.On("Children", "/sessions").Return(nil, nil, zk.ErrNoNode).Once()
+			mocks.Conn.On("Exists", "/node").Return(true, &zk.Stat{Czxid: 1, EphemeralOwner: 123}, nil).Once()
+
+			alive, err := verifier.IsOwnerAlive(client, "/node", stat)
+
+			So(alive, ShouldBeFalse)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When the node has been recreated", func() {
+			mocks.Conn.On("Children", "/sessions").Return([]string{}, nil, nil).Once()
+			mocks.Conn.On("Exists", "/node").Return(true, &zk.Stat{Czxid: 2, EphemeralOwner: 456}, nil).Once()
+
+			alive, err := verifier.IsOwnerAlive(client, "/node", stat)
+
+			So(alive, ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When the node has gone", func() {
+			mocks.Conn.On("Children", "/sessions").Return([]string{}, nil, nil).Once()
+			mocks.Conn.On("Exists", "/node").Return(false, nil, nil).Once()
+
+			alive, err := verifier.IsOwnerAlive(client, "/node", stat)
+
+			So(alive, ShouldBeFalse)
+			So(err, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("When failed to list the sessions", func() {
+			mocks.Conn.On("Children", "/sessions").Return(nil, nil, zk.ErrConnectionClosed).Once()
+
+			alive, err := verifier.IsOwnerAlive(client, "/node", stat)
+
+			So(alive, ShouldBeTrue)
+			So(err, ShouldEqual, zk.ErrConnectionClosed)
+
+			mocks.Check(t)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package recipes
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The length of the sequence suffix appended by ZooKeeper to the sequential nodes
+const SEQUENCE_LENGTH = 10
+
+var ErrNoLeader = errors.New("no leader of the election path")
+
+// The current leader of an election path
+type Leader struct {
+	Path   string    // the full path of the election node held by the leader
+	Data   []byte    // the payload of the election node, e.g. the participant id
+	Stat   *zk.Stat  // the stat of the election node
+	Joined time.Time // when the leader joined the election, the creation time of its election node
+}
+
+// Return how long the leader has been in the election, which bounds how long it has led.
+//
+// The leader is elected when the participants in front of it leave the election,
+// which is later than it joined if it wasn't the first one in line.
+func (l *Leader) Duration() time.Duration {
+	return time.Since(l.Joined)
+}
+
+// Determine the current leader of a latch or selector election path without joining the election.
+//
+// The participants are ordered by the sequence of their election nodes, and the first one is the leader.
+// If the leader leaves while its node is read, the next participant in line is checked instead.
+func GetCurrentLeader(client curator.CuratorFramework, path string) (*Leader, error) {
+	children, err := client.GetChildren().ForPath(path)
+
+	if err == zk.ErrNoNode {
+		return nil, ErrNoLeader
+	} else if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(ChildrenSorter{children, func(lhs, rhs string) bool {
+		return sequenceOf(lhs) < sequenceOf(rhs)
+	}})
+
+	for _, child := range children {
+		childPath := curator.JoinPath(path, child)
+
+		var stat zk.Stat
+
+		if data, err := client.GetData().StoringStatIn(&stat).ForPath(childPath); err == zk.ErrNoNode {
+			continue // the participant has left, the next one takes over
+		} else if err != nil {
+			return nil, err
+		} else {
+			return &Leader{
+				Path:   childPath,
+				Data:   data,
+				Stat:   &stat,
+				Joined: time.Unix(0, stat.Ctime*int64(time.Millisecond)),
+			}, nil
+		}
+	}
+
+	return nil, ErrNoLeader
+}
+
+func sequenceOf(name string) string {
+	if len(name) > SEQUENCE_LENGTH {
+		return name[len(name)-SEQUENCE_LENGTH:]
+	}
+
+	return name
+}
@@ -0,0 +1,77 @@
+package recipes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetCurrentLeader(t *testing.T) {
+	Convey("Given an election path", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		ctime := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+
+		Convey("When the path doesn't exist", func() {
+			mocks.Conn.On("Children", "/election").Return(nil, nil, zk.ErrNoNode).Once()
+
+			leader, err := GetCurrentLeader(client, "/election")
+
+			So(leader, ShouldBeNil)
+			So(err, ShouldEqual, ErrNoLeader)
+
+			mocks.Check(t)
+		})
+
+		Convey("When there are participants", func() {
+			mocks.Conn.On("Children", "/election").Return([]string{"_c_abc-lock-0000000003", "lock-0000000002", "latch-0000000001"}, nil, nil).Once()
+
+			Convey("The first participant should be the leader", func() {
+				mocks.Conn.On("Get", "/election/latch-0000000001").Return([]byte("node1"), &zk.Stat{Ctime: ctime}, nil).Once()
+
+				leader, err := GetCurrentLeader(client, "/election")
+
+				So(err, ShouldBeNil)
+				So(leader, ShouldNotBeNil)
+				So(leader.Path, ShouldEqual, "/election/latch-0000000001")
+				So(string(leader.Data), ShouldEqual, "node1")
+				So(leader.Joined.UnixNano()/int64(time.Millisecond), ShouldEqual, ctime)
+				So(leader.Duration(), ShouldBeGreaterThanOrEqualTo, time.Hour)
+
+				mocks.Check(t)
+			})
+
+			Convey("The next participant should take over when the leader has left", func() {
+				mocks.Conn.On("Get", "/election/latch-0000000001").Return(nil, nil, zk.ErrNoNode).Once()
+				mocks.Conn.On("Get", "/election/lock-0000000002").Return([]byte("node2"), &zk.Stat{Ctime: ctime}, nil).Once()
+
+				leader, err := GetCurrentLeader(client, "/election")
+
+				So(err, ShouldBeNil)
+				So(leader, ShouldNotBeNil)
+				So(leader.Path, ShouldEqual, "/election/lock-0000000002")
+				So(string(leader.Data), ShouldEqual, "node2")
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When all the participants have left", func() {
+			mocks.Conn.On("Children", "/election").Return([]string{"lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("Get", "/election/lock-0000000001").Return(nil, nil, zk.ErrNoNode).Once()
+
+			leader, err := GetCurrentLeader(client, "/election")
+
+			So(leader, ShouldBeNil)
+			So(err, ShouldEqual, ErrNoLeader)
+
+			mocks.Check(t)
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package recipes
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -13,6 +14,9 @@ import (
 
 const LockPrefix = "lock-"
 
+// The connection entered an error state of the ConnectionStateErrorPolicy of the client while waiting for the lock
+var ErrLockConnectionState = errors.New("the connection entered an error state while waiting for the lock")
+
 type InterProcessLock interface {
 	// Acquire the mutex - blocking until it's available.
 	// Each call to acquire must be balanced by a call to Release()
@@ -98,9 +102,9 @@ func (d *StandardLockInternalsDriver) GetsTheLock(client curator.CuratorFramewor
 
 func (d *StandardLockInternalsDriver) CreatesTheLock(client curator.CuratorFramework, path string, lockNodeBytes []byte) (string, error) {
 	if lockNodeBytes == nil {
-		return client.Create().CreatingParentsIfNeeded().WithMode(curator.EPHEMERAL_SEQUENTIAL).ForPath(path)
+		return client.Create().CreatingParentContainersIfNeeded().WithProtection().WithMode(curator.EPHEMERAL_SEQUENTIAL).ForPath(path)
 	} else {
-		return client.Create().CreatingParentsIfNeeded().WithMode(curator.EPHEMERAL_SEQUENTIAL).ForPathWithData(path, lockNodeBytes)
+		return client.Create().CreatingParentContainersIfNeeded().WithProtection().WithMode(curator.EPHEMERAL_SEQUENTIAL).ForPathWithData(path, lockNodeBytes)
 	}
 }
 
@@ -219,11 +223,12 @@ func (l *lockInternals) attemptLock(waitTime time.Duration, lockNodeBytes []byte
 	retryCount := 0
 
 	for {
-		var ourPath string
-		var err error
+		ourPath, err := l.driver.CreatesTheLock(l.client, l.lockPath, lockNodeBytes)
+
+		if err == nil {
+			var hasTheLock bool
 
-		if ourPath, err = l.driver.CreatesTheLock(l.client, l.lockPath, lockNodeBytes); err == nil {
-			if hasTheLock, err := l.internalLockLoop(startTime, waitTime, ourPath); err == nil {
+			if hasTheLock, err = l.internalLockLoop(startTime, waitTime, ourPath); err == nil {
 				if hasTheLock {
 					return ourPath, nil
 				} else {
@@ -240,9 +245,7 @@ func (l *lockInternals) attemptLock(waitTime time.Duration, lockNodeBytes []byte
 			}
 		}
 
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 }
 
@@ -251,47 +254,96 @@ func (l *lockInternals) releaseLock(path string) error {
 }
 
 func (l *lockInternals) deleteOurPath(path string) error {
-	if err := l.client.Delete().ForPath(path); err == zk.ErrNoNode {
-		return nil // ignore - already deleted (possibly expired session, etc.)
-	} else {
-		return err
-	}
+	// ignore - already deleted (possibly expired session, etc.)
+	return l.client.Delete().Quietly().ForPath(path)
 }
 
 func (l *lockInternals) internalLockLoop(startTime time.Time, waitTime time.Duration, path string) (haveTheLock bool, err error) {
 	var doDelete bool
 
-	for l.client.State() == curator.STARTED && !haveTheLock {
-		if children, err := l.getSortedChildren(); err != nil {
+	wait := trackLockWait(l.basePath, path, startTime)
+
+	defer wait.done()
+
+	failed := make(chan struct{}, 1)
+
+	stateListener := curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		if client.ConnectionStateErrorPolicy().IsErrorState(newState) {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	l.client.ConnectionStateListenable().AddListener(stateListener)
+
+	defer l.client.ConnectionStateListenable().RemoveListener(stateListener)
+
+	for l.client.State() == curator.STARTED && !haveTheLock && !doDelete {
+		var children []string
+		var results *PredicateResults
+
+		if children, err = l.getSortedChildren(); err != nil {
 			break
-		} else {
-			sequenceNodeName := path[len(l.basePath)+1:]
+		}
 
-			if results, err := l.driver.GetsTheLock(l.client, children, sequenceNodeName, l.maxLeases); err != nil {
-				break
-			} else if results.GetsTheLock {
-				haveTheLock = true
+		sequenceNodeName := path[len(l.basePath)+1:]
+
+		for i, child := range children {
+			if child == sequenceNodeName {
+				wait.setPosition(i)
 
 				break
-			} else {
-				previousSequencePath := curator.JoinPath(l.basePath, results.PathToWatch)
+			}
+		}
 
-				c := make(chan error)
+		if results, err = l.driver.GetsTheLock(l.client, children, sequenceNodeName, l.maxLeases); err != nil {
+			break
+		} else if results.GetsTheLock {
+			haveTheLock = true
 
-				t := time.NewTimer(waitTime - time.Now().Sub(startTime))
+			break
+		}
 
-				l.client.GetData().UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
-					c <- event.Err
-				})).ForPath(previousSequencePath)
+		previousSequencePath := curator.JoinPath(l.basePath, results.PathToWatch)
 
-				select {
-				case err := <-c:
-					if err != nil && err != zk.ErrNoNode {
-						break
-					}
-				case <-t.C:
-				}
+		c := make(chan error, 1)
+
+		if _, err = l.client.GetData().UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
+			c <- event.Err
+		})).ForPath(previousSequencePath); err == zk.ErrNoNode {
+			err = nil // the previous one has gone, try again
+
+			continue
+		} else if err != nil {
+			break
+		}
+
+		if waitTime < 0 {
+			select {
+			case <-c:
+			case <-failed:
+				err = ErrLockConnectionState
 			}
+		} else if remaining := waitTime - time.Now().Sub(startTime); remaining <= 0 {
+			doDelete = true // timed out
+		} else {
+			t := time.NewTimer(remaining)
+
+			select {
+			case <-c:
+			case <-failed:
+				err = ErrLockConnectionState
+			case <-t.C:
+				doDelete = true // timed out
+			}
+
+			t.Stop()
+		}
+
+		if err != nil {
+			break
 		}
 	}
 
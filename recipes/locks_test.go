@@ -2,8 +2,12 @@ package recipes
 
 import (
 	"testing"
+	"time"
 
 	"github.com/flier/curator.go"
+	"github.com/flier/curator.go/curatortest"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -85,7 +89,7 @@ func TestLockInternalsDriver(t *testing.T) {
 				So(client.Start(), ShouldBeNil)
 
 				Convey("When lock with data", func() {
-					mocks.conn.On("Create", "/lock", []byte("data"), int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock", nil).Once()
+					mocks.Conn.On("Create", zktest.ProtectedPath("/", "lock"), []byte("data"), int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock", nil).Once()
 
 					path, err := driver.CreatesTheLock(client, "/lock", []byte("data"))
 
@@ -98,7 +102,7 @@ func TestLockInternalsDriver(t *testing.T) {
 				})
 
 				Convey("When lock without data", func() {
-					mocks.conn.On("Create", "/lock", mocks.builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock", nil).Once()
+					mocks.Conn.On("Create", zktest.ProtectedPath("/", "lock"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock", nil).Once()
 
 					path, err := driver.CreatesTheLock(client, "/lock", nil)
 
@@ -142,6 +146,148 @@ func TestLockInternals(t *testing.T) {
 
 func TestInterProcessMutex(t *testing.T) {
 	Convey("Given an InterProcessMutex base on a path", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		mutex, err := NewInterProcessMutex(client, "/lock")
+
+		So(mutex, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		Convey("When the wait time expires while a predecessor holds the lock", func() {
+			mocks.Conn.On("Create", zktest.ProtectedPath("/lock", "lock-"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock/lock-0000000002", nil).Once()
+			mocks.Conn.On("Children", "/lock").Return([]string{"lock-0000000002", "lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("GetW", "/lock/lock-0000000001").Return([]byte{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+			mocks.Conn.On("Delete", "/lock/lock-0000000002", curator.AnyVersion).Return(nil).Once()
+
+			start := time.Now()
+
+			acquired, err := mutex.AcquireTimeout(50 * time.Millisecond)
+
+			Convey("The lock should not be acquired and our node should be deleted", func() {
+				So(acquired, ShouldBeFalse)
+				So(err, ShouldBeNil)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 50*time.Millisecond)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the predecessor releases the lock before the wait time expires", func() {
+			events := make(chan zk.Event, 1)
+
+			mocks.Conn.On("Create", zktest.ProtectedPath("/lock", "lock-"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock/lock-0000000002", nil).Once()
+			mocks.Conn.On("Children", "/lock").Return([]string{"lock-0000000002", "lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("GetW", "/lock/lock-0000000001").Return([]byte{}, &zk.Stat{}, events, nil).Once()
+			mocks.Conn.On("Children", "/lock").Return([]string{"lock-0000000002"}, nil, nil).Once()
+
+			events <- zk.Event{Type: zk.EventNodeDeleted, Path: "/lock/lock-0000000001"}
+
+			acquired, err := mutex.AcquireTimeout(time.Minute)
+
+			Convey("The lock should be acquired", func() {
+				So(acquired, ShouldBeTrue)
+				So(err, ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the acquisition waits longer than the stall threshold", func() {
+			SetLockStallThreshold(10 * time.Millisecond)
+
+			defer SetLockStallThreshold(DEFAULT_LOCK_STALL_THRESHOLD)
+
+			stalls := make(chan LockWait, 1)
+			waits := make(chan []LockWait, 1)
+
+			listener := NewLockStallListener(func(wait LockWait) {
+				waits <- CurrentLockWaits()
+				stalls <- wait
+			})
+
+			LockStalls().AddListener(listener)
+
+			defer LockStalls().RemoveListener(listener)
+
+			mocks.Conn.On("Create", zktest.ProtectedPath("/lock", "lock-"), mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/lock/lock-0000000002", nil).Once()
+			mocks.Conn.On("Children", "/lock").Return([]string{"lock-0000000002", "lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("GetW", "/lock/lock-0000000001").Return([]byte{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+			mocks.Conn.On("Delete", "/lock/lock-0000000002", curator.AnyVersion).Return(nil).Once()
+
+			acquired, err := mutex.AcquireTimeout(50 * time.Millisecond)
 
+			Convey("The stall listeners should be notified with the queue position", func() {
+				So(acquired, ShouldBeFalse)
+				So(err, ShouldBeNil)
+
+				wait := <-stalls
+
+				So(wait.LockPath, ShouldEqual, "/lock")
+				So(wait.NodePath, ShouldEqual, "/lock/lock-0000000002")
+				So(wait.Position, ShouldEqual, 1)
+				So(wait.Waited(), ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+
+				So(<-waits, ShouldResemble, []LockWait{wait})
+				So(CurrentLockWaits(), ShouldBeEmpty)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
+
+func TestInterProcessMutexWithTestingServer(t *testing.T) {
+	Convey("Given two InterProcessMutexes of the clients of a TestingServer", t, func() {
+		server, err := curatortest.NewTestingServer()
+
+		So(err, ShouldBeNil)
+
+		defer server.Close()
+
+		var mutexes []*InterProcessMutex
+
+		for i := 0; i < 2; i++ {
+			client := server.NewClient(curator.NewRetryNTimes(3, 10*time.Millisecond))
+
+			So(client.Start(), ShouldBeNil)
+
+			defer client.Close()
+
+			mutex, err := NewInterProcessMutex(client, "/lock")
+
+			So(err, ShouldBeNil)
+
+			mutexes = append(mutexes, mutex)
+		}
+
+		Convey("When the first mutex holds the lock", func() {
+			acquired, err := mutexes[0].Acquire()
+
+			So(acquired, ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			Convey("The second mutex should acquire the lock once it is released", func() {
+				acquired, err := mutexes[1].AcquireTimeout(50 * time.Millisecond)
+
+				So(acquired, ShouldBeFalse)
+				So(err, ShouldBeNil)
+
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+
+					mutexes[0].Release()
+				}()
+
+				acquired, err = mutexes[1].AcquireTimeout(time.Second)
+
+				So(acquired, ShouldBeTrue)
+				So(err, ShouldBeNil)
+				So(mutexes[1].Release(), ShouldBeNil)
+			})
+		})
 	})
 }
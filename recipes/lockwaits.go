@@ -0,0 +1,158 @@
+package recipes
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flier/curator.go"
+)
+
+// The default time a lock acquisition waits before the LockStallListeners are notified
+const DEFAULT_LOCK_STALL_THRESHOLD = 30 * time.Second
+
+// A lock acquisition waiting in the queue of a lock
+type LockWait struct {
+	LockPath string    // the base path of the lock
+	NodePath string    // the lock node of the acquisition
+	Position int       // the number of the lock nodes in front of the lock node
+	Started  time.Time // when the acquisition started
+}
+
+// Return how long the acquisition has been waiting
+func (w *LockWait) Waited() time.Duration {
+	return time.Since(w.Started)
+}
+
+// Listener of the lock acquisitions waiting longer than the stall threshold
+type LockStallListener interface {
+	// Called once per acquisition when it has been waiting longer than the threshold
+	LockStalled(wait LockWait)
+}
+
+type lockStallListenerCallback func(wait LockWait)
+
+type lockStallListenerStub struct {
+	callback lockStallListenerCallback
+}
+
+func NewLockStallListener(callback lockStallListenerCallback) LockStallListener {
+	return &lockStallListenerStub{callback}
+}
+
+func (l *lockStallListenerStub) LockStalled(wait LockWait) {
+	l.callback(wait)
+}
+
+type LockStallListenable interface {
+	curator.Listenable /* [T] */
+
+	AddListener(listener LockStallListener)
+
+	RemoveListener(listener LockStallListener)
+}
+
+type LockStallListenerContainer struct {
+	*curator.ListenerContainer
+}
+
+func (c *LockStallListenerContainer) AddListener(listener LockStallListener) {
+	c.Add(listener)
+}
+
+func (c *LockStallListenerContainer) RemoveListener(listener LockStallListener) {
+	c.Remove(listener)
+}
+
+var (
+	lockStallThreshold = int64(DEFAULT_LOCK_STALL_THRESHOLD)
+	lockStallListeners = &LockStallListenerContainer{&curator.ListenerContainer{}}
+	lockWaitsLock      sync.Mutex
+	lockWaits          = make(map[*lockWaitTracker]bool)
+)
+
+// Return the listeners notified of the stalled lock acquisitions of all the locks in the process
+func LockStalls() LockStallListenable {
+	return lockStallListeners
+}
+
+// Set how long a lock acquisition waits before the LockStallListeners are notified, zero disables the notifications.
+// The threshold applies to the acquisitions started afterwards.
+func SetLockStallThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&lockStallThreshold, int64(threshold))
+}
+
+// Return the lock acquisitions of all the locks in the process which are waiting for their locks, longest first
+func CurrentLockWaits() []LockWait {
+	lockWaitsLock.Lock()
+
+	waits := make([]LockWait, 0, len(lockWaits))
+
+	for tracker := range lockWaits {
+		waits = append(waits, tracker.wait)
+	}
+
+	lockWaitsLock.Unlock()
+
+	sort.Sort(lockWaitsByStarted(waits))
+
+	return waits
+}
+
+type lockWaitsByStarted []LockWait
+
+func (w lockWaitsByStarted) Len() int { return len(w) }
+
+func (w lockWaitsByStarted) Less(i, j int) bool { return w[i].Started.Before(w[j].Started) }
+
+func (w lockWaitsByStarted) Swap(i, j int) { w[i], w[j] = w[j], w[i] }
+
+type lockWaitTracker struct {
+	wait  LockWait
+	timer *time.Timer
+}
+
+func trackLockWait(lockPath, nodePath string, started time.Time) *lockWaitTracker {
+	tracker := &lockWaitTracker{wait: LockWait{LockPath: lockPath, NodePath: nodePath, Position: -1, Started: started}}
+
+	lockWaitsLock.Lock()
+	lockWaits[tracker] = true
+	lockWaitsLock.Unlock()
+
+	if threshold := time.Duration(atomic.LoadInt64(&lockStallThreshold)); threshold > 0 {
+		tracker.timer = time.AfterFunc(threshold-time.Since(started), tracker.stalled)
+	}
+
+	return tracker
+}
+
+func (t *lockWaitTracker) setPosition(position int) {
+	lockWaitsLock.Lock()
+	t.wait.Position = position
+	lockWaitsLock.Unlock()
+}
+
+func (t *lockWaitTracker) stalled() {
+	lockWaitsLock.Lock()
+
+	wait, waiting := t.wait, lockWaits[t]
+
+	lockWaitsLock.Unlock()
+
+	if waiting {
+		lockStallListeners.ForEach(func(listener interface{}) {
+			listener.(LockStallListener).LockStalled(wait)
+		})
+	}
+}
+
+func (t *lockWaitTracker) done() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	lockWaitsLock.Lock()
+	delete(lockWaits, t)
+	lockWaitsLock.Unlock()
+}
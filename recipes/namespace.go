@@ -0,0 +1,25 @@
+package recipes
+
+import (
+	"strings"
+
+	"github.com/flier/curator.go"
+)
+
+// Return a namespaced facade of the client rooted at the base path, within the namespace of the client if any.
+//
+// All the recipes accept the facade as their client, the paths of the recipe are relative to the base path,
+// so the recipe can't accidentally escape its subtree. The base path is created when the facade is first used.
+func UsingBasePath(client curator.CuratorFramework, basePath string) (curator.CuratorFramework, error) {
+	if err := curator.ValidatePath(basePath); err != nil {
+		return nil, err
+	}
+
+	if basePath == curator.PATH_SEPARATOR {
+		return client, nil
+	}
+
+	namespace := strings.TrimPrefix(curator.JoinPath(client.Namespace(), basePath), curator.PATH_SEPARATOR)
+
+	return client.UsingNamespace(namespace), nil
+}
@@ -0,0 +1,59 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUsingBasePath(t *testing.T) {
+	Convey("Given a client", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("When the base path is invalid", func() {
+			facade, err := UsingBasePath(client, "app/")
+
+			So(facade, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("When the base path is the root", func() {
+			facade, err := UsingBasePath(client, "/")
+
+			So(facade, ShouldEqual, client)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("When the client is namespaced", func() {
+			facade, err := UsingBasePath(client.UsingNamespace("app"), "/recipes/locks")
+
+			So(err, ShouldBeNil)
+			So(facade.Namespace(), ShouldEqual, "app/recipes/locks")
+		})
+
+		Convey("When a recipe uses the facade", func() {
+			facade, err := UsingBasePath(client, "/app")
+
+			So(err, ShouldBeNil)
+			So(facade.Namespace(), ShouldEqual, "app")
+
+			mocks.Conn.On("Exists", "/app").Return(true, nil, nil).Once()
+			mocks.Conn.On("Children", "/app/election").Return([]string{"lock-0000000001"}, nil, nil).Once()
+			mocks.Conn.On("Get", "/app/election/lock-0000000001").Return([]byte("node"), &zk.Stat{}, nil).Once()
+
+			leader, err := GetCurrentLeader(facade, "/election")
+
+			Convey("The recipe paths should be relative to the base path", func() {
+				So(err, ShouldBeNil)
+				So(leader.Path, ShouldEqual, "/election/lock-0000000001")
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
@@ -0,0 +1,80 @@
+package recipes
+
+import (
+	"sync"
+
+	"github.com/flier/curator.go"
+)
+
+// The max number of the paths primed concurrently by Prefetch
+const PREFETCH_PARALLELISM = 8
+
+// Prime the caches of the given paths and arm their watches, e.g. before a service starts taking traffic,
+// so the first reads are served by the caches instead of cold ZooKeeper reads. The paths are read concurrently.
+//
+// Return the started caches by path, which must be closed when they are no longer used.
+// If a path fails to be primed, the caches already started are closed.
+func Prefetch(client curator.CuratorFramework, paths ...string) (map[string]*NodeCache, error) {
+	caches := make(map[string]*NodeCache, len(paths))
+
+	for _, path := range paths {
+		caches[path] = NewNodeCache(client, path, false)
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var firstErr error
+
+	tokens := make(chan struct{}, PREFETCH_PARALLELISM)
+
+	for _, cache := range caches {
+		wg.Add(1)
+
+		tokens <- struct{}{}
+
+		go func(cache *NodeCache) {
+			defer func() {
+				<-tokens
+
+				wg.Done()
+			}()
+
+			if err := cache.StartAndInitalize(true); err != nil {
+				lock.Lock()
+
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				lock.Unlock()
+			}
+		}(cache)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		for _, cache := range caches {
+			cache.Close()
+		}
+
+		return nil, firstErr
+	}
+
+	return caches, nil
+}
+
+// Prime a TreeCache of the given path up to the depth and arm its watches, see Prefetch.
+//
+// Return the started cache, which must be closed when it is no longer used.
+func PrefetchTree(client curator.CuratorFramework, path string, depth int) (*TreeCache, error) {
+	cache := NewTreeCache(client, path, true, false).SetMaxDepth(depth)
+
+	if err := cache.Start(); err != nil {
+		cache.Close()
+
+		return nil, err
+	}
+
+	return cache, nil
+}
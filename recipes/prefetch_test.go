@@ -0,0 +1,84 @@
+package recipes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Wait until the background read of the watched node is cached
+func waitForData(cache *NodeCache, mzxid int64) *ChildData {
+	for i := 0; i < 100; i++ {
+		if data := cache.CurrentData(); data != nil && data.Stat.Mzxid == mzxid {
+			return data
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return cache.CurrentData()
+}
+
+func TestPrefetch(t *testing.T) {
+	Convey("Given some paths to prefetch", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("The caches should be primed and watched", func() {
+			for _, path := range []string{"/config/a", "/config/b"} {
+				mocks.Conn.On("Exists", "/config").Return(true, &zk.Stat{}, nil).Once()
+				mocks.Conn.On("Get", path).Return([]byte(path), &zk.Stat{Mzxid: 1}, nil).Once()
+				mocks.Conn.On("ExistsW", path).Return(true, &zk.Stat{Mzxid: 2}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("GetW", path).Return([]byte(path), &zk.Stat{Mzxid: 2}, make(chan zk.Event), nil).Once()
+			}
+
+			caches, err := Prefetch(client, "/config/a", "/config/b")
+
+			So(err, ShouldBeNil)
+			So(caches, ShouldHaveLength, 2)
+
+			for path, cache := range caches {
+				So(string(cache.CurrentData().Data), ShouldEqual, path)
+				So(waitForData(cache, 2).Stat.Mzxid, ShouldEqual, 2)
+				So(cache.Close(), ShouldBeNil)
+			}
+
+			mocks.Check(t)
+		})
+
+		Convey("The caches should not be returned if a path fails", func() {
+			mocks.Conn.On("Get", "/broken").Return(nil, nil, errors.New("broken")).Once()
+
+			caches, err := Prefetch(client, "/broken")
+
+			So(err, ShouldNotBeNil)
+			So(caches, ShouldBeNil)
+
+			mocks.Check(t)
+		})
+
+		Convey("The tree should be primed up to the depth", func() {
+			mocks.Conn.On("AddPersistentWatch", "/tree", true).Return(nil).Once()
+			mocks.Conn.On("Exists", "/tree").Return(true, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Get", "/tree").Return([]byte("root"), &zk.Stat{}, nil).Once()
+
+			cache, err := PrefetchTree(client, "/tree", 0)
+
+			So(err, ShouldBeNil)
+			So(string(cache.CurrentData("/tree").Data), ShouldEqual, "root")
+			So(cache.CurrentChildren("/tree"), ShouldBeEmpty)
+
+			mocks.Conn.On("RemovePersistentWatch", "/tree", true).Return(nil).Once()
+
+			So(cache.Close(), ShouldBeNil)
+
+			mocks.Check(t)
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package recipes
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const SESSION_NODE_NAME = "session-"
+
+// Tracks the live sessions of the cooperating clients under a registry path.
+//
+// Each client registers its session with an ephemeral node, whose ephemeral owner is the session of the client,
+// so the node is deleted by the server when the session is closed or expires.
+// The node is registered again after a reconnection, since the session may have expired meanwhile.
+type SessionRegistry struct {
+	client                  curator.CuratorFramework
+	path                    string
+	state                   curator.State
+	lock                    sync.Mutex
+	nodePath                string
+	connectionStateListener curator.ConnectionStateListener
+}
+
+func NewSessionRegistry(client curator.CuratorFramework, path string) (*SessionRegistry, error) {
+	if err := curator.ValidatePath(path); err != nil {
+		return nil, err
+	}
+
+	r := &SessionRegistry{client: client, path: path}
+
+	r.connectionStateListener = curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		if newState == curator.RECONNECTED {
+			if err := r.register(); err != nil {
+				client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+					listener.(curator.UnhandledErrorListener).UnhandledError(fmt.Errorf("Trying to register the session after reconnection, %s", err))
+				})
+			}
+		}
+	})
+
+	return r, nil
+}
+
+// Start the registry and register the session of the client. You must call this method.
+func (r *SessionRegistry) Start() error {
+	if !r.state.Change(curator.LATENT, curator.STARTED) {
+		return fmt.Errorf("Cannot be started more than once")
+	}
+
+	r.client.ConnectionStateListenable().AddListener(r.connectionStateListener)
+
+	return r.register()
+}
+
+// Close the registry and unregister the session of the client
+func (r *SessionRegistry) Close() error {
+	if !r.state.Change(curator.STARTED, curator.STOPPED) {
+		return nil
+	}
+
+	r.client.ConnectionStateListenable().RemoveListener(r.connectionStateListener)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.nodePath) > 0 {
+		if err := r.client.Delete().Quietly().ForPath(r.nodePath); err != nil {
+			return err
+		}
+
+		r.nodePath = ""
+	}
+
+	return nil
+}
+
+func (r *SessionRegistry) register() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.nodePath) > 0 {
+		if stat, err := r.client.CheckExists().ForPath(r.nodePath); err != nil {
+			return err
+		} else if stat != nil {
+			return nil // the session is still alive
+		}
+	}
+
+	if nodePath, err := r.client.Create().CreatingParentContainersIfNeeded().WithMode(curator.EPHEMERAL_SEQUENTIAL).ForPath(curator.JoinPath(r.path, SESSION_NODE_NAME)); err != nil {
+		return err
+	} else {
+		r.nodePath = nodePath
+	}
+
+	return nil
+}
+
+// Return the IDs of the registered live sessions
+func (r *SessionRegistry) LiveSessions() (map[int64]bool, error) {
+	children, err := r.client.GetChildren().ForPath(r.path)
+
+	if err == zk.ErrNoNode {
+		return map[int64]bool{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[int64]bool, len(children))
+
+	for _, child := range children {
+		if stat, err := r.client.CheckExists().ForPath(curator.JoinPath(r.path, child)); err != nil {
+			return nil, err
+		} else if stat != nil && stat.EphemeralOwner != 0 {
+			sessions[stat.EphemeralOwner] = true
+		}
+	}
+
+	return sessions, nil
+}
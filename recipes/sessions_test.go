@@ -0,0 +1,80 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	Convey("Given a SessionRegistry", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		Convey("base on an invalid path", func() {
+			registry, err := NewSessionRegistry(client, "sessions")
+
+			So(registry, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+
+		registry, err := NewSessionRegistry(client, "/sessions")
+
+		So(registry, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		Convey("When the registry is started", func() {
+			mocks.Conn.On("Create", "/sessions/session-", mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/sessions/session-0000000001", nil).Once()
+
+			So(registry.Start(), ShouldBeNil)
+
+			Convey("The session should be registered again once it has expired", func() {
+				mocks.Conn.On("Exists", "/sessions/session-0000000001").Return(false, nil, nil).Once()
+				mocks.Conn.On("Create", "/sessions/session-", mocks.Builder.DefaultData, int32(curator.EPHEMERAL_SEQUENTIAL), curator.OPEN_ACL_UNSAFE).Return("/sessions/session-0000000002", nil).Once()
+
+				So(registry.register(), ShouldBeNil)
+				So(registry.nodePath, ShouldEqual, "/sessions/session-0000000002")
+
+				mocks.Check(t)
+			})
+
+			Convey("The alive session should be kept", func() {
+				mocks.Conn.On("Exists", "/sessions/session-0000000001").Return(true, &zk.Stat{EphemeralOwner: 123}, nil).Once()
+
+				So(registry.register(), ShouldBeNil)
+				So(registry.nodePath, ShouldEqual, "/sessions/session-0000000001")
+
+				mocks.Check(t)
+			})
+
+			Convey("The session should be unregistered when closed", func() {
+				mocks.Conn.On("Delete", "/sessions/session-0000000001", curator.AnyVersion).Return(nil).Once()
+
+				So(registry.Close(), ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When list the live sessions", func() {
+			mocks.Conn.On("Children", "/sessions").Return([]string{"session-0000000001", "session-0000000002", "session-0000000003"}, nil, nil).Once()
+			mocks.Conn.On("Exists", "/sessions/session-0000000001").Return(true, &zk.Stat{EphemeralOwner: 123}, nil).Once()
+			mocks.Conn.On("Exists", "/sessions/session-0000000002").Return(false, nil, nil).Once()
+			mocks.Conn.On("Exists", "/sessions/session-0000000003").Return(true, &zk.Stat{EphemeralOwner: 456}, nil).Once()
+
+			sessions, err := registry.LiveSessions()
+
+			Convey("The sessions of the registered nodes should be returned", func() {
+				So(sessions, ShouldResemble, map[int64]bool{123: true, 456: true})
+				So(err, ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
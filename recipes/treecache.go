@@ -0,0 +1,635 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The default max depth of a TreeCache, all the descendants are cached
+const TREE_CACHE_MAX_DEPTH = math.MaxInt32
+
+type treeCacheListenerCallback func(client curator.CuratorFramework, event TreeCacheEvent) error
+
+type treeCacheListenerStub struct {
+	callback treeCacheListenerCallback
+}
+
+func NewTreeCacheListener(callback treeCacheListenerCallback) TreeCacheListener {
+	return &treeCacheListenerStub{callback}
+}
+
+func (l *treeCacheListenerStub) ChildEvent(client curator.CuratorFramework, event TreeCacheEvent) error {
+	return l.callback(client, event)
+}
+
+type TreeCacheListenable interface {
+	curator.Listenable /* [T] */
+
+	AddListener(listener TreeCacheListener)
+
+	RemoveListener(listener TreeCacheListener)
+}
+
+type TreeCacheListenerContainer struct {
+	*curator.ListenerContainer
+}
+
+func (c *TreeCacheListenerContainer) AddListener(listener TreeCacheListener) {
+	c.Add(listener)
+}
+
+func (c *TreeCacheListenerContainer) RemoveListener(listener TreeCacheListener) {
+	c.Remove(listener)
+}
+
+type treeNode struct {
+	data     *ChildData
+	children map[string]*treeNode
+}
+
+// A utility that attempts to keep all the data of all the nodes under a path locally cached, up to a max depth.
+// This class will watch the tree, respond to update/create/delete events, pull down the data, etc.
+// You can register a listener that will get notified when changes occur.
+//
+// The paths are interned by the cached nodes, the children of a node are keyed by the names sharing the backing array
+// of their paths, and the data is compacted, so a cache mirroring many nodes doesn't retain the buffers it has read.
+//
+// When the server supports the persistent recursive watches (ZooKeeper 3.6+), the tree is watched with a single
+// recursive watch, otherwise the cache falls back to the exists and children watches of each node,
+// which are registered again after each event. The tree is rebuilt after a reconnection,
+// since the watches may have been lost meanwhile.
+type TreeCache struct {
+	client                  curator.CuratorFramework
+	path                    string
+	cacheData               bool
+	dataIsCompressed        bool
+	maxDepth                int
+	state                   curator.State
+	recursive               curator.AtomicBool // the tree is watched with a persistent recursive watch
+	lock                    sync.RWMutex
+	nodes                   map[string]*treeNode
+	watchLock               sync.Mutex
+	existsWatched           map[string]bool
+	childrenWatched         map[string]bool
+	eventsLock              sync.Mutex
+	listeners               *TreeCacheListenerContainer
+	connectionStateListener curator.ConnectionStateListener
+	curatorListener         curator.CuratorListener
+}
+
+func NewTreeCache(client curator.CuratorFramework, path string, cacheData, dataIsCompressed bool) *TreeCache {
+	c := &TreeCache{
+		client:           client,
+		path:             path,
+		cacheData:        cacheData,
+		dataIsCompressed: dataIsCompressed,
+		maxDepth:         TREE_CACHE_MAX_DEPTH,
+		nodes:            make(map[string]*treeNode),
+		existsWatched:    make(map[string]bool),
+		childrenWatched:  make(map[string]bool),
+		listeners:        &TreeCacheListenerContainer{&curator.ListenerContainer{}},
+	}
+
+	c.connectionStateListener = curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		switch newState {
+		case curator.SUSPENDED:
+			c.publishEvent(CONNECTION_SUSPENDED, ChildData{})
+		case curator.LOST:
+			c.publishEvent(CONNECTION_LOST, ChildData{})
+		case curator.RECONNECTED:
+			c.publishEvent(CONNECTION_RECONNECTED, ChildData{})
+
+			if err := c.reset(); err != nil {
+				c.unhandledError(fmt.Errorf("Trying to reset after reconnection, %s", err))
+			}
+		}
+	})
+
+	c.curatorListener = curator.NewCuratorListener(func(client curator.CuratorFramework, event curator.CuratorEvent) error {
+		if event.Type() == curator.WATCHED && c.recursive.Load() {
+			c.processRecursiveEvent(event.WatchedEvent().Type, event.Path())
+		}
+
+		return nil
+	})
+
+	return c
+}
+
+// Set the max depth of the cached descendants, the children of the path have the depth 1 and 0 only caches the path
+func (c *TreeCache) SetMaxDepth(maxDepth int) *TreeCache {
+	c.maxDepth = maxDepth
+
+	return c
+}
+
+// Start the cache. The cache is not started automatically. You must call this method.
+// The INITIALIZED event is posted when the initial tree has been loaded.
+func (c *TreeCache) Start() error {
+	if !c.state.Change(curator.LATENT, curator.STARTED) {
+		return fmt.Errorf("Cannot be started more than once")
+	}
+
+	c.client.ConnectionStateListenable().AddListener(c.connectionStateListener)
+	c.client.CuratorListenable().AddListener(c.curatorListener)
+
+	if err := c.reset(); err != nil {
+		return err
+	}
+
+	c.publishEvent(INITIALIZED, ChildData{})
+
+	return nil
+}
+
+// Close the cache, the persistent recursive watch is removed
+func (c *TreeCache) Close() error {
+	if !c.state.Change(curator.STARTED, curator.STOPPED) {
+		return nil
+	}
+
+	c.client.ConnectionStateListenable().RemoveListener(c.connectionStateListener)
+	c.client.CuratorListenable().RemoveListener(c.curatorListener)
+
+	c.listeners.Clear()
+
+	if c.recursive.CompareAndSwap(true, false) {
+		if err := c.withPersistentWatchConn(func(conn curator.PersistentWatchZookeeperConnection, path string) error {
+			return conn.RemovePersistentWatch(path, true)
+		}); err != nil && err != zk.ErrNoNode && err != zk.ErrConnectionClosed {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return the cache listenable
+func (c *TreeCache) Listenable() TreeCacheListenable {
+	return c.listeners
+}
+
+// Return true if the tree is watched with a persistent recursive watch, instead of the watches of each node
+func (c *TreeCache) UsesRecursiveWatch() bool {
+	return c.recursive.Load()
+}
+
+// Return the current data of the given full path, or nil if the node isn't cached.
+// There are no guarantees of accuracy. This is merely the most recent view of the data.
+func (c *TreeCache) CurrentData(fullPath string) *ChildData {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if node, exists := c.nodes[fullPath]; exists {
+		return node.data
+	}
+
+	return nil
+}
+
+// Return the current data of the children of the given full path keyed by their names,
+// or nil if the node isn't cached. There are no guarantees of accuracy.
+func (c *TreeCache) CurrentChildren(fullPath string) map[string]*ChildData {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	node, exists := c.nodes[fullPath]
+
+	if !exists {
+		return nil
+	}
+
+	children := make(map[string]*ChildData, len(node.children))
+
+	for name, child := range node.children {
+		children[name] = child.data
+	}
+
+	return children
+}
+
+// Watch the tree and load it again, the nodes which no longer exist are removed
+func (c *TreeCache) reset() error {
+	if c.state.Value() != curator.STARTED {
+		return nil
+	}
+
+	c.watchLock.Lock()
+	c.existsWatched = make(map[string]bool)
+	c.childrenWatched = make(map[string]bool)
+	c.watchLock.Unlock()
+
+	if err := c.withPersistentWatchConn(func(conn curator.PersistentWatchZookeeperConnection, path string) error {
+		return conn.AddPersistentWatch(path, true)
+	}); err == nil {
+		c.recursive.Set(true)
+	} else if err == errPersistentWatchNotSupported || err == curator.ErrUnimplemented || err == zk.ErrBadArguments {
+		c.recursive.Set(false) // fallback to the watches of each node
+	} else {
+		return err
+	}
+
+	loaded := make(map[string]bool)
+
+	if err := c.load(c.path, 0, loaded); err != nil {
+		return err
+	}
+
+	c.lock.RLock()
+
+	var removed []string
+
+	for path := range c.nodes {
+		if !loaded[path] {
+			removed = append(removed, path)
+		}
+	}
+
+	c.lock.RUnlock()
+
+	for _, path := range removed {
+		c.removeNode(path)
+	}
+
+	return nil
+}
+
+var errPersistentWatchNotSupported = errors.New("The persistent watches are not supported by the connection")
+
+func (c *TreeCache) withPersistentWatchConn(fn func(conn curator.PersistentWatchZookeeperConnection, path string) error) error {
+	path, err := curator.FixForNamespace(c.client.Namespace(), c.path, false)
+
+	if err != nil {
+		return err
+	}
+
+	zkClient := c.client.ZookeeperClient()
+
+	_, err = zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+		if conn, err := zkClient.Conn(); err != nil {
+			return nil, err
+		} else if watchConn, ok := conn.(curator.PersistentWatchZookeeperConnection); !ok {
+			return nil, errPersistentWatchNotSupported
+		} else {
+			return nil, fn(watchConn, path)
+		}
+	})
+
+	return err
+}
+
+// Load the node and its descendants up to the max depth, collect the loaded paths
+func (c *TreeCache) load(path string, depth int, loaded map[string]bool) error {
+	if exists, err := c.refreshData(path); err != nil || !exists {
+		return err
+	}
+
+	loaded[path] = true
+
+	if depth >= c.maxDepth {
+		return nil
+	}
+
+	children, err := c.refreshChildren(path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if err := c.load(curator.JoinPath(path, child), depth+1, loaded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read the data of the node, watching it unless the tree is watched recursively
+func (c *TreeCache) refreshData(path string) (bool, error) {
+	if c.state.Value() != curator.STARTED {
+		return false, nil
+	}
+
+	builder := c.client.CheckExists()
+
+	if !c.recursive.Load() && c.mark(c.existsWatched, path, true) {
+		builder.UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
+			c.processExistsEvent(path, event)
+		}))
+	}
+
+	stat, err := builder.ForPath(path)
+
+	if err != nil {
+		c.mark(c.existsWatched, path, false)
+
+		return false, err
+	} else if stat == nil {
+		c.removeNode(path)
+
+		return false, nil
+	}
+
+	var data []byte
+
+	if c.cacheData {
+		builder := c.client.GetData()
+
+		if c.dataIsCompressed {
+			builder.Decompressed()
+		}
+
+		stat = &zk.Stat{}
+
+		if data, err = builder.StoringStatIn(stat).ForPath(path); err == zk.ErrNoNode {
+			c.removeNode(path)
+
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	c.setNode(path, stat, compactData(data))
+
+	return true, nil
+}
+
+// Read the children of the node, watching them unless the tree is watched recursively.
+// The cached children which no longer exist are removed.
+func (c *TreeCache) refreshChildren(path string) ([]string, error) {
+	if c.state.Value() != curator.STARTED {
+		return nil, nil
+	}
+
+	builder := c.client.GetChildren()
+
+	if !c.recursive.Load() && c.mark(c.childrenWatched, path, true) {
+		builder.UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
+			c.processChildrenEvent(path, event)
+		}))
+	}
+
+	children, err := builder.ForPath(path)
+
+	if err == zk.ErrNoNode {
+		c.mark(c.childrenWatched, path, false)
+
+		return nil, nil
+	} else if err != nil {
+		c.mark(c.childrenWatched, path, false)
+
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(children))
+
+	for _, child := range children {
+		existing[child] = true
+	}
+
+	c.lock.RLock()
+
+	var removed []string
+
+	if node, exists := c.nodes[path]; exists {
+		for name, child := range node.children {
+			if !existing[name] {
+				removed = append(removed, child.data.Path)
+			}
+		}
+	}
+
+	c.lock.RUnlock()
+
+	for _, childPath := range removed {
+		c.removeNode(childPath)
+	}
+
+	return children, nil
+}
+
+func (c *TreeCache) processExistsEvent(path string, event *zk.Event) {
+	if event.Type == zk.EventNotWatching || event.Type == zk.EventSession {
+		return
+	}
+
+	c.mark(c.existsWatched, path, false)
+
+	c.processEvent(event.Type, path)
+}
+
+func (c *TreeCache) processChildrenEvent(path string, event *zk.Event) {
+	c.mark(c.childrenWatched, path, false)
+
+	if event.Type == zk.EventNodeChildrenChanged {
+		c.processEvent(event.Type, path)
+	}
+}
+
+func (c *TreeCache) processRecursiveEvent(eventType zk.EventType, path string) {
+	if _, inTree := c.depthOf(path); inTree {
+		c.processEvent(eventType, path)
+	}
+}
+
+func (c *TreeCache) processEvent(eventType zk.EventType, path string) {
+	depth, inTree := c.depthOf(path)
+
+	if !inTree || depth > c.maxDepth {
+		return
+	}
+
+	var err error
+
+	switch eventType {
+	case zk.EventNodeCreated:
+		// the children created before the node is read don't have their own events
+		err = c.load(path, depth, make(map[string]bool))
+
+	case zk.EventNodeDataChanged:
+		_, err = c.refreshData(path)
+
+	case zk.EventNodeDeleted:
+		c.removeNode(path)
+
+		if path == c.path && !c.recursive.Load() {
+			_, err = c.refreshData(path) // watch the creation of the path
+		}
+
+	case zk.EventNodeChildrenChanged:
+		var children []string
+
+		if children, err = c.refreshChildren(path); err == nil && depth < c.maxDepth {
+			for _, child := range children {
+				if childPath := curator.JoinPath(path, child); c.CurrentData(childPath) == nil {
+					if err = c.load(childPath, depth+1, make(map[string]bool)); err != nil {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		c.unhandledError(err)
+	}
+}
+
+// Return the depth of the path in the tree, the path of the cache has the depth 0
+func (c *TreeCache) depthOf(path string) (int, bool) {
+	if path == c.path {
+		return 0, true
+	}
+
+	prefix := c.path
+
+	if prefix != curator.PATH_SEPARATOR {
+		prefix += curator.PATH_SEPARATOR
+	}
+
+	if !strings.HasPrefix(path, prefix) {
+		return 0, false
+	}
+
+	return strings.Count(path[len(prefix):], curator.PATH_SEPARATOR) + 1, true
+}
+
+func (c *TreeCache) mark(watched map[string]bool, path string, value bool) bool {
+	c.watchLock.Lock()
+	defer c.watchLock.Unlock()
+
+	if value {
+		if watched[path] {
+			return false
+		}
+
+		watched[path] = true
+	} else {
+		delete(watched, path)
+	}
+
+	return true
+}
+
+func (c *TreeCache) setNode(path string, stat *zk.Stat, data []byte) {
+	c.eventsLock.Lock()
+	defer c.eventsLock.Unlock()
+
+	c.lock.Lock()
+
+	node, exists := c.nodes[path]
+
+	if exists {
+		path = node.data.Path // intern the path, the reloaded node and its events share the cached copy
+	}
+
+	newData := &ChildData{path, stat, data}
+
+	if exists && (isStaleData(node.data, newData) || reflect.DeepEqual(node.data, newData)) {
+		c.lock.Unlock()
+
+		return
+	}
+
+	if exists {
+		node.data = newData
+	} else {
+		node = &treeNode{data: newData, children: make(map[string]*treeNode)}
+
+		c.nodes[path] = node
+	}
+
+	// the node may be loaded before its parent, which links its children when they are loaded again
+	if path != c.path {
+		if parent, exists := c.nodes[parentOf(path)]; exists {
+			parent.children[curator.GetNodeFromPath(path)] = node
+		}
+	}
+
+	c.lock.Unlock()
+
+	if exists {
+		c.publishEvent(CHILD_UPDATED, *newData)
+	} else {
+		c.publishEvent(CHILD_ADDED, *newData)
+	}
+}
+
+// Remove the node and its descendants, the descendants are removed first
+func (c *TreeCache) removeNode(path string) {
+	c.eventsLock.Lock()
+	defer c.eventsLock.Unlock()
+
+	c.lock.Lock()
+
+	node, exists := c.nodes[path]
+
+	if !exists {
+		c.lock.Unlock()
+
+		return
+	}
+
+	var removed []*ChildData
+
+	var remove func(node *treeNode)
+
+	remove = func(node *treeNode) {
+		for _, child := range node.children {
+			remove(child)
+		}
+
+		delete(c.nodes, node.data.Path)
+
+		removed = append(removed, node.data)
+	}
+
+	remove(node)
+
+	if parent, exists := c.nodes[parentOf(path)]; exists && path != c.path {
+		delete(parent.children, curator.GetNodeFromPath(path))
+	}
+
+	c.lock.Unlock()
+
+	for _, data := range removed {
+		c.publishEvent(CHILD_REMOVED, *data)
+	}
+}
+
+func parentOf(path string) string {
+	if pathAndNode, err := curator.SplitPath(path); err != nil {
+		return path
+	} else {
+		return pathAndNode.Path
+	}
+}
+
+func (c *TreeCache) publishEvent(eventType CacheEventType, data ChildData) {
+	if c.state.Value() != curator.STARTED {
+		return
+	}
+
+	event := TreeCacheEvent{Type: eventType, Data: data}
+
+	c.listeners.ForEach(func(listener interface{}) {
+		if err := listener.(TreeCacheListener).ChildEvent(c.client, event); err != nil {
+			c.unhandledError(err)
+		}
+	})
+}
+
+func (c *TreeCache) unhandledError(err error) {
+	c.client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+		listener.(curator.UnhandledErrorListener).UnhandledError(err)
+	})
+}
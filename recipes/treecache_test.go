@@ -0,0 +1,263 @@
+package recipes
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTreeCache(t *testing.T) {
+	Convey("Given a TreeCache", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		cache := NewTreeCache(client, "/tree", true, false)
+
+		events := make(chan TreeCacheEvent, 16)
+
+		cache.Listenable().AddListener(NewTreeCacheListener(func(client curator.CuratorFramework, event TreeCacheEvent) error {
+			events <- event
+
+			return nil
+		}))
+
+		Convey("When the server supports the persistent recursive watches", func() {
+			mocks.Conn.On("AddPersistentWatch", "/tree", true).Return(nil).Once()
+
+			mocks.Conn.On("Exists", "/tree").Return(true, &zk.Stat{Mzxid: 1}, nil).Once()
+			mocks.Conn.On("Get", "/tree").Return([]byte("root"), &zk.Stat{Mzxid: 1}, nil).Once()
+			mocks.Conn.On("Children", "/tree").Return([]string{"a"}, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Exists", "/tree/a").Return(true, &zk.Stat{Czxid: 2, Mzxid: 2}, nil).Once()
+			mocks.Conn.On("Get", "/tree/a").Return([]byte("a"), &zk.Stat{Czxid: 2, Mzxid: 2}, nil).Once()
+			mocks.Conn.On("Children", "/tree/a").Return([]string{}, &zk.Stat{}, nil).Once()
+
+			So(cache.Start(), ShouldBeNil)
+
+			Convey("The tree should be loaded with a single recursive watch", func() {
+				So(cache.UsesRecursiveWatch(), ShouldBeTrue)
+
+				So((<-events).Data.Path, ShouldEqual, "/tree")
+
+				added := <-events
+
+				So(added.Data.Path, ShouldEqual, "/tree/a")
+				So((<-events).Type, ShouldEqual, INITIALIZED)
+
+				So(string(cache.CurrentData("/tree").Data), ShouldEqual, "root")
+				So(cache.CurrentChildren("/tree"), ShouldHaveLength, 1)
+				So(string(cache.CurrentChildren("/tree")["a"].Data), ShouldEqual, "a")
+
+				mocks.Conn.On("Exists", "/tree/a").Return(true, &zk.Stat{Czxid: 2, Mzxid: 3}, nil).Once()
+				mocks.Conn.On("Get", "/tree/a").Return([]byte("b"), &zk.Stat{Czxid: 2, Mzxid: 3}, nil).Once()
+
+				mocks.Events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: "/tree/a"}
+
+				event := <-events
+
+				So(event.Type, ShouldEqual, CHILD_UPDATED)
+				So(event.Data.Path, ShouldEqual, "/tree/a")
+				So(stringData(event.Data.Path) == stringData(added.Data.Path), ShouldBeTrue) // share the cached path
+				So(string(event.Data.Data), ShouldEqual, "b")
+
+				mocks.Events <- zk.Event{Type: zk.EventNodeDeleted, State: zk.StateHasSession, Path: "/tree/a"}
+
+				event = <-events
+
+				So(event.Type, ShouldEqual, CHILD_REMOVED)
+				So(event.Data.Path, ShouldEqual, "/tree/a")
+				So(cache.CurrentData("/tree/a"), ShouldBeNil)
+				So(cache.CurrentChildren("/tree"), ShouldBeEmpty)
+
+				// the events out of the tree are ignored
+				mocks.Events <- zk.Event{Type: zk.EventNodeDeleted, State: zk.StateHasSession, Path: "/treetop"}
+
+				mocks.Conn.On("RemovePersistentWatch", "/tree", true).Return(nil).Once()
+
+				So(cache.Close(), ShouldBeNil)
+				So(cache.UsesRecursiveWatch(), ShouldBeFalse)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the server doesn't support the persistent watches", func() {
+			rootEvents := make(chan zk.Event, 1)
+			childrenEvents := make(chan zk.Event, 1)
+			nodeEvents := make(chan zk.Event, 1)
+
+			mocks.Conn.On("AddPersistentWatch", "/tree", true).Return(curator.ErrUnimplemented).Once()
+
+			mocks.Conn.On("ExistsW", "/tree").Return(true, &zk.Stat{}, rootEvents, nil).Once()
+			mocks.Conn.On("Get", "/tree").Return([]byte("root"), &zk.Stat{}, nil).Once()
+			mocks.Conn.On("ChildrenW", "/tree").Return([]string{"a"}, &zk.Stat{}, childrenEvents, nil).Once()
+			mocks.Conn.On("ExistsW", "/tree/a").Return(true, &zk.Stat{}, nodeEvents, nil).Once()
+			mocks.Conn.On("Get", "/tree/a").Return([]byte("a"), &zk.Stat{}, nil).Once()
+			mocks.Conn.On("ChildrenW", "/tree/a").Return([]string{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+			So(cache.Start(), ShouldBeNil)
+
+			Convey("The tree should be watched by the watches of each node", func() {
+				So(cache.UsesRecursiveWatch(), ShouldBeFalse)
+
+				So((<-events).Data.Path, ShouldEqual, "/tree")
+				So((<-events).Data.Path, ShouldEqual, "/tree/a")
+				So((<-events).Type, ShouldEqual, INITIALIZED)
+
+				mocks.Conn.On("ChildrenW", "/tree").Return([]string{"a", "b"}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("ExistsW", "/tree/b").Return(true, &zk.Stat{}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("Get", "/tree/b").Return([]byte("b"), &zk.Stat{}, nil).Once()
+				mocks.Conn.On("ChildrenW", "/tree/b").Return([]string{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+				childrenEvents <- zk.Event{Type: zk.EventNodeChildrenChanged, Path: "/tree"}
+
+				event := <-events
+
+				So(event.Type, ShouldEqual, CHILD_ADDED)
+				So(event.Data.Path, ShouldEqual, "/tree/b")
+				So(cache.CurrentChildren("/tree"), ShouldHaveLength, 2)
+
+				nodeEvents <- zk.Event{Type: zk.EventNodeDeleted, Path: "/tree/a"}
+
+				event = <-events
+
+				So(event.Type, ShouldEqual, CHILD_REMOVED)
+				So(event.Data.Path, ShouldEqual, "/tree/a")
+				So(cache.CurrentChildren("/tree"), ShouldHaveLength, 1)
+
+				So(cache.Close(), ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the max depth is limited", func() {
+			cache.SetMaxDepth(0)
+
+			mocks.Conn.On("AddPersistentWatch", "/tree", true).Return(nil).Once()
+			mocks.Conn.On("Exists", "/tree").Return(true, &zk.Stat{}, nil).Once()
+			mocks.Conn.On("Get", "/tree").Return([]byte("root"), &zk.Stat{}, nil).Once()
+
+			So(cache.Start(), ShouldBeNil)
+
+			Convey("The descendants should not be cached", func() {
+				So((<-events).Data.Path, ShouldEqual, "/tree")
+				So((<-events).Type, ShouldEqual, INITIALIZED)
+
+				So(cache.CurrentChildren("/tree"), ShouldBeEmpty)
+
+				mocks.Conn.On("RemovePersistentWatch", "/tree", true).Return(nil).Once()
+
+				So(cache.Close(), ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
+
+const BENCHMARK_NODES = 10000
+
+// A connection serving the children of a path, the version of a node is bumped each time it is read
+type benchmarkConn struct {
+	curator.ZookeeperConnection
+
+	children []string
+	zxid     int64
+}
+
+func (c *benchmarkConn) Close() {}
+
+func (c *benchmarkConn) AddPersistentWatch(path string, recursive bool) error { return nil }
+
+func (c *benchmarkConn) RemovePersistentWatch(path string, recursive bool) error { return nil }
+
+func (c *benchmarkConn) Exists(path string) (bool, *zk.Stat, error) {
+	return true, &zk.Stat{Mzxid: atomic.AddInt64(&c.zxid, 1)}, nil
+}
+
+func (c *benchmarkConn) Get(path string) ([]byte, *zk.Stat, error) {
+	return []byte("data"), &zk.Stat{Mzxid: atomic.AddInt64(&c.zxid, 1)}, nil
+}
+
+func (c *benchmarkConn) Children(path string) ([]string, *zk.Stat, error) {
+	if path == "/services/region/cluster/instances" {
+		return c.children, &zk.Stat{}, nil
+	}
+
+	return nil, &zk.Stat{}, nil
+}
+
+// Load the tree, then load it again after the data of all the nodes has changed, like after a reconnection.
+// Reports the heap retained by the loaded cache and by the reloaded one, which shares the cached paths.
+func BenchmarkTreeCache(b *testing.B) {
+	children := make([]string, BENCHMARK_NODES)
+
+	for i := range children {
+		children[i] = fmt.Sprintf("node-%010d", i)
+	}
+
+	var loaded, reloaded int64
+	var before, after runtime.MemStats
+
+	for n := 0; n < b.N; n++ {
+		conn := &benchmarkConn{children: children}
+
+		client := (&curator.CuratorFrameworkBuilder{
+			ZookeeperDialer: curator.NewZookeeperDialer(func(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (curator.ZookeeperConnection, <-chan zk.Event, error) {
+				return conn, make(chan zk.Event), nil
+			}),
+		}).ConnectString("connStr").Build()
+
+		if err := client.Start(); err != nil {
+			b.Fatal(err)
+		}
+
+		cache := NewTreeCache(client, "/services/region/cluster/instances", true, false)
+
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		if err := cache.Start(); err != nil {
+			b.Fatal(err)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+
+		loaded += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+
+		if err := cache.reset(); err != nil {
+			b.Fatal(err)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+
+		reloaded += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+
+		runtime.KeepAlive(cache)
+
+		cache.Close()
+		client.Close()
+	}
+
+	b.ReportMetric(float64(loaded)/float64(b.N), "loaded-B/op")
+	b.ReportMetric(float64(reloaded)/float64(b.N), "reloaded-B/op")
+}
+
+func stringData(s string) *byte {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return &[]byte(s)[0]
+}
@@ -0,0 +1,293 @@
+package recipes
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The size of the events queue of the PersistentWatcher
+const PERSISTENT_WATCHER_QUEUE_SIZE = 64
+
+// Listener for PersistentWatcher events
+type PersistentWatcherListener interface {
+	// Called when a watched event has occurred
+	EventReceived(event *zk.Event) error
+}
+
+type persistentWatcherListenerCallback func(event *zk.Event) error
+
+type persistentWatcherListenerStub struct {
+	callback persistentWatcherListenerCallback
+}
+
+func NewPersistentWatcherListener(callback persistentWatcherListenerCallback) PersistentWatcherListener {
+	return &persistentWatcherListenerStub{callback}
+}
+
+func (l *persistentWatcherListenerStub) EventReceived(event *zk.Event) error {
+	return l.callback(event)
+}
+
+type PersistentWatcherListenable interface {
+	curator.Listenable /* [T] */
+
+	AddListener(listener PersistentWatcherListener)
+
+	RemoveListener(listener PersistentWatcherListener)
+}
+
+type PersistentWatcherListenerContainer struct {
+	*curator.ListenerContainer
+}
+
+func (c *PersistentWatcherListenerContainer) AddListener(listener PersistentWatcherListener) {
+	c.Add(listener)
+}
+
+func (c *PersistentWatcherListenerContainer) RemoveListener(listener PersistentWatcherListener) {
+	c.Remove(listener)
+}
+
+// A managed persistent watcher. The watch is re-registered after every event and after the connection is re-established.
+//
+// The watcher is emulated with one-shot exists and children watches, the node itself is watched for creation,
+// deletion and data changes, and its children for changes. A recursive watcher also watches all the descendants,
+// reporting new nodes with a NodeCreated event.
+// Changes made between an event and the re-registration may be missed, and events may be repeated after a reconnection.
+type PersistentWatcher struct {
+	client                  curator.CuratorFramework
+	path                    string
+	recursive               bool
+	state                   curator.State
+	lock                    sync.Mutex
+	existsWatched           map[string]bool
+	childrenWatched         map[string]bool
+	eventsLock              sync.RWMutex
+	events                  chan *zk.Event
+	done                    chan struct{}
+	listeners               *PersistentWatcherListenerContainer
+	connectionStateListener curator.ConnectionStateListener
+}
+
+func NewPersistentWatcher(client curator.CuratorFramework, path string, recursive bool) *PersistentWatcher {
+	w := &PersistentWatcher{
+		client:          client,
+		path:            path,
+		recursive:       recursive,
+		existsWatched:   make(map[string]bool),
+		childrenWatched: make(map[string]bool),
+		events:          make(chan *zk.Event, PERSISTENT_WATCHER_QUEUE_SIZE),
+		done:            make(chan struct{}),
+		listeners:       &PersistentWatcherListenerContainer{&curator.ListenerContainer{}},
+	}
+
+	w.connectionStateListener = curator.NewConnectionStateListener(func(client curator.CuratorFramework, newState curator.ConnectionState) {
+		if newState == curator.RECONNECTED {
+			if err := w.reset(); err != nil {
+				w.unhandledError(fmt.Errorf("Trying to reset after reconnection, %s", err))
+			}
+		}
+	})
+
+	return w
+}
+
+// Start the watcher. The watcher is not started automatically. You must call this method.
+func (w *PersistentWatcher) Start() error {
+	if !w.state.Change(curator.LATENT, curator.STARTED) {
+		return fmt.Errorf("Cannot be started more than once")
+	}
+
+	w.client.ConnectionStateListenable().AddListener(w.connectionStateListener)
+
+	return w.reset()
+}
+
+// Close the watcher, the events channel will be closed
+func (w *PersistentWatcher) Close() error {
+	if !w.state.Change(curator.STARTED, curator.STOPPED) {
+		return nil
+	}
+
+	w.client.ConnectionStateListenable().RemoveListener(w.connectionStateListener)
+
+	w.listeners.Clear()
+
+	close(w.done)
+
+	w.eventsLock.Lock()
+	close(w.events)
+	w.eventsLock.Unlock()
+
+	return nil
+}
+
+// Return the channel of the watched events.
+// The events are dropped and reported to the UnhandledErrorListeners when the channel isn't drained in time.
+func (w *PersistentWatcher) Events() <-chan *zk.Event {
+	return w.events
+}
+
+// Return the listenable for the watched events
+func (w *PersistentWatcher) Listenable() PersistentWatcherListenable {
+	return w.listeners
+}
+
+func (w *PersistentWatcher) reset() error {
+	w.lock.Lock()
+
+	for path := range w.existsWatched {
+		delete(w.existsWatched, path)
+	}
+
+	for path := range w.childrenWatched {
+		delete(w.childrenWatched, path)
+	}
+
+	w.lock.Unlock()
+
+	_, err := w.watchNode(w.path)
+
+	return err
+}
+
+// Register the watches of the path, return true if the exists watch wasn't registered before
+func (w *PersistentWatcher) watchNode(path string) (bool, error) {
+	if w.state.Value() != curator.STARTED || !w.mark(w.existsWatched, path, true) {
+		return false, nil
+	}
+
+	stat, err := w.client.CheckExists().UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
+		w.processExistsEvent(path, event)
+	})).ForPath(path)
+
+	if err != nil {
+		w.mark(w.existsWatched, path, false)
+
+		return false, err
+	}
+
+	if stat != nil {
+		return true, w.watchChildren(path, false)
+	}
+
+	return true, nil
+}
+
+// Register the children watch of the path, notify the descendants which weren't watched before if required
+func (w *PersistentWatcher) watchChildren(path string, notify bool) error {
+	if path != w.path && !w.recursive {
+		return nil
+	}
+
+	if w.state.Value() != curator.STARTED || !w.mark(w.childrenWatched, path, true) {
+		return nil
+	}
+
+	children, err := w.client.GetChildren().UsingWatcher(curator.NewWatcher(func(event *zk.Event) {
+		w.processChildrenEvent(path, event)
+	})).ForPath(path)
+
+	if err == zk.ErrNoNode {
+		w.mark(w.childrenWatched, path, false)
+
+		return nil
+	} else if err != nil {
+		w.mark(w.childrenWatched, path, false)
+
+		return err
+	}
+
+	if w.recursive {
+		for _, child := range children {
+			childPath := curator.JoinPath(path, child)
+
+			if created, err := w.watchNode(childPath); err != nil {
+				return err
+			} else if created && notify {
+				w.send(&zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession, Path: childPath})
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *PersistentWatcher) processExistsEvent(path string, event *zk.Event) {
+	if event.Type == zk.EventNotWatching || event.Type == zk.EventSession {
+		return
+	}
+
+	w.mark(w.existsWatched, path, false)
+
+	// a deleted descendant will be watched again by its parent when it is recreated
+	if event.Type != zk.EventNodeDeleted || path == w.path {
+		if _, err := w.watchNode(path); err != nil {
+			w.unhandledError(err)
+		}
+	}
+
+	w.send(&zk.Event{Type: event.Type, State: event.State, Path: path, Err: event.Err})
+}
+
+func (w *PersistentWatcher) processChildrenEvent(path string, event *zk.Event) {
+	w.mark(w.childrenWatched, path, false)
+
+	if event.Type != zk.EventNodeChildrenChanged {
+		return
+	}
+
+	if err := w.watchChildren(path, true); err != nil {
+		w.unhandledError(err)
+	}
+
+	if !w.recursive {
+		w.send(&zk.Event{Type: event.Type, State: event.State, Path: path, Err: event.Err})
+	}
+}
+
+func (w *PersistentWatcher) mark(watched map[string]bool, path string, value bool) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if value {
+		if watched[path] {
+			return false
+		}
+
+		watched[path] = true
+	} else {
+		delete(watched, path)
+	}
+
+	return true
+}
+
+func (w *PersistentWatcher) send(event *zk.Event) {
+	w.listeners.ForEach(func(listener interface{}) {
+		if err := listener.(PersistentWatcherListener).EventReceived(event); err != nil {
+			w.unhandledError(err)
+		}
+	})
+
+	w.eventsLock.RLock()
+	defer w.eventsLock.RUnlock()
+
+	if w.state.Value() == curator.STARTED {
+		select {
+		case w.events <- event:
+		default:
+			// never block the watch callbacks, a caller using only the listeners doesn't drain the channel
+			w.unhandledError(fmt.Errorf("Dropped the %s event of %s, the events queue is full", event.Type, event.Path))
+		}
+	}
+}
+
+func (w *PersistentWatcher) unhandledError(err error) {
+	w.client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+		listener.(curator.UnhandledErrorListener).UnhandledError(err)
+	})
+}
@@ -0,0 +1,142 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPersistentWatcher(t *testing.T) {
+	Convey("Given a PersistentWatcher", t, func() {
+		mocks := newMockBuilder(t)
+
+		client := mocks.Build()
+
+		So(client.Start(), ShouldBeNil)
+
+		existsEvents := make(chan zk.Event, 1)
+		childrenEvents := make(chan zk.Event, 1)
+
+		mocks.Conn.On("ExistsW", "/config").Return(true, &zk.Stat{}, existsEvents, nil).Once()
+
+		Convey("When watch a node", func() {
+			watcher := NewPersistentWatcher(client, "/config", false)
+
+			received := make(chan *zk.Event, 1)
+
+			watcher.Listenable().AddListener(NewPersistentWatcherListener(func(event *zk.Event) error {
+				received <- event
+
+				return nil
+			}))
+
+			mocks.Conn.On("ChildrenW", "/config").Return([]string{"a"}, &zk.Stat{}, childrenEvents, nil).Once()
+
+			So(watcher.Start(), ShouldBeNil)
+
+			Convey("The watch should be registered again after an event", func() {
+				mocks.Conn.On("ExistsW", "/config").Return(true, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+				existsEvents <- zk.Event{Type: zk.EventNodeDataChanged, Path: "/config"}
+
+				event := <-watcher.Events()
+
+				So(event.Type, ShouldEqual, zk.EventNodeDataChanged)
+				So(event.Path, ShouldEqual, "/config")
+				So(<-received, ShouldResemble, event)
+
+				mocks.Conn.On("ChildrenW", "/config").Return([]string{"a", "b"}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+				childrenEvents <- zk.Event{Type: zk.EventNodeChildrenChanged, Path: "/config"}
+
+				event = <-watcher.Events()
+
+				So(event.Type, ShouldEqual, zk.EventNodeChildrenChanged)
+				So(event.Path, ShouldEqual, "/config")
+
+				So(watcher.Close(), ShouldBeNil)
+
+				_, ok := <-watcher.Events()
+
+				So(ok, ShouldBeFalse)
+
+				mocks.Check(t)
+			})
+		})
+
+		Convey("When the events channel isn't drained", func() {
+			watcher := NewPersistentWatcher(client, "/config", false)
+
+			listened := 0
+
+			watcher.Listenable().AddListener(NewPersistentWatcherListener(func(event *zk.Event) error {
+				listened++
+
+				return nil
+			}))
+
+			var errs []error
+
+			client.UnhandledErrorListenable().AddListener(curator.NewUnhandledErrorListener(func(err error) {
+				errs = append(errs, err)
+			}))
+
+			mocks.Conn.On("ChildrenW", "/config").Return([]string{}, &zk.Stat{}, childrenEvents, nil).Once()
+
+			So(watcher.Start(), ShouldBeNil)
+
+			for i := 0; i <= PERSISTENT_WATCHER_QUEUE_SIZE; i++ {
+				watcher.send(&zk.Event{Type: zk.EventNodeDataChanged, Path: "/config"})
+			}
+
+			Convey("The overflowed events should be dropped and reported", func() {
+				So(listened, ShouldEqual, PERSISTENT_WATCHER_QUEUE_SIZE+1)
+				So(len(watcher.Events()), ShouldEqual, PERSISTENT_WATCHER_QUEUE_SIZE)
+				So(errs, ShouldHaveLength, 1)
+				So(errs[0].Error(), ShouldEqual, "Dropped the EventNodeDataChanged event of /config, the events queue is full")
+
+				So(watcher.Close(), ShouldBeNil)
+			})
+		})
+
+		Convey("When watch a node recursively", func() {
+			watcher := NewPersistentWatcher(client, "/config", true)
+
+			childEvents := make(chan zk.Event, 1)
+
+			mocks.Conn.On("ChildrenW", "/config").Return([]string{"a"}, &zk.Stat{}, childrenEvents, nil).Once()
+			mocks.Conn.On("ExistsW", "/config/a").Return(true, &zk.Stat{}, childEvents, nil).Once()
+			mocks.Conn.On("ChildrenW", "/config/a").Return([]string{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+			So(watcher.Start(), ShouldBeNil)
+
+			Convey("The new descendants should be watched and notified", func() {
+				mocks.Conn.On("ChildrenW", "/config").Return([]string{"a", "b"}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("ExistsW", "/config/b").Return(true, &zk.Stat{}, make(chan zk.Event), nil).Once()
+				mocks.Conn.On("ChildrenW", "/config/b").Return([]string{}, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+				childrenEvents <- zk.Event{Type: zk.EventNodeChildrenChanged, Path: "/config"}
+
+				event := <-watcher.Events()
+
+				So(event.Type, ShouldEqual, zk.EventNodeCreated)
+				So(event.Path, ShouldEqual, "/config/b")
+
+				mocks.Conn.On("ExistsW", "/config/a").Return(true, &zk.Stat{}, make(chan zk.Event), nil).Once()
+
+				childEvents <- zk.Event{Type: zk.EventNodeDataChanged, Path: "/config/a"}
+
+				event = <-watcher.Events()
+
+				So(event.Type, ShouldEqual, zk.EventNodeDataChanged)
+				So(event.Path, ShouldEqual, "/config/a")
+
+				So(watcher.Close(), ShouldBeNil)
+
+				mocks.Check(t)
+			})
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package curator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A facade recording the watchers set through it, so they can be removed at once,
+// e.g. when the recipe owning them is closed.
+type WatcherRemoveCuratorFramework interface {
+	CuratorFramework
+
+	// Remove the watchers set through the facade.
+	// The events of the removed watchers are dropped, and the persistent watches are removed from the servers
+	// unless they are still watched by the other watchers of the client.
+	RemoveWatchers() error
+}
+
+type watcherRemovalFacade struct {
+	curatorFramework
+}
+
+func (c *curatorFramework) NewWatcherRemoveCuratorFramework() WatcherRemoveCuratorFramework {
+	facade := &watcherRemovalFacade{
+		curatorFramework: *c,
+	}
+
+	facade.watcherRemoval = &watcherRemovalManager{watchers: make(map[*removableWatcher]bool)}
+
+	return facade
+}
+
+func (f *watcherRemovalFacade) Start() error {
+	return errors.New("the requested operation is not supported")
+}
+
+func (f *watcherRemovalFacade) Close() error {
+	return errors.New("the requested operation is not supported")
+}
+
+func (f *watcherRemovalFacade) RemoveWatchers() error {
+	watchers, watches := f.watcherRemoval.drain()
+
+	for _, watcher := range watchers {
+		watcher.removed.Set(true)
+	}
+
+	var err error
+
+	for _, watch := range watches {
+		if e := f.removePersistentWatch(watch); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// The watchers and the persistent watches set through a WatcherRemoveCuratorFramework
+type watcherRemovalManager struct {
+	lock     sync.Mutex
+	watchers map[*removableWatcher]bool
+	watches  []*persistentWatch
+}
+
+func (m *watcherRemovalManager) wrap(watcher Watcher) Watcher {
+	if watcher == nil {
+		return nil
+	}
+
+	removable := &removableWatcher{watcher: watcher, manager: m}
+
+	m.lock.Lock()
+	m.watchers[removable] = true
+	m.lock.Unlock()
+
+	return removable
+}
+
+func (m *watcherRemovalManager) forget(watcher *removableWatcher) {
+	m.lock.Lock()
+	delete(m.watchers, watcher)
+	m.lock.Unlock()
+}
+
+func (m *watcherRemovalManager) addPersistentWatch(watch *persistentWatch) {
+	m.lock.Lock()
+	m.watches = append(m.watches, watch)
+	m.lock.Unlock()
+}
+
+func (m *watcherRemovalManager) drain() ([]*removableWatcher, []*persistentWatch) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	watchers := make([]*removableWatcher, 0, len(m.watchers))
+
+	for watcher := range m.watchers {
+		watchers = append(watchers, watcher)
+	}
+
+	watches := m.watches
+
+	m.watchers = make(map[*removableWatcher]bool)
+	m.watches = nil
+
+	return watchers, watches
+}
+
+// A watcher set through a WatcherRemoveCuratorFramework, which drops the events once it is removed.
+// A triggered watcher is forgotten, since a watch is triggered only once.
+type removableWatcher struct {
+	watcher Watcher
+	manager *watcherRemovalManager
+	removed AtomicBool
+}
+
+func (w *removableWatcher) process(event *zk.Event) {
+	if w.removed.Load() {
+		return
+	}
+
+	w.manager.forget(w)
+
+	w.watcher.process(event)
+}
@@ -0,0 +1,107 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatcherRemovalTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestWatcherRemoval(t *testing.T) {
+	suite.Run(t, new(WatcherRemovalTestSuite))
+}
+
+func (s *WatcherRemovalTestSuite) TestRemoveWatchers() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		facade := client.NewWatcherRemoveCuratorFramework()
+
+		assert.EqualError(s.T(), facade.Start(), "the requested operation is not supported")
+		assert.EqualError(s.T(), facade.Close(), "the requested operation is not supported")
+
+		events := make(chan zk.Event)
+		watched := make(chan zk.Event, 1)
+
+		defer close(events)
+
+		conn.On("GetW", "/node").Return(data, stat, events, nil).Once()
+
+		_, err := facade.GetData().UsingWatcherChannel(watched).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+		assert.NoError(s.T(), facade.RemoveWatchers())
+
+		// the event of the removed watcher is dropped
+		events <- zk.Event{Type: zk.EventNodeDataChanged, Path: "/node"}
+
+		time.Sleep(10 * time.Millisecond)
+
+		select {
+		case event := <-watched:
+			assert.Fail(s.T(), "unexpected event", "event=%v", event)
+		default:
+		}
+	})
+}
+
+func (s *WatcherRemovalTestSuite) TestTriggeredWatcher() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		facade := client.NewWatcherRemoveCuratorFramework()
+
+		events := make(chan zk.Event)
+		watched := make(chan zk.Event, 1)
+
+		defer close(events)
+
+		conn.On("GetW", "/node").Return(data, stat, events, nil).Once()
+
+		_, err := facade.GetData().UsingWatcherChannel(watched).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+
+		events <- zk.Event{Type: zk.EventNodeDataChanged, Path: "/node"}
+
+		assert.Equal(s.T(), "/node", (<-watched).Path)
+
+		// the triggered watcher is forgotten
+		watchers, _ := facade.(*watcherRemovalFacade).watcherRemoval.drain()
+
+		assert.Empty(s.T(), watchers)
+	})
+}
+
+func (s *WatcherRemovalTestSuite) TestRemovePersistentWatches() {
+	s.With(func(client CuratorFramework, conn *mockConn, events chan zk.Event) {
+		facade := client.NewWatcherRemoveCuratorFramework()
+
+		watched := make(chan zk.Event, 1)
+		removed := make(chan zk.Event, 1)
+
+		conn.On("AddPersistentWatch", "/node", true).Return(nil).Twice()
+		conn.On("AddPersistentWatch", "/other", false).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Watches().Add().UsingWatcherChannel(watched).ForPath("/node"))
+		assert.NoError(s.T(), facade.Watches().Add().UsingWatcherChannel(removed).ForPath("/node"))
+		assert.NoError(s.T(), facade.Watches().Add().WithMode(PERSISTENT_WATCH).UsingWatcherChannel(removed).ForPath("/other"))
+
+		// only the watch no longer used by the client is removed from the server
+		conn.On("RemovePersistentWatch", "/other", false).Return(nil).Once()
+
+		assert.NoError(s.T(), facade.RemoveWatchers())
+
+		events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: "/node"}
+
+		assert.Equal(s.T(), "/node", (<-watched).Path)
+
+		select {
+		case event := <-removed:
+			assert.Fail(s.T(), "unexpected event", "event=%v", event)
+		default:
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package curator
 
 import (
+	"context"
+	"log"
 	"math"
 	"math/rand"
 	"net"
@@ -33,6 +35,24 @@ func (s *defaultRetrySleeper) SleepFor(d time.Duration) error {
 	return nil
 }
 
+// The sleeper aborting the sleep once the context is done
+type contextRetrySleeper struct {
+	ctx context.Context
+}
+
+func (s *contextRetrySleeper) SleepFor(d time.Duration) error {
+	timer := time.NewTimer(d)
+
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
 // Mechanism to perform an operation on Zookeeper that is safe against disconnections and "recoverable" errors.
 type RetryLoop interface {
 	// creates a retry loop calling the given proc and retrying if needed
@@ -40,12 +60,50 @@ type RetryLoop interface {
 }
 
 type retryLoop struct {
-	done         bool
-	retryCount   int
-	startTime    time.Time
-	retryPolicy  RetryPolicy
-	retrySleeper RetrySleeper
-	tracer       TracerDriver
+	done           bool
+	retryCount     int
+	startTime      time.Time
+	retryPolicy    RetryPolicy
+	retrySleeper   RetrySleeper
+	tracer         TracerDriver
+	ctx            context.Context         // stop retrying once the context is done
+	operation      string                  // the operation reported to the retry listeners
+	listeners      *retryListenerContainer // receive the retries, if any
+	unhandledError func(err error)         // report the panics of the retry listeners, which are logged by default
+	lastSleepTime  time.Duration           // the sleep time of the previous retry, for the decorrelated jitter
+}
+
+// A sleeper knowing the sleep time of the previous retry of its sequence
+type previousRetrySleeper interface {
+	previousSleepTime() time.Duration
+}
+
+// The sleeper of a retry of the loop, which records the sleep time chosen by the retry policy,
+// and notifies the retry listeners of it if any
+type notifyingRetrySleeper struct {
+	RetrySleeper
+
+	previous  time.Duration // the sleep time of the previous retry of the loop
+	sleepTime time.Duration
+	notified  bool
+	notify    func(sleepTime time.Duration)
+}
+
+func (s *notifyingRetrySleeper) SleepFor(d time.Duration) error {
+	if !s.notified {
+		s.notified = true
+		s.sleepTime = d
+
+		if s.notify != nil {
+			s.notify(d)
+		}
+	}
+
+	return s.RetrySleeper.SleepFor(d)
+}
+
+func (s *notifyingRetrySleeper) previousSleepTime() time.Duration {
+	return s.previous
 }
 
 func newRetryLoop(retryPolicy RetryPolicy, tracer TracerDriver) *retryLoop {
@@ -58,51 +116,166 @@ func newRetryLoop(retryPolicy RetryPolicy, tracer TracerDriver) *retryLoop {
 
 // return true if the given Zookeeper result code is retry-able
 func (l *retryLoop) ShouldRetry(err error) bool {
-	if err == zk.ErrSessionExpired || err == zk.ErrSessionMoved {
-		return true
-	}
-
-	if netErr, ok := err.(net.Error); ok {
-		return netErr.Timeout() || netErr.Temporary()
-	}
-
-	return false
+	return IsRetryableError(err)
 }
 
 func (l *retryLoop) CallWithRetry(proc func() (interface{}, error)) (interface{}, error) {
 	for {
+		if l.ctx != nil && l.ctx.Err() != nil {
+			return nil, l.ctx.Err()
+		}
+
 		if ret, err := proc(); err == nil || !l.ShouldRetry(err) {
 			return ret, err
 		} else {
-			l.retryCount++
+			sleeper := l.retrySleeper
 
-			if sleeper := l.retrySleeper; sleeper == nil {
+			if sleeper == nil {
 				sleeper = DefaultRetrySleeper
-			} else {
-				if !l.retryPolicy.AllowRetry(l.retryCount, time.Now().Sub(l.startTime), sleeper) {
-					l.tracer.AddCount("retries-disallowed", 1)
+			}
+
+			notifier := &notifyingRetrySleeper{RetrySleeper: sleeper, previous: l.lastSleepTime}
+
+			if l.listeners != nil && l.listeners.Len() > 0 {
+				notifier.notify = func(sleepTime time.Duration) {
+					l.fireRetryEvent(err, sleepTime)
+				}
+			}
+
+			// the policy is given the count of the retries done so far, like Curator does
+			if !l.retryPolicy.AllowRetry(l.retryCount, time.Now().Sub(l.startTime), notifier) {
+				l.tracer.AddCount("retries-disallowed", 1)
 
-					return ret, err
-				} else {
-					l.tracer.AddCount("retries-allowed", 1)
+				if l.ctx != nil && l.ctx.Err() != nil {
+					return nil, l.ctx.Err()
 				}
+
+				return ret, err
 			}
+
+			if notifier.notify != nil && !notifier.notified {
+				l.fireRetryEvent(err, 0) // the policy retries without sleeping
+			}
+
+			l.retryCount++
+			l.lastSleepTime = notifier.sleepTime
+
+			l.tracer.AddCount("retries-allowed", 1)
+		}
+	}
+}
+
+func (l *retryLoop) fireRetryEvent(err error, sleepTime time.Duration) {
+	handler := l.unhandledError
+
+	if handler == nil {
+		handler = func(err error) { log.Printf("error: %s", err) }
+	}
+
+	event := &RetryEvent{Operation: l.operation, Attempt: l.retryCount + 1, Err: err, SleepTime: sleepTime}
+
+	l.listeners.ForEach(func(listener interface{}) {
+		defer recoverPanic("Retry listener", handler)
+
+		listener.(RetryListener).RetryAttempted(event)
+	})
+}
+
+// Return true if the operation failed with the error should be retried, e.g. the session expired or the network timed out
+func IsRetryableError(err error) bool {
+	if err == zk.ErrSessionExpired || err == zk.ErrSessionMoved || err == ErrRequestTimeout {
+		return true
+	}
+
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+
+	return false
+}
+
+// Call the operations in the retry loop of the client, the same way the framework calls its operations.
+// The operations are retried as a whole, so they should be idempotent.
+func CallWithRetry(client CuratorZookeeperClient, proc func() error) error {
+	_, err := client.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+		return nil, proc()
+	})
+
+	return err
+}
+
+// The randomization of the sleep times between the retries,
+// so the clients don't reconnect all at once after an ensemble outage
+type Jitter int
+
+const (
+	NO_JITTER           Jitter = iota // sleep the time of the policy
+	FULL_JITTER                       // sleep a random time up to the time of the policy
+	EQUAL_JITTER                      // sleep half the time of the policy plus a random time up to the other half
+	DECORRELATED_JITTER               // sleep a random time between the time of the policy and 3 times the previous sleep, up to 3 times the time of the policy
+)
+
+// Return the randomized sleep time of a retry, given the sleep time of the policy and the previous sleep time
+func (j Jitter) apply(sleepTime, lastSleepTime time.Duration) time.Duration {
+	if sleepTime <= 0 {
+		return sleepTime
+	}
+
+	switch j {
+	case FULL_JITTER:
+		return time.Duration(rand.Int63n(int64(sleepTime)))
+
+	case EQUAL_JITTER:
+		return sleepTime/2 + time.Duration(rand.Int63n(int64(sleepTime-sleepTime/2)))
+
+	case DECORRELATED_JITTER:
+		upper := 3 * lastSleepTime
+
+		if upper > 3*sleepTime {
+			upper = 3 * sleepTime
+		}
+
+		if upper <= sleepTime {
+			return sleepTime
 		}
+
+		return sleepTime + time.Duration(rand.Int63n(int64(upper-sleepTime)))
 	}
 
-	return nil, nil
+	return sleepTime
 }
 
 type SleepingRetry struct {
 	RetryPolicy
 
-	N            int
-	getSleepTime func(retryCount int, elapsedTime time.Duration) time.Duration
+	N      int
+	Jitter Jitter // randomize the sleep times, applied to all the policies sleeping between the retries
+
+	getSleepTime    func(retryCount int, elapsedTime time.Duration) time.Duration
+	getMaxSleepTime func(elapsedTime time.Duration) time.Duration // bound the randomized sleep times, if any
 }
 
 func (r *SleepingRetry) AllowRetry(retryCount int, elapsedTime time.Duration, sleeper RetrySleeper) bool {
 	if retryCount < r.N {
-		if err := sleeper.SleepFor(r.getSleepTime(retryCount, elapsedTime)); err != nil {
+		sleepTime := r.getSleepTime(retryCount, elapsedTime)
+
+		// the previous sleep is kept by the retry loop, so each sequence of the retries is decorrelated on its own,
+		// the first retry or a sleeper without it starts a new sequence
+		lastSleepTime := sleepTime
+
+		if previous, ok := sleeper.(previousRetrySleeper); ok && retryCount > 0 && previous.previousSleepTime() > 0 {
+			lastSleepTime = previous.previousSleepTime()
+		}
+
+		sleepTime = r.Jitter.apply(sleepTime, lastSleepTime)
+
+		if r.getMaxSleepTime != nil {
+			if maxSleepTime := r.getMaxSleepTime(elapsedTime); sleepTime > maxSleepTime {
+				sleepTime = maxSleepTime
+			}
+		}
+
+		if err := sleeper.SleepFor(sleepTime); err != nil {
 			return false
 		}
 
@@ -147,18 +320,36 @@ type ExponentialBackoffRetry struct {
 	SleepingRetry
 }
 
+// Create a retry policy sleeping a random multiple of the base sleep time, between 1 and 2^(retryCount+1) like Curator does,
+// but no longer than the max sleep time. The max retries are limited to MAX_RETRIES_LIMIT, the max sleep time to DEFAULT_MAX_SLEEP if not positive.
 func NewExponentialBackoffRetry(baseSleepTime time.Duration, maxRetries int, maxSleep time.Duration) *ExponentialBackoffRetry {
 	if maxRetries > MAX_RETRIES_LIMIT {
+		log.Printf("maxRetries too large (%d). Pinning to %d", maxRetries, MAX_RETRIES_LIMIT)
+
 		maxRetries = MAX_RETRIES_LIMIT
 	}
 
+	if maxSleep <= 0 {
+		maxSleep = DEFAULT_MAX_SLEEP
+	}
+
 	return &ExponentialBackoffRetry{
 		SleepingRetry: SleepingRetry{
 			N: maxRetries,
 			getSleepTime: func(retryCount int, elapsedTime time.Duration) time.Duration {
-				sleepTime := time.Duration(int64(baseSleepTime) * rand.Int63n(1<<uint(retryCount)))
+				if retryCount >= MAX_RETRIES_LIMIT {
+					retryCount = MAX_RETRIES_LIMIT - 1
+				}
+
+				multiplier := rand.Int63n(1 << uint(retryCount+1))
+
+				if multiplier < 1 {
+					multiplier = 1
+				}
 
-				if sleepTime > maxSleep {
+				sleepTime := baseSleepTime * time.Duration(multiplier)
+
+				if sleepTime > maxSleep || sleepTime < 0 {
 					sleepTime = maxSleep
 				}
 
@@ -167,6 +358,26 @@ func NewExponentialBackoffRetry(baseSleepTime time.Duration, maxRetries int, max
 		}}
 }
 
+// Retry policy that retries a set number of times with increasing sleep time between retries,
+// bounded by a max sleep time so the backoff can't grow unbounded during long outages
+type BoundedExponentialBackoffRetry struct {
+	ExponentialBackoffRetry
+
+	maxSleepTime time.Duration
+}
+
+func NewBoundedExponentialBackoffRetry(baseSleepTime, maxSleepTime time.Duration, maxRetries int) *BoundedExponentialBackoffRetry {
+	return &BoundedExponentialBackoffRetry{
+		ExponentialBackoffRetry: *NewExponentialBackoffRetry(baseSleepTime, maxRetries, maxSleepTime),
+		maxSleepTime:            maxSleepTime,
+	}
+}
+
+// Return the max time to sleep between the retries
+func (r *BoundedExponentialBackoffRetry) MaxSleepTime() time.Duration {
+	return r.maxSleepTime
+}
+
 // A retry policy that retries until a given amount of time elapses
 type RetryUntilElapsed struct {
 	SleepingRetry
@@ -174,16 +385,32 @@ type RetryUntilElapsed struct {
 	maxElapsedTime time.Duration
 }
 
+// Create a retry policy that retries until the wall-clock budget is exhausted rather than a fixed count,
+// the last sleep is shortened so the retries don't outlast the budget, even with the jitter.
 func NewRetryUntilElapsed(maxElapsedTime, sleepBetweenRetries time.Duration) *RetryUntilElapsed {
 	return &RetryUntilElapsed{
 		SleepingRetry: SleepingRetry{
-			N:            math.MaxInt64,
-			getSleepTime: func(retryCount int, elapsedTime time.Duration) time.Duration { return sleepBetweenRetries },
+			N: math.MaxInt32,
+			getSleepTime: func(retryCount int, elapsedTime time.Duration) time.Duration {
+				if remaining := maxElapsedTime - elapsedTime; remaining < sleepBetweenRetries {
+					return remaining
+				}
+
+				return sleepBetweenRetries
+			},
+			getMaxSleepTime: func(elapsedTime time.Duration) time.Duration {
+				return maxElapsedTime - elapsedTime
+			},
 		},
 		maxElapsedTime: maxElapsedTime,
 	}
 }
 
+// Return the time after which the operations are no longer retried
+func (r *RetryUntilElapsed) MaxElapsedTime() time.Duration {
+	return r.maxElapsedTime
+}
+
 func (r *RetryUntilElapsed) AllowRetry(retryCount int, elapsedTime time.Duration, sleeper RetrySleeper) bool {
 	return elapsedTime < r.maxElapsedTime && r.SleepingRetry.AllowRetry(retryCount, elapsedTime, sleeper)
 }
@@ -47,6 +47,80 @@ func TestRetryLoop(t *testing.T) {
 	assert.EqualError(t, err, zk.ErrClosing.Error())
 }
 
+func TestRetryListener(t *testing.T) {
+	d := 3 * time.Second
+	sleeper := &mockRetrySleeper{}
+	tracer := &mockTracerDriver{}
+
+	var events []*RetryEvent
+	var panics []error
+
+	retryLoop := newRetryLoop(NewRetryNTimes(2, d), tracer)
+	retryLoop.retrySleeper = sleeper
+	retryLoop.operation = CREATE.String()
+	retryLoop.listeners = new(retryListenerContainer)
+	retryLoop.unhandledError = func(err error) { panics = append(panics, err) }
+	retryLoop.listeners.AddListener(NewRetryListener(func(event *RetryEvent) {
+		events = append(events, event)
+	}))
+	retryLoop.listeners.AddListener(NewRetryListener(func(event *RetryEvent) {
+		panic("listener")
+	}))
+
+	sleeper.On("SleepFor", d).Return(nil).Twice()
+	tracer.On("AddCount", "retries-allowed", 1).Return().Twice()
+	tracer.On("AddCount", "retries-disallowed", 1).Return().Once()
+
+	_, err := retryLoop.CallWithRetry(func() (interface{}, error) {
+		return nil, zk.ErrSessionExpired
+	})
+
+	assert.Equal(t, zk.ErrSessionExpired, err)
+	assert.Equal(t, []*RetryEvent{
+		{Operation: "CREATE", Attempt: 1, Err: zk.ErrSessionExpired, SleepTime: d},
+		{Operation: "CREATE", Attempt: 2, Err: zk.ErrSessionExpired, SleepTime: d},
+	}, events)
+	assert.Len(t, panics, 2)
+
+	sleeper.AssertExpectations(t)
+	tracer.AssertExpectations(t)
+}
+
+func TestCallWithRetry(t *testing.T) {
+	client := &mockCuratorZookeeperClient{}
+	tracer := &mockTracerDriver{}
+
+	client.On("NewRetryLoop").Return(newRetryLoop(NewRetryNTimes(3, 0), tracer)).Twice()
+	tracer.On("AddCount", "retries-allowed", 1).Return().Once()
+
+	var calls int
+
+	assert.NoError(t, CallWithRetry(client, func() error {
+		if calls++; calls == 1 {
+			return zk.ErrSessionExpired
+		}
+
+		return nil
+	}))
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+
+	assert.Equal(t, zk.ErrNoNode, CallWithRetry(client, func() error {
+		calls++
+
+		return zk.ErrNoNode
+	}))
+	assert.Equal(t, 1, calls)
+
+	client.AssertExpectations(t)
+	tracer.AssertExpectations(t)
+
+	assert.True(t, IsRetryableError(zk.ErrSessionMoved))
+	assert.True(t, IsRetryableError(ErrRequestTimeout))
+	assert.False(t, IsRetryableError(zk.ErrNodeExists))
+}
+
 func TestRetryNTimes(t *testing.T) {
 	d := 3 * time.Second
 	p := NewRetryNTimes(3, d)
@@ -93,9 +167,107 @@ func TestExponentialBackoffRetry(t *testing.T) {
 	assert.True(t, p.AllowRetry(2, 0, s))
 	assert.False(t, p.AllowRetry(3, 0, s))
 
-	assert.True(t, s.Calls[0].Arguments.Get(0).(time.Duration) < 1*d)
-	assert.True(t, s.Calls[1].Arguments.Get(0).(time.Duration) < 2*d)
-	assert.True(t, s.Calls[2].Arguments.Get(0).(time.Duration) < 4*d)
+	// sleep a random multiple of the base sleep time, between 1 and 2^(retryCount+1), up to the max sleep time
+	assert.Equal(t, d, s.Calls[0].Arguments.Get(0).(time.Duration))
+
+	for _, call := range s.Calls[1:] {
+		sleepTime := call.Arguments.Get(0).(time.Duration)
+
+		assert.True(t, sleepTime >= d && sleepTime <= 9*time.Second && sleepTime%d == 0, "sleepTime=%v", sleepTime)
+	}
+
+	s.AssertExpectations(t)
+
+	// the max retries are limited, and the sleep time too without max sleep time
+	p = NewExponentialBackoffRetry(time.Millisecond, 100, 0)
+	s = &mockRetrySleeper{}
+
+	assert.Equal(t, MAX_RETRIES_LIMIT, p.N)
+
+	s.On("SleepFor", mock.AnythingOfType("Duration")).Return(nil).Once()
+
+	assert.True(t, p.AllowRetry(MAX_RETRIES_LIMIT-1, 0, s))
+	assert.False(t, p.AllowRetry(MAX_RETRIES_LIMIT, 0, s))
+	assert.True(t, s.Calls[0].Arguments.Get(0).(time.Duration) < time.Duration(1<<MAX_RETRIES_LIMIT)*time.Millisecond)
+
+	s.AssertExpectations(t)
+}
+
+func TestBoundedExponentialBackoffRetry(t *testing.T) {
+	d := 3 * time.Second
+	p := NewBoundedExponentialBackoffRetry(d, 2*d, 10)
+	s := &mockRetrySleeper{}
+
+	assert.NotNil(t, p)
+	assert.Equal(t, 2*d, p.MaxSleepTime())
+
+	s.On("SleepFor", mock.AnythingOfType("Duration")).Return(nil).Times(10)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, p.AllowRetry(i, 0, s))
+	}
+
+	assert.False(t, p.AllowRetry(10, 0, s))
+
+	for _, call := range s.Calls {
+		sleepTime := call.Arguments.Get(0).(time.Duration)
+
+		assert.True(t, sleepTime >= d && sleepTime <= 2*d, "sleepTime=%v", sleepTime)
+	}
+
+	s.AssertExpectations(t)
+}
+
+func TestJitter(t *testing.T) {
+	d := 3 * time.Second
+
+	assert.Equal(t, d, NO_JITTER.apply(d, d))
+	assert.Equal(t, time.Duration(0), FULL_JITTER.apply(0, d))
+
+	for i := 0; i < 100; i++ {
+		sleepTime := FULL_JITTER.apply(d, d)
+
+		assert.True(t, sleepTime >= 0 && sleepTime < d, "sleepTime=%v", sleepTime)
+
+		sleepTime = EQUAL_JITTER.apply(d, d)
+
+		assert.True(t, sleepTime >= d/2 && sleepTime < d, "sleepTime=%v", sleepTime)
+
+		sleepTime = DECORRELATED_JITTER.apply(d, 2*d)
+
+		assert.True(t, sleepTime >= d && sleepTime < 3*d, "sleepTime=%v", sleepTime)
+	}
+
+	// the jitter is applied to the sleep times of the policies
+	p := NewRetryNTimes(3, d)
+	s := &mockRetrySleeper{}
+
+	p.Jitter = EQUAL_JITTER
+
+	s.On("SleepFor", mock.AnythingOfType("Duration")).Return(nil).Times(3)
+
+	assert.True(t, p.AllowRetry(0, 0, s))
+	assert.True(t, p.AllowRetry(1, 0, s))
+	assert.True(t, p.AllowRetry(2, 0, s))
+	assert.False(t, p.AllowRetry(3, 0, s))
+
+	for _, call := range s.Calls {
+		sleepTime := call.Arguments.Get(0).(time.Duration)
+
+		assert.True(t, sleepTime >= d/2 && sleepTime < d, "sleepTime=%v", sleepTime)
+	}
+
+	s.AssertExpectations(t)
+
+	// the decorrelated jitter follows the previous sleep of the retry loop, not of the other loops sharing the policy
+	p = NewRetryNTimes(3, d)
+	s = &mockRetrySleeper{}
+
+	p.Jitter = DECORRELATED_JITTER
+
+	s.On("SleepFor", d).Return(nil).Once()
+
+	assert.True(t, p.AllowRetry(1, 0, &notifyingRetrySleeper{RetrySleeper: s, previous: d / 10}))
 
 	s.AssertExpectations(t)
 }
@@ -115,4 +287,27 @@ func TestRetryUntilElapsed(t *testing.T) {
 	assert.False(t, p.AllowRetry(0, d*3, s))
 
 	s.AssertExpectations(t)
+
+	// the last sleep doesn't outlast the budget
+	s = &mockRetrySleeper{}
+
+	s.On("SleepFor", d/2).Return(nil).Once()
+
+	assert.Equal(t, 3*d, p.MaxElapsedTime())
+	assert.True(t, p.AllowRetry(10, d*5/2, s))
+
+	s.AssertExpectations(t)
+
+	// even with the jitter
+	s = &mockRetrySleeper{}
+
+	p.Jitter = DECORRELATED_JITTER
+
+	s.On("SleepFor", d/2).Return(nil).Times(10)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, p.AllowRetry(10, d*5/2, &notifyingRetrySleeper{RetrySleeper: s, previous: d}))
+	}
+
+	s.AssertExpectations(t)
 }
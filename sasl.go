@@ -0,0 +1,251 @@
+package curator
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The opcode and the xid of the SASL requests, which the servers reply to before the other requests
+const (
+	opSasl   = 102
+	saslXid  = -10
+	saslHost = "zk-sasl-md5" // the server name of the DIGEST-MD5 mechanism of the servers
+)
+
+var ErrSASLRspAuth = errors.New("zk: invalid SASL rspauth of the server")
+
+// The client side of a SASL authentication, e.g. DIGEST-MD5 or the GSSAPI client of a Kerberos library
+type SASLClient interface {
+	// Return the initial response, empty if the server sends the first challenge
+	Start() ([]byte, error)
+
+	// Evaluate a challenge of the server and return the response, nil once the authentication completes
+	Next(challenge []byte) ([]byte, error)
+
+	// Return true once the authentication completes
+	Complete() bool
+}
+
+// Create the SASL clients authenticating the connections, a new one for each connection
+type SASLMechanism interface {
+	// Create a client authenticating a connection to the host, e.g. for the zookeeper/host service principal
+	NewClient(host string) (SASLClient, error)
+}
+
+type saslMechanismCallback struct {
+	callback func(host string) (SASLClient, error)
+}
+
+func NewSASLMechanism(callback func(host string) (SASLClient, error)) SASLMechanism {
+	return &saslMechanismCallback{callback}
+}
+
+func (m *saslMechanismCallback) NewClient(host string) (SASLClient, error) {
+	return m.callback(host)
+}
+
+type digestSASLMechanism struct {
+	username string
+	password string
+}
+
+// Authenticate with the DIGEST-MD5 mechanism, the user is defined in the JAAS config of the servers
+func NewDigestSASLMechanism(username, password string) SASLMechanism {
+	return &digestSASLMechanism{username, password}
+}
+
+func (m *digestSASLMechanism) NewClient(host string) (SASLClient, error) {
+	var cnonce [16]byte
+
+	if _, err := rand.Read(cnonce[:]); err != nil {
+		return nil, err
+	}
+
+	return &digestSASLClient{
+		username:  m.username,
+		password:  m.password,
+		digestURI: "zookeeper/" + saslHost,
+		cnonce:    base64.StdEncoding.EncodeToString(cnonce[:]),
+	}, nil
+}
+
+// The DIGEST-MD5 client of RFC 2831, with the auth quality of protection
+type digestSASLClient struct {
+	username  string
+	password  string
+	digestURI string
+	cnonce    string
+	rspauth   string
+	complete  bool
+}
+
+func (c *digestSASLClient) Start() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (c *digestSASLClient) Next(challenge []byte) ([]byte, error) {
+	directives := parseDigestDirectives(string(challenge))
+
+	if c.rspauth != "" {
+		if directives["rspauth"] != c.rspauth {
+			return nil, ErrSASLRspAuth
+		}
+
+		c.complete = true
+
+		return nil, nil
+	}
+
+	nonce, realm := directives["nonce"], directives["realm"]
+
+	if nonce == "" {
+		return nil, fmt.Errorf("zk: invalid SASL challenge of the server, %s", challenge)
+	}
+
+	if qop, exists := directives["qop"]; exists && !strings.Contains(qop, "auth") {
+		return nil, fmt.Errorf("zk: unsupported SASL qop of the server, %s", qop)
+	}
+
+	const nc = "00000001"
+
+	secret := md5.Sum([]byte(c.username + ":" + realm + ":" + c.password))
+	a1 := md5Hex(string(secret[:]) + ":" + nonce + ":" + c.cnonce)
+	digest := func(a2 string) string {
+		return md5Hex(a1 + ":" + nonce + ":" + nc + ":" + c.cnonce + ":auth:" + md5Hex(a2))
+	}
+
+	c.rspauth = digest(":" + c.digestURI)
+
+	return []byte(fmt.Sprintf(`charset=utf-8,username="%s",realm="%s",nonce="%s",nc=%s,cnonce="%s",digest-uri="%s",maxbuf=65536,response=%s,qop=auth`,
+		c.username, realm, nonce, nc, c.cnonce, c.digestURI, digest("AUTHENTICATE:"+c.digestURI))), nil
+}
+
+func (c *digestSASLClient) Complete() bool {
+	return c.complete
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse the directives of a DIGEST-MD5 challenge, e.g. realm="zk-sasl-md5",nonce="...",qop="auth",charset=utf-8
+func parseDigestDirectives(challenge string) map[string]string {
+	directives := make(map[string]string)
+
+	for len(challenge) > 0 {
+		pos := strings.IndexByte(challenge, '=')
+
+		if pos < 0 {
+			break
+		}
+
+		key := strings.TrimSpace(challenge[:pos])
+		challenge = challenge[pos+1:]
+
+		var value string
+
+		if strings.HasPrefix(challenge, `"`) {
+			if end := strings.IndexByte(challenge[1:], '"'); end < 0 {
+				value, challenge = challenge[1:], ""
+			} else {
+				value, challenge = challenge[1:end+1], challenge[end+2:]
+			}
+		} else if end := strings.IndexByte(challenge, ','); end < 0 {
+			value, challenge = challenge, ""
+		} else {
+			value, challenge = challenge[:end], challenge[end:]
+		}
+
+		directives[key] = value
+		challenge = strings.TrimLeft(challenge, ", ")
+	}
+
+	return directives
+}
+
+// Authenticate a new connection with SASL, after the connect response and before any other request
+func authenticateSASL(conn net.Conn, mechanism SASLMechanism) error {
+	host := conn.RemoteAddr().String()
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	client, err := mechanism.NewClient(host)
+
+	if err != nil {
+		return err
+	}
+
+	token, err := client.Start()
+
+	for err == nil {
+		var challenge []byte
+
+		if challenge, err = exchangeSASL(conn, token); err != nil || client.Complete() {
+			break
+		} else if token, err = client.Next(challenge); err == nil && token == nil && client.Complete() {
+			break
+		}
+	}
+
+	return err
+}
+
+// Send a SASL token and return the challenge of the server
+func exchangeSASL(conn net.Conn, token []byte) ([]byte, error) {
+	var body bytes.Buffer
+
+	writeBuffer(&body, token)
+
+	var xid bytes.Buffer
+
+	binary.Write(&xid, binary.BigEndian, int32(saslXid))
+
+	if _, err := conn.Write(newPacket(xid.Bytes(), opSasl, body.Bytes())); err != nil {
+		return nil, err
+	}
+
+	var header [4]byte
+
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+
+	packet := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	if _, err := io.ReadFull(conn, packet); err != nil {
+		return nil, err
+	}
+
+	if len(packet) < 16 {
+		return nil, fmt.Errorf("zk: invalid SASL response of the server")
+	}
+
+	switch code := int32(binary.BigEndian.Uint32(packet[12:])); code {
+	case 0:
+	case -115:
+		return nil, zk.ErrAuthFailed
+	default:
+		return nil, fmt.Errorf("zk: SASL authentication failed, error %d", code)
+	}
+
+	if challenge := readBuffer(bytes.NewReader(packet[16:])); challenge == nil {
+		return nil, fmt.Errorf("zk: invalid SASL response of the server")
+	} else {
+		return challenge, nil
+	}
+}
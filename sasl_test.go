@@ -0,0 +1,140 @@
+package curator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestSASLClient(t *testing.T) {
+	// the example of RFC 2831
+	client := &digestSASLClient{
+		username:  "chris",
+		password:  "secret",
+		digestURI: "imap/elwood.innosoft.com",
+		cnonce:    "OA6MHXh6VqTrRk",
+	}
+
+	token, err := client.Start()
+
+	assert.Empty(t, token)
+	assert.NoError(t, err)
+
+	token, err = client.Next([]byte(`realm="elwood.innosoft.com",nonce="OA6MG9tEQGm2hh",qop="auth",algorithm=md5-sess,charset=utf-8`))
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(token), `username="chris",realm="elwood.innosoft.com",nonce="OA6MG9tEQGm2hh"`)
+	assert.Contains(t, string(token), "response=d388dad90d4bbd760a152321f2143af7")
+	assert.False(t, client.Complete())
+
+	_, err = client.Next([]byte("rspauth=00000000000000000000000000000000"))
+
+	assert.Equal(t, ErrSASLRspAuth, err)
+
+	token, err = client.Next([]byte("rspauth=ea40f60335c427b5527b84dbabcdfffd"))
+
+	assert.Nil(t, token)
+	assert.NoError(t, err)
+	assert.True(t, client.Complete())
+}
+
+type scriptedSASLClient struct {
+	complete bool
+}
+
+func (c *scriptedSASLClient) Start() ([]byte, error) { return []byte("hello"), nil }
+
+func (c *scriptedSASLClient) Next(challenge []byte) ([]byte, error) {
+	c.complete = true
+
+	return []byte("response to " + string(challenge)), nil
+}
+
+func (c *scriptedSASLClient) Complete() bool { return c.complete }
+
+// Reply to a SASL request with the error code and the challenge, return the token of the request
+func replySASL(t *testing.T, conn net.Conn, code int32, challenge string) string {
+	var header [4]byte
+
+	io.ReadFull(conn, header[:])
+
+	packet := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	io.ReadFull(conn, packet)
+
+	assert.Equal(t, int32(saslXid), int32(binary.BigEndian.Uint32(packet)))
+	assert.Equal(t, int32(opSasl), int32(binary.BigEndian.Uint32(packet[4:])))
+
+	var body bytes.Buffer
+
+	binary.Write(&body, binary.BigEndian, int32(saslXid))
+	binary.Write(&body, binary.BigEndian, int64(0))
+	binary.Write(&body, binary.BigEndian, code)
+	writeBuffer(&body, []byte(challenge))
+
+	binary.Write(conn, binary.BigEndian, int32(body.Len()))
+	conn.Write(body.Bytes())
+
+	return string(readBuffer(bytes.NewReader(packet[8:])))
+}
+
+func TestProtocolConnSASL(t *testing.T) {
+	var connectResponse bytes.Buffer
+
+	binary.Write(&connectResponse, binary.BigEndian, int32(36))
+	binary.Write(&connectResponse, binary.BigEndian, int32(0))    // protocol version
+	binary.Write(&connectResponse, binary.BigEndian, int32(4000)) // timeout
+	binary.Write(&connectResponse, binary.BigEndian, int64(1))    // session id
+	writeBuffer(&connectResponse, make([]byte, 16))
+
+	var hosts []string
+
+	mechanism := NewSASLMechanism(func(host string) (SASLClient, error) {
+		hosts = append(hosts, host)
+
+		return &scriptedSASLClient{}, nil
+	})
+
+	for _, code := range []int32{0, -115} {
+		client, server := net.Pipe()
+		conn := &protocolConn{Conn: client, sasl: mechanism, responses: make(map[int32]func([]byte) []byte)}
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			server.Write(connectResponse.Bytes())
+
+			assert.Equal(t, "hello", replySASL(t, server, 0, "challenge"))
+
+			if code == 0 {
+				assert.Equal(t, "response to challenge", replySASL(t, server, 0, ""))
+			} else {
+				replySASL(t, server, code, "")
+			}
+		}()
+
+		buf := make([]byte, connectResponse.Len())
+
+		_, err := io.ReadFull(conn, buf)
+
+		if code == 0 {
+			assert.NoError(t, err)
+			assert.Equal(t, connectResponse.Bytes(), buf)
+		} else {
+			assert.Equal(t, zk.ErrAuthFailed, err)
+		}
+
+		<-done
+
+		client.Close()
+		server.Close()
+	}
+
+	assert.Equal(t, []string{"pipe", "pipe"}, hosts)
+}
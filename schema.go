@@ -0,0 +1,193 @@
+package curator
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Whether a node matching a schema can, must or cannot have a property, e.g. be ephemeral
+type Allowance int
+
+const (
+	CAN    Allowance = iota // the node can have the property or not
+	MUST                    // the node must have the property
+	CANNOT                  // the node cannot have the property
+)
+
+func (a Allowance) String() string {
+	switch a {
+	case CAN:
+		return "CAN"
+	case MUST:
+		return "MUST"
+	case CANNOT:
+		return "CANNOT"
+	default:
+		return fmt.Sprintf("Allowance(%d)", a)
+	}
+}
+
+func (a Allowance) allows(value bool) bool {
+	switch a {
+	case MUST:
+		return value
+	case CANNOT:
+		return !value
+	default:
+		return true
+	}
+}
+
+// Validate the data and ACLs of a node created or set under a schema
+type SchemaValidator interface {
+	IsValid(schema *Schema, path string, data []byte, acls []zk.ACL) bool
+}
+
+type schemaValidatorCallback struct {
+	callback func(schema *Schema, path string, data []byte, acls []zk.ACL) bool
+}
+
+func NewSchemaValidator(callback func(schema *Schema, path string, data []byte, acls []zk.ACL) bool) SchemaValidator {
+	return &schemaValidatorCallback{callback}
+}
+
+func (v *schemaValidatorCallback) IsValid(schema *Schema, path string, data []byte, acls []zk.ACL) bool {
+	return v.callback(schema, path, data, acls)
+}
+
+// The constraints of the nodes matching a path, or a regex of paths
+type Schema struct {
+	Name          string          // the name of the schema, used in the violations
+	Path          string          // the exact path of the nodes, or
+	PathRegex     *regexp.Regexp  // the regex of the paths of the nodes
+	Documentation string          // the documentation of the schema
+	DataValidator SchemaValidator // validate the data and ACLs of the nodes, if any
+	Ephemeral     Allowance       // whether the nodes can be ephemeral
+	Sequential    Allowance       // whether the nodes can be sequential
+	CanBeDeleted  bool            // whether the nodes can be deleted
+}
+
+// The schema of any node, allowing everything
+var DefaultSchema = &Schema{
+	Name:          "default",
+	PathRegex:     regexp.MustCompile(".*"),
+	Documentation: "Default schema",
+	Ephemeral:     CAN,
+	Sequential:    CAN,
+	CanBeDeleted:  true,
+}
+
+func (s *Schema) matches(path string) bool {
+	if s.PathRegex != nil {
+		return s.PathRegex.MatchString(path)
+	}
+
+	return s.Path == path
+}
+
+// Validate a node to be created with the given mode, data and ACLs
+func (s *Schema) ValidateCreate(mode CreateMode, path string, data []byte, acls []zk.ACL) error {
+	if !s.Ephemeral.allows(mode.IsEphemeral()) {
+		return &SchemaViolation{s, path, fmt.Sprintf("Ephemerality %s, mode %d", s.Ephemeral, mode)}
+	}
+
+	if !s.Sequential.allows(mode.IsSequential()) {
+		return &SchemaViolation{s, path, fmt.Sprintf("Sequential %s, mode %d", s.Sequential, mode)}
+	}
+
+	return s.ValidateGeneral(path, data, acls)
+}
+
+// Validate the data and ACLs of a node
+func (s *Schema) ValidateGeneral(path string, data []byte, acls []zk.ACL) error {
+	if s.DataValidator != nil && !s.DataValidator.IsValid(s, path, data, acls) {
+		return &SchemaViolation{s, path, "Data is not valid"}
+	}
+
+	return nil
+}
+
+// Validate a node to be deleted
+func (s *Schema) ValidateDelete(path string) error {
+	if !s.CanBeDeleted {
+		return &SchemaViolation{s, path, "Cannot be deleted"}
+	}
+
+	return nil
+}
+
+func (s *Schema) String() string {
+	if s.PathRegex != nil {
+		return fmt.Sprintf("Schema{name=%s, pathRegex=%s}", s.Name, s.PathRegex)
+	}
+
+	return fmt.Sprintf("Schema{name=%s, path=%s}", s.Name, s.Path)
+}
+
+// The error of an operation violating a schema
+type SchemaViolation struct {
+	Schema    *Schema // the violated schema, nil if no schema matches the path
+	Path      string  // the path of the operation
+	Violation string  // the description of the violation
+}
+
+func (v *SchemaViolation) Error() string {
+	if v.Schema == nil {
+		return fmt.Sprintf("Schema violation: %s at %s", v.Violation, v.Path)
+	}
+
+	return fmt.Sprintf("Schema violation: %s for %s at %s", v.Violation, v.Schema, v.Path)
+}
+
+// A set of schemas, enforced by the framework for the nodes matching them
+type SchemaSet struct {
+	schemas          []*Schema
+	useDefaultSchema bool
+}
+
+// Create a schema set, the schemas with an exact path are matched before the regex ones,
+// the latter in the given order. The nodes matching no schema are rejected unless the default schema is used.
+func NewSchemaSet(useDefaultSchema bool, schemas ...*Schema) *SchemaSet {
+	set := &SchemaSet{useDefaultSchema: useDefaultSchema}
+
+	for _, schema := range schemas {
+		if schema.PathRegex == nil {
+			set.schemas = append(set.schemas, schema)
+		}
+	}
+
+	for _, schema := range schemas {
+		if schema.PathRegex != nil {
+			set.schemas = append(set.schemas, schema)
+		}
+	}
+
+	return set
+}
+
+// The schema set allowing everything
+func DefaultSchemaSet() *SchemaSet {
+	return NewSchemaSet(true)
+}
+
+// Return the schemas of the set
+func (s *SchemaSet) Schemas() []*Schema {
+	return s.schemas
+}
+
+// Return the schema matching the path, the default schema or a SchemaViolation if none matches
+func (s *SchemaSet) Schema(path string) (*Schema, error) {
+	for _, schema := range s.schemas {
+		if schema.matches(path) {
+			return schema, nil
+		}
+	}
+
+	if s.useDefaultSchema {
+		return DefaultSchema, nil
+	}
+
+	return nil, &SchemaViolation{nil, path, "No schema found"}
+}
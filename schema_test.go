@@ -0,0 +1,92 @@
+package curator
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSchemaSet(t *testing.T) {
+	exact := &Schema{Name: "exact", Path: "/a/b", CanBeDeleted: true}
+	regex := &Schema{Name: "regex", PathRegex: regexp.MustCompile("^/a/.*"), CanBeDeleted: true}
+
+	schemas := NewSchemaSet(false, regex, exact)
+
+	assert.Equal(t, []*Schema{exact, regex}, schemas.Schemas())
+
+	schema, err := schemas.Schema("/a/b")
+
+	assert.Equal(t, exact, schema)
+	assert.NoError(t, err)
+
+	schema, err = schemas.Schema("/a/c")
+
+	assert.Equal(t, regex, schema)
+	assert.NoError(t, err)
+
+	_, err = schemas.Schema("/b")
+
+	assert.EqualError(t, err, "Schema violation: No schema found at /b")
+
+	schema, err = DefaultSchemaSet().Schema("/b")
+
+	assert.Equal(t, DefaultSchema, schema)
+	assert.NoError(t, err)
+}
+
+func TestSchema(t *testing.T) {
+	schema := &Schema{
+		Name:       "test",
+		Path:       "/node",
+		Ephemeral:  MUST,
+		Sequential: CANNOT,
+		DataValidator: NewSchemaValidator(func(schema *Schema, path string, data []byte, acls []zk.ACL) bool {
+			return len(data) > 0
+		}),
+	}
+
+	assert.NoError(t, schema.ValidateCreate(EPHEMERAL, "/node", []byte("data"), nil))
+	assert.EqualError(t, schema.ValidateCreate(PERSISTENT, "/node", []byte("data"), nil),
+		"Schema violation: Ephemerality MUST, mode 0 for Schema{name=test, path=/node} at /node")
+	assert.EqualError(t, schema.ValidateCreate(EPHEMERAL_SEQUENTIAL, "/node", []byte("data"), nil),
+		"Schema violation: Sequential CANNOT, mode 3 for Schema{name=test, path=/node} at /node")
+	assert.EqualError(t, schema.ValidateGeneral("/node", nil, nil),
+		"Schema violation: Data is not valid for Schema{name=test, path=/node} at /node")
+	assert.EqualError(t, schema.ValidateDelete("/node"),
+		"Schema violation: Cannot be deleted for Schema{name=test, path=/node} at /node")
+}
+
+type SchemaTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestSchemaEnforced(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
+
+func (s *SchemaTestSuite) TestViolations() {
+	schema := &Schema{Name: "locked", Path: "/locked", Ephemeral: CANNOT}
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.SchemaSet = NewSchemaSet(true, schema)
+	}, func(client CuratorFramework, conn *mockConn, data []byte) {
+		assert.Equal(s.T(), []*Schema{schema}, client.SchemaSet().Schemas())
+
+		_, err := client.Create().WithMode(EPHEMERAL).ForPathWithData("/locked", data)
+
+		if assert.IsType(s.T(), &SchemaViolation{}, err) {
+			assert.Equal(s.T(), schema, err.(*SchemaViolation).Schema)
+			assert.Equal(s.T(), "/locked", err.(*SchemaViolation).Path)
+		}
+
+		assert.IsType(s.T(), &SchemaViolation{}, client.Delete().ForPath("/locked"))
+
+		// the nodes matching no schema use the default one
+		conn.On("Delete", "/other", AnyVersion).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Delete().ForPath("/other"))
+	})
+}
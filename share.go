@@ -0,0 +1,55 @@
+package curator
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// A facade sharing the session of a framework, with its own namespace and ACL provider
+type sharedFacade struct {
+	curatorFramework
+}
+
+func (c *curatorFramework) Share(namespace string, aclProvider ACLProvider) CuratorFramework {
+	c.state.Check(STARTED, "instance must be started before calling this method")
+
+	atomic.AddInt32(c.references, 1)
+
+	facade := &sharedFacade{
+		curatorFramework: *c,
+	}
+
+	facade.released = NewAtomicBool(false)
+	facade.namespace = newNamespace(c, namespace)
+	facade.fixForNamespace = facade.namespace.fixForNamespace
+	facade.unfixForNamespace = facade.namespace.unfixForNamespace
+
+	if aclProvider != nil {
+		facade.aclProvider = aclProvider
+	}
+
+	return facade
+}
+
+func (f *sharedFacade) Start() error {
+	return errors.New("the requested operation is not supported")
+}
+
+// Release the session, which is closed once the framework and all the shared facades are closed
+func (f *sharedFacade) Close() error {
+	if f.State() != STARTED || !f.released.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	return f.root.release()
+}
+
+func (f *sharedFacade) CuratorListenable() CuratorListenable {
+	f.logError(errors.New("CuratorListenable() is only available from a non-shared CuratorFramework instance"))
+
+	return f.curatorFramework.listeners
+}
+
+func (f *sharedFacade) Namespace() string {
+	return f.namespace.namespace
+}
@@ -0,0 +1,71 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SharedFacadeTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestSharedFacade(t *testing.T) {
+	suite.Run(t, new(SharedFacadeTestSuite))
+}
+
+func (s *SharedFacadeTestSuite) TestShare() {
+	s.With(func(client CuratorFramework, conn *mockConn, data []byte) {
+		acls := NewDigestACLProvider(zk.PermAll, "user", "password")
+		facade := client.Share("tenant", acls)
+
+		assert.Equal(s.T(), "tenant", facade.Namespace())
+		assert.Equal(s.T(), "", client.Namespace())
+		assert.Equal(s.T(), client.ZookeeperClient(), facade.ZookeeperClient())
+
+		conn.On("Exists", "/tenant").Return(true, nil, nil).Once()
+		conn.On("Create", "/tenant/node", data, int32(PERSISTENT), acls.GetDefaultAcl()).Return("/tenant/node", nil).Once()
+
+		path, err := facade.Create().ForPathWithData("/node", data)
+
+		assert.Equal(s.T(), "/node", path)
+		assert.NoError(s.T(), err)
+
+		// the session is kept by the client
+		assert.NoError(s.T(), facade.Close())
+		assert.NoError(s.T(), facade.Close())
+		assert.True(s.T(), client.Started())
+	})
+}
+
+func TestSharedFacadeClose(t *testing.T) {
+	conn := &mockConn{log: t.Logf}
+
+	client := (&CuratorFrameworkBuilder{
+		ZookeeperDialer: NewZookeeperDialer(func(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (ZookeeperConnection, <-chan zk.Event, error) {
+			return conn, make(chan zk.Event), nil
+		}),
+	}).ConnectString("connStr").Build()
+
+	assert.NoError(t, client.Start())
+
+	first := client.Share("first", nil)
+	second := first.Share("second", nil)
+
+	// the session is kept by the shared facades
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close())
+	assert.NoError(t, first.Close())
+	assert.True(t, second.Started())
+
+	conn.On("Close").Return().Once()
+
+	assert.NoError(t, second.Close())
+	assert.False(t, client.Started())
+	assert.Equal(t, STOPPED, first.State())
+
+	conn.AssertExpectations(t)
+}
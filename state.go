@@ -1,6 +1,7 @@
 package curator
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -26,10 +27,23 @@ type zookeeperHelper interface {
 
 type zookeeperFactory struct {
 	holder *handleHolder
+	lock   sync.Mutex // serializes the callers, so only one of them dials the connection
 }
 
 func (f *zookeeperFactory) GetConnectionString() string { return "" }
 func (f *zookeeperFactory) GetZookeeperConnection() (ZookeeperConnection, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// the connection has been dialed by another caller, or the holder has been reset meanwhile
+	if helper := f.holder.getHelper(); helper != f {
+		if helper == nil {
+			return nil, nil
+		}
+
+		return helper.GetZookeeperConnection()
+	}
+
 	connectString := f.holder.ensembleProvider.ConnectionString()
 	conn, events, err := f.holder.zookeeperDialer.Dial(connectString, f.holder.sessionTimeout, f.holder.canBeReadOnly)
 
@@ -38,10 +52,15 @@ func (f *zookeeperFactory) GetZookeeperConnection() (ZookeeperConnection, error)
 	}
 
 	if events != nil {
-		go NewWatchers(f.holder.watcher).Watch(events)
+		go NewWatchers(NewWatcher(func(event *zk.Event) {
+			// the events of a connection closed by a reset, e.g. its disconnection, would change the state of the following one
+			if f.holder.isCurrent(f, conn) {
+				f.holder.watcher.process(event)
+			}
+		})).Watch(events)
 	}
 
-	f.holder.helper = &zookeeperCache{connectString, conn}
+	f.holder.setHelper(&zookeeperCache{connectString, conn})
 
 	return conn, err
 }
@@ -60,41 +79,78 @@ type handleHolder struct {
 	watcher          Watcher
 	sessionTimeout   time.Duration
 	canBeReadOnly    bool
+	lock             sync.Mutex // guards the helper, which is replaced while the events of the connection are processed
 	helper           zookeeperHelper
 }
 
+func (h *handleHolder) getHelper() zookeeperHelper {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.helper
+}
+
+func (h *handleHolder) setHelper(helper zookeeperHelper) {
+	h.lock.Lock()
+	h.helper = helper
+	h.lock.Unlock()
+}
+
+// Return true if the connection dialed by the factory is still used, or being dialed
+func (h *handleHolder) isCurrent(factory *zookeeperFactory, conn ZookeeperConnection) bool {
+	switch helper := h.getHelper().(type) {
+	case *zookeeperFactory:
+		return helper == factory
+	case *zookeeperCache:
+		return helper.conn == conn
+	}
+
+	return false
+}
+
 func (h *handleHolder) getConnectionString() string {
-	if h.helper != nil {
-		return h.helper.GetConnectionString()
+	if helper := h.getHelper(); helper != nil {
+		return helper.GetConnectionString()
 	}
 
 	return ""
 }
 
 func (h *handleHolder) hasNewConnectionString() bool {
-	if h.helper != nil {
-		return h.ensembleProvider.ConnectionString() != h.helper.GetConnectionString()
+	if helper := h.getHelper(); helper != nil {
+		return h.ensembleProvider.ConnectionString() != helper.GetConnectionString()
 	}
 
 	return false
 }
 
 func (h *handleHolder) getZookeeperConnection() (ZookeeperConnection, error) {
-	if h.helper != nil {
-		return h.helper.GetZookeeperConnection()
+	if helper := h.getHelper(); helper != nil {
+		return helper.GetZookeeperConnection()
 	}
 
 	return nil, nil
 }
 
+// Read the session of the current connection from a snapshot of the helper
+func (h *handleHolder) sessionID() int64 {
+	if cache, ok := h.getHelper().(*zookeeperCache); ok {
+		if conn, ok := cache.conn.(interface{ SessionID() int64 }); ok {
+			return conn.SessionID()
+		}
+	}
+
+	return 0
+}
+
 func (h *handleHolder) closeAndClear() error {
-	if _, ok := h.helper.(*zookeeperFactory); ok {
+	if _, ok := h.getHelper().(*zookeeperFactory); ok {
 		return nil
 	}
 
 	err := h.internalClose()
 
-	h.helper = nil
+	h.setHelper(nil)
 
 	return err
 }
@@ -104,13 +160,13 @@ func (h *handleHolder) closeAndReset() error {
 		return err
 	}
 
-	h.helper = &zookeeperFactory{holder: h}
+	h.setHelper(&zookeeperFactory{holder: h})
 
 	return nil
 }
 
 func (h *handleHolder) internalClose() error {
-	if h.helper != nil {
+	if h.getHelper() != nil {
 		if conn, err := h.getZookeeperConnection(); err != nil {
 			return err
 		} else if conn != nil {
@@ -121,6 +177,92 @@ func (h *handleHolder) internalClose() error {
 	return nil
 }
 
+// The result of checking the timeouts of a connection which isn't connected
+type CheckTimeoutsResult int
+
+const (
+	TIMEOUTS_NOP                   CheckTimeoutsResult = iota // the connection is within its timeouts, keep waiting
+	TIMEOUTS_NEW_CONNECTION_STRING                            // the connection string has changed, connect to the new one
+	TIMEOUTS_RESET_CONNECTION                                 // reset the connection and try again with a new one
+	TIMEOUTS_CONNECTION_TIMEOUT                               // the connection has timed out, the operation fails with ErrConnectionLoss
+	TIMEOUTS_SESSION_EXPIRED                                  // the session has timed out, expire it as if the server had expired it
+)
+
+// The policy of how the connection timeouts and the retries of the operations interact
+type ConnectionHandlingPolicy interface {
+	// Call the operation with the retry loop of the client
+	CallWithRetry(client CuratorZookeeperClient, retryLoop RetryLoop, proc func() (interface{}, error)) (interface{}, error)
+
+	// Check the timeouts of the connection which isn't connected since the given time
+	CheckTimeouts(hasNewConnectionString func() bool, connectionStart time.Time, sessionTimeout, connectionTimeout time.Duration) CheckTimeoutsResult
+}
+
+// The classic handling, the operations fail as soon as the connection times out,
+// and the connection is silently reset after the longer of the timeouts
+type ClassicConnectionHandlingPolicy struct{}
+
+func (p ClassicConnectionHandlingPolicy) CallWithRetry(client CuratorZookeeperClient, retryLoop RetryLoop, proc func() (interface{}, error)) (interface{}, error) {
+	return retryLoop.CallWithRetry(proc)
+}
+
+func (p ClassicConnectionHandlingPolicy) CheckTimeouts(hasNewConnectionString func() bool, connectionStart time.Time, sessionTimeout, connectionTimeout time.Duration) CheckTimeoutsResult {
+	minTimeout, maxTimeout := sessionTimeout, connectionTimeout
+
+	if minTimeout > maxTimeout {
+		minTimeout, maxTimeout = maxTimeout, minTimeout
+	}
+
+	if elapsed := time.Since(connectionStart); elapsed < minTimeout {
+		return TIMEOUTS_NOP
+	} else if hasNewConnectionString() {
+		return TIMEOUTS_NEW_CONNECTION_STRING
+	} else if elapsed >= maxTimeout {
+		return TIMEOUTS_RESET_CONNECTION
+	}
+
+	return TIMEOUTS_CONNECTION_TIMEOUT
+}
+
+// The new handling, the operations wait up to the connection timeout for the connection before they are tried,
+// and the session is expired on the client once the connection has been lost for the session timeout,
+// so the LOST state is reported even if the client can't reach the servers to learn about the expiration
+type StandardConnectionHandlingPolicy struct{}
+
+func (p StandardConnectionHandlingPolicy) CallWithRetry(client CuratorZookeeperClient, retryLoop RetryLoop, proc func() (interface{}, error)) (interface{}, error) {
+	if !client.Connected() {
+		client.BlockUntilConnectedOrTimedOut() // the operation reports the connection loss if it is still lost
+	}
+
+	return retryLoop.CallWithRetry(proc)
+}
+
+func (p StandardConnectionHandlingPolicy) CheckTimeouts(hasNewConnectionString func() bool, connectionStart time.Time, sessionTimeout, connectionTimeout time.Duration) CheckTimeoutsResult {
+	minTimeout := sessionTimeout
+
+	if connectionTimeout < minTimeout {
+		minTimeout = connectionTimeout
+	}
+
+	if elapsed := time.Since(connectionStart); elapsed < minTimeout {
+		return TIMEOUTS_NOP
+	} else if hasNewConnectionString() {
+		return TIMEOUTS_NEW_CONNECTION_STRING
+	} else if elapsed >= sessionTimeout {
+		return TIMEOUTS_SESSION_EXPIRED
+	}
+
+	return TIMEOUTS_CONNECTION_TIMEOUT
+}
+
+// How an operation issued while the connection isn't connected acquires the connection
+type ConnectionAcquisition int
+
+const (
+	ACQUIRE_QUEUED    ConnectionAcquisition = iota // hand the operation over to the connection, which queues it until the connection times out
+	ACQUIRE_FAIL_FAST                              // fail the operation with ErrConnectionLoss at once
+	ACQUIRE_WAIT                                   // block up to the wait timeout for the connection, then fail the operation with ErrConnectionLoss
+)
+
 type connectionState struct {
 	ensembleProvider  EnsembleProvider
 	sessionTimeout    time.Duration
@@ -129,9 +271,19 @@ type connectionState struct {
 	parentWatchers    *Watchers
 	zooKeeper         *handleHolder
 	instanceIndex     int64
-	connectionStart   time.Time
+	connectionLock    sync.Mutex
+	connectionStart   time.Time // the time the connection state changed, guarded by the connection lock
 	isConnected       AtomicBool
+	isReadOnly        AtomicBool
 	backgroundErrors  chan error
+	handlingPolicy    ConnectionHandlingPolicy
+	acquisition       ConnectionAcquisition
+	waitTimeout       time.Duration
+	connectedLock     sync.Mutex
+	connectedChan     chan struct{} // closed when the connection becomes connected, for the operations waiting for it
+	sessionLock       sync.Mutex
+	sessionStarted    time.Time // the time the current session was established
+	sessionStartedID  int64     // the current session
 }
 
 func newConnectionState(zookeeperDialer ZookeeperDialer, ensembleProvider EnsembleProvider, sessionTimeout, connectionTimeout time.Duration,
@@ -145,6 +297,7 @@ func newConnectionState(zookeeperDialer ZookeeperDialer, ensembleProvider Ensemb
 		parentWatchers:    NewWatchers(),
 		connectionStart:   time.Now(),
 		backgroundErrors:  make(chan error, MAX_BACKGROUND_ERRORS),
+		handlingPolicy:    ClassicConnectionHandlingPolicy{},
 	}
 
 	if zookeeperDialer == nil {
@@ -170,10 +323,19 @@ func (s *connectionState) Connected() bool {
 	return s.isConnected.Load()
 }
 
+func (s *connectionState) ReadOnly() bool {
+	return s.isReadOnly.Load()
+}
+
 func (s *connectionState) InstanceIndex() int64 {
 	return atomic.LoadInt64(&s.instanceIndex)
 }
 
+// Return the id of the session of the current connection, 0 if it isn't known
+func (s *connectionState) sessionID() int64 {
+	return s.zooKeeper.sessionID()
+}
+
 func (s *connectionState) Conn() (ZookeeperConnection, error) {
 	if err := s.dequeBackgroundException(); err != nil {
 		return nil, err
@@ -183,11 +345,62 @@ func (s *connectionState) Conn() (ZookeeperConnection, error) {
 		if err := s.checkTimeout(); err != nil {
 			return nil, err
 		}
+
+		switch s.acquisition {
+		case ACQUIRE_FAIL_FAST:
+			return nil, ErrConnectionLoss
+
+		case ACQUIRE_WAIT:
+			if !s.waitForConnection(s.waitTimeout) {
+				return nil, ErrConnectionLoss
+			}
+		}
 	}
 
 	return s.zooKeeper.getZookeeperConnection()
 }
 
+// Wait up to the timeout for the connection to become connected, return true if it is connected
+func (s *connectionState) waitForConnection(timeout time.Duration) bool {
+	s.connectedLock.Lock()
+
+	if s.isConnected.Load() {
+		s.connectedLock.Unlock()
+
+		return true
+	}
+
+	if s.connectedChan == nil {
+		s.connectedChan = make(chan struct{})
+	}
+
+	connected := s.connectedChan
+
+	s.connectedLock.Unlock()
+
+	timer := time.NewTimer(timeout)
+
+	defer timer.Stop()
+
+	select {
+	case <-connected:
+		return true
+	case <-timer.C:
+		return s.isConnected.Load()
+	}
+}
+
+func (s *connectionState) notifyConnected() {
+	s.connectedLock.Lock()
+	defer s.connectedLock.Unlock()
+
+	if s.connectedChan != nil {
+		close(s.connectedChan)
+
+		s.connectedChan = nil
+	}
+}
+
 func (s *connectionState) Start() error {
 	if err := s.ensembleProvider.Start(); err != nil {
 		return err
@@ -210,6 +423,7 @@ func (s *connectionState) reset() error {
 	atomic.AddInt64(&s.instanceIndex, 1)
 
 	s.isConnected.Set(false)
+	s.setConnectionStart(time.Now())
 
 	s.zooKeeper.closeAndReset()
 
@@ -226,40 +440,66 @@ func (s *connectionState) RemoveParentWatcher(watcher Watcher) Watcher {
 	return s.parentWatchers.Remove(watcher)
 }
 
-func (s *connectionState) checkTimeout() error {
-	var minTimeout, maxTimeout time.Duration
+func (s *connectionState) getConnectionStart() time.Time {
+	s.connectionLock.Lock()
+	defer s.connectionLock.Unlock()
 
-	if s.sessionTimeout > s.connectionTimeout {
-		minTimeout = s.connectionTimeout
-		maxTimeout = s.sessionTimeout
-	} else {
-		minTimeout = s.sessionTimeout
-		maxTimeout = s.connectionTimeout
-	}
+	return s.connectionStart
+}
 
-	elapsed := time.Since(s.connectionStart)
+func (s *connectionState) setConnectionStart(t time.Time) {
+	s.connectionLock.Lock()
+	defer s.connectionLock.Unlock()
 
-	if elapsed >= minTimeout {
-		if s.zooKeeper.hasNewConnectionString() {
-			s.handleNewConnectionString()
-		} else if elapsed >= maxTimeout {
-			log.Printf("Connection attempt unsuccessful after %v (greater than max timeout of %v). Resetting connection and trying again with a new connection.", elapsed, maxTimeout)
+	s.connectionStart = t
+}
 
-			s.tracer.AddCount("session-timed-out", 1)
+func (s *connectionState) checkTimeout() error {
+	connectionStart := s.getConnectionStart()
+	elapsed := time.Since(connectionStart)
 
-			return s.reset()
-		} else {
-			log.Printf("Connection timed out for connection string (%s) and timeout (%v) / elapsed (%v)", s.zooKeeper.getConnectionString(), s.connectionTimeout, elapsed)
+	switch s.handlingPolicy.CheckTimeouts(s.zooKeeper.hasNewConnectionString, connectionStart, s.sessionTimeout, s.connectionTimeout) {
+	case TIMEOUTS_NEW_CONNECTION_STRING:
+		s.handleNewConnectionString()
 
-			s.tracer.AddCount("connections-timed-out", 1)
+	case TIMEOUTS_RESET_CONNECTION:
+		maxTimeout := s.sessionTimeout
 
-			return ErrConnectionLoss
+		if s.connectionTimeout > maxTimeout {
+			maxTimeout = s.connectionTimeout
 		}
+
+		log.Printf("Connection attempt unsuccessful after %v (greater than max timeout of %v). Resetting connection and trying again with a new connection.", elapsed, maxTimeout)
+
+		s.tracer.AddCount("session-timed-out", 1)
+
+		return s.reset()
+
+	case TIMEOUTS_CONNECTION_TIMEOUT:
+		log.Printf("Connection timed out for connection string (%s) and timeout (%v) / elapsed (%v)", s.zooKeeper.getConnectionString(), s.connectionTimeout, elapsed)
+
+		s.tracer.AddCount("connections-timed-out", 1)
+
+		return ErrConnectionLoss
+
+	case TIMEOUTS_SESSION_EXPIRED:
+		log.Printf("Connection attempt unsuccessful after %v (greater than session timeout of %v). Expiring the session.", elapsed, s.sessionTimeout)
+
+		s.injectSessionExpiration()
+
+		return zk.ErrSessionExpired
 	}
 
 	return nil
 }
 
+// Process a session expiration as if the server expired the session
+func (s *connectionState) injectSessionExpiration() {
+	s.tracer.AddCount("session-expiration-injected", 1)
+
+	s.process(&zk.Event{Type: zk.EventSession, State: zk.StateExpired, Err: zk.ErrSessionExpired})
+}
+
 func (s *connectionState) process(event *zk.Event) {
 	//log.Printf("connectionState.process received %v with %d watchers", event, s.parentWatchers.Len())
 
@@ -274,11 +514,25 @@ func (s *connectionState) process(event *zk.Event) {
 	}
 
 	if event.Type == zk.EventSession {
+		if driver, ok := s.tracer.(AdvancedTracerDriver); ok {
+			driver.AddEvent(&TracedEvent{Name: event.State.String(), SessionID: s.sessionID()})
+		}
+
+		if event.State == zk.StateHasSession {
+			s.sessionEstablished()
+		}
+
 		wasConnected := s.isConnected.Load()
+		instanceIndex := s.InstanceIndex()
 
-		if newIsConnected := s.checkState(event.State, event.Err, wasConnected); newIsConnected != wasConnected {
+		// a connection reset by the event has its own events, which may have been processed meanwhile
+		if newIsConnected := s.checkState(event.State, event.Err, wasConnected); newIsConnected != wasConnected && instanceIndex == s.InstanceIndex() {
 			s.isConnected.Set(newIsConnected)
-			s.connectionStart = time.Now()
+			s.setConnectionStart(time.Now())
+
+			if newIsConnected {
+				s.notifyConnected()
+			}
 		}
 	}
 }
@@ -287,8 +541,10 @@ func (s *connectionState) checkState(state zk.State, err error, wasConnected boo
 	isConnected := wasConnected
 	checkNewConnectionString := true
 
+	s.isReadOnly.Set(state == zk.StateConnectedReadOnly)
+
 	switch state {
-	case zk.StateHasSession:
+	case zk.StateHasSession, zk.StateConnectedReadOnly:
 		isConnected = true
 
 	case zk.StateExpired:
@@ -313,6 +569,13 @@ func (s *connectionState) checkState(state zk.State, err error, wasConnected boo
 	return isConnected
 }
 
+// Reconnect with the new connection string now, e.g. when the ensemble provider notices the servers changed
+func (s *connectionState) checkNewConnectionString() {
+	if s.zooKeeper.hasNewConnectionString() {
+		s.handleNewConnectionString()
+	}
+}
+
 func (s *connectionState) handleNewConnectionString() {
 	log.Print("Connection string changed")
 
@@ -386,24 +649,58 @@ func (s ConnectionState) String() string {
 	return connectionStateNames[s]
 }
 
+// Decides which connection states are errors for the recipes, e.g. a lock stops waiting when the connection is in an error state
+type ConnectionStateErrorPolicy interface {
+	// Return true if the given state is an error state
+	IsErrorState(state ConnectionState) bool
+}
+
+// The SUSPENDED and LOST states are error states
+type StandardConnectionStateErrorPolicy struct{}
+
+func (p StandardConnectionStateErrorPolicy) IsErrorState(state ConnectionState) bool {
+	return state == SUSPENDED || state == LOST
+}
+
+// Only the LOST state is an error state, since the session may survive a SUSPENDED connection
+type SessionConnectionStateErrorPolicy struct{}
+
+func (p SessionConnectionStateErrorPolicy) IsErrorState(state ConnectionState) bool {
+	return state == LOST
+}
+
 const STATE_QUEUE_SIZE = 25
 
+// The percent of the session timeout after which the session of a SUSPENDED connection is expired by the client
+const SIMULATED_SESSION_EXPIRATION_PERCENT = 100
+
 type connectionStateManager struct {
-	client                    CuratorFramework
-	listeners                 ConnectionStateListenable
-	state                     State
-	currentConnectionState    ConnectionState
-	lock                      sync.Mutex
-	initialConnectMessageSent AtomicBool
-	events                    chan ConnectionState
-	QueueSize                 int
+	client                            CuratorFramework
+	listeners                         ConnectionStateListenable
+	state                             State
+	currentConnectionState            ConnectionState
+	lock                              sync.Mutex
+	initialConnectMessageSent         AtomicBool
+	events                            chan ConnectionState
+	QueueSize                         int
+	SessionTimeout                    time.Duration
+	SimulatedSessionExpirationPercent int       // disabled if not positive
+	injectSessionExpiration           func()    // expire the session, set by the framework
+	suspendedSince                    time.Time // the start of the SUSPENDED state
+	tracer                            TracerDriver
+	stateChanged                      time.Time     // the time of the last state transition
+	reconnects                        int           // the RECONNECTED states posted
+	suspendedTime                     time.Duration // the time spent SUSPENDED, before the current state
+	lostTime                          time.Duration // the time spent LOST, before the current state
 }
 
 func newConnectionStateManager(client CuratorFramework) *connectionStateManager {
 	return &connectionStateManager{
-		client:    client,
-		listeners: new(connectionStateListenerContainer),
-		QueueSize: STATE_QUEUE_SIZE,
+		client:                            client,
+		listeners:                         new(connectionStateListenerContainer),
+		QueueSize:                         STATE_QUEUE_SIZE,
+		SimulatedSessionExpirationPercent: SIMULATED_SESSION_EXPIRATION_PERCENT,
+		tracer:                            newDefaultTracerDriver(),
 	}
 }
 
@@ -433,6 +730,14 @@ func (m *connectionStateManager) Listenable() ConnectionStateListenable {
 	return m.listeners
 }
 
+// Return the last state posted, UNKNOWN before the first connection
+func (m *connectionStateManager) currentState() ConnectionState {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.currentConnectionState
+}
+
 // Change to ConnectionState.SUSPENDED only if not already suspended and not lost
 func (m *connectionStateManager) SetToSuspended() bool {
 	m.lock.Lock()
@@ -446,7 +751,8 @@ func (m *connectionStateManager) SetToSuspended() bool {
 		return false
 	}
 
-	m.currentConnectionState = SUSPENDED
+	m.transition(SUSPENDED)
+	m.suspendedSince = time.Now()
 
 	m.postState(SUSPENDED)
 
@@ -467,7 +773,11 @@ func (m *connectionStateManager) AddStateChange(newConnectionState ConnectionSta
 		return false
 	}
 
-	m.currentConnectionState = newConnectionState
+	m.transition(newConnectionState)
+
+	if newConnectionState == SUSPENDED {
+		m.suspendedSince = time.Now()
+	}
 
 	localState := newConnectionState
 
@@ -486,17 +796,32 @@ func (m *connectionStateManager) AddStateChange(newConnectionState ConnectionSta
 }
 
 func (m *connectionStateManager) BlockUntilConnected(maxWaitTime time.Duration) error {
-	if m.currentConnectionState.Connected() {
-		return nil
+	ctx := context.Background()
+
+	if maxWaitTime > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, maxWaitTime)
+
+		defer cancel()
 	}
 
-	c := make(chan ConnectionState)
+	if err := m.BlockUntilConnectedContext(ctx); err == context.DeadlineExceeded {
+		return ErrTimeout
+	} else {
+		return err
+	}
+}
 
-	defer close(c)
+func (m *connectionStateManager) BlockUntilConnectedContext(ctx context.Context) error {
+	c := make(chan ConnectionState, 1)
 
 	listener := NewConnectionStateListener(func(client CuratorFramework, newState ConnectionState) {
 		if newState.Connected() {
-			c <- newState
+			select {
+			case c <- newState:
+			default:
+			}
 		}
 	})
 
@@ -504,19 +829,16 @@ func (m *connectionStateManager) BlockUntilConnected(maxWaitTime time.Duration)
 
 	defer m.listeners.RemoveListener(listener)
 
-	if maxWaitTime > 0 {
-		timer := time.NewTimer(maxWaitTime)
-
-		select {
-		case <-c:
-			return nil
-		case <-timer.C:
-			return ErrTimeout
-		}
-	} else {
-		<-c
+	// checked after the listener is added, so a connection in between isn't missed
+	if m.Connected() {
+		return nil
+	}
 
+	select {
+	case <-c:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -550,14 +872,74 @@ func (m *connectionStateManager) postState(state ConnectionState) {
 	}
 }
 
+func (m *connectionStateManager) unhandledError(err error) {
+	log.Printf("error: %s", err)
+
+	m.client.UnhandledErrorListenable().ForEach(func(listener interface{}) {
+		listener.(UnhandledErrorListener).UnhandledError(err)
+	})
+}
+
 func (m *connectionStateManager) processEvents() {
 	for {
-		if newState, ok := <-m.events; !ok {
-			return // queue closed
-		} else {
+		var timer *time.Timer
+		var expired <-chan time.Time
+
+		if delay, suspended := m.sessionExpirationDelay(); suspended {
+			timer = time.NewTimer(delay)
+			expired = timer.C
+		}
+
+		select {
+		case newState, ok := <-m.events:
+			if !ok {
+				return // queue closed
+			}
+
 			m.listeners.ForEach(func(listener interface{}) {
+				defer recoverPanic("Connection state listener", m.unhandledError)
+
 				listener.(ConnectionStateListener).StateChanged(m.client, newState)
 			})
+
+		case <-expired:
+			m.checkSessionExpiration()
+		}
+
+		if timer != nil {
+			timer.Stop()
 		}
 	}
 }
+
+// Return the time left before the session of the SUSPENDED connection is expired by the client
+func (m *connectionStateManager) sessionExpirationDelay() (time.Duration, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.currentConnectionState != SUSPENDED || m.SimulatedSessionExpirationPercent <= 0 || m.injectSessionExpiration == nil {
+		return 0, false
+	}
+
+	timeout := m.SessionTimeout * time.Duration(m.SimulatedSessionExpirationPercent) / 100
+
+	return time.Until(m.suspendedSince.Add(timeout)), true
+}
+
+// Expire the session once the connection has been SUSPENDED for the percent of the session timeout,
+// so the recipes observe the LOST state even if the server can't be reached to expire the session.
+func (m *connectionStateManager) checkSessionExpiration() {
+	if delay, suspended := m.sessionExpirationDelay(); !suspended || delay > 0 {
+		return
+	}
+
+	m.lock.Lock()
+	elapsed := time.Since(m.suspendedSince)
+	m.suspendedSince = time.Now()
+	m.lock.Unlock()
+
+	log.Printf("Session timeout has elapsed while SUSPENDED. Injecting a session expiration. Elapsed: %v, adjusted session timeout: %v",
+		elapsed, m.SessionTimeout*time.Duration(m.SimulatedSessionExpirationPercent)/100)
+
+	m.injectSessionExpiration()
+}
@@ -1,6 +1,7 @@
 package curator
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -207,6 +208,84 @@ func (s *ConnectionStateTestSuite) TestSessionTimeout() {
 	assert.Equal(s.T(), instanceIndex+1, s.state.InstanceIndex())
 }
 
+func (s *ConnectionStateTestSuite) TestSessionExpirationInjected() {
+	s.connStrTimes = 3
+	s.dialTimes = 2
+	s.connCloseTimes = 2
+
+	s.state.handlingPolicy = StandardConnectionHandlingPolicy{}
+
+	s.Start()
+	defer s.Close()
+
+	instanceIndex := s.state.InstanceIndex()
+
+	// force to session timeout
+	s.state.connectionStart = time.Now().Add(-s.sessionTimeout * 2)
+
+	s.tracer.On("AddCount", "session-expiration-injected", 1).Return().Once()
+	s.tracer.On("AddCount", "session-expired", 1).Return().Once()
+
+	processed := make(chan struct{})
+
+	s.tracer.On("AddTime", "connection-state-parent-process", mock.AnythingOfType("Duration")).Return().Once().Run(func(mock.Arguments) {
+		close(processed)
+	})
+
+	conn, err := s.state.Conn()
+
+	assert.Nil(s.T(), conn)
+	assert.Equal(s.T(), zk.ErrSessionExpired, err)
+	assert.Equal(s.T(), instanceIndex+1, s.state.InstanceIndex())
+
+	<-processed
+
+	// the late events of the previous tests may be recorded too
+	expired := 0
+
+	for _, event := range s.sessionEvents {
+		if event.State == zk.StateExpired {
+			expired++
+		}
+	}
+
+	assert.Equal(s.T(), 1, expired)
+
+	// the new connection has its own timeouts
+	conn, err = s.state.Conn()
+
+	assert.NotNil(s.T(), conn)
+	assert.NoError(s.T(), err)
+}
+
+func TestConnectionHandlingPolicy(t *testing.T) {
+	sessionTimeout, connectionTimeout := 15*time.Second, 5*time.Second
+
+	checkTimeouts := func(policy ConnectionHandlingPolicy, elapsed time.Duration, hasNewConnectionString bool) CheckTimeoutsResult {
+		return policy.CheckTimeouts(func() bool { return hasNewConnectionString }, time.Now().Add(-elapsed), sessionTimeout, connectionTimeout)
+	}
+
+	classic, standard := ClassicConnectionHandlingPolicy{}, StandardConnectionHandlingPolicy{}
+
+	assert.Equal(t, TIMEOUTS_NOP, checkTimeouts(classic, time.Second, true))
+	assert.Equal(t, TIMEOUTS_NEW_CONNECTION_STRING, checkTimeouts(classic, 10*time.Second, true))
+	assert.Equal(t, TIMEOUTS_CONNECTION_TIMEOUT, checkTimeouts(classic, 10*time.Second, false))
+	assert.Equal(t, TIMEOUTS_RESET_CONNECTION, checkTimeouts(classic, 20*time.Second, false))
+
+	assert.Equal(t, TIMEOUTS_NOP, checkTimeouts(standard, time.Second, true))
+	assert.Equal(t, TIMEOUTS_NEW_CONNECTION_STRING, checkTimeouts(standard, 10*time.Second, true))
+	assert.Equal(t, TIMEOUTS_CONNECTION_TIMEOUT, checkTimeouts(standard, 10*time.Second, false))
+	assert.Equal(t, TIMEOUTS_SESSION_EXPIRED, checkTimeouts(standard, 20*time.Second, false))
+
+	client := (&CuratorFrameworkBuilder{}).ConnectString("connStr").Build()
+
+	assert.Equal(t, classic, client.ZookeeperClient().ConnectionHandlingPolicy())
+
+	client = (&CuratorFrameworkBuilder{ConnectionHandlingPolicy: standard}).ConnectString("connStr").Build()
+
+	assert.Equal(t, standard, client.ZookeeperClient().ConnectionHandlingPolicy())
+}
+
 func (s *ConnectionStateTestSuite) TestBackgroundException() {
 	s.tracer.On("AddCount", "background-exceptions", 1).Return().Times(2)
 
@@ -259,6 +338,85 @@ func (s *ConnectionStateTestSuite) TestConnected() {
 	assert.True(s.T(), s.state.Connected())
 }
 
+func (s *ConnectionStateTestSuite) TestConnectionAcquisition() {
+	s.connStrTimes = 2
+
+	s.Start()
+	defer s.Close()
+
+	// fail at once while not connected
+	s.state.acquisition = ACQUIRE_FAIL_FAST
+
+	conn, err := s.state.Conn()
+
+	assert.Nil(s.T(), conn)
+	assert.Equal(s.T(), ErrConnectionLoss, err)
+
+	// fail after waiting for the connection
+	s.state.acquisition = ACQUIRE_WAIT
+	s.state.waitTimeout = 10 * time.Millisecond
+
+	start := time.Now()
+	conn, err = s.state.Conn()
+
+	assert.Nil(s.T(), conn)
+	assert.Equal(s.T(), ErrConnectionLoss, err)
+	assert.True(s.T(), time.Since(start) >= s.state.waitTimeout)
+
+	// get the connection once it is connected
+	s.state.waitTimeout = 5 * time.Second
+
+	s.tracer.On("AddTime", "connection-state-parent-process", mock.AnythingOfType("Duration")).Return().Once()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+
+		s.events <- zk.Event{
+			Type:  zk.EventSession,
+			State: zk.StateHasSession,
+		}
+	}()
+
+	conn, err = s.state.Conn()
+
+	assert.NotNil(s.T(), conn)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), s.state.Connected())
+
+	time.Sleep(10 * time.Millisecond) // the parent watcher
+}
+
+func (s *ConnectionStateTestSuite) TestReadOnly() {
+	s.connStrTimes = 3
+
+	s.Start()
+	defer s.Close()
+
+	s.tracer.On("AddTime", "connection-state-parent-process", mock.AnythingOfType("Duration")).Return().Twice()
+
+	// connected to a read-only server
+	s.events <- zk.Event{
+		Type:  zk.EventSession,
+		State: zk.StateConnectedReadOnly,
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.True(s.T(), s.state.Connected())
+	assert.True(s.T(), s.state.ReadOnly())
+
+	// reconnected to a read-write server
+	s.events <- zk.Event{
+		Type:  zk.EventSession,
+		State: zk.StateHasSession,
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.True(s.T(), s.state.Connected())
+	assert.False(s.T(), s.state.ReadOnly())
+}
+
 func (s *ConnectionStateTestSuite) TestNewConnectionString() {
 	s.connStrTimes = 3
 	s.dialTimes = 2
@@ -466,6 +624,53 @@ func (s *ConnectionStateManagerTestSuite) TestStateChange() {
 	assert.Equal(s.T(), []ConnectionState{CONNECTED, CONNECTED, SUSPENDED}, s.receivedStates)
 }
 
+func (s *ConnectionStateManagerTestSuite) TestSimulatedSessionExpiration() {
+	injected := make(chan struct{}, 1)
+
+	s.state.SessionTimeout = 100 * time.Millisecond
+	s.state.SimulatedSessionExpirationPercent = 50
+	s.state.injectSessionExpiration = func() { injected <- struct{}{} }
+
+	assert.NoError(s.T(), s.state.Start())
+
+	defer s.state.Close()
+
+	assert.True(s.T(), s.state.AddStateChange(CONNECTED))
+	assert.True(s.T(), s.state.SetToSuspended())
+
+	select {
+	case <-injected:
+	case <-time.After(time.Second):
+		assert.Fail(s.T(), "session expiration not injected")
+	}
+
+	// no expiration is injected once the connection is back
+	assert.True(s.T(), s.state.AddStateChange(RECONNECTED))
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Empty(s.T(), injected)
+}
+
+func (s *ConnectionStateManagerTestSuite) TestSimulatedSessionExpirationDisabled() {
+	injected := make(chan struct{}, 1)
+
+	s.state.SessionTimeout = 10 * time.Millisecond
+	s.state.SimulatedSessionExpirationPercent = -1
+	s.state.injectSessionExpiration = func() { injected <- struct{}{} }
+
+	assert.NoError(s.T(), s.state.Start())
+
+	defer s.state.Close()
+
+	assert.True(s.T(), s.state.AddStateChange(CONNECTED))
+	assert.True(s.T(), s.state.SetToSuspended())
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Empty(s.T(), injected)
+}
+
 func (s *ConnectionStateManagerTestSuite) TestBlockUntilConnected() {
 	var wc sync.WaitGroup
 
@@ -533,3 +738,118 @@ func (s *ConnectionStateManagerTestSuite) TestBlockUntilConnectedTimeouted() {
 
 	assert.Equal(s.T(), UNKNOWN, s.state.currentConnectionState)
 }
+
+func (s *ConnectionStateManagerTestSuite) TestBlockUntilConnectedContext() {
+	var wc sync.WaitGroup
+
+	assert.NoError(s.T(), s.state.Start())
+
+	defer s.state.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	wc.Add(1)
+
+	go func() {
+		defer wc.Done()
+
+		assert.NoError(s.T(), s.state.BlockUntilConnectedContext(ctx))
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	s.state.AddStateChange(CONNECTED)
+
+	wc.Wait()
+
+	// return at once when connected
+	assert.NoError(s.T(), s.state.BlockUntilConnectedContext(ctx))
+}
+
+func (s *ConnectionStateManagerTestSuite) TestBlockUntilConnectedContextCanceled() {
+	assert.NoError(s.T(), s.state.Start())
+
+	defer s.state.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	assert.Equal(s.T(), context.Canceled, s.state.BlockUntilConnectedContext(ctx))
+	assert.Equal(s.T(), UNKNOWN, s.state.currentConnectionState)
+}
+
+func TestStateQueueSize(t *testing.T) {
+	client := (&CuratorFrameworkBuilder{}).ConnectString("connStr").Build().(*curatorFramework)
+
+	assert.Equal(t, STATE_QUEUE_SIZE, client.stateManager.QueueSize)
+
+	client = (&CuratorFrameworkBuilder{StateQueueSize: 100}).ConnectString("connStr").Build().(*curatorFramework)
+
+	assert.Equal(t, 100, client.stateManager.QueueSize)
+}
+
+func TestConnectionStateErrorPolicy(t *testing.T) {
+	client := (&CuratorFrameworkBuilder{}).ConnectString("connStr").Build()
+
+	assert.Equal(t, StandardConnectionStateErrorPolicy{}, client.ConnectionStateErrorPolicy())
+
+	standard, session := StandardConnectionStateErrorPolicy{}, SessionConnectionStateErrorPolicy{}
+
+	for _, state := range []ConnectionState{CONNECTED, RECONNECTED, READ_ONLY} {
+		assert.False(t, standard.IsErrorState(state))
+		assert.False(t, session.IsErrorState(state))
+	}
+
+	assert.True(t, standard.IsErrorState(SUSPENDED))
+	assert.True(t, standard.IsErrorState(LOST))
+	assert.False(t, session.IsErrorState(SUSPENDED))
+	assert.True(t, session.IsErrorState(LOST))
+
+	client = (&CuratorFrameworkBuilder{StateErrorPolicy: session}).ConnectString("connStr").Build()
+
+	assert.Equal(t, session, client.ConnectionStateErrorPolicy())
+}
+
+func TestReadOnlyTransitions(t *testing.T) {
+	conn := &mockConn{log: t.Logf}
+	events := make(chan zk.Event)
+
+	client := (&CuratorFrameworkBuilder{
+		ZookeeperDialer: NewZookeeperDialer(func(connString string, sessionTimeout time.Duration, canBeReadOnly bool) (ZookeeperConnection, <-chan zk.Event, error) {
+			assert.True(t, canBeReadOnly)
+
+			return conn, events, nil
+		}),
+		CanBeReadOnly: true,
+	}).ConnectString("connStr").Build()
+
+	states := make(chan ConnectionState, 10)
+
+	client.ConnectionStateListenable().AddListener(NewConnectionStateListener(func(client CuratorFramework, newState ConnectionState) {
+		states <- newState
+	}))
+
+	assert.NoError(t, client.Start())
+
+	// the session of the zk package leaves the read-only mode, the events are processed concurrently
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	assert.Equal(t, CONNECTED, <-states)
+
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateConnectedReadOnly}
+
+	assert.Equal(t, READ_ONLY, <-states)
+
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	assert.Equal(t, RECONNECTED, <-states)
+
+	conn.On("Close").Return().Once()
+
+	assert.NoError(t, client.Close())
+
+	conn.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package curator
+
+import (
+	"time"
+)
+
+// The stats of the connection and the session of a client
+type ConnectionStats struct {
+	State          ConnectionState // the current state of the connection
+	LastTransition time.Time       // the time of the last state transition, zero before the first one
+	SessionID      int64           // the current session, 0 if it isn't known
+	SessionAge     time.Duration   // the time since the current session was established
+	Reconnects     int             // the times the connection was RECONNECTED
+	SuspendedTime  time.Duration   // the time spent SUSPENDED, including the current state
+	LostTime       time.Duration   // the time spent LOST, including the current state
+}
+
+func (c *curatorFramework) Stats() *ConnectionStats {
+	stats := c.stateManager.stats()
+
+	stats.SessionID, stats.SessionAge = c.client.state.sessionStats()
+
+	return stats
+}
+
+// Record the session established, which starts its age unless it is the current session reconnected
+func (s *connectionState) sessionEstablished() {
+	sessionID := s.sessionID()
+
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	if sessionID != s.sessionStartedID || s.sessionStarted.IsZero() {
+		s.sessionStarted = time.Now()
+		s.sessionStartedID = sessionID
+	}
+}
+
+func (s *connectionState) sessionStats() (sessionID int64, age time.Duration) {
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	if s.sessionStarted.IsZero() {
+		return 0, 0
+	}
+
+	return s.sessionStartedID, time.Since(s.sessionStarted)
+}
+
+// Change the current state, the time spent in the previous state is recorded, the lock must be held
+func (m *connectionStateManager) transition(newState ConnectionState) {
+	now := time.Now()
+
+	switch spent := now.Sub(m.stateChanged); m.currentConnectionState {
+	case SUSPENDED:
+		m.suspendedTime += spent
+
+		m.tracer.AddTime("connection-suspended", spent)
+
+	case LOST:
+		m.lostTime += spent
+
+		m.tracer.AddTime("connection-lost", spent)
+	}
+
+	if newState == RECONNECTED {
+		m.reconnects++
+
+		m.tracer.AddCount("connection-reconnected", 1)
+	}
+
+	m.currentConnectionState = newState
+	m.stateChanged = now
+}
+
+func (m *connectionStateManager) stats() *ConnectionStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats := &ConnectionStats{
+		State:          m.currentConnectionState,
+		LastTransition: m.stateChanged,
+		Reconnects:     m.reconnects,
+		SuspendedTime:  m.suspendedTime,
+		LostTime:       m.lostTime,
+	}
+
+	switch spent := time.Since(m.stateChanged); m.currentConnectionState {
+	case SUSPENDED:
+		stats.SuspendedTime += spent
+
+	case LOST:
+		stats.LostTime += spent
+	}
+
+	return stats
+}
@@ -0,0 +1,67 @@
+package curator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConnectionStats(t *testing.T) {
+	tracer := &mockTracerDriver{}
+
+	tracer.On("AddTime", "connection-suspended", mock.AnythingOfType("time.Duration")).Return().Once()
+	tracer.On("AddCount", "connection-reconnected", 1).Return().Once()
+
+	m := newConnectionStateManager(nil)
+	m.tracer = tracer
+
+	assert.NoError(t, m.Start())
+
+	defer m.Close()
+
+	assert.Equal(t, &ConnectionStats{State: UNKNOWN}, m.stats())
+
+	started := time.Now()
+
+	assert.True(t, m.AddStateChange(CONNECTED))
+	assert.True(t, m.SetToSuspended())
+
+	time.Sleep(10 * time.Millisecond)
+
+	// the current state is included
+	stats := m.stats()
+
+	assert.Equal(t, SUSPENDED, stats.State)
+	assert.True(t, stats.SuspendedTime >= 10*time.Millisecond)
+	assert.True(t, stats.LastTransition.After(started))
+
+	assert.True(t, m.AddStateChange(RECONNECTED))
+
+	stats = m.stats()
+
+	assert.Equal(t, RECONNECTED, stats.State)
+	assert.Equal(t, 1, stats.Reconnects)
+	assert.True(t, stats.SuspendedTime >= 10*time.Millisecond)
+	assert.Zero(t, stats.LostTime)
+
+	tracer.AssertExpectations(t)
+}
+
+func TestSessionStats(t *testing.T) {
+	s := newConnectionState(nil, NewFixedEnsembleProvider("localhost:2181"), time.Second, time.Second, nil, newDefaultTracerDriver(), false)
+
+	sessionID, age := s.sessionStats()
+
+	assert.Zero(t, sessionID)
+	assert.Zero(t, age)
+
+	s.sessionEstablished()
+
+	time.Sleep(time.Millisecond)
+
+	_, age = s.sessionStats()
+
+	assert.True(t, age >= time.Millisecond)
+}
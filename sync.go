@@ -9,7 +9,7 @@ func (b *syncBuilder) ForPath(givenPath string) (string, error) {
 	adjustedPath := b.client.fixForNamespace(givenPath, false)
 
 	if b.backgrounding.inBackground {
-		go b.pathInBackground(adjustedPath, givenPath)
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
 
 		return givenPath, nil
 	} else {
@@ -24,28 +24,27 @@ func (b *syncBuilder) pathInBackground(path string, givenPath string) {
 
 	syncPath, err := b.pathInForeground(path)
 
-	if b.backgrounding.callback != nil {
-		event := &curatorEvent{
-			eventType: SYNC,
-			err:       err,
-			path:      b.client.unfixForNamespace(syncPath),
-			context:   b.backgrounding.context,
-		}
+	event := &curatorEvent{
+		eventType: SYNC,
+		err:       err,
+		path:      b.client.unfixForNamespace(syncPath),
+		context:   b.backgrounding.context,
+	}
 
-		if err != nil {
-			event.path = givenPath
-		}
+	if err != nil {
+		event.path = givenPath
+	}
 
-		event.name = GetNodeFromPath(event.path)
+	event.name = GetNodeFromPath(event.path)
 
-		b.backgrounding.callback(b.client, event)
-	}
+	b.client.processBackgroundEvent(b.backgrounding, event)
 }
 
 func (b *syncBuilder) pathInForeground(path string) (string, error) {
 	zkClient := b.client.ZookeeperClient()
+	trace := b.client.client.startAdvancedTracer(SYNC.String(), path)
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := newRetryLoopForPath(zkClient, SYNC.String(), path).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
 		} else {
@@ -55,6 +54,8 @@ func (b *syncBuilder) pathInForeground(path string) (string, error) {
 
 	syncPath, _ := result.(string)
 
+	trace.Commit(err)
+
 	return b.client.unfixForNamespace(syncPath), err
 }
 
@@ -64,3 +64,51 @@ func (s *SyncBuilderTestSuite) TestBackground() {
 		assert.NoError(s.T(), err)
 	})
 }
+
+func (s *SyncBuilderTestSuite) TestListenAllEvents() {
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.ListenAllEvents = true
+	}, func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		called := false
+
+		conn.On("Sync", "/node").Return("/node", nil).Once()
+
+		client.CuratorListenable().AddListener(NewCuratorListener(func(client CuratorFramework, event CuratorEvent) error {
+			if event.Type() == SYNC {
+				defer wg.Done()
+
+				assert.True(s.T(), called)
+				assert.Equal(s.T(), "/node", event.Path())
+			}
+
+			return nil
+		}))
+
+		// the listeners receive the event after the callback
+		_, err := client.Sync().InBackgroundWithCallback(func(client CuratorFramework, event CuratorEvent) error {
+			called = true
+
+			return nil
+		}).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *SyncBuilderTestSuite) TestBackgroundPanic() {
+	s.With(func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		conn.On("Sync", "/node").Return("/node", nil).Once()
+
+		client.UnhandledErrorListenable().AddListener(NewUnhandledErrorListener(func(err error) {
+			defer wg.Done()
+
+			assert.EqualError(s.T(), err, "Background operation callback panicked, boom")
+		}))
+
+		_, err := client.Sync().InBackgroundWithCallback(func(client CuratorFramework, event CuratorEvent) error {
+			panic("boom")
+		}).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+	})
+}
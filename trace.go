@@ -1,8 +1,13 @@
 package curator
 
 import (
+	"context"
+	"log"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
 )
 
 // Mechanism for timing methods and recording counters
@@ -18,6 +23,52 @@ type Tracer interface {
 	Commit()
 }
 
+// The structured trace of an operation of the framework
+type TracedOperation struct {
+	Name          string        // the operation, e.g. CREATE
+	Path          string        // the path of the operation, including the namespace
+	PathPrefix    string        // the longest traced path prefix of the path, if any
+	Latency       time.Duration // the time of the operation, including its retries
+	RequestBytes  int           // the size of the data sent, e.g. the data of the node created
+	ResponseBytes int           // the size of the data received, e.g. the data of the node read
+	SessionID     int64         // the session of the connection, 0 if it isn't known
+	WithWatcher   bool          // a watch was set by the operation
+	Stat          *zk.Stat      // the stat of the node returned by the operation, if any
+	Err           error         // the error of the operation
+}
+
+// The structured trace of an event of the session, e.g. the change of its state
+type TracedEvent struct {
+	Name      string // the event, e.g. StateHasSession
+	SessionID int64  // the session of the connection, 0 if it isn't known
+}
+
+// A TracerDriver receiving the structured traces of the operations and the events, besides the times and the counters
+type AdvancedTracerDriver interface {
+	TracerDriver
+
+	// Record the trace of an operation
+	AddTrace(trace *TracedOperation)
+
+	// Record the trace of an event
+	AddEvent(trace *TracedEvent)
+}
+
+// Trace the operations of the framework, e.g. with the OpenTelemetry spans of the tracing package
+type OperationTracer interface {
+	// Start the trace of an operation on the path, e.g. CREATE, the context is the one the client is bound to, if any
+	StartOperation(ctx context.Context, operation, path string) OperationTrace
+}
+
+// The trace of an operation, which is finished once the operation succeeds or isn't retried any more
+type OperationTrace interface {
+	// Record a failed attempt of the operation which is retried, the retries are numbered from 1
+	RetryAttempted(retry int, err error)
+
+	// Finish the trace with the result of the last attempt
+	Finish(attempts int, err error)
+}
+
 type defaultTracerDriver struct {
 	TracerDriver
 
@@ -77,3 +128,143 @@ func (t *timeTracer) Commit() {
 func (t *timeTracer) CommitAt(tm time.Time) {
 	t.driver.AddTime(t.name, tm.Sub(t.startTime))
 }
+
+// Utility to trace an operation through the same TracerDriver as the framework, e.g. a custom operation of a recipe,
+// the trace is passed as a whole to an AdvancedTracerDriver, or recorded as the time of the operation otherwise.
+type AdvancedTracer struct {
+	trace     TracedOperation
+	driver    TracerDriver
+	state     *connectionState // the connection of the client, which returns the session if it isn't set
+	prefixes  []string         // the traced path prefixes of the client
+	startTime time.Time
+}
+
+// Create and start the trace of an operation
+func NewAdvancedTracer(name string, driver TracerDriver) *AdvancedTracer {
+	return &AdvancedTracer{
+		trace:     TracedOperation{Name: name},
+		driver:    driver,
+		startTime: time.Now(),
+	}
+}
+
+// Start the trace of an operation on the path
+func (c *curatorZookeeperClient) startAdvancedTracer(name, path string) *AdvancedTracer {
+	return c.StartAdvancedTracer(name).WithPath(path)
+}
+
+func (t *AdvancedTracer) WithPath(path string) *AdvancedTracer {
+	t.trace.Path = path
+
+	return t
+}
+
+func (t *AdvancedTracer) WithRequestBytes(data []byte) *AdvancedTracer {
+	t.trace.RequestBytes = len(data)
+
+	return t
+}
+
+func (t *AdvancedTracer) WithResponseBytes(data []byte) *AdvancedTracer {
+	t.trace.ResponseBytes = len(data)
+
+	return t
+}
+
+func (t *AdvancedTracer) WithWatcher(withWatcher bool) *AdvancedTracer {
+	t.trace.WithWatcher = withWatcher
+
+	return t
+}
+
+func (t *AdvancedTracer) WithStat(stat *zk.Stat) *AdvancedTracer {
+	t.trace.Stat = stat
+
+	return t
+}
+
+func (t *AdvancedTracer) WithSessionID(sessionID int64) *AdvancedTracer {
+	t.trace.SessionID = sessionID
+
+	return t
+}
+
+// Record the trace with the error of the operation
+func (t *AdvancedTracer) Commit(err error) {
+	t.trace.Latency = time.Since(t.startTime)
+	t.trace.Err = err
+
+	if t.trace.SessionID == 0 && t.state != nil {
+		t.trace.SessionID = t.state.sessionID()
+	}
+
+	if t.trace.PathPrefix == "" {
+		t.trace.PathPrefix = matchPathPrefix(t.trace.Path, t.prefixes)
+	}
+
+	addTrace(t.driver, &t.trace)
+}
+
+// Pass the trace to an AdvancedTracerDriver, or record the time of the operation, and the one per path prefix if any
+func addTrace(driver TracerDriver, trace *TracedOperation) {
+	if advanced, ok := driver.(AdvancedTracerDriver); ok {
+		advanced.AddTrace(trace)
+	} else {
+		driver.AddTime(trace.Name, trace.Latency)
+
+		if trace.PathPrefix != "" {
+			driver.AddTime(trace.Name+" "+trace.PathPrefix, trace.Latency)
+		}
+	}
+}
+
+// Return the longest prefix of the path, which matches the whole nodes, e.g. /locks matches /locks/lock-1 but not /locksmith
+func matchPathPrefix(path string, prefixes []string) string {
+	var matched string
+
+	for _, prefix := range prefixes {
+		if len(prefix) > len(matched) && (path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, PATH_SEPARATOR)+PATH_SEPARATOR)) {
+			matched = prefix
+		}
+	}
+
+	return matched
+}
+
+// Record an event through the TracerDriver, which is passed to an AdvancedTracerDriver, or counted otherwise
+func TraceEvent(driver TracerDriver, name string, sessionID int64) {
+	if advanced, ok := driver.(AdvancedTracerDriver); ok {
+		advanced.AddEvent(&TracedEvent{Name: name, SessionID: sessionID})
+	} else {
+		driver.AddCount(name, 1)
+	}
+}
+
+// Receive the operations exceeding the slow operation threshold, e.g. to catch the latency regressions of the ensemble
+type SlowOperationListener interface {
+	// Called once a slow operation finishes, the latency includes its retries
+	SlowOperation(operation, path string, latency time.Duration)
+}
+
+type slowOperationListenerCallback struct {
+	callback func(operation, path string, latency time.Duration)
+}
+
+func NewSlowOperationListener(callback func(operation, path string, latency time.Duration)) SlowOperationListener {
+	return &slowOperationListenerCallback{callback}
+}
+
+func (l *slowOperationListenerCallback) SlowOperation(operation, path string, latency time.Duration) {
+	l.callback(operation, path, latency)
+}
+
+// Count the slow operation, and pass it to the listener, or log it without a listener
+func (c *curatorZookeeperClient) slowOperation(operation, path string, latency time.Duration) {
+	c.TracerDriver.AddCount("slow-operations", 1)
+
+	if c.slowOperationListener != nil {
+		c.slowOperationListener.SlowOperation(operation, path, latency)
+	} else {
+		log.Printf("slow operation %s on `%s` took %s", operation, path, latency)
+	}
+}
@@ -1,11 +1,16 @@
 package curator
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
 )
 
 func TestDefaultTracerDriver(t *testing.T) {
@@ -39,3 +44,208 @@ func TestTimeTrace(t *testing.T) {
 
 	d.AssertExpectations(t)
 }
+
+func TestAdvancedTracer(t *testing.T) {
+	d := &mockTracerDriver{}
+
+	d.On("AddTime", "custom", mock.AnythingOfType("time.Duration")).Return().Once()
+	d.On("AddCount", "event", 1).Return().Once()
+
+	// recorded as the time and the counter of a plain driver
+	NewAdvancedTracer("custom", d).WithPath("/node").Commit(nil)
+
+	TraceEvent(d, "event", 123)
+
+	d.AssertExpectations(t)
+
+	// passed as a whole to an advanced driver
+	driver := &recordingAdvancedTracerDriver{defaultTracerDriver: newDefaultTracerDriver()}
+
+	NewAdvancedTracer("custom", driver).WithPath("/node").WithRequestBytes([]byte("data")).WithSessionID(123).Commit(zk.ErrNoNode)
+
+	TraceEvent(driver, "event", 123)
+
+	assert.Equal(t, []TracedOperation{{Name: "custom", Path: "/node", Latency: driver.traces[0].Latency, RequestBytes: 4, SessionID: 123, Err: zk.ErrNoNode}}, driver.traces)
+	assert.Equal(t, []TracedEvent{{Name: "event", SessionID: 123}}, driver.events)
+}
+
+type recordingOperationTracer struct {
+	traces []string
+}
+
+func (t *recordingOperationTracer) StartOperation(ctx context.Context, operation, path string) OperationTrace {
+	t.traces = append(t.traces, fmt.Sprintf("start %s %s", operation, path))
+
+	return t
+}
+
+func (t *recordingOperationTracer) RetryAttempted(retry int, err error) {
+	t.traces = append(t.traces, fmt.Sprintf("retry %d %v", retry, err))
+}
+
+func (t *recordingOperationTracer) Finish(attempts int, err error) {
+	t.traces = append(t.traces, fmt.Sprintf("finish %d %v", attempts, err))
+}
+
+type OperationTracerTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestOperationTracer(t *testing.T) {
+	suite.Run(t, new(OperationTracerTestSuite))
+}
+
+func (s *OperationTracerTestSuite) TestRetries() {
+	tracer := &recordingOperationTracer{}
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.OperationTracer = tracer
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat, retryPolicy *mockRetryPolicy) {
+		retryPolicy.On("AllowRetry", 0, mock.Anything, mock.Anything).Return(true).Once()
+
+		conn.On("Get", "/node").Return(nil, nil, zk.ErrSessionExpired).Once()
+		conn.On("Get", "/node").Return(data, stat, nil).Once()
+		conn.On("Delete", "/node", AnyVersion).Return(zk.ErrNoNode).Once()
+
+		_, err := client.GetData().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+
+		err = client.Delete().ForPath("/node")
+
+		assert.Equal(s.T(), zk.ErrNoNode, err)
+		assert.Equal(s.T(), []string{
+			"start GET_DATA /node",
+			"retry 1 " + zk.ErrSessionExpired.Error(),
+			"finish 2 <nil>",
+			"start DELETE /node",
+			"finish 1 " + zk.ErrNoNode.Error(),
+		}, tracer.traces)
+	})
+}
+
+type recordingAdvancedTracerDriver struct {
+	*defaultTracerDriver
+
+	lock   sync.Mutex
+	traces []TracedOperation
+	events []TracedEvent
+}
+
+func (d *recordingAdvancedTracerDriver) AddTrace(trace *TracedOperation) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.traces = append(d.traces, *trace)
+}
+
+func (d *recordingAdvancedTracerDriver) AddEvent(trace *TracedEvent) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.events = append(d.events, *trace)
+}
+
+func (s *OperationTracerTestSuite) TestAdvancedTracerDriver() {
+	driver := &recordingAdvancedTracerDriver{defaultTracerDriver: newDefaultTracerDriver()}
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.TracerDriver = driver
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat, acls []zk.ACL) {
+		conn.On("Create", "/node", data, int32(PERSISTENT), acls).Return("/node", nil).Once()
+		conn.On("GetW", "/node").Return(data, stat, nil, nil).Once()
+
+		_, err := client.Create().WithACL(acls...).ForPathWithData("/node", data)
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.GetData().Watched().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), driver.traces, 2)
+
+		created, read := driver.traces[0], driver.traces[1]
+
+		assert.Equal(s.T(), "CREATE", created.Name)
+		assert.Equal(s.T(), "/node", created.Path)
+		assert.Equal(s.T(), len(data), created.RequestBytes)
+		assert.False(s.T(), created.WithWatcher)
+		assert.NoError(s.T(), created.Err)
+
+		assert.Equal(s.T(), "GET_DATA", read.Name)
+		assert.Equal(s.T(), len(data), read.ResponseBytes)
+		assert.True(s.T(), read.WithWatcher)
+		assert.Equal(s.T(), stat, read.Stat)
+		assert.True(s.T(), read.Latency > 0)
+	})
+}
+
+func (s *OperationTracerTestSuite) TestSlowOperations() {
+	var slows []string
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.SlowOperationThreshold = 10 * time.Millisecond
+		builder.SlowOperationListener = NewSlowOperationListener(func(operation, path string, latency time.Duration) {
+			assert.True(s.T(), latency > 10*time.Millisecond)
+
+			slows = append(slows, operation+" "+path)
+		})
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		conn.On("Exists", "/fast").Return(true, stat, nil).Once()
+		conn.On("Get", "/slow").Return(data, stat, nil).After(20 * time.Millisecond).Once()
+
+		_, err := client.CheckExists().ForPath("/fast")
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.GetData().ForPath("/slow")
+
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{"GET_DATA /slow"}, slows)
+	})
+}
+
+func TestTracedPathPrefixes(t *testing.T) {
+	prefixes := []string{"/locks", "/locks/leader/", "/services"}
+
+	assert.Equal(t, "/locks", matchPathPrefix("/locks", prefixes))
+	assert.Equal(t, "/locks", matchPathPrefix("/locks/lock-1", prefixes))
+	assert.Equal(t, "/locks/leader/", matchPathPrefix("/locks/leader/lock-1", prefixes))
+	assert.Equal(t, "", matchPathPrefix("/locksmith", prefixes))
+	assert.Equal(t, "", matchPathPrefix("/", prefixes))
+
+	// a plain driver records the time per path prefix too
+	d := &mockTracerDriver{}
+
+	d.On("AddTime", "GET_DATA", mock.AnythingOfType("time.Duration")).Return().Once()
+	d.On("AddTime", "GET_DATA /services", mock.AnythingOfType("time.Duration")).Return().Once()
+
+	tracer := NewAdvancedTracer("GET_DATA", d).WithPath("/services/web")
+	tracer.prefixes = prefixes
+	tracer.Commit(nil)
+
+	d.AssertExpectations(t)
+}
+
+func (s *OperationTracerTestSuite) TestTracedPathPrefixes() {
+	driver := &recordingAdvancedTracerDriver{defaultTracerDriver: newDefaultTracerDriver()}
+
+	s.WithPrepare(func(builder *CuratorFrameworkBuilder) {
+		builder.TracerDriver = driver
+		builder.TracedPathPrefixes = []string{"/locks"}
+	}, func(client CuratorFramework, conn *mockConn, data []byte, stat *zk.Stat) {
+		conn.On("Exists", "/locks/lock-1").Return(true, stat, nil).Once()
+		conn.On("Exists", "/node").Return(true, stat, nil).Once()
+
+		_, err := client.CheckExists().ForPath("/locks/lock-1")
+
+		assert.NoError(s.T(), err)
+
+		_, err = client.CheckExists().ForPath("/node")
+
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), driver.traces, 2)
+		assert.Equal(s.T(), "/locks", driver.traces[0].PathPrefix)
+		assert.Equal(s.T(), "", driver.traces[1].PathPrefix)
+	})
+}
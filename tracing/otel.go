@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the tracer of the spans
+const TRACER_NAME = "github.com/flier/curator.go"
+
+// The attributes of the spans
+const (
+	OPERATION_ATTRIBUTE = attribute.Key("zookeeper.operation") // the operation, e.g. CREATE
+	PATH_ATTRIBUTE      = attribute.Key("zookeeper.path")      // the path of the operation, including the namespace
+	RESULT_ATTRIBUTE    = attribute.Key("zookeeper.result")    // the result code of the operation, e.g. OK or NONODE
+	ATTEMPTS_ATTRIBUTE  = attribute.Key("zookeeper.attempts")  // the number of the attempts of the operation
+	RETRY_ATTRIBUTE     = attribute.Key("zookeeper.retry")     // the number of a retry, starting from 1
+)
+
+// The result codes of the errors, named after the codes of the ZooKeeper server
+var resultCodes = map[error]string{
+	zk.ErrNoNode:                  "NONODE",
+	zk.ErrNodeExists:              "NODEEXISTS",
+	zk.ErrBadVersion:              "BADVERSION",
+	zk.ErrNotEmpty:                "NOTEMPTY",
+	zk.ErrNoChildrenForEphemerals: "NOCHILDRENFOREPHEMERALS",
+	zk.ErrNoAuth:                  "NOAUTH",
+	zk.ErrAuthFailed:              "AUTHFAILED",
+	zk.ErrInvalidACL:              "INVALIDACL",
+	zk.ErrBadArguments:            "BADARGUMENTS",
+	zk.ErrAPIError:                "APIERROR",
+	zk.ErrSessionExpired:          "SESSIONEXPIRED",
+	zk.ErrSessionMoved:            "SESSIONMOVED",
+	zk.ErrConnectionClosed:        "CONNECTIONLOSS",
+	curator.ErrConnectionLoss:     "CONNECTIONLOSS",
+	curator.ErrRequestTimeout:     "OPERATIONTIMEOUT",
+	curator.ErrUnimplemented:      "UNIMPLEMENTED",
+	curator.ErrReadOnlyConnection: "NOTREADONLY",
+	context.Canceled:              "CANCELED",
+	context.DeadlineExceeded:      "DEADLINEEXCEEDED",
+}
+
+// Return the result code of the error of an operation, OK if it succeeded, UNKNOWN if the error isn't known
+func ResultCode(err error) string {
+	if err == nil {
+		return "OK"
+	} else if code, ok := resultCodes[err]; ok {
+		return code
+	}
+
+	return "UNKNOWN"
+}
+
+type openTelemetryTracer struct {
+	tracer trace.Tracer
+}
+
+// Trace each operation of the framework with a span, which records its retries as events,
+// the spans are children of the span of the context the client is bound to, e.g. with CuratorFramework.WithContext.
+//
+// The spans are created by the provider, or the global provider of OpenTelemetry if it is nil.
+func NewOpenTelemetryTracer(provider trace.TracerProvider) curator.OperationTracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &openTelemetryTracer{provider.Tracer(TRACER_NAME)}
+}
+
+func (t *openTelemetryTracer) StartOperation(ctx context.Context, operation, path string) curator.OperationTrace {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attributes := []attribute.KeyValue{OPERATION_ATTRIBUTE.String(operation)}
+
+	if path != "" {
+		attributes = append(attributes, PATH_ATTRIBUTE.String(path))
+	}
+
+	_, span := t.tracer.Start(ctx, "zookeeper "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+
+	return &openTelemetryTrace{span}
+}
+
+type openTelemetryTrace struct {
+	span trace.Span
+}
+
+func (t *openTelemetryTrace) RetryAttempted(retry int, err error) {
+	t.span.AddEvent("retry", trace.WithAttributes(RETRY_ATTRIBUTE.Int(retry), RESULT_ATTRIBUTE.String(ResultCode(err))))
+}
+
+func (t *openTelemetryTrace) Finish(attempts int, err error) {
+	t.span.SetAttributes(RESULT_ATTRIBUTE.String(ResultCode(err)), ATTEMPTS_ATTRIBUTE.Int(attempts))
+
+	if err != nil {
+		t.span.RecordError(err)
+		t.span.SetStatus(codes.Error, err.Error())
+	}
+
+	t.span.End()
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestResultCode(t *testing.T) {
+	assert.Equal(t, "OK", ResultCode(nil))
+	assert.Equal(t, "NONODE", ResultCode(zk.ErrNoNode))
+	assert.Equal(t, "CONNECTIONLOSS", ResultCode(curator.ErrConnectionLoss))
+	assert.Equal(t, "OPERATIONTIMEOUT", ResultCode(curator.ErrRequestTimeout))
+	assert.Equal(t, "UNKNOWN", ResultCode(assert.AnError))
+}
+
+func TestOpenTelemetryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewOpenTelemetryTracer(provider)
+
+	// the span is a child of the span of the context
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	operation := tracer.StartOperation(ctx, "GET_DATA", "/node")
+
+	operation.RetryAttempted(1, zk.ErrSessionExpired)
+	operation.Finish(2, zk.ErrNoNode)
+
+	parent.End()
+
+	spans := recorder.Ended()
+
+	assert.Len(t, spans, 2)
+
+	span := spans[0]
+
+	assert.Equal(t, "zookeeper GET_DATA", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, []attribute.KeyValue{
+		OPERATION_ATTRIBUTE.String("GET_DATA"),
+		PATH_ATTRIBUTE.String("/node"),
+		RESULT_ATTRIBUTE.String("NONODE"),
+		ATTEMPTS_ATTRIBUTE.Int(2),
+	}, span.Attributes())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	events := span.Events()
+
+	assert.Len(t, events, 2) // the retry and the recorded error
+	assert.Equal(t, "retry", events[0].Name)
+	assert.Equal(t, []attribute.KeyValue{RETRY_ATTRIBUTE.Int(1), RESULT_ATTRIBUTE.String("SESSIONEXPIRED")}, events[0].Attributes)
+
+	// a context isn't required
+	tracer.StartOperation(nil, "TRANSACTION", "").Finish(1, nil)
+
+	spans = recorder.Ended()
+
+	assert.Len(t, spans, 3)
+	assert.Equal(t, []attribute.KeyValue{
+		OPERATION_ATTRIBUTE.String("TRANSACTION"),
+		RESULT_ATTRIBUTE.String("OK"),
+		ATTEMPTS_ATTRIBUTE.Int(1),
+	}, spans[2].Attributes())
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+}
@@ -1,9 +1,14 @@
 package curator
 
 import (
+	"errors"
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+var ErrTransactionTooLarge = errors.New("the transaction exceeds the max packet size of the servers")
+
 // Transactional/atomic operations.
 //
 // The general form for this interface is:
@@ -35,6 +40,39 @@ type Transaction interface {
 	Check() TransactionCheckBuilder
 }
 
+// A reified transaction operation, allocated by CuratorFramework.TransactionOp()
+//
+//		op := client.TransactionOp()
+//
+//		client.Transaction().ForOperations(
+//              op.Create().ForPathWithData("/path-one", path-one-data),
+//              op.Delete().ForPath("/path-two"))
+//
+type CuratorOp interface {
+	// Return the operation type
+	Type() OperationType
+
+	// Return the path of the operation
+	Path() string
+
+	request() (interface{}, error)
+}
+
+// Builds reified transaction operations
+type TransactionOp interface {
+	// Start a create builder of the operation
+	Create() TransactionOpCreateBuilder
+
+	// Start a delete builder of the operation
+	Delete() TransactionOpDeleteBuilder
+
+	// Start a set data builder of the operation
+	SetData() TransactionOpSetDataBuilder
+
+	// Start a check builder of the operation
+	Check() TransactionOpCheckBuilder
+}
+
 // Transaction operation types
 type OperationType int
 
@@ -60,7 +98,16 @@ type TransactionFinal interface {
 	// Commit all added operations as an atomic unit and return results for the operations.
 	// One result is returned for each operation added.
 	// Further, the ordering of the results matches the ordering that the operations were added.
+	//
+	// Fails with ErrTransactionTooLarge if the estimated size of the request exceeds the MaxPacketSize of the client.
 	Commit() ([]TransactionResult, error)
+
+	// Commit the operations in order, split into as few transactions as fit the MaxPacketSize of the client.
+	//
+	// Each transaction is atomic but the operations are not committed as a unit,
+	// so it should only be used for the independent operations. If a transaction fails,
+	// the results of the transactions already committed are returned with the error.
+	CommitSplitting() ([]TransactionResult, error)
 }
 
 // Syntactic sugar to make the fluent interface more readable
@@ -73,10 +120,11 @@ type TransactionBridge interface {
 type curatorTransaction struct {
 	client     *curatorFramework
 	operations []interface{}
+	err        error // the first error when building the operations
 }
 
 func (t *curatorTransaction) Create() TransactionCreateBuilder {
-	return &transactionCreateBuilder{transaction: t, acling: acling{aclProvider: t.client.aclProvider}}
+	return &transactionCreateBuilder{transaction: t, acling: acling{aclProvider: t.client.aclProvider}, compress: t.client.compressionEnabled}
 }
 
 func (t *curatorTransaction) Delete() TransactionDeleteBuilder {
@@ -84,7 +132,7 @@ func (t *curatorTransaction) Delete() TransactionDeleteBuilder {
 }
 
 func (t *curatorTransaction) SetData() TransactionSetDataBuilder {
-	return &transactionSetDataBuilder{transaction: t, version: AnyVersion}
+	return &transactionSetDataBuilder{transaction: t, version: AnyVersion, compress: t.client.compressionEnabled}
 }
 
 func (t *curatorTransaction) Check() TransactionCheckBuilder {
@@ -96,13 +144,65 @@ func (t *curatorTransaction) And() TransactionFinal {
 }
 
 func (t *curatorTransaction) Commit() ([]TransactionResult, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	if estimateMultiSize(t.operations) > t.client.maxPacketSize {
+		return nil, ErrTransactionTooLarge
+	}
+
+	return t.commit(t.operations)
+}
+
+func (t *curatorTransaction) CommitSplitting() ([]TransactionResult, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	var results []TransactionResult
+
+	for start := 0; start < len(t.operations); {
+		end, size := start, estimateMultiSize(nil)
+
+		for ; end < len(t.operations); end++ {
+			if size += multiHeaderSize + estimateRequestSize(t.operations[end]); size > t.client.maxPacketSize {
+				break
+			}
+		}
+
+		if end == start {
+			return results, ErrTransactionTooLarge // the operation alone is too large
+		}
+
+		batchResults, err := t.commit(t.operations[start:end])
+
+		results = append(results, batchResults...)
+
+		if err != nil {
+			return results, err
+		}
+
+		start = end
+	}
+
+	return results, nil
+}
+
+func (t *curatorTransaction) commit(operations []interface{}) ([]TransactionResult, error) {
+	if err := t.client.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	zkClient := t.client.ZookeeperClient()
 
-	result, err := zkClient.NewRetryLoop().CallWithRetry(func() (interface{}, error) {
+	result, err := zkClient.NewRetryLoopFor(TRANSACTION.String()).CallWithRetry(func() (interface{}, error) {
 		if conn, err := zkClient.Conn(); err != nil {
 			return nil, err
+		} else if _, ok := conn.(TTLZookeeperConnection); !ok && hasTTL(operations) {
+			return nil, ErrTTLNotSupported
 		} else {
-			return conn.Multi(t.operations...)
+			return conn.Multi(operations...)
 		}
 	})
 
@@ -110,28 +210,28 @@ func (t *curatorTransaction) Commit() ([]TransactionResult, error) {
 
 	if responses, ok := result.([]zk.MultiResponse); ok {
 		for i, res := range responses {
-			switch req := t.operations[i].(type) {
+			switch req := operations[i].(type) {
 			case *zk.CreateRequest:
 				results = append(results, TransactionResult{
 					Type:       OP_CREATE,
-					ForPath:    req.Path,
+					ForPath:    t.client.unfixForNamespace(req.Path),
 					ResultPath: t.client.unfixForNamespace(res.String),
 				})
 			case *zk.DeleteRequest:
 				results = append(results, TransactionResult{
 					Type:    OP_DELETE,
-					ForPath: req.Path,
+					ForPath: t.client.unfixForNamespace(req.Path),
 				})
 			case *zk.SetDataRequest:
 				results = append(results, TransactionResult{
 					Type:       OP_SET_DATA,
-					ForPath:    req.Path,
+					ForPath:    t.client.unfixForNamespace(req.Path),
 					ResultStat: res.Stat,
 				})
 			case *zk.CheckVersionRequest:
 				results = append(results, TransactionResult{
 					Type:    OP_CHECK,
-					ForPath: req.Path,
+					ForPath: t.client.unfixForNamespace(req.Path),
 				})
 			}
 		}
@@ -140,30 +240,95 @@ func (t *curatorTransaction) Commit() ([]TransactionResult, error) {
 	return results, err
 }
 
+// The size of the header of each operation in a multi request, the opcode, the done flag and the error
+const multiHeaderSize = 4 + 1 + 4
+
+// Estimate the serialized size of a multi request with the operations, including the packet length
+func estimateMultiSize(operations []interface{}) int {
+	size := 4 + 4 + 4 + multiHeaderSize // the packet length, xid, opcode and the end header
+
+	for _, op := range operations {
+		size += multiHeaderSize + estimateRequestSize(op)
+	}
+
+	return size
+}
+
+func estimateRequestSize(op interface{}) int {
+	switch req := op.(type) {
+	case *zk.CreateRequest:
+		size := 4 + len(req.Path) + 4 + len(req.Data) + 4 + 4 + 8 // with the flags and the TTL
+
+		for _, acl := range req.Acl {
+			size += 4 + 4 + len(acl.Scheme) + 4 + len(acl.ID)
+		}
+
+		return size
+	case *zk.DeleteRequest:
+		return 4 + len(req.Path) + 4
+	case *zk.SetDataRequest:
+		return 4 + len(req.Path) + 4 + len(req.Data) + 4
+	case *zk.CheckVersionRequest:
+		return 4 + len(req.Path) + 4
+	}
+
+	return 0
+}
+
+// Check if any create operation is marked with the TTL
+func hasTTL(operations []interface{}) bool {
+	for _, op := range operations {
+		if req, ok := op.(*zk.CreateRequest); ok && CreateMode(req.Flags).IsTTL() {
+			return true
+		}
+	}
+
+	return false
+}
+
 type transactionCreateBuilder struct {
 	transaction *curatorTransaction
 	createMode  CreateMode
 	compress    bool
 	acling      acling
+	ttl         time.Duration
 }
 
 func (b *transactionCreateBuilder) ForPath(path string) TransactionBridge {
-	return b.ForPathWithData(path, b.transaction.client.defaultData)
+	return b.ForPathWithData(path, b.transaction.client.defaultData.GetDefaultData(path))
 }
 
 func (b *transactionCreateBuilder) ForPathWithData(path string, payload []byte) TransactionBridge {
-	var data []byte
+	data := payload
 
 	if b.compress {
-		data, _ = b.transaction.client.compressionProvider.Compress(path, payload)
-	} else {
-		data = payload
+		var err error
+
+		if data, err = b.transaction.client.compressionProvider.Compress(path, payload); err != nil && b.transaction.err == nil {
+			b.transaction.err = err
+		}
+	}
+
+	adjustedPath := b.transaction.client.fixForNamespace(path, false)
+
+	acls := b.acling.getAclList(adjustedPath) // the same path as the createBuilder gives the ACLProvider
+
+	if err := b.transaction.client.compatibility.checkCreateMode(b.createMode); err != nil && b.transaction.err == nil {
+		b.transaction.err = err
+	}
+
+	if b.createMode.IsTTL() {
+		if (b.ttl <= 0 || b.ttl > MAX_TTL) && b.transaction.err == nil {
+			b.transaction.err = ErrInvalidTTL
+		}
+
+		acls = withTTL(acls, b.ttl)
 	}
 
 	b.transaction.operations = append(b.transaction.operations, &zk.CreateRequest{
-		Path:  b.transaction.client.fixForNamespace(path, false),
+		Path:  adjustedPath,
 		Data:  data,
-		Acl:   b.acling.getAclList(path),
+		Acl:   acls,
 		Flags: int32(b.createMode),
 	})
 
@@ -176,6 +341,12 @@ func (b *transactionCreateBuilder) WithMode(mode CreateMode) TransactionCreateBu
 	return b
 }
 
+func (b *transactionCreateBuilder) WithTTL(ttl time.Duration) TransactionCreateBuilder {
+	b.ttl = ttl
+
+	return b
+}
+
 func (b *transactionCreateBuilder) WithACL(acls ...zk.ACL) TransactionCreateBuilder {
 	b.acling.aclList = acls
 
@@ -188,6 +359,12 @@ func (b *transactionCreateBuilder) Compressed() TransactionCreateBuilder {
 	return b
 }
 
+func (b *transactionCreateBuilder) Uncompressed() TransactionCreateBuilder {
+	b.compress = false
+
+	return b
+}
+
 type transactionDeleteBuilder struct {
 	transaction *curatorTransaction
 	version     int32
@@ -215,16 +392,18 @@ type transactionSetDataBuilder struct {
 }
 
 func (b *transactionSetDataBuilder) ForPath(path string) TransactionBridge {
-	return b.ForPathWithData(path, b.transaction.client.defaultData)
+	return b.ForPathWithData(path, b.transaction.client.defaultData.GetDefaultData(path))
 }
 
 func (b *transactionSetDataBuilder) ForPathWithData(path string, payload []byte) TransactionBridge {
-	var data []byte
+	data := payload
 
 	if b.compress {
-		data, _ = b.transaction.client.compressionProvider.Compress(path, payload)
-	} else {
-		data = payload
+		var err error
+
+		if data, err = b.transaction.client.compressionProvider.Compress(path, payload); err != nil && b.transaction.err == nil {
+			b.transaction.err = err
+		}
 	}
 
 	b.transaction.operations = append(b.transaction.operations, &zk.SetDataRequest{
@@ -248,6 +427,12 @@ func (b *transactionSetDataBuilder) Compressed() TransactionSetDataBuilder {
 	return b
 }
 
+func (b *transactionSetDataBuilder) Uncompressed() TransactionSetDataBuilder {
+	b.compress = false
+
+	return b
+}
+
 type transactionCheckBuilder struct {
 	transaction *curatorTransaction
 	version     int32
@@ -267,3 +452,217 @@ func (b *transactionCheckBuilder) WithVersion(version int32) TransactionCheckBui
 
 	return b
 }
+
+type curatorOp struct {
+	opType OperationType
+	path   string
+	req    interface{}
+	err    error
+}
+
+// Reify the operation just added to the transaction by the builder, which may be reused for more operations
+func newCuratorOp(opType OperationType, path string, bridge TransactionBridge) CuratorOp {
+	transaction := bridge.(*curatorTransaction)
+
+	return &curatorOp{opType: opType, path: path, req: transaction.operations[len(transaction.operations)-1], err: transaction.err}
+}
+
+func (o *curatorOp) Type() OperationType { return o.opType }
+
+func (o *curatorOp) Path() string { return o.path }
+
+func (o *curatorOp) request() (interface{}, error) { return o.req, o.err }
+
+type transactionOp struct {
+	client *curatorFramework
+}
+
+func (o *transactionOp) Create() TransactionOpCreateBuilder {
+	transaction := &curatorTransaction{client: o.client}
+
+	return &transactionOpCreateBuilder{transaction.Create().(*transactionCreateBuilder)}
+}
+
+func (o *transactionOp) Delete() TransactionOpDeleteBuilder {
+	transaction := &curatorTransaction{client: o.client}
+
+	return &transactionOpDeleteBuilder{transaction.Delete().(*transactionDeleteBuilder)}
+}
+
+func (o *transactionOp) SetData() TransactionOpSetDataBuilder {
+	transaction := &curatorTransaction{client: o.client}
+
+	return &transactionOpSetDataBuilder{transaction.SetData().(*transactionSetDataBuilder)}
+}
+
+func (o *transactionOp) Check() TransactionOpCheckBuilder {
+	transaction := &curatorTransaction{client: o.client}
+
+	return &transactionOpCheckBuilder{transaction.Check().(*transactionCheckBuilder)}
+}
+
+type transactionOpCreateBuilder struct {
+	builder *transactionCreateBuilder
+}
+
+func (b *transactionOpCreateBuilder) ForPath(path string) CuratorOp {
+	return newCuratorOp(OP_CREATE, path, b.builder.ForPath(path))
+}
+
+func (b *transactionOpCreateBuilder) ForPathWithData(path string, payload []byte) CuratorOp {
+	return newCuratorOp(OP_CREATE, path, b.builder.ForPathWithData(path, payload))
+}
+
+func (b *transactionOpCreateBuilder) WithMode(mode CreateMode) TransactionOpCreateBuilder {
+	b.builder.WithMode(mode)
+
+	return b
+}
+
+func (b *transactionOpCreateBuilder) WithTTL(ttl time.Duration) TransactionOpCreateBuilder {
+	b.builder.WithTTL(ttl)
+
+	return b
+}
+
+func (b *transactionOpCreateBuilder) WithACL(acls ...zk.ACL) TransactionOpCreateBuilder {
+	b.builder.WithACL(acls...)
+
+	return b
+}
+
+func (b *transactionOpCreateBuilder) Compressed() TransactionOpCreateBuilder {
+	b.builder.Compressed()
+
+	return b
+}
+
+func (b *transactionOpCreateBuilder) Uncompressed() TransactionOpCreateBuilder {
+	b.builder.Uncompressed()
+
+	return b
+}
+
+type transactionOpDeleteBuilder struct {
+	builder *transactionDeleteBuilder
+}
+
+func (b *transactionOpDeleteBuilder) ForPath(path string) CuratorOp {
+	return newCuratorOp(OP_DELETE, path, b.builder.ForPath(path))
+}
+
+func (b *transactionOpDeleteBuilder) WithVersion(version int32) TransactionOpDeleteBuilder {
+	b.builder.WithVersion(version)
+
+	return b
+}
+
+type transactionOpSetDataBuilder struct {
+	builder *transactionSetDataBuilder
+}
+
+func (b *transactionOpSetDataBuilder) ForPath(path string) CuratorOp {
+	return newCuratorOp(OP_SET_DATA, path, b.builder.ForPath(path))
+}
+
+func (b *transactionOpSetDataBuilder) ForPathWithData(path string, payload []byte) CuratorOp {
+	return newCuratorOp(OP_SET_DATA, path, b.builder.ForPathWithData(path, payload))
+}
+
+func (b *transactionOpSetDataBuilder) WithVersion(version int32) TransactionOpSetDataBuilder {
+	b.builder.WithVersion(version)
+
+	return b
+}
+
+func (b *transactionOpSetDataBuilder) Compressed() TransactionOpSetDataBuilder {
+	b.builder.Compressed()
+
+	return b
+}
+
+func (b *transactionOpSetDataBuilder) Uncompressed() TransactionOpSetDataBuilder {
+	b.builder.Uncompressed()
+
+	return b
+}
+
+type transactionOpCheckBuilder struct {
+	builder *transactionCheckBuilder
+}
+
+func (b *transactionOpCheckBuilder) ForPath(path string) CuratorOp {
+	return newCuratorOp(OP_CHECK, path, b.builder.ForPath(path))
+}
+
+func (b *transactionOpCheckBuilder) WithVersion(version int32) TransactionOpCheckBuilder {
+	b.builder.WithVersion(version)
+
+	return b
+}
+
+type curatorMultiTransaction struct {
+	client        *curatorFramework
+	backgrounding backgrounding
+}
+
+func (t *curatorMultiTransaction) ForOperations(operations ...CuratorOp) ([]TransactionResult, error) {
+	transaction := &curatorTransaction{client: t.client}
+
+	for _, op := range operations {
+		if req, err := op.request(); err != nil {
+			return nil, err
+		} else {
+			transaction.operations = append(transaction.operations, req)
+		}
+	}
+
+	if t.backgrounding.inBackground {
+		t.client.runSafe(func() { t.commitInBackground(transaction) })
+
+		return nil, nil
+	}
+
+	return transaction.Commit()
+}
+
+func (t *curatorMultiTransaction) commitInBackground(transaction *curatorTransaction) {
+	tracer := t.client.ZookeeperClient().StartTracer("curatorMultiTransaction.commitInBackground")
+
+	defer tracer.Commit()
+
+	results, err := transaction.Commit()
+
+	event := &curatorEvent{
+		eventType: TRANSACTION,
+		err:       err,
+		opResults: results,
+		context:   t.backgrounding.context,
+	}
+
+	t.client.processBackgroundEvent(t.backgrounding, event)
+}
+
+func (t *curatorMultiTransaction) InBackground() CuratorMultiTransaction {
+	t.backgrounding = backgrounding{inBackground: true}
+
+	return t
+}
+
+func (t *curatorMultiTransaction) InBackgroundWithContext(context interface{}) CuratorMultiTransaction {
+	t.backgrounding = backgrounding{inBackground: true, context: context}
+
+	return t
+}
+
+func (t *curatorMultiTransaction) InBackgroundWithCallback(callback BackgroundCallback) CuratorMultiTransaction {
+	t.backgrounding = backgrounding{inBackground: true, callback: callback}
+
+	return t
+}
+
+func (t *curatorMultiTransaction) InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) CuratorMultiTransaction {
+	t.backgrounding = backgrounding{inBackground: true, context: context, callback: callback}
+
+	return t
+}
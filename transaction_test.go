@@ -1,7 +1,10 @@
 package curator
 
 import (
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
@@ -55,22 +58,233 @@ func TestTransaction(t *testing.T) {
 		assert.Equal(t, results, []TransactionResult{
 			{
 				Type:       OP_CREATE,
-				ForPath:    "/parent/node1",
+				ForPath:    "/node1",
 				ResultPath: "/node1",
 			},
 			{
 				Type:    OP_DELETE,
-				ForPath: "/parent/node2",
+				ForPath: "/node2",
 			},
 			{
 				Type:       OP_SET_DATA,
-				ForPath:    "/parent/node3",
+				ForPath:    "/node3",
 				ResultStat: &zk.Stat{},
 			},
 			{
 				Type:    OP_CHECK,
-				ForPath: "/parent/node4",
+				ForPath: "/node4",
 			},
 		})
 	})
 }
+
+func TestTransactionACLProvider(t *testing.T) {
+	newMockContainer().WithNamespace("parent").Test(t, func(client CuratorFramework, conn *mockConn, aclProvider *mockACLProvider, acls []zk.ACL) {
+		aclProvider.On("GetAclForPath", "/parent/node").Return(acls).Once()
+
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: nil, String: "/parent/node"}}, nil).Once()
+
+		results, err := client.InTransaction().Create().ForPathWithData("/node", []byte("data")).Commit()
+
+		assert.NoError(t, err)
+		assert.Equal(t, conn.operations, []interface{}{
+			&zk.CreateRequest{
+				Path:  "/parent/node",
+				Data:  []byte("data"),
+				Acl:   acls,
+				Flags: int32(PERSISTENT),
+			},
+		})
+		assert.Equal(t, results, []TransactionResult{{Type: OP_CREATE, ForPath: "/node", ResultPath: "/node"}})
+	})
+}
+
+func TestTransactionCompressionError(t *testing.T) {
+	newMockContainer().Test(t, func(client CuratorFramework, conn *mockConn, compress *mockCompressionProvider, acls []zk.ACL) {
+		compress.On("Compress", "/node1", []byte("data")).Return(nil, errors.New("compress failed")).Once()
+
+		results, err := client.InTransaction().
+			Create().WithACL(acls...).Compressed().ForPathWithData("/node1", []byte("data")).
+			Check().ForPath("/node2").
+			Commit()
+
+		assert.Nil(t, results)
+		assert.EqualError(t, err, "compress failed")
+		assert.Empty(t, conn.operations)
+	})
+}
+
+func TestTransactionTooLarge(t *testing.T) {
+	newMockContainer().Prepare(func(builder *CuratorFrameworkBuilder) {
+		builder.MaxPacketSize = 160
+	}).Test(t, func(client CuratorFramework, conn *mockConn) {
+		data := make([]byte, 40)
+
+		transaction := client.InTransaction().
+			SetData().ForPathWithData("/node1", data).
+			SetData().ForPathWithData("/node2", data).
+			SetData().ForPathWithData("/node3", data)
+
+		results, err := transaction.Commit()
+
+		assert.Nil(t, results)
+		assert.Equal(t, ErrTransactionTooLarge, err)
+		assert.Empty(t, conn.operations)
+
+		// split into the transactions fitting the max packet size
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: &zk.Stat{Version: 1}}, {Stat: &zk.Stat{Version: 2}}}, nil).Once()
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: &zk.Stat{Version: 3}}}, nil).Once()
+
+		results, err = transaction.CommitSplitting()
+
+		assert.NoError(t, err)
+		assert.Len(t, conn.operations, 3)
+		assert.Equal(t, []TransactionResult{
+			{Type: OP_SET_DATA, ForPath: "/node1", ResultStat: &zk.Stat{Version: 1}},
+			{Type: OP_SET_DATA, ForPath: "/node2", ResultStat: &zk.Stat{Version: 2}},
+			{Type: OP_SET_DATA, ForPath: "/node3", ResultStat: &zk.Stat{Version: 3}},
+		}, results)
+
+		conn.AssertNumberOfCalls(t, "Multi", 2)
+
+		// an operation can't be split
+		results, err = client.InTransaction().SetData().ForPathWithData("/node", make([]byte, 200)).CommitSplitting()
+
+		assert.Nil(t, results)
+		assert.Equal(t, ErrTransactionTooLarge, err)
+	})
+}
+
+func TestTransactionTTL(t *testing.T) {
+	newMockContainer().Test(t, func(client CuratorFramework, conn *mockConn, data []byte, acls []zk.ACL) {
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{
+			{Stat: nil, String: "/node1"},
+			{Stat: nil, String: "/node20000000001"},
+		}, nil).Once()
+
+		op := client.TransactionOp()
+
+		results, err := client.Transaction().ForOperations(
+			op.Create().WithMode(PERSISTENT_WITH_TTL).WithTTL(time.Minute).WithACL(acls...).ForPathWithData("/node1", data),
+			op.Create().WithMode(PERSISTENT_SEQUENTIAL_WITH_TTL).WithTTL(time.Second).WithACL(acls...).ForPathWithData("/node2", data))
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "/node20000000001", results[1].ResultPath)
+		assert.Equal(t, conn.operations, []interface{}{
+			&zk.CreateRequest{
+				Path:  "/node1",
+				Data:  data,
+				Acl:   withTTL(acls, time.Minute),
+				Flags: int32(PERSISTENT_WITH_TTL),
+			},
+			&zk.CreateRequest{
+				Path:  "/node2",
+				Data:  data,
+				Acl:   withTTL(acls, time.Second),
+				Flags: int32(PERSISTENT_SEQUENTIAL_WITH_TTL),
+			},
+		})
+
+		// the TTL is required
+		results, err = client.InTransaction().Create().WithMode(PERSISTENT_WITH_TTL).WithACL(acls...).ForPathWithData("/node3", data).Commit()
+
+		assert.Nil(t, results)
+		assert.EqualError(t, err, ErrInvalidTTL.Error())
+	})
+}
+
+func TestCuratorMultiTransaction(t *testing.T) {
+	newMockContainer().WithNamespace("parent").Test(t, func(client CuratorFramework, conn *mockConn, compress *mockCompressionProvider, acls []zk.ACL, version int32) {
+		compress.On("Compress", "/node3", []byte("data")).Return([]byte("compressed(data)"), nil).Once()
+
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{
+			{Stat: nil, String: "/parent/node1"},
+			{Stat: nil, String: ""},
+			{Stat: &zk.Stat{}, String: ""},
+			{Stat: nil, String: ""},
+		}, nil).Once()
+
+		op := client.TransactionOp()
+
+		ops := []CuratorOp{
+			op.Create().WithMode(EPHEMERAL).WithACL(acls...).ForPathWithData("/node1", []byte("data")),
+			op.Delete().WithVersion(version).ForPath("/node2"),
+			op.SetData().WithVersion(version+1).Compressed().ForPathWithData("/node3", []byte("data")),
+			op.Check().WithVersion(version + 2).ForPath("/node4"),
+		}
+
+		assert.Equal(t, OP_CREATE, ops[0].Type())
+		assert.Equal(t, "/node1", ops[0].Path())
+
+		results, err := client.Transaction().ForOperations(ops...)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 4)
+		assert.Equal(t, "/node1", results[0].ResultPath)
+		assert.Equal(t, conn.operations, []interface{}{
+			&zk.CreateRequest{
+				Path:  "/parent/node1",
+				Data:  []byte("data"),
+				Acl:   acls,
+				Flags: int32(EPHEMERAL),
+			},
+			&zk.DeleteRequest{
+				Path:    "/parent/node2",
+				Version: version,
+			},
+			&zk.SetDataRequest{
+				Path:    "/parent/node3",
+				Data:    []byte("compressed(data)"),
+				Version: version + 1,
+			},
+			&zk.CheckVersionRequest{
+				Path:    "/parent/node4",
+				Version: version + 2,
+			},
+		})
+	})
+}
+
+func TestCuratorOpReusedBuilder(t *testing.T) {
+	newMockContainer().Test(t, func(client CuratorFramework, conn *mockConn, version int32) {
+		builder := client.TransactionOp().Delete().WithVersion(version)
+
+		op1 := builder.ForPath("/node1")
+		op2 := builder.ForPath("/node2")
+
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: nil, String: ""}, {Stat: nil, String: ""}}, nil).Once()
+
+		_, err := client.Transaction().ForOperations(op1, op2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, conn.operations, []interface{}{
+			&zk.DeleteRequest{Path: "/node1", Version: version},
+			&zk.DeleteRequest{Path: "/node2", Version: version},
+		})
+	})
+}
+
+func TestCuratorMultiTransactionInBackground(t *testing.T) {
+	newMockContainer().Test(t, func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		ctxt := "context"
+
+		conn.On("Multi", mock.Anything).Return([]zk.MultiResponse{{Stat: nil, String: ""}}, nil).Once()
+
+		results, err := client.Transaction().InBackgroundWithCallbackAndContext(func(client CuratorFramework, event CuratorEvent) error {
+			defer wg.Done()
+
+			assert.Equal(t, TRANSACTION, event.Type())
+			assert.NoError(t, event.Err())
+			assert.Equal(t, []TransactionResult{{Type: OP_DELETE, ForPath: "/node"}}, event.OpResults())
+			assert.Equal(t, ctxt, event.Context())
+
+			return nil
+		}, ctxt).ForOperations(client.TransactionOp().Delete().ForPath("/node"))
+
+		assert.Nil(t, results)
+		assert.NoError(t, err)
+	})
+}
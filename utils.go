@@ -1,6 +1,7 @@
 package curator
 
 import (
+	"fmt"
 	"log"
 	"sync/atomic"
 	"unsafe"
@@ -28,6 +29,13 @@ func CloseQuietly(closeable Closeable) (err error) {
 	return
 }
 
+// Report a panic of a callback to the handler, instead of crashing the process, must be deferred
+func recoverPanic(callback string, handler func(err error)) {
+	if v := recover(); v != nil {
+		handler(fmt.Errorf("%s panicked, %v", callback, v))
+	}
+}
+
 type AtomicBool int32
 
 const (
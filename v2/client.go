@@ -0,0 +1,298 @@
+// Package curator is the v2 API of curator.go, a context-first client configured with functional options.
+//
+// The client is a thin layer over the v1 CuratorFramework, which remains available with Client.Framework(),
+// so both APIs can be used side by side during a migration.
+//
+//	client, err := curator.New("localhost:2181", curator.WithNamespace("app"))
+//
+//	if err := client.Start(ctx); err != nil {
+//		...
+//	}
+//
+//	defer client.Close()
+//
+//	path, err := client.Create(ctx, "/node", data, curator.WithMode(v1.EPHEMERAL), curator.CreatingParents())
+//
+// An operation returns when it is done or the context is done, whichever happens first.
+// The operation isn't retried once the context is done, but the operation sent to the server isn't aborted,
+// since ZooKeeper can't cancel a submitted operation.
+package curator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// How long Start waits for the connection before checking the context again
+const CONNECT_POLL_INTERVAL = 100 * time.Millisecond
+
+// A context-first ZooKeeper client
+type Client struct {
+	framework v1.CuratorFramework
+}
+
+// Create a new client connecting to the given servers
+func New(connectString string, opts ...Option) (*Client, error) {
+	if len(connectString) == 0 {
+		return nil, errors.New("missed connect string")
+	}
+
+	builder := &v1.CuratorFrameworkBuilder{}
+
+	builder.ConnectString(connectString)
+
+	for _, opt := range opts {
+		opt(builder)
+	}
+
+	return Wrap(builder.Build()), nil
+}
+
+// Wrap a v1 CuratorFramework
+func Wrap(framework v1.CuratorFramework) *Client {
+	return &Client{framework}
+}
+
+// Return the underlying v1 CuratorFramework
+func (c *Client) Framework() v1.CuratorFramework {
+	return c.framework
+}
+
+// Start the client and wait until it is connected or the context is done
+func (c *Client) Start(ctx context.Context) error {
+	if err := c.framework.Start(); err != nil {
+		return &OpError{"start", "", err}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return &OpError{"start", "", err}
+		}
+
+		// wait for a bounded time, so nothing is left waiting once the context is done
+		maxWaitTime := CONNECT_POLL_INTERVAL
+
+		if deadline, ok := ctx.Deadline(); ok {
+			if remaining := time.Until(deadline); remaining <= 0 {
+				<-ctx.Done()
+
+				continue
+			} else if remaining < maxWaitTime {
+				maxWaitTime = remaining
+			}
+		}
+
+		if err := c.framework.BlockUntilConnectedTimeout(maxWaitTime); err == nil {
+			return nil
+		} else if err != v1.ErrTimeout {
+			return &OpError{"start", "", err}
+		}
+	}
+}
+
+// Stop the client
+func (c *Client) Close() error {
+	if err := c.framework.Close(); err != nil {
+		return &OpError{"close", "", err}
+	}
+
+	return nil
+}
+
+// Create a node with the given data, return the created path
+func (c *Client) Create(ctx context.Context, path string, data []byte, opts ...OpOption) (string, error) {
+	options := newOpOptions(opts)
+
+	result, err := do(ctx, "create", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).Create().WithMode(options.mode)
+
+		if options.acls != nil {
+			builder = builder.WithACL(options.acls...)
+		}
+		if options.ttl > 0 {
+			builder = builder.WithTTL(options.ttl)
+		}
+		if options.creatingParents {
+			builder = builder.CreatingParentsIfNeeded()
+		}
+		if options.protected {
+			builder = builder.WithProtection()
+		}
+		if options.compressed {
+			builder = builder.Compressed()
+		}
+
+		if data == nil {
+			return builder.ForPath(path)
+		}
+
+		return builder.ForPathWithData(path, data)
+	})
+
+	createdPath, _ := result.(string)
+
+	return createdPath, err
+}
+
+// Delete the node
+func (c *Client) Delete(ctx context.Context, path string, opts ...OpOption) error {
+	options := newOpOptions(opts)
+
+	_, err := do(ctx, "delete", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).Delete().WithVersion(options.version)
+
+		if options.deletingChildren {
+			builder = builder.DeletingChildrenIfNeeded()
+		}
+
+		return nil, builder.ForPath(path)
+	})
+
+	return err
+}
+
+// Return the data and the stat of the node
+func (c *Client) Get(ctx context.Context, path string, opts ...OpOption) ([]byte, *zk.Stat, error) {
+	options := newOpOptions(opts)
+
+	var stat zk.Stat
+
+	result, err := do(ctx, "get", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).GetData().StoringStatIn(&stat)
+
+		if options.compressed {
+			builder = builder.Decompressed()
+		}
+		if options.watcher != nil {
+			builder = builder.UsingWatcher(options.watcher)
+		}
+
+		return builder.ForPath(path)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, _ := result.([]byte)
+
+	return data, &stat, nil
+}
+
+// Set the data of the node, return the new stat
+func (c *Client) Set(ctx context.Context, path string, data []byte, opts ...OpOption) (*zk.Stat, error) {
+	options := newOpOptions(opts)
+
+	result, err := do(ctx, "set", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).SetData().WithVersion(options.version)
+
+		if options.compressed {
+			builder = builder.Compressed()
+		}
+
+		return builder.ForPathWithData(path, data)
+	})
+
+	stat, _ := result.(*zk.Stat)
+
+	return stat, err
+}
+
+// Return the stat of the node, or nil if it doesn't exist
+func (c *Client) Exists(ctx context.Context, path string, opts ...OpOption) (*zk.Stat, error) {
+	options := newOpOptions(opts)
+
+	result, err := do(ctx, "exists", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).CheckExists()
+
+		if options.watcher != nil {
+			builder = builder.UsingWatcher(options.watcher)
+		}
+
+		return builder.ForPath(path)
+	})
+
+	stat, _ := result.(*zk.Stat)
+
+	return stat, err
+}
+
+// Return the children of the node
+func (c *Client) Children(ctx context.Context, path string, opts ...OpOption) ([]string, error) {
+	options := newOpOptions(opts)
+
+	result, err := do(ctx, "children", path, func() (interface{}, error) {
+		builder := c.framework.WithContext(ctx).GetChildren()
+
+		if options.watcher != nil {
+			builder = builder.UsingWatcher(options.watcher)
+		}
+
+		return builder.ForPath(path)
+	})
+
+	children, _ := result.([]string)
+
+	return children, err
+}
+
+// Flush the channel between the server and the leader for the node
+func (c *Client) Sync(ctx context.Context, path string) error {
+	_, err := do(ctx, "sync", path, func() (interface{}, error) {
+		return c.framework.WithContext(ctx).Sync().ForPath(path)
+	})
+
+	return err
+}
+
+// Return the factory of the operations committed with Transaction
+func (c *Client) Op() v1.TransactionOp {
+	return c.framework.TransactionOp()
+}
+
+// Commit the operations in a transaction
+func (c *Client) Transaction(ctx context.Context, ops ...v1.CuratorOp) ([]v1.TransactionResult, error) {
+	result, err := do(ctx, "transaction", "", func() (interface{}, error) {
+		return c.framework.WithContext(ctx).Transaction().ForOperations(ops...)
+	})
+
+	results, _ := result.([]v1.TransactionResult)
+
+	return results, err
+}
+
+type opResult struct {
+	value interface{}
+	err   error
+}
+
+// Call the operation until it is done or the context is done, and wrap its error
+func do(ctx context.Context, op, path string, fn func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &OpError{op, path, err}
+	}
+
+	done := make(chan opResult, 1)
+
+	go func() {
+		value, err := fn()
+
+		done <- opResult{value, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return result.value, &OpError{op, path, result.err}
+		}
+
+		return result.value, nil
+
+	case <-ctx.Done():
+		return nil, &OpError{op, path, ctx.Err()}
+	}
+}
@@ -0,0 +1,194 @@
+package curator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/flier/curator.go"
+	"github.com/flier/curator.go/internal/zktest"
+	"github.com/samuel/go-zookeeper/zk"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClient(t *testing.T) {
+	Convey("Given a client", t, func() {
+		conn := &zktest.MockZookeeperConnection{Log: t.Logf}
+		dialer := &zktest.MockZookeeperDialer{Log: t.Logf}
+		events := make(chan zk.Event)
+
+		dialer.On("Dial", "connStr", v1.DEFAULT_SESSION_TIMEOUT, false).Return(conn, events, nil).Once()
+
+		client, err := New("connStr", WithDialer(dialer), WithDefaultData([]byte("default")))
+
+		So(err, ShouldBeNil)
+		So(client, ShouldNotBeNil)
+		So(client.Framework().Start(), ShouldBeNil)
+
+		ctx := context.Background()
+
+		Convey("When create a node", func() {
+			conn.On("Create", "/node", []byte("data"), int32(v1.EPHEMERAL), v1.OPEN_ACL_UNSAFE).Return("/node", nil).Once()
+
+			path, err := client.Create(ctx, "/node", []byte("data"), WithMode(v1.EPHEMERAL))
+
+			So(path, ShouldEqual, "/node")
+			So(err, ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When create a node without data", func() {
+			conn.On("Create", "/node", []byte("default"), int32(v1.PERSISTENT), v1.READ_ACL_UNSAFE).Return("/node", nil).Once()
+
+			path, err := client.Create(ctx, "/node", nil, WithACL(v1.READ_ACL_UNSAFE...))
+
+			So(path, ShouldEqual, "/node")
+			So(err, ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When get a missing node", func() {
+			conn.On("Get", "/node").Return(nil, nil, zk.ErrNoNode).Once()
+
+			data, stat, err := client.Get(ctx, "/node")
+
+			Convey("The error should be wrapped", func() {
+				So(data, ShouldBeNil)
+				So(stat, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "get /node: "+zk.ErrNoNode.Error())
+				So(errors.Is(err, zk.ErrNoNode), ShouldBeTrue)
+
+				conn.AssertExpectations(t)
+			})
+		})
+
+		Convey("When get a node", func() {
+			conn.On("Get", "/node").Return([]byte("data"), &zk.Stat{Version: 3}, nil).Once()
+
+			data, stat, err := client.Get(ctx, "/node")
+
+			So(string(data), ShouldEqual, "data")
+			So(stat.Version, ShouldEqual, 3)
+			So(err, ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When set a node with version", func() {
+			conn.On("Set", "/node", []byte("data"), int32(3)).Return(&zk.Stat{Version: 4}, nil).Once()
+
+			stat, err := client.Set(ctx, "/node", []byte("data"), WithVersion(3))
+
+			So(stat.Version, ShouldEqual, 4)
+			So(err, ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When delete a node", func() {
+			conn.On("Delete", "/node", v1.AnyVersion).Return(nil).Once()
+
+			So(client.Delete(ctx, "/node"), ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When check and list a node", func() {
+			conn.On("Exists", "/node").Return(true, &zk.Stat{NumChildren: 1}, nil).Once()
+			conn.On("Children", "/node").Return([]string{"child"}, nil, nil).Once()
+
+			stat, err := client.Exists(ctx, "/node")
+
+			So(stat.NumChildren, ShouldEqual, 1)
+			So(err, ShouldBeNil)
+
+			children, err := client.Children(ctx, "/node")
+
+			So(children, ShouldResemble, []string{"child"})
+			So(err, ShouldBeNil)
+
+			conn.AssertExpectations(t)
+		})
+
+		Convey("When the context is done", func() {
+			ctx, cancel := context.WithCancel(ctx)
+
+			cancel()
+
+			_, err := client.Create(ctx, "/node", nil)
+
+			Convey("The operation should not be called", func() {
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+				conn.AssertExpectations(t)
+			})
+		})
+
+		Convey("When the operation is slow", func() {
+			blocked := make(chan time.Time)
+
+			conn.On("Sync", "/node").WaitUntil(blocked).Return("/node", nil).Once()
+
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+
+			defer cancel()
+
+			err := client.Sync(ctx, "/node")
+
+			Convey("The operation should return when the context is done", func() {
+				So(err.Error(), ShouldEqual, "sync /node: "+context.DeadlineExceeded.Error())
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+
+				// wait for the blocked operation, so it doesn't log after the test
+				done := make(chan struct{})
+
+				conn.Log = func(format string, args ...interface{}) {
+					t.Logf(format, args...)
+
+					close(done)
+				}
+
+				close(blocked)
+
+				<-done
+			})
+		})
+	})
+
+	Convey("Given a client never connected", t, func() {
+		conn := &zktest.MockZookeeperConnection{Log: t.Logf}
+		dialer := &zktest.MockZookeeperDialer{Log: t.Logf}
+
+		dialer.On("Dial", "connStr", v1.DEFAULT_SESSION_TIMEOUT, false).Return(conn, make(chan zk.Event), nil).Once()
+
+		client, err := New("connStr", WithDialer(dialer))
+
+		So(err, ShouldBeNil)
+
+		Convey("When the context is done before the connection", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+
+			defer cancel()
+
+			start := time.Now()
+
+			err := client.Start(ctx)
+
+			Convey("Start should return the context error", func() {
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, CONNECT_POLL_INTERVAL)
+			})
+		})
+	})
+
+	Convey("Given an empty connect string", t, func() {
+		client, err := New("")
+
+		So(client, ShouldBeNil)
+		So(err, ShouldNotBeNil)
+	})
+}
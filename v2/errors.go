@@ -0,0 +1,25 @@
+package curator
+
+import (
+	"fmt"
+)
+
+// The error of an operation, wrapping the ZooKeeper or context error
+type OpError struct {
+	Op   string // the failed operation, e.g. "create"
+	Path string // the path of the operation, if any
+	Err  error  // the underlying error, e.g. zk.ErrNoNode or context.DeadlineExceeded
+}
+
+func (e *OpError) Error() string {
+	if len(e.Path) > 0 {
+		return fmt.Sprintf("%s %s: %s", e.Op, e.Path, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Op, e.Err)
+}
+
+// Return the underlying error, so errors.Is(err, zk.ErrNoNode) works
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,176 @@
+package curator
+
+import (
+	"time"
+
+	v1 "github.com/flier/curator.go"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Configures the client built by New
+type Option func(builder *v1.CuratorFrameworkBuilder)
+
+// Stay within the namespace, all the paths are relative to it
+func WithNamespace(namespace string) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.Namespace = namespace
+	}
+}
+
+// Set the session timeout
+func WithSessionTimeout(timeout time.Duration) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.SessionTimeout = timeout
+	}
+}
+
+// Set the connection timeout
+func WithConnectionTimeout(timeout time.Duration) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.ConnectionTimeout = timeout
+	}
+}
+
+// Set the retry policy to use
+func WithRetryPolicy(policy v1.RetryPolicy) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.RetryPolicy = policy
+	}
+}
+
+// Set the provider for ACLs
+func WithACLProvider(provider v1.ACLProvider) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.AclProvider = provider
+	}
+}
+
+// Set the compression provider
+func WithCompressionProvider(provider v1.CompressionProvider) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.CompressionProvider = provider
+	}
+}
+
+// Add connection authorization
+func WithAuthorization(scheme string, auth []byte) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.Authorization(scheme, auth)
+	}
+}
+
+// Add a list of connection authorizations
+func WithAuthInfos(authInfos ...v1.AuthInfo) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.Authorizations(authInfos...)
+	}
+}
+
+// Set the zookeeper dialer to use
+func WithDialer(dialer v1.ZookeeperDialer) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.ZookeeperDialer = dialer
+	}
+}
+
+// Set the data to use when a node is created without data
+func WithDefaultData(data []byte) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.DefaultData = data
+	}
+}
+
+// Set the data to use per path when a node is created without data
+func WithDefaultDataProvider(provider func(path string) []byte) Option {
+	return func(builder *v1.CuratorFrameworkBuilder) {
+		builder.DefaultDataProvider = v1.NewDefaultDataProvider(provider)
+	}
+}
+
+type opOptions struct {
+	mode             v1.CreateMode
+	ttl              time.Duration
+	acls             []zk.ACL
+	version          int32
+	creatingParents  bool
+	protected        bool
+	compressed       bool
+	deletingChildren bool
+	watcher          v1.Watcher
+}
+
+func newOpOptions(opts []OpOption) *opOptions {
+	options := &opOptions{version: v1.AnyVersion}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// Configures an operation of the client
+type OpOption func(options *opOptions)
+
+// Set a create mode - the default is PERSISTENT
+func WithMode(mode v1.CreateMode) OpOption {
+	return func(options *opOptions) {
+		options.mode = mode
+	}
+}
+
+// Set the TTL of the node created with PERSISTENT_WITH_TTL or PERSISTENT_SEQUENTIAL_WITH_TTL
+func WithTTL(ttl time.Duration) OpOption {
+	return func(options *opOptions) {
+		options.ttl = ttl
+	}
+}
+
+// Set an ACL list of the created node
+func WithACL(acls ...zk.ACL) OpOption {
+	return func(options *opOptions) {
+		options.acls = acls
+	}
+}
+
+// Use the given version, the operation fails if the node has another version
+func WithVersion(version int32) OpOption {
+	return func(options *opOptions) {
+		options.version = version
+	}
+}
+
+// Create any parent nodes if they haven't already been
+func CreatingParents() OpOption {
+	return func(options *opOptions) {
+		options.creatingParents = true
+	}
+}
+
+// Prefix the created node name with a GUID, so the node can be found after a connection loss
+func WithProtection() OpOption {
+	return func(options *opOptions) {
+		options.protected = true
+	}
+}
+
+// Compress the written data, or decompress the read data, with the compression provider
+func Compressed() OpOption {
+	return func(options *opOptions) {
+		options.compressed = true
+	}
+}
+
+// Delete the children of the node if they exist
+func DeletingChildren() OpOption {
+	return func(options *opOptions) {
+		options.deletingChildren = true
+	}
+}
+
+// Set a watch on the node with the given function
+func WithWatcher(fn func(event *zk.Event)) OpOption {
+	return func(options *opOptions) {
+		options.watcher = v1.NewWatcher(fn)
+	}
+}
@@ -1,11 +1,14 @@
 package curator
 
 import (
+	"log"
 	"sync"
 
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+// A watcher of the ZooKeeper events.
+// The events of a path are delivered to the watchers in order, see CuratorFrameworkBuilder.WatcherDispatchMode.
 type Watcher interface {
 	process(event *zk.Event)
 }
@@ -22,13 +25,108 @@ func (w *simpleWatcher) process(event *zk.Event) {
 	w.Func(event)
 }
 
+// A watcher delivering the events to a function, with the paths of the events relative to the namespace of the client
+type namespaceWatcher struct {
+	fn    func(event zk.Event)
+	unfix func(path string) string
+}
+
+func (w *namespaceWatcher) process(event *zk.Event) {
+	translated := *event
+
+	translated.Path = w.unfix(event.Path)
+
+	w.fn(translated)
+}
+
+// The ordering of the watched events delivered to the watchers.
+//
+// The events are delivered one at a time per path, or globally with DISPATCH_GLOBAL, so a watcher blocking
+// in its callback stalls the following events of its path. With DISPATCH_GLOBAL it stalls every watcher of the client,
+// including the lock waiters and the caches, so a long running callback should hand the event over to its own go-routine.
+type DispatchMode int
+
+const (
+	DISPATCH_PER_PATH DispatchMode = iota // the events of a path are delivered in order, the events of different paths may be delivered concurrently
+	DISPATCH_GLOBAL                       // all the events are delivered in order, one at a time
+)
+
+// Serializes the dispatched events per path, or globally with DISPATCH_GLOBAL.
+// A queue of a path is drained by its own go-routine, which exits when the queue becomes empty.
+type eventDispatcher struct {
+	mode           DispatchMode
+	lock           sync.Mutex
+	queues         map[string][]func()
+	unhandledError func(err error) // report the panics of the watchers, which are logged by default
+}
+
+var defaultEventDispatcher = newEventDispatcher(DISPATCH_PER_PATH)
+
+func newEventDispatcher(mode DispatchMode) *eventDispatcher {
+	return &eventDispatcher{mode: mode, queues: make(map[string][]func())}
+}
+
+func (d *eventDispatcher) Dispatch(path string, fn func()) {
+	if d.mode == DISPATCH_GLOBAL {
+		path = ""
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if queue, running := d.queues[path]; running {
+		d.queues[path] = append(queue, fn)
+	} else {
+		d.queues[path] = nil
+
+		go d.drain(path, fn)
+	}
+}
+
+func (d *eventDispatcher) drain(path string, fn func()) {
+	for fn != nil {
+		d.run(fn)
+
+		d.lock.Lock()
+
+		if queue := d.queues[path]; len(queue) > 0 {
+			fn, d.queues[path] = queue[0], queue[1:]
+		} else {
+			fn = nil
+
+			delete(d.queues, path)
+		}
+
+		d.lock.Unlock()
+	}
+}
+
+// Run a watcher callback, a panic is reported and the following events of the path are still delivered
+func (d *eventDispatcher) run(fn func()) {
+	handler := d.unhandledError
+
+	if handler == nil {
+		handler = func(err error) { log.Printf("error: %s", err) }
+	}
+
+	defer recoverPanic("Watcher", handler)
+
+	fn()
+}
+
+// A set of watchers, the events of a path are delivered to the watchers in order
 type Watchers struct {
-	lock     sync.Mutex
-	watchers []Watcher
+	lock       sync.Mutex
+	watchers   []Watcher
+	dispatcher *eventDispatcher
 }
 
 func NewWatchers(watchers ...Watcher) *Watchers {
-	return &Watchers{watchers: watchers}
+	return newWatchers(defaultEventDispatcher, watchers...)
+}
+
+func newWatchers(dispatcher *eventDispatcher, watchers ...Watcher) *Watchers {
+	return &Watchers{watchers: watchers, dispatcher: dispatcher}
 }
 
 func (w *Watchers) Len() int { return len(w.watchers) }
@@ -59,9 +157,17 @@ func (w *Watchers) Remove(watcher Watcher) Watcher {
 }
 
 func (w *Watchers) Fire(event *zk.Event) {
+	dispatcher := w.dispatcher
+
+	if dispatcher == nil {
+		dispatcher = defaultEventDispatcher
+	}
+
 	for _, watcher := range w.watchers {
 		if watcher != nil {
-			go watcher.process(event)
+			watcher := watcher
+
+			dispatcher.Dispatch(event.Path, func() { watcher.process(event) })
 		}
 	}
 }
@@ -66,3 +66,63 @@ func TestWatchers(t *testing.T) {
 	assert.Equal(t, 1, len(events[2]))
 	assert.Equal(t, &evt, events[0][1])
 }
+
+func TestEventDispatcher(t *testing.T) {
+	// the events of a path are delivered in order
+	d := newEventDispatcher(DISPATCH_PER_PATH)
+
+	delivered := make(chan int, 100)
+
+	for i := 0; i < 100; i++ {
+		i := i
+
+		d.Dispatch("/node", func() { delivered <- i })
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, i, <-delivered)
+	}
+
+	// the events of different paths are delivered concurrently
+	blocked := make(chan struct{})
+	done := make(chan string, 2)
+
+	d.Dispatch("/a", func() { <-blocked; done <- "/a" })
+	d.Dispatch("/b", func() { done <- "/b" })
+
+	assert.Equal(t, "/b", <-done)
+
+	close(blocked)
+
+	assert.Equal(t, "/a", <-done)
+
+	// all the events are delivered in order with DISPATCH_GLOBAL
+	d = newEventDispatcher(DISPATCH_GLOBAL)
+
+	blocked = make(chan struct{})
+
+	d.Dispatch("/a", func() { <-blocked; done <- "/a" })
+	d.Dispatch("/b", func() { done <- "/b" })
+
+	close(blocked)
+
+	assert.Equal(t, "/a", <-done)
+	assert.Equal(t, "/b", <-done)
+}
+
+func TestEventDispatcherPanic(t *testing.T) {
+	errs := make(chan error, 1)
+
+	d := newEventDispatcher(DISPATCH_PER_PATH)
+
+	d.unhandledError = func(err error) { errs <- err }
+
+	delivered := make(chan int, 1)
+
+	d.Dispatch("/node", func() { panic("boom") })
+	d.Dispatch("/node", func() { delivered <- 1 })
+
+	// the panic is reported and the following events are still delivered
+	assert.EqualError(t, <-errs, "Watcher panicked, boom")
+	assert.Equal(t, 1, <-delivered)
+}
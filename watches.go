@@ -0,0 +1,272 @@
+package curator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// The mode of a persistent watch added with the addWatch opcode, which requires ZooKeeper 3.6.0+
+type AddWatchMode int
+
+const (
+	PERSISTENT_RECURSIVE_WATCH AddWatchMode = iota // watch the node and all its descendants, without the children changed events
+	PERSISTENT_WATCH                               // watch the node for the data changed, children changed, created and deleted events
+)
+
+var ErrPersistentWatchNotSupported = errors.New("the connection doesn't support the persistent watches")
+
+type addWatchBuilder struct {
+	client        *curatorFramework
+	backgrounding backgrounding
+	mode          AddWatchMode
+	watcher       Watcher
+}
+
+func (b *addWatchBuilder) ForPath(givenPath string) error {
+	if err := b.client.compatibility.check("Persistent watches", PERSISTENT_WATCH_SERVER_VERSION); err != nil {
+		return err
+	}
+
+	adjustedPath := b.client.fixForNamespace(givenPath, false)
+
+	if b.backgrounding.inBackground {
+		b.client.runSafe(func() { b.pathInBackground(adjustedPath, givenPath) })
+
+		return nil
+	}
+
+	return b.pathInForeground(adjustedPath)
+}
+
+func (b *addWatchBuilder) pathInBackground(adjustedPath, givenPath string) {
+	tracer := b.client.ZookeeperClient().StartTracer("addWatchBuilder.pathInBackground")
+
+	defer tracer.Commit()
+
+	err := b.pathInForeground(adjustedPath)
+
+	event := &curatorEvent{
+		eventType: ADD_WATCH,
+		err:       err,
+		path:      givenPath,
+		name:      GetNodeFromPath(givenPath),
+		context:   b.backgrounding.context,
+	}
+
+	b.client.processBackgroundEvent(b.backgrounding, event)
+}
+
+func (b *addWatchBuilder) pathInForeground(path string) error {
+	watch := &persistentWatch{path: path, recursive: b.mode == PERSISTENT_RECURSIVE_WATCH, watcher: b.watcher}
+
+	if err := b.client.addPersistentWatch(watch); err != nil {
+		return err
+	}
+
+	if watch.watcher != nil {
+		b.client.persistentWatches.add(watch)
+	}
+
+	if b.client.watcherRemoval != nil {
+		b.client.watcherRemoval.addPersistentWatch(watch)
+	}
+
+	return nil
+}
+
+func (b *addWatchBuilder) WithMode(mode AddWatchMode) AddWatchBuilder {
+	b.mode = mode
+
+	return b
+}
+
+func (b *addWatchBuilder) UsingWatcher(watcher Watcher) AddWatchBuilder {
+	b.watcher = b.client.getNamespaceWatcher(watcher)
+
+	return b
+}
+
+func (b *addWatchBuilder) UsingWatcherFunc(fn func(event zk.Event)) AddWatchBuilder {
+	b.watcher = b.client.newNamespaceWatcher(fn)
+
+	return b
+}
+
+func (b *addWatchBuilder) UsingWatcherChannel(events chan<- zk.Event) AddWatchBuilder {
+	return b.UsingWatcherFunc(func(event zk.Event) { events <- event })
+}
+
+func (b *addWatchBuilder) InBackground() AddWatchBuilder {
+	b.backgrounding = backgrounding{inBackground: true}
+
+	return b
+}
+
+func (b *addWatchBuilder) InBackgroundWithContext(context interface{}) AddWatchBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context}
+
+	return b
+}
+
+func (b *addWatchBuilder) InBackgroundWithCallback(callback BackgroundCallback) AddWatchBuilder {
+	b.backgrounding = backgrounding{inBackground: true, callback: callback}
+
+	return b
+}
+
+func (b *addWatchBuilder) InBackgroundWithCallbackAndContext(callback BackgroundCallback, context interface{}) AddWatchBuilder {
+	b.backgrounding = backgrounding{inBackground: true, context: context, callback: callback}
+
+	return b
+}
+
+type watchesBuilder struct {
+	client *curatorFramework
+}
+
+func (b *watchesBuilder) Add() AddWatchBuilder {
+	return &addWatchBuilder{client: b.client, mode: PERSISTENT_RECURSIVE_WATCH}
+}
+
+// A persistent watch added with a watcher, the path is the full path of the node
+type persistentWatch struct {
+	path      string
+	recursive bool
+	watcher   Watcher
+}
+
+// Return true if the event of the given full path is delivered to the watch
+func (w *persistentWatch) matches(path string) bool {
+	if path == w.path {
+		return true
+	}
+
+	if !w.recursive {
+		return false
+	}
+
+	return w.path == PATH_SEPARATOR || strings.HasPrefix(path, w.path+PATH_SEPARATOR)
+}
+
+// The persistent watches of a client, which deliver the events of the session to their watchers
+type persistentWatches struct {
+	lock    sync.Mutex
+	watches []*persistentWatch
+}
+
+func (w *persistentWatches) add(watch *persistentWatch) {
+	w.lock.Lock()
+
+	w.watches = append(w.watches, watch)
+
+	w.lock.Unlock()
+}
+
+func (w *persistentWatches) remove(watch *persistentWatch) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for i, v := range w.watches {
+		if v == watch {
+			w.watches = append(w.watches[:i], w.watches[i+1:]...)
+
+			return
+		}
+	}
+}
+
+// Return true if the given full path is watched by a watch of the given mode
+func (w *persistentWatches) watching(path string, recursive bool) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for _, watch := range w.watches {
+		if watch.path == path && watch.recursive == recursive {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *persistentWatches) all() []*persistentWatch {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]*persistentWatch(nil), w.watches...)
+}
+
+// Return the watchers of the watches matching the given full path
+func (w *persistentWatches) watchers(path string) []Watcher {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var watchers []Watcher
+
+	for _, watch := range w.watches {
+		if watch.matches(path) {
+			watchers = append(watchers, watch.watcher)
+		}
+	}
+
+	return watchers
+}
+
+func (c *curatorFramework) addPersistentWatch(watch *persistentWatch) error {
+	_, err := newRetryLoopForPath(c.ZookeeperClient(), ADD_WATCH.String(), watch.path).CallWithRetry(func() (interface{}, error) {
+		if conn, err := c.client.Conn(); err != nil {
+			return nil, err
+		} else if watchConn, ok := conn.(PersistentWatchZookeeperConnection); !ok {
+			return nil, ErrPersistentWatchNotSupported
+		} else {
+			return nil, watchConn.AddPersistentWatch(watch.path, watch.recursive)
+		}
+	})
+
+	return err
+}
+
+// Remove the persistent watch, it is removed from the servers unless the path is still watched by the other watches
+func (c *curatorFramework) removePersistentWatch(watch *persistentWatch) error {
+	c.persistentWatches.remove(watch)
+
+	if c.persistentWatches.watching(watch.path, watch.recursive) {
+		return nil
+	}
+
+	_, err := newRetryLoopForPath(c.ZookeeperClient(), "REMOVE_WATCH", watch.path).CallWithRetry(func() (interface{}, error) {
+		if conn, err := c.client.Conn(); err != nil {
+			return nil, err
+		} else if watchConn, ok := conn.(PersistentWatchZookeeperConnection); !ok {
+			return nil, ErrPersistentWatchNotSupported
+		} else {
+			return nil, watchConn.RemovePersistentWatch(watch.path, watch.recursive)
+		}
+	})
+
+	return err
+}
+
+// Deliver the event of the session to the watchers of the persistent watches
+func (c *curatorFramework) firePersistentWatches(event *zk.Event) {
+	if event.Type == zk.EventSession || event.Type == zk.EventNotWatching {
+		return
+	}
+
+	if watchers := c.persistentWatches.watchers(event.Path); len(watchers) > 0 {
+		newWatchers(c.dispatcher, watchers...).Fire(event)
+	}
+}
+
+// Add the persistent watches again, the server drops them with the connection
+func (c *curatorFramework) restorePersistentWatches() {
+	for _, watch := range c.persistentWatches.all() {
+		if err := c.addPersistentWatch(watch); err != nil {
+			c.logError(fmt.Errorf("Trying to restore the persistent watch of %s, %s", watch.path, err))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package curator
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchesBuilderTestSuite struct {
+	mockContainerTestSuite
+}
+
+func TestWatchesBuilder(t *testing.T) {
+	suite.Run(t, new(WatchesBuilderTestSuite))
+}
+
+func (s *WatchesBuilderTestSuite) TestAddWatch() {
+	s.WithNamespace("parent", func(client CuratorFramework, conn *mockConn, events chan zk.Event) {
+		watched := make(chan zk.Event, 4)
+
+		conn.On("Exists", "/parent").Return(true, nil, nil).Once()
+		conn.On("AddPersistentWatch", "/parent/node", true).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Watches().Add().UsingWatcherChannel(watched).ForPath("/node"))
+
+		// the events of the node and its descendants are delivered
+		events <- zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateHasSession, Path: "/parent/node"}
+		events <- zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession, Path: "/parent/node/child"}
+		events <- zk.Event{Type: zk.EventNodeDeleted, State: zk.StateHasSession, Path: "/parent/nodes"}
+		events <- zk.Event{Type: zk.EventNodeDeleted, State: zk.StateHasSession, Path: "/parent/node/child"}
+
+		event := <-watched
+
+		assert.Equal(s.T(), zk.EventNodeDataChanged, event.Type)
+		assert.Equal(s.T(), "/node", event.Path)
+
+		event = <-watched
+
+		assert.Equal(s.T(), zk.EventNodeCreated, event.Type)
+		assert.Equal(s.T(), "/node/child", event.Path)
+
+		event = <-watched
+
+		assert.Equal(s.T(), zk.EventNodeDeleted, event.Type)
+		assert.Equal(s.T(), "/node/child", event.Path)
+	})
+}
+
+func (s *WatchesBuilderTestSuite) TestAddPersistentWatch() {
+	s.With(func(client CuratorFramework, conn *mockConn, events chan zk.Event, wg *sync.WaitGroup) {
+		conn.On("AddPersistentWatch", "/node", false).Return(nil).Once()
+
+		assert.NoError(s.T(), client.Watches().Add().WithMode(PERSISTENT_WATCH).UsingWatcher(NewWatcher(func(event *zk.Event) {
+			defer wg.Done()
+
+			assert.Equal(s.T(), zk.EventNodeChildrenChanged, event.Type)
+			assert.Equal(s.T(), "/node", event.Path)
+		})).ForPath("/node"))
+
+		// the descendants aren't watched
+		events <- zk.Event{Type: zk.EventNodeCreated, State: zk.StateHasSession, Path: "/node/child"}
+		events <- zk.Event{Type: zk.EventNodeChildrenChanged, State: zk.StateHasSession, Path: "/node"}
+	})
+}
+
+func (s *WatchesBuilderTestSuite) TestNotSupported() {
+	s.With(func(client CuratorFramework, conn *mockConn) {
+		conn.On("AddPersistentWatch", "/node", true).Return(ErrUnimplemented).Once()
+
+		assert.Equal(s.T(), ErrUnimplemented, client.Watches().Add().ForPath("/node"))
+	})
+}
+
+func (s *WatchesBuilderTestSuite) TestBackground() {
+	s.With(func(client CuratorFramework, conn *mockConn, wg *sync.WaitGroup) {
+		ctxt := "context"
+
+		conn.On("AddPersistentWatch", "/node", true).Return(nil).Once()
+
+		err := client.Watches().Add().InBackgroundWithCallbackAndContext(
+			func(client CuratorFramework, event CuratorEvent) error {
+				defer wg.Done()
+
+				assert.Equal(s.T(), ADD_WATCH, event.Type())
+				assert.Equal(s.T(), "/node", event.Path())
+				assert.NoError(s.T(), event.Err())
+				assert.Equal(s.T(), ctxt, event.Context())
+
+				return nil
+			}, ctxt).ForPath("/node")
+
+		assert.NoError(s.T(), err)
+	})
+}